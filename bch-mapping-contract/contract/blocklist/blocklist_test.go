@@ -0,0 +1,80 @@
+package blocklist
+
+import (
+	"bch-mapping-contract/contract/constants"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// minedRegtestRun returns n linked regtest headers, each with a nonce that
+// meets the regtest proof of work.
+func minedRegtestRun(t *testing.T, n int) []wire.BlockHeader {
+	t.Helper()
+	params := &chaincfg.RegressionNetParams
+	headers := make([]wire.BlockHeader, n)
+	prev := chainhash.Hash{}
+	for i := range headers {
+		headers[i] = wire.BlockHeader{
+			Version:   4,
+			PrevBlock: prev,
+			Timestamp: time.Unix(1700000000+int64(i)*600, 0),
+			Bits:      params.PowLimitBits,
+		}
+		for {
+			msgBlock := wire.MsgBlock{Header: headers[i]}
+			if blockchain.CheckProofOfWork(btcutil.NewBlock(&msgBlock), params.PowLimit) == nil {
+				break
+			}
+			headers[i].Nonce++
+		}
+		prev = headers[i].BlockHash()
+	}
+	return headers
+}
+
+func TestCheckSeedRun(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	headers := minedRegtestRun(t, 5)
+	const first = 100
+
+	if err := checkSeedRun(params, nil, true, first, headers); err != nil {
+		t.Fatalf("valid run: %v", err)
+	}
+
+	broken := append([]wire.BlockHeader(nil), headers...)
+	broken[3].PrevBlock = chainhash.Hash{1}
+	if err := checkSeedRun(params, nil, true, first, broken); err == nil {
+		t.Error("expected broken linkage to fail")
+	}
+
+	weak := append([]wire.BlockHeader(nil), headers...)
+	weak[2].Bits = 0x1d00ffff
+	if err := checkSeedRun(params, nil, true, first, weak); err == nil {
+		t.Error("expected header without proof of work to fail")
+	}
+
+	match := []constants.Checkpoint{{Height: first + 2, Hash: headers[2].BlockHash().String()}}
+	if err := checkSeedRun(params, match, false, first, headers); err != nil {
+		t.Errorf("matching checkpoint: %v", err)
+	}
+	mismatch := []constants.Checkpoint{{Height: first + 2, Hash: headers[1].BlockHash().String()}}
+	if err := checkSeedRun(params, mismatch, false, first, headers); err == nil {
+		t.Error("expected checkpoint mismatch to fail")
+	}
+
+	// Only networks that refuse old seeds reject a run below the last
+	// checkpoint.
+	later := []constants.Checkpoint{{Height: first + 10, Hash: headers[0].BlockHash().String()}}
+	if err := checkSeedRun(params, later, true, first, headers); err == nil {
+		t.Error("expected seed below the last checkpoint to fail")
+	}
+	if err := checkSeedRun(params, later, false, first, headers); err != nil {
+		t.Errorf("seed below checkpoint on a test network: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package blocklist
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	ce "bch-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BCH has retargeted every block with ASERT (aserti3-2d) since the November
+// 2020 upgrade. The target moves exponentially with how far the parent's
+// timestamp is ahead of or behind an ideal schedule starting at a fixed
+// anchor block, doubling or halving every asertHalfLife seconds.
+const (
+	asertHalfLife      = 2 * 24 * 60 * 60
+	asertTargetSpacing = 600
+)
+
+// asertAnchor is the last block before ASERT activated, and the timestamp of
+// its parent, which starts the ideal schedule.
+type asertAnchor struct {
+	Height     uint32
+	Bits       uint32
+	ParentTime int64
+}
+
+// networkAsertAnchor returns the ASERT anchor for a network. BCH shares
+// Bitcoin's chaincfg params, so the network is told apart by its magic.
+func networkAsertAnchor(params *chaincfg.Params) asertAnchor {
+	if params.Net == wire.MainNet {
+		return asertAnchor{Height: 661647, Bits: 0x1804dafe, ParentTime: 1605447844}
+	}
+	return asertAnchor{Height: 1421481, Bits: 0x1d00ffff, ParentTime: 1605445400}
+}
+
+// calculateASERT returns the target for the block after prev, following
+// BCHN's CalculateASERT. The cubic approximation of 2^x and the truncating
+// divisions are part of consensus and are reproduced exactly.
+func calculateASERT(
+	powLimit *big.Int,
+	anchor asertAnchor,
+	prevHeight uint32,
+	prevTime int64,
+) *big.Int {
+	timeDiff := prevTime - anchor.ParentTime
+	heightDiff := int64(prevHeight) - int64(anchor.Height)
+
+	// 16.16 fixed-point exponent; Go's division truncates towards zero like C++.
+	exponent := (timeDiff - asertTargetSpacing*(heightDiff+1)) * 65536 / asertHalfLife
+	shifts := exponent >> 16
+	frac := uint64(uint16(exponent))
+	factor := 65536 + ((195766423245049*frac + 971821376*frac*frac + 5127*frac*frac*frac + 1<<47) >> 48)
+
+	next := new(big.Int).Mul(blockchain.CompactToBig(anchor.Bits), new(big.Int).SetUint64(factor))
+	shifts -= 16
+	if shifts <= 0 {
+		next.Rsh(next, uint(-shifts))
+	} else {
+		next.Lsh(next, uint(shifts))
+	}
+
+	if next.Sign() == 0 {
+		return big.NewInt(1)
+	}
+	if next.Cmp(powLimit) > 0 {
+		return new(big.Int).Set(powLimit)
+	}
+	return next
+}
+
+// calcRequiredBits returns the compact target that a header at height with
+// the given timestamp must carry, given its parent header. It follows BCHN's
+// GetNextASERTWorkRequired, which needs nothing but the parent and the
+// network's fixed anchor.
+func calcRequiredBits(
+	params *chaincfg.Params,
+	height uint32,
+	prev *wire.BlockHeader,
+	timestamp time.Time,
+) (uint32, error) {
+	anchor := networkAsertAnchor(params)
+	if height <= anchor.Height {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" predates ASERT (height "+
+				strconv.FormatUint(uint64(anchor.Height+1), 10)+")",
+		)
+	}
+
+	// Testnet: a block more than 20 minutes after its parent may be mined at
+	// the minimum difficulty.
+	if params.ReduceMinDifficulty && timestamp.After(prev.Timestamp.Add(params.MinDiffReductionTime)) {
+		return params.PowLimitBits, nil
+	}
+
+	target := calculateASERT(params.PowLimit, anchor, height-1, prev.Timestamp.Unix())
+	return blockchain.BigToCompact(target), nil
+}
+
+// checkDifficulty rejects a header whose bits differ from the network's
+// required target at that height.
+func checkDifficulty(params *chaincfg.Params, height uint32, prev *wire.BlockHeader, header *wire.BlockHeader) error {
+	// Regtest never retargets; its headers are only bounded by PowLimit,
+	// which CheckProofOfWork already enforces.
+	if params.PoWNoRetargeting {
+		return nil
+	}
+
+	expected, err := calcRequiredBits(params, height, prev, header.Timestamp)
+	if err != nil {
+		return err
+	}
+	if header.Bits != expected {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" has unexpected difficulty bits "+
+				strconv.FormatUint(uint64(header.Bits), 16)+", expected "+strconv.FormatUint(uint64(expected), 16),
+		)
+	}
+	return nil
+}
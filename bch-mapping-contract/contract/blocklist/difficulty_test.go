@@ -0,0 +1,123 @@
+package blocklist
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// onSchedule returns the timestamp of the parent of a block at height when
+// every block since the anchor came exactly asertTargetSpacing apart.
+func onSchedule(anchor asertAnchor, height uint32) int64 {
+	return anchor.ParentTime + int64(height-anchor.Height)*asertTargetSpacing
+}
+
+func TestRequiredBitsASERT(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	anchor := networkAsertAnchor(params)
+	anchorTarget := blockchain.CompactToBig(anchor.Bits)
+	const height = 900000
+
+	cases := []struct {
+		name   string
+		offset int64
+		want   *big.Int
+	}{
+		{"on schedule keeps the anchor target", 0, anchorTarget},
+		{"a half-life behind doubles the target", asertHalfLife, new(big.Int).Lsh(anchorTarget, 1)},
+		{"a half-life ahead halves the target", -asertHalfLife, new(big.Int).Rsh(anchorTarget, 1)},
+		{"two half-lives behind quadruples the target", 2 * asertHalfLife, new(big.Int).Lsh(anchorTarget, 2)},
+		{"far behind is capped at PowLimit", 100 * asertHalfLife, params.PowLimit},
+	}
+	for _, c := range cases {
+		prevTime := onSchedule(anchor, height) + c.offset
+		prev := &wire.BlockHeader{Bits: anchor.Bits, Timestamp: time.Unix(prevTime, 0)}
+		got, err := calcRequiredBits(params, height, prev, time.Unix(prevTime+600, 0))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if want := blockchain.BigToCompact(c.want); got != want {
+			t.Errorf("%s: got %08x, want %08x", c.name, got, want)
+		}
+	}
+
+	// Mainnet never allows min-difficulty blocks.
+	prevTime := onSchedule(anchor, height)
+	prev := &wire.BlockHeader{Bits: anchor.Bits, Timestamp: time.Unix(prevTime, 0)}
+	got, err := calcRequiredBits(params, height, prev, time.Unix(prevTime+3*60*60, 0))
+	if err != nil || got != anchor.Bits {
+		t.Fatalf("got %08x, %v; want %08x", got, err, anchor.Bits)
+	}
+
+	// Heights up to the anchor used the old difficulty algorithm.
+	if _, err := calcRequiredBits(params, anchor.Height, prev, prev.Timestamp); err == nil {
+		t.Fatal("expected pre-ASERT height to be rejected")
+	}
+}
+
+func TestCalculateASERTFraction(t *testing.T) {
+	// Half a half-life behind schedule scales the target by sqrt(2), to within
+	// the polynomial approximation's 0.013%.
+	anchor := networkAsertAnchor(&chaincfg.MainNetParams)
+	const prevHeight = 700000
+	prevTime := onSchedule(anchor, prevHeight+1) + asertHalfLife/2
+	got := calculateASERT(chaincfg.MainNetParams.PowLimit, anchor, prevHeight, prevTime)
+
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(got), new(big.Float).SetInt(blockchain.CompactToBig(anchor.Bits))).Float64()
+	if ratio < 1.4140 || ratio > 1.4144 {
+		t.Fatalf("got ratio %f, want sqrt(2)", ratio)
+	}
+}
+
+func TestRequiredBitsTestnetMinDifficulty(t *testing.T) {
+	params := &chaincfg.TestNet3Params
+	anchor := networkAsertAnchor(params)
+	const height = 1600000
+	// Well ahead of schedule, so ASERT alone would not allow PowLimit.
+	prevTime := onSchedule(anchor, height) - asertHalfLife
+	prev := &wire.BlockHeader{Bits: 0x1c7fff80, Timestamp: time.Unix(prevTime, 0)}
+
+	cases := []struct {
+		name  string
+		delay int64
+		want  uint32
+	}{
+		{"more than 20 minutes after the parent", 20*60 + 1, params.PowLimitBits},
+		{"otherwise ASERT", 20 * 60, 0x1c7fff80},
+	}
+	for _, c := range cases {
+		got, err := calcRequiredBits(params, height, prev, time.Unix(prevTime+c.delay, 0))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %08x, want %08x", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCheckDifficulty(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	anchor := networkAsertAnchor(params)
+	const height = 900000
+	prevTime := onSchedule(anchor, height)
+	prev := &wire.BlockHeader{Bits: anchor.Bits, Timestamp: time.Unix(prevTime, 0)}
+
+	header := &wire.BlockHeader{Bits: anchor.Bits, Timestamp: time.Unix(prevTime+600, 0)}
+	if err := checkDifficulty(params, height, prev, header); err != nil {
+		t.Fatal(err)
+	}
+	header.Bits = params.PowLimitBits
+	if err := checkDifficulty(params, height, prev, header); err == nil {
+		t.Fatal("expected unexpected-bits failure")
+	}
+
+	// Regtest never retargets.
+	if err := checkDifficulty(&chaincfg.RegressionNetParams, 1, prev, header); err != nil {
+		t.Fatalf("regtest should skip retargeting: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package blocklist
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// mmrProof builds the sibling path of leaf index among leaves the slow way,
+// by rebuilding the mountain that holds it.
+func mmrProof(leaves []chainhash.Hash, index int) []chainhash.Hash {
+	offset := 0
+	for height := bits.Len(uint(len(leaves))) - 1; height >= 0; height-- {
+		size := 1 << height
+		if len(leaves)&size == 0 {
+			continue
+		}
+		if index >= offset+size {
+			offset += size
+			continue
+		}
+		level := append([]chainhash.Hash(nil), leaves[offset:offset+size]...)
+		pos := index - offset
+		var proof []chainhash.Hash
+		for len(level) > 1 {
+			proof = append(proof, level[pos^1])
+			next := make([]chainhash.Hash, len(level)/2)
+			for i := range next {
+				next[i] = mmrParent(level[2*i], level[2*i+1])
+			}
+			level, pos = next, pos/2
+		}
+		return proof
+	}
+	return nil
+}
+
+func TestHeaderMMR(t *testing.T) {
+	var leaves []chainhash.Hash
+	m := &headerMMR{}
+	for n := 1; n <= 37; n++ {
+		leaf := chainhash.HashH([]byte{byte(n)})
+		leaves = append(leaves, leaf)
+		m.append(leaf)
+		if m.leaves != uint64(n) || len(m.peaks) != bits.OnesCount(uint(n)) {
+			t.Fatalf("after %d leaves: %d leaves, %d peaks", n, m.leaves, len(m.peaks))
+		}
+		for i := range leaves {
+			proof := mmrProof(leaves, i)
+			if !m.verify(uint64(i), leaves[i], proof) {
+				t.Fatalf("%d leaves: proof of leaf %d rejected", n, i)
+			}
+			if m.verify(uint64(i), chainhash.Hash{1}, proof) {
+				t.Fatalf("%d leaves: wrong leaf %d accepted", n, i)
+			}
+			if len(proof) > 0 && m.verify(uint64(i), leaves[i], proof[1:]) {
+				t.Fatalf("%d leaves: short proof of leaf %d accepted", n, i)
+			}
+		}
+		if m.verify(uint64(n), leaves[0], nil) {
+			t.Fatalf("%d leaves: index past the end accepted", n)
+		}
+	}
+}
+
+func TestHeaderMMRArchive(t *testing.T) {
+	m := &headerMMR{}
+	if !m.archive(500, chainhash.Hash{1}) || m.start != 500 {
+		t.Fatalf("first archived header should set the start, got %d", m.start)
+	}
+	if m.archive(502, chainhash.Hash{2}) {
+		t.Error("expected a gap in the archive to be refused")
+	}
+	if !m.archive(501, chainhash.Hash{2}) || m.leaves != 2 {
+		t.Errorf("expected the next height to be archived, got %d leaves", m.leaves)
+	}
+}
+
+func TestHeaderMMRUpdate(t *testing.T) {
+	var leaves []chainhash.Hash
+	m := &headerMMR{}
+	for n := 1; n <= 13; n++ {
+		leaf := chainhash.HashH([]byte{byte(n)})
+		leaves = append(leaves, leaf)
+		m.append(leaf)
+	}
+	for i := range leaves {
+		proof := mmrProof(leaves, i)
+		newLeaf := chainhash.HashH([]byte{byte(i), 0xff})
+		if m.update(uint64(i), chainhash.Hash{1}, newLeaf, proof) {
+			t.Fatalf("update of leaf %d from a wrong leaf accepted", i)
+		}
+		if !m.update(uint64(i), leaves[i], newLeaf, proof) {
+			t.Fatalf("update of leaf %d rejected", i)
+		}
+		if m.verify(uint64(i), leaves[i], proof) {
+			t.Fatalf("old leaf %d still verifies after its update", i)
+		}
+		leaves[i] = newLeaf
+		for j := range leaves {
+			if !m.verify(uint64(j), leaves[j], mmrProof(leaves, j)) {
+				t.Fatalf("after updating leaf %d: proof of leaf %d rejected", i, j)
+			}
+		}
+	}
+}
+
+func TestArchiveLeaf(t *testing.T) {
+	hash := chainhash.Hash{1}
+	if archiveLeaf(hash, nil) != archiveLeaf(hash, []byte{}) {
+		t.Error("a missing observed list should archive as an empty one")
+	}
+	if archiveLeaf(hash, nil) == archiveLeaf(hash, []byte{0}) {
+		t.Error("the observed list should change the leaf")
+	}
+	if archiveLeaf(hash, nil) == archiveLeaf(chainhash.Hash{2}, nil) {
+		t.Error("the block hash should change the leaf")
+	}
+}
@@ -0,0 +1,194 @@
+package mapping
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// CashAddr is Bitcoin Cash's address format: a network prefix, then a base32
+// payload of a version byte and the hash, protected by a 40-bit BCH code.
+// Only the 160-bit P2PKH and P2SH types are supported.
+
+const cashAddrCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	cashAddrTypeP2PKH byte = 0
+	cashAddrTypeP2SH  byte = 1
+)
+
+// cashAddrPrefix returns the CashAddr prefix for a network. BCH shares
+// Bitcoin's chaincfg params, so the network is told apart by its magic.
+func cashAddrPrefix(network *chaincfg.Params) string {
+	switch network.Net {
+	case wire.MainNet:
+		return "bitcoincash"
+	case wire.TestNet:
+		return "bchreg"
+	default:
+		return "bchtest"
+	}
+}
+
+func cashAddrPolymod(values []byte) uint64 {
+	c := uint64(1)
+	for _, d := range values {
+		c0 := byte(c >> 35)
+		c = (c&0x07ffffffff)<<5 ^ uint64(d)
+		if c0&0x01 != 0 {
+			c ^= 0x98f2bc8e61
+		}
+		if c0&0x02 != 0 {
+			c ^= 0x79b76d99e2
+		}
+		if c0&0x04 != 0 {
+			c ^= 0xf33e5fb3c4
+		}
+		if c0&0x08 != 0 {
+			c ^= 0xae2eabe2a8
+		}
+		if c0&0x10 != 0 {
+			c ^= 0x1e4f43e470
+		}
+	}
+	return c ^ 1
+}
+
+// cashAddrChecksumInput is the lower 5 bits of each prefix character, a zero
+// separator, then the payload.
+func cashAddrChecksumInput(prefix string, payload []byte) []byte {
+	values := make([]byte, 0, len(prefix)+1+len(payload)+8)
+	for i := 0; i < len(prefix); i++ {
+		values = append(values, prefix[i]&0x1f)
+	}
+	values = append(values, 0)
+	return append(values, payload...)
+}
+
+// convertBits regroups data from fromBits-bit to toBits-bit values. When pad
+// is false, leftover bits must be zero padding.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, bool) {
+	var acc uint32
+	var bits uint
+	maxV := uint32(1)<<toBits - 1
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxV))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxV))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxV != 0 {
+		return nil, false
+	}
+	return out, true
+}
+
+func encodeCashAddr(prefix string, addrType byte, hash []byte) string {
+	// The size bits of the version byte are 0 for a 160-bit hash.
+	payload, _ := convertBits(append([]byte{addrType << 3}, hash...), 8, 5, true)
+	checksum := cashAddrPolymod(append(cashAddrChecksumInput(prefix, payload), make([]byte, 8)...))
+
+	var b strings.Builder
+	b.Grow(len(prefix) + 1 + len(payload) + 8)
+	b.WriteString(prefix)
+	b.WriteByte(':')
+	for _, v := range payload {
+		b.WriteByte(cashAddrCharset[v])
+	}
+	for i := 0; i < 8; i++ {
+		b.WriteByte(cashAddrCharset[checksum>>(5*(7-i))&0x1f])
+	}
+	return b.String()
+}
+
+// decodeCashAddr parses a CashAddr for the given prefix. The prefix may be
+// omitted, but the address may not mix upper and lower case.
+func decodeCashAddr(address, prefix string) (byte, []byte, error) {
+	lower := strings.ToLower(address)
+	if lower != address && strings.ToUpper(address) != address {
+		return 0, nil, errors.New("mixed case cashaddr")
+	}
+	data := lower
+	if i := strings.IndexByte(lower, ':'); i >= 0 {
+		if lower[:i] != prefix {
+			return 0, nil, errors.New("cashaddr prefix \"" + lower[:i] + "\" is not \"" + prefix + "\"")
+		}
+		data = lower[i+1:]
+	}
+	if len(data) <= 8 {
+		return 0, nil, errors.New("cashaddr too short")
+	}
+
+	values := make([]byte, len(data))
+	for i := 0; i < len(data); i++ {
+		v := strings.IndexByte(cashAddrCharset, data[i])
+		if v < 0 {
+			return 0, nil, errors.New("invalid cashaddr character")
+		}
+		values[i] = byte(v)
+	}
+	if cashAddrPolymod(cashAddrChecksumInput(prefix, values)) != 0 {
+		return 0, nil, errors.New("invalid cashaddr checksum")
+	}
+
+	payload, ok := convertBits(values[:len(values)-8], 5, 8, false)
+	if !ok || len(payload) != 21 {
+		return 0, nil, errors.New("invalid cashaddr payload")
+	}
+	if payload[0]&0x87 != 0 {
+		return 0, nil, errors.New("unsupported cashaddr version")
+	}
+	return payload[0] >> 3, payload[1:], nil
+}
+
+// EncodeAddress returns the CashAddr form of a P2PKH or P2SH address.
+func EncodeAddress(addr btcutil.Address, network *chaincfg.Params) (string, error) {
+	switch a := addr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		return encodeCashAddr(cashAddrPrefix(network), cashAddrTypeP2PKH, a.Hash160()[:]), nil
+	case *btcutil.AddressScriptHash:
+		return encodeCashAddr(cashAddrPrefix(network), cashAddrTypeP2SH, a.Hash160()[:]), nil
+	default:
+		return "", errors.New("only P2PKH and P2SH addresses exist on bitcoin cash")
+	}
+}
+
+// DecodeAddress parses a CashAddr, with or without its prefix, or a legacy
+// base58 address. BCH has no segwit, so bech32 addresses are rejected.
+func DecodeAddress(address string, network *chaincfg.Params) (btcutil.Address, error) {
+	addrType, hash, err := decodeCashAddr(address, cashAddrPrefix(network))
+	if err == nil {
+		switch addrType {
+		case cashAddrTypeP2PKH:
+			return btcutil.NewAddressPubKeyHash(hash, network)
+		case cashAddrTypeP2SH:
+			return btcutil.NewAddressScriptHashFromHash(hash, network)
+		default:
+			return nil, errors.New("unsupported cashaddr type")
+		}
+	}
+	if strings.IndexByte(address, ':') >= 0 {
+		return nil, err
+	}
+
+	addr, legacyErr := btcutil.DecodeAddress(address, network)
+	if legacyErr != nil {
+		return nil, err
+	}
+	switch addr.(type) {
+	case *btcutil.AddressPubKeyHash, *btcutil.AddressScriptHash:
+		return addr, nil
+	default:
+		return nil, errors.New("only P2PKH and P2SH addresses exist on bitcoin cash")
+	}
+}
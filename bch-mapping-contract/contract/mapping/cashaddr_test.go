@@ -0,0 +1,105 @@
+package mapping
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// Hash160 76a04053bda0a88bda5177b86a15c3b29f559873, legacy
+// 1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu, from the CashAddr specification.
+const cashAddrTestHash = "76a04053bda0a88bda5177b86a15c3b29f559873"
+
+func TestCashAddrSpecVectors(t *testing.T) {
+	hash, _ := hex.DecodeString(cashAddrTestHash)
+	p2pkh, _ := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	p2sh, _ := btcutil.NewAddressScriptHashFromHash(hash, &chaincfg.MainNetParams)
+
+	cases := []struct {
+		addr btcutil.Address
+		want string
+	}{
+		{p2pkh, "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"},
+		{p2sh, "bitcoincash:ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq"},
+	}
+	for _, c := range cases {
+		got, err := EncodeAddress(c.addr, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("got %s, want %s", got, c.want)
+		}
+
+		decoded, err := DecodeAddress(c.want, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("decode %s: %v", c.want, err)
+		}
+		if decoded.String() != c.addr.String() {
+			t.Errorf("decode %s: got %s, want %s", c.want, decoded, c.addr)
+		}
+	}
+}
+
+func TestDecodeAddressForms(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	const want = "1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu"
+
+	for _, in := range []string{
+		"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a",
+		"qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a",
+		"BITCOINCASH:QPM2QSZNHKS23Z7629MMS6S4CWEF74VCWVY22GDX6A",
+		want,
+	} {
+		addr, err := DecodeAddress(in, params)
+		if err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if addr.EncodeAddress() != want {
+			t.Errorf("%s: got %s, want %s", in, addr.EncodeAddress(), want)
+		}
+	}
+
+	for _, in := range []string{
+		// mixed case
+		"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6A",
+		// bad checksum
+		"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6q",
+		// testnet prefix on mainnet
+		"bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a",
+		// segwit does not exist on BCH
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	} {
+		if _, err := DecodeAddress(in, params); err == nil {
+			t.Errorf("%s: expected error", in)
+		}
+	}
+}
+
+func TestCashAddrPrefixByNetwork(t *testing.T) {
+	hash, _ := hex.DecodeString(cashAddrTestHash)
+	cases := []struct {
+		params *chaincfg.Params
+		prefix string
+	}{
+		{&chaincfg.MainNetParams, "bitcoincash:"},
+		{&chaincfg.TestNet3Params, "bchtest:"},
+		{&chaincfg.RegressionNetParams, "bchreg:"},
+	}
+	for _, c := range cases {
+		addr, _ := btcutil.NewAddressScriptHashFromHash(hash, c.params)
+		got, err := EncodeAddress(addr, c.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[:len(c.prefix)] != c.prefix {
+			t.Errorf("got %s, want prefix %s", got, c.prefix)
+		}
+		decoded, err := DecodeAddress(got, c.params)
+		if err != nil || decoded.String() != addr.String() {
+			t.Errorf("round trip %s: got %v, %v", got, decoded, err)
+		}
+	}
+}
@@ -47,3 +47,41 @@ func TestBTCC6_ClampHonoursCeiling(t *testing.T) {
 		}
 	}
 }
+
+func TestPushFeeObservation(t *testing.T) {
+	var window []int64
+	for fee := int64(1); fee <= constants.FeeWindowSize+3; fee++ {
+		window = pushFeeObservation(window, fee)
+	}
+	if len(window) != constants.FeeWindowSize {
+		t.Fatalf("window holds %d observations, want %d", len(window), constants.FeeWindowSize)
+	}
+	if window[0] != 4 || window[len(window)-1] != constants.FeeWindowSize+3 {
+		t.Errorf("window should keep the newest observations, got %v", window)
+	}
+}
+
+func TestNextBaseFeeRate(t *testing.T) {
+	cases := []struct {
+		name    string
+		current int64
+		window  []int64
+		want    int64
+	}{
+		{"unset rate takes the first observation", 0, []int64{40}, 40},
+		{"first observation stepped from a set rate", 10, []int64{40}, 12},
+		{"unset rate takes the median", 0, []int64{10, 30, 20}, 20},
+		{"median within the step", 20, []int64{20, 22, 24}, 22},
+		{"one spike does not move the median", 20, []int64{20, 20, 500}, 20},
+		{"one crash does not move the median", 20, []int64{20, 20, 1}, 20},
+		{"rise capped at the step", 100, []int64{400, 400, 400}, 125},
+		{"fall capped at the step", 100, []int64{1, 1, 1}, 75},
+		{"step is at least one", 2, []int64{9, 9, 9}, 3},
+		{"capped at the maximum", constants.MaxBaseFeeRate, []int64{constants.MaxBaseFeeRate, 1000}, constants.MaxBaseFeeRate},
+	}
+	for _, tc := range cases {
+		if got := nextBaseFeeRate(tc.current, tc.window); got != tc.want {
+			t.Errorf("%s: nextBaseFeeRate(%d, %v) = %d, want %d", tc.name, tc.current, tc.window, got, tc.want)
+		}
+	}
+}
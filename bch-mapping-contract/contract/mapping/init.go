@@ -3,26 +3,15 @@ package mapping
 import (
 	"bch-mapping-contract/contract/constants"
 	ce "bch-mapping-contract/contract/contracterrors"
+	"bch-mapping-contract/contract/network"
 	"bch-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
 	"net/url"
 	"strings"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
-func IntializeContractState(publicKeys PublicKeys, networkMode string) (*ContractState, error) {
-	var networkParams *chaincfg.Params
-	switch networkMode {
-	case constants.Testnet:
-		networkParams = &chaincfg.TestNet3Params
-	case constants.Regtest:
-		networkParams = &chaincfg.RegressionNetParams
-	default:
-		networkParams = &chaincfg.MainNetParams
-	}
-
+func IntializeContractState(publicKeys PublicKeys, net *network.Network) (*ContractState, error) {
 	// Load UTXO registry (binary: 9 bytes/entry)
 	var utxos UtxoRegistry
 	utxoState := sdk.StateGetObject(constants.UtxoRegistryKey)
@@ -73,16 +62,16 @@ func IntializeContractState(publicKeys PublicKeys, networkMode string) (*Contrac
 		TxSpendsList:      txSpends,
 		Supply:            supply,
 		PublicKeys:        publicKeys,
-		NetworkParams:     networkParams,
+		Network:           net,
 	}, nil
 }
 
 func InitializeMappingState(
 	publicKeys PublicKeys,
-	networkMode string,
+	net *network.Network,
 	instructions ...string,
 ) (*MappingState, error) {
-	contractState, err := IntializeContractState(publicKeys, networkMode)
+	contractState, err := IntializeContractState(publicKeys, net)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +79,7 @@ func InitializeMappingState(
 	var registry map[string]*AddressMetadata
 	if len(instructions) > 0 {
 		var err error
-		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.NetworkParams)
+		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.Network)
 		if err != nil {
 			return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error unmarshalling address registry")
 		}
@@ -105,7 +94,7 @@ func InitializeMappingState(
 func (cs *ContractState) parseInstructions(
 	publicKeys PublicKeys,
 	instrs []string,
-	networkParams *chaincfg.Params,
+	net *network.Network,
 ) (map[string]*AddressMetadata, error) {
 	parsedInstructions := make([]url.Values, len(instrs))
 	registry := make(map[string]*AddressMetadata, len(instrs))
@@ -149,11 +138,11 @@ func (cs *ContractState) parseInstructions(
 			hasher := sha256.New()
 			hasher.Write([]byte(instr))
 			hashBytes := hasher.Sum(nil)
-			address, _, err := createP2WSHAddressWithBackup(
+			address, _, err := createP2SHAddressWithBackup(
 				publicKeys.Primary,
 				publicKeys.Backup,
 				hashBytes,
-				networkParams,
+				net,
 			)
 			if err != nil {
 				return nil, err
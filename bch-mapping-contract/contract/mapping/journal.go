@@ -0,0 +1,494 @@
+package mapping
+
+import (
+	"bch-mapping-contract/contract/constants"
+	ce "bch-mapping-contract/contract/contracterrors"
+	"bch-mapping-contract/contract/network"
+	"bch-mapping-contract/sdk"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ---------------------------------------------------------------------------
+// Mint journal
+//
+// Every deposit minted by map is journaled under the height it was proven at.
+// When that block is orphaned by a reorg or replacement, its journal moves to
+// the orphaned-mint queue. A deposit re-proven against the new chain before
+// its deadline leaves the queue without minting again; any still queued after
+// the deadline is clawed back from the recipient, with whatever they no longer
+// hold recorded as a protocol deficit.
+//
+// Record layout: 34-byte observed entry (txid + vout) | 8-byte amount BE |
+// 2-byte UTXO id BE | 1-byte recipient length | recipient. Orphaned mints are
+// prefixed with the 4-byte BE height they were proven at and the 4-byte BE
+// deadline height.
+// ---------------------------------------------------------------------------
+
+const mintRecordFixedSize = observedEntrySize + 8 + 2 + 1
+const orphanedMintPrefixSize = 8
+
+type mintRecord struct {
+	Entry     observedEntry
+	Amount    int64
+	UtxoId    uint16
+	Recipient string
+}
+
+type orphanedMint struct {
+	mintRecord
+	Height   uint32 // height the deposit was proven at
+	Deadline uint32 // last height at which it can be re-proven
+}
+
+func appendMintRecord(buf []byte, r *mintRecord) ([]byte, error) {
+	if len(r.Recipient) > 255 {
+		return nil, errors.New("mint recipient too long")
+	}
+	buf = append(buf, r.Entry[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(r.Amount))
+	buf = binary.BigEndian.AppendUint16(buf, r.UtxoId)
+	buf = append(buf, byte(len(r.Recipient)))
+	return append(buf, r.Recipient...), nil
+}
+
+// decodeMintRecord decodes one record from the front of data and returns the
+// number of bytes it used.
+func decodeMintRecord(data []byte) (mintRecord, int, error) {
+	var r mintRecord
+	if len(data) < mintRecordFixedSize {
+		return r, 0, errors.New("truncated mint record")
+	}
+	copy(r.Entry[:], data[:observedEntrySize])
+	off := observedEntrySize
+	r.Amount = int64(binary.BigEndian.Uint64(data[off:]))
+	off += 8
+	r.UtxoId = binary.BigEndian.Uint16(data[off:])
+	off += 2
+	n := int(data[off])
+	off++
+	if len(data) < off+n {
+		return r, 0, errors.New("truncated mint recipient")
+	}
+	r.Recipient = string(data[off : off+n])
+	return r, off + n, nil
+}
+
+func marshalMintJournal(list []mintRecord) ([]byte, error) {
+	var buf []byte
+	for i := range list {
+		var err error
+		if buf, err = appendMintRecord(buf, &list[i]); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func unmarshalMintJournal(data []byte) ([]mintRecord, error) {
+	var out []mintRecord
+	for len(data) > 0 {
+		r, n, err := decodeMintRecord(data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+		data = data[n:]
+	}
+	return out, nil
+}
+
+func marshalOrphanedMints(list []orphanedMint) ([]byte, error) {
+	var buf []byte
+	for i := range list {
+		buf = binary.BigEndian.AppendUint32(buf, list[i].Height)
+		buf = binary.BigEndian.AppendUint32(buf, list[i].Deadline)
+		var err error
+		if buf, err = appendMintRecord(buf, &list[i].mintRecord); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func unmarshalOrphanedMints(data []byte) ([]orphanedMint, error) {
+	var out []orphanedMint
+	for len(data) > 0 {
+		if len(data) < orphanedMintPrefixSize {
+			return nil, errors.New("truncated orphaned mint")
+		}
+		m := orphanedMint{
+			Height:   binary.BigEndian.Uint32(data[0:]),
+			Deadline: binary.BigEndian.Uint32(data[4:]),
+		}
+		r, n, err := decodeMintRecord(data[orphanedMintPrefixSize:])
+		if err != nil {
+			return nil, err
+		}
+		m.mintRecord = r
+		out = append(out, m)
+		data = data[orphanedMintPrefixSize+n:]
+	}
+	return out, nil
+}
+
+func mintJournalKey(blockHeight uint32) string {
+	return constants.MintJournalPrefix + strconv.FormatUint(uint64(blockHeight), 10)
+}
+
+func loadMintJournal(blockHeight uint32) ([]mintRecord, error) {
+	raw := sdk.StateGetObject(mintJournalKey(blockHeight))
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	list, err := unmarshalMintJournal([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding mint journal")
+	}
+	return list, nil
+}
+
+func saveMintJournal(blockHeight uint32, list []mintRecord) error {
+	data, err := marshalMintJournal(list)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error encoding mint journal")
+	}
+	sdk.StateSetObject(mintJournalKey(blockHeight), string(data))
+	return nil
+}
+
+func loadOrphanedMints() ([]orphanedMint, error) {
+	raw := sdk.StateGetObject(constants.OrphanedMintsKey)
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	list, err := unmarshalOrphanedMints([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding orphaned mints")
+	}
+	return list, nil
+}
+
+func saveOrphanedMints(list []orphanedMint) error {
+	if len(list) == 0 {
+		sdk.StateDeleteObject(constants.OrphanedMintsKey)
+		return nil
+	}
+	data, err := marshalOrphanedMints(list)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error encoding orphaned mints")
+	}
+	sdk.StateSetObject(constants.OrphanedMintsKey, string(data))
+	return nil
+}
+
+// takeOrphanedMint removes the orphaned mint for entry from list, if present.
+func takeOrphanedMint(list []orphanedMint, entry observedEntry) ([]orphanedMint, mintRecord, bool) {
+	for i := range list {
+		if list[i].Entry == entry {
+			r := list[i].mintRecord
+			return slices.Delete(list, i, i+1), r, true
+		}
+	}
+	return list, mintRecord{}, false
+}
+
+// splitExpiredMints separates the orphaned mints whose deadline has passed at
+// lastHeight from those that can still be re-proven.
+func splitExpiredMints(list []orphanedMint, lastHeight uint32) (expired, pending []orphanedMint) {
+	for _, m := range list {
+		if lastHeight > m.Deadline {
+			expired = append(expired, m)
+		} else {
+			pending = append(pending, m)
+		}
+	}
+	return expired, pending
+}
+
+// OrphanMints moves the journaled deposits of heights from..to, which are no
+// longer on the stored chain, to the orphaned-mint queue. They can be
+// re-proven until MintReproveWindow blocks past lastHeight, the new tip.
+func OrphanMints(from, to, lastHeight uint32) error {
+	orphaned, err := loadOrphanedMints()
+	if err != nil {
+		return err
+	}
+	deadline := lastHeight + constants.MintReproveWindow
+	added := 0
+	for h := from; h <= to; h++ {
+		journal, err := loadMintJournal(h)
+		if err != nil {
+			return err
+		}
+		if len(journal) == 0 {
+			continue
+		}
+		for _, r := range journal {
+			orphaned = append(orphaned, orphanedMint{mintRecord: r, Height: h, Deadline: deadline})
+		}
+		sdk.StateDeleteObject(mintJournalKey(h))
+		sdk.Log(createOrphanLog(h, len(journal), deadline))
+		added += len(journal)
+	}
+	if added == 0 {
+		return nil
+	}
+	return saveOrphanedMints(orphaned)
+}
+
+// SettleOrphanedMints claws back every orphaned deposit not re-proven by its
+// deadline. The recipient's balance is debited up to the minted amount and
+// the shortfall is added to the protocol deficit. The deposit's UTXO, which
+// never confirmed, is dropped from the registry, and its observed entry is
+// cleared so a later genuine inclusion at that height can still be mapped.
+// Deposits that are still pending are cancelled instead.
+func SettleOrphanedMints(lastHeight uint32, net *network.Network) error {
+	orphaned, err := loadOrphanedMints()
+	if err != nil || len(orphaned) == 0 {
+		return err
+	}
+	expired, pending := splitExpiredMints(orphaned, lastHeight)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	cs, err := IntializeContractState(PublicKeys{}, net)
+	if err != nil {
+		return err
+	}
+
+	var deficit int64
+	for _, m := range expired {
+		// A deposit still pending was never credited, so there is nothing
+		// to claw back.
+		cancelled, err := cancelPendingDeposit(m.Entry)
+		if err != nil {
+			return err
+		}
+		if cancelled != nil {
+			// Only deposits proven before outputs were held have one in
+			// the pool.
+			if cancelled.Utxo == nil {
+				if err := cs.dropOrphanedUtxo(m.UtxoId, m.Entry); err != nil {
+					return err
+				}
+			}
+			removeObserved(m.Height, m.Entry)
+			continue
+		}
+
+		bal := getAccBal(m.Recipient)
+		taken := min(bal, m.Amount)
+		setAccBal(m.Recipient, bal-taken)
+
+		if cs.Supply.ActiveSupply, err = safeSubtract64(cs.Supply.ActiveSupply, taken); err != nil {
+			return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing active supply")
+		}
+		if cs.Supply.UserSupply, err = safeSubtract64(cs.Supply.UserSupply, taken); err != nil {
+			return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing user supply")
+		}
+		if deficit, err = safeAdd64(deficit, m.Amount-taken); err != nil {
+			return ce.WrapContractError(ce.ErrArithmetic, err, "error accumulating mint deficit")
+		}
+
+		if err := cs.dropOrphanedUtxo(m.UtxoId, m.Entry); err != nil {
+			return err
+		}
+		removeObserved(m.Height, m.Entry)
+		sdk.Log(createClawbackLog(&m.mintRecord, taken, m.Amount-taken))
+	}
+
+	if err := addMintDeficit(deficit); err != nil {
+		return err
+	}
+	if err := saveOrphanedMints(pending); err != nil {
+		return err
+	}
+	return cs.SaveToState()
+}
+
+// dropOrphanedUtxo removes the UTXO created for an orphaned deposit, if it is
+// still in the registry and has not been reused for another output. If it
+// has left the registry, a withdrawal has spent it.
+func (cs *ContractState) dropOrphanedUtxo(id uint16, entry observedEntry) error {
+	i := slices.IndexFunc(cs.UtxoList, func(e UtxoRegistryEntry) bool { return e.Id == id })
+	if i < 0 {
+		return cs.flagOrphanedSpends(entry)
+	}
+	utxo, err := loadUtxo(id)
+	if err != nil {
+		return err
+	}
+	same, err := isUtxoOf(utxo, entry)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return cs.flagOrphanedSpends(entry)
+	}
+	cs.UtxoList = slices.Delete(cs.UtxoList, i, i+1)
+	sdk.StateDeleteObject(getUtxoKey(id))
+	return nil
+}
+
+// isUtxoOf reports whether utxo is the output of the observed entry.
+func isUtxoOf(utxo *Utxo, entry observedEntry) (bool, error) {
+	utxoEntry, err := makeObservedEntry(utxo.TxId, utxo.Vout)
+	if err != nil {
+		return false, ce.WrapContractError(ce.ErrStateAccess, err, "invalid utxo txid")
+	}
+	return utxoEntry == entry, nil
+}
+
+// flagOrphanedSpends flags every pending spend of the orphaned deposit entry.
+// Such a spend can never confirm, so it is logged for the owner to cancel, and
+// the deposit is marked spent so that cancelling it does not restore the
+// deposit to the pool. Spends of it already cancelled are forgotten, as they
+// can no longer confirm either.
+func (cs *ContractState) flagOrphanedSpends(entry observedEntry) error {
+	hash, err := chainhash.NewHashFromStr(hex.EncodeToString(entry[:32]))
+	if err != nil {
+		return ce.WrapContractError(ce.ErrStateAccess, err, "invalid orphaned deposit txid")
+	}
+	outPoint := wire.OutPoint{Hash: *hash, Index: uint32(binary.BigEndian.Uint16(entry[32:]))}
+	flagged := false
+	for _, txId := range cs.TxSpendsList {
+		sd, err := loadSigningData(txId)
+		if err != nil {
+			return err
+		}
+		if sd == nil {
+			continue
+		}
+		var tx wire.MsgTx
+		if err := tx.Deserialize(bytes.NewReader(sd.Tx)); err != nil {
+			return ce.WrapContractError(ce.ErrTransaction, err, "error deserializing pending spend")
+		}
+		if !slices.ContainsFunc(tx.TxIn, func(in *wire.TxIn) bool { return in.PreviousOutPoint == outPoint }) {
+			continue
+		}
+		flagged = true
+		sdk.Log(createOrphanSpendLog(entry, txId))
+	}
+	if flagged {
+		sdk.StateSetObject(constants.SpentInputPrefix+outPoint.String(), "1")
+	}
+	return forgetCancelledSpendsOf(outPoint)
+}
+
+func removeObserved(blockHeight uint32, entry observedEntry) {
+	list := loadObservedList(blockHeight)
+	i := slices.Index(list, entry)
+	if i < 0 {
+		return
+	}
+	list = slices.Delete(list, i, i+1)
+	if len(list) == 0 {
+		DeleteObservedList(blockHeight)
+		return
+	}
+	saveObservedList(blockHeight, list)
+}
+
+func getMintDeficit() int64 {
+	s := sdk.StateGetObject(constants.MintDeficitKey)
+	if s == nil || *s == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(*s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// addMintDeficit adds amount to the protocol deficit.
+func addMintDeficit(amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	total, err := safeAdd64(getMintDeficit(), amount)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing mint deficit")
+	}
+	sdk.StateSetObject(constants.MintDeficitKey, strconv.FormatInt(total, 10))
+	return nil
+}
+
+// observedEntryString formats an observed entry as txid:vout.
+func observedEntryString(entry observedEntry) string {
+	return hex.EncodeToString(entry[:32]) + ":" +
+		strconv.FormatUint(uint64(binary.BigEndian.Uint16(entry[32:])), 10)
+}
+
+// createOrphanLog records the deposits of an orphaned height entering the
+// re-proof queue: the height, the number of deposits and their deadline.
+func createOrphanLog(blockHeight uint32, count int, deadline uint32) string {
+	var b strings.Builder
+	b.Grow(64)
+	b.WriteString("orphan")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("h")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(blockHeight), 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("n")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.Itoa(count))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("d")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(deadline), 10))
+	return b.String()
+}
+
+// createOrphanSpendLog records a pending spend that spends an orphaned
+// deposit and so can never confirm: the deposit output and the spend.
+func createOrphanSpendLog(entry observedEntry, txId string) string {
+	var b strings.Builder
+	b.Grow(160)
+	b.WriteString("orphanspend")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("o")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(observedEntryString(entry))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("s")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(txId)
+	return b.String()
+}
+
+// createClawbackLog records the reversal of an orphaned deposit: the output,
+// the recipient, the amount clawed back and the shortfall left as deficit.
+func createClawbackLog(r *mintRecord, taken, deficit int64) string {
+	var b strings.Builder
+	b.Grow(160)
+	b.WriteString("clawback")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("o")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(observedEntryString(r.Entry))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("t")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(r.Recipient)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("a")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatInt(taken, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("d")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatInt(deficit, 10))
+	return b.String()
+}
@@ -15,7 +15,122 @@ var (
 	_ tinyjson.Marshaler
 )
 
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp(in *jlexer.Lexer, out *VerificationRequest) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp(in *jlexer.Lexer, out *WithdrawalInfo) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = uint64(in.Uint64())
+		case "status":
+			out.Status = string(in.String())
+		case "from":
+			out.From = string(in.String())
+		case "to":
+			out.To = string(in.String())
+		case "deducted":
+			out.Deducted = string(in.String())
+		case "sent":
+			out.Sent = string(in.String())
+		case "tx_id":
+			out.TxId = string(in.String())
+		case "requested_at":
+			out.RequestedAt = uint64(in.Uint64())
+		case "updated_at":
+			out.UpdatedAt = uint64(in.Uint64())
+		case "confirmed_at":
+			out.ConfirmedAt = uint32(in.Uint32())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp(out *jwriter.Writer, in WithdrawalInfo) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Uint64(uint64(in.Id))
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.String(string(in.Status))
+	}
+	{
+		const prefix string = ",\"from\":"
+		out.RawString(prefix)
+		out.String(string(in.From))
+	}
+	{
+		const prefix string = ",\"to\":"
+		out.RawString(prefix)
+		out.String(string(in.To))
+	}
+	{
+		const prefix string = ",\"deducted\":"
+		out.RawString(prefix)
+		out.String(string(in.Deducted))
+	}
+	{
+		const prefix string = ",\"sent\":"
+		out.RawString(prefix)
+		out.String(string(in.Sent))
+	}
+	if in.TxId != "" {
+		const prefix string = ",\"tx_id\":"
+		out.RawString(prefix)
+		out.String(string(in.TxId))
+	}
+	{
+		const prefix string = ",\"requested_at\":"
+		out.RawString(prefix)
+		out.Uint64(uint64(in.RequestedAt))
+	}
+	{
+		const prefix string = ",\"updated_at\":"
+		out.RawString(prefix)
+		out.Uint64(uint64(in.UpdatedAt))
+	}
+	if in.ConfirmedAt != 0 {
+		const prefix string = ",\"confirmed_at\":"
+		out.RawString(prefix)
+		out.Uint32(uint32(in.ConfirmedAt))
+	}
+	out.RawByte('}')
+}
+
+// MarshalTinyJSON supports tinyjson.Marshaler interface
+func (v WithdrawalInfo) MarshalTinyJSON(w *jwriter.Writer) {
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp(w, v)
+}
+
+// UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
+func (v *WithdrawalInfo) UnmarshalTinyJSON(l *jlexer.Lexer) {
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp(l, v)
+}
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp1(in *jlexer.Lexer, out *VerificationRequest) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -42,6 +157,16 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp(in *jlex
 			out.MerkleProofHex = string(in.String())
 		case "tx_index":
 			out.TxIndex = uint32(in.Uint32())
+		case "coinbase_tx_hex":
+			out.CoinbaseTxHex = string(in.String())
+		case "coinbase_merkle_proof_hex":
+			out.CoinbaseProofHex = string(in.String())
+		case "archival_header_hex":
+			out.ArchivalHeaderHex = string(in.String())
+		case "archive_proof_hex":
+			out.ArchiveProofHex = string(in.String())
+		case "archived_observed_hex":
+			out.ArchivedObservedHex = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -52,7 +177,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp(in *jlex
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp(out *jwriter.Writer, in VerificationRequest) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp1(out *jwriter.Writer, in VerificationRequest) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -76,19 +201,44 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp(out *jwr
 		out.RawString(prefix)
 		out.Uint32(uint32(in.TxIndex))
 	}
+	if in.CoinbaseTxHex != "" {
+		const prefix string = ",\"coinbase_tx_hex\":"
+		out.RawString(prefix)
+		out.String(string(in.CoinbaseTxHex))
+	}
+	if in.CoinbaseProofHex != "" {
+		const prefix string = ",\"coinbase_merkle_proof_hex\":"
+		out.RawString(prefix)
+		out.String(string(in.CoinbaseProofHex))
+	}
+	if in.ArchivalHeaderHex != "" {
+		const prefix string = ",\"archival_header_hex\":"
+		out.RawString(prefix)
+		out.String(string(in.ArchivalHeaderHex))
+	}
+	if in.ArchiveProofHex != "" {
+		const prefix string = ",\"archive_proof_hex\":"
+		out.RawString(prefix)
+		out.String(string(in.ArchiveProofHex))
+	}
+	if in.ArchivedObservedHex != "" {
+		const prefix string = ",\"archived_observed_hex\":"
+		out.RawString(prefix)
+		out.String(string(in.ArchivedObservedHex))
+	}
 	out.RawByte('}')
 }
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v VerificationRequest) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp1(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *VerificationRequest) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp1(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp1(in *jlexer.Lexer, out *TransferParams) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp2(in *jlexer.Lexer, out *TransferParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -135,7 +285,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp1(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp1(out *jwriter.Writer, in TransferParams) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp2(out *jwriter.Writer, in TransferParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -169,14 +319,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp1(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v TransferParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp1(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp2(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *TransferParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp1(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp2(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp2(in *jlexer.Lexer, out *SwapResult) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp3(in *jlexer.Lexer, out *SwapResult) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -209,7 +359,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp2(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp2(out *jwriter.Writer, in SwapResult) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp3(out *jwriter.Writer, in SwapResult) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -228,14 +378,73 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp2(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v SwapResult) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp2(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp3(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *SwapResult) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp2(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp3(l, v)
+}
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp4(in *jlexer.Lexer, out *SetMinConfirmationsParams) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "action":
+			out.Action = string(in.String())
+		case "confirmations":
+			out.Confirmations = uint32(in.Uint32())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp4(out *jwriter.Writer, in SetMinConfirmationsParams) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"action\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Action))
+	}
+	{
+		const prefix string = ",\"confirmations\":"
+		out.RawString(prefix)
+		out.Uint32(uint32(in.Confirmations))
+	}
+	out.RawByte('}')
+}
+
+// MarshalTinyJSON supports tinyjson.Marshaler interface
+func (v SetMinConfirmationsParams) MarshalTinyJSON(w *jwriter.Writer) {
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp4(w, v)
+}
+
+// UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
+func (v *SetMinConfirmationsParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp4(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp3(in *jlexer.Lexer, out *RouterContract) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp5(in *jlexer.Lexer, out *RouterContract) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -266,7 +475,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp3(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp3(out *jwriter.Writer, in RouterContract) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp5(out *jwriter.Writer, in RouterContract) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -280,14 +489,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp3(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v RouterContract) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp3(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp5(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *RouterContract) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp3(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp5(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp4(in *jlexer.Lexer, out *RegisterKeyParams) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp6(in *jlexer.Lexer, out *RegisterKeyParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -320,7 +529,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp4(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp4(out *jwriter.Writer, in RegisterKeyParams) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp6(out *jwriter.Writer, in RegisterKeyParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -345,14 +554,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp4(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v RegisterKeyParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp4(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp6(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *RegisterKeyParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp4(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp6(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp5(in *jlexer.Lexer, out *PoolInfo) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp7(in *jlexer.Lexer, out *PoolInfo) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -393,7 +602,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp5(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp5(out *jwriter.Writer, in PoolInfo) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp7(out *jwriter.Writer, in PoolInfo) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -432,14 +641,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp5(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v PoolInfo) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp5(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp7(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *PoolInfo) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp5(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp7(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp6(in *jlexer.Lexer, out *MapParams) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp8(in *jlexer.Lexer, out *MapParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -501,7 +710,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp6(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp6(out *jwriter.Writer, in MapParams) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp8(out *jwriter.Writer, in MapParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -535,14 +744,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp6(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v MapParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp6(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp8(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *MapParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp6(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp8(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp7(in *jlexer.Lexer, out *DexInstruction) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp9(in *jlexer.Lexer, out *DexInstruction) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -609,7 +818,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp7(in *jle
 				if out.ReturnAddress == nil {
 					out.ReturnAddress = new(ReturnAddress)
 				}
-				tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp8(in, out.ReturnAddress)
+				tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp10(in, out.ReturnAddress)
 			}
 		case "metadata":
 			if in.IsNull() {
@@ -645,7 +854,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp7(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp7(out *jwriter.Writer, in DexInstruction) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp9(out *jwriter.Writer, in DexInstruction) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -692,7 +901,7 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp7(out *jw
 	if in.ReturnAddress != nil {
 		const prefix string = ",\"return_address\":"
 		out.RawString(prefix)
-		tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp8(out, *in.ReturnAddress)
+		tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp10(out, *in.ReturnAddress)
 	}
 	if len(in.Metadata) != 0 {
 		const prefix string = ",\"metadata\":"
@@ -728,14 +937,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp7(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v DexInstruction) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp7(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp9(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *DexInstruction) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp7(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp9(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp8(in *jlexer.Lexer, out *ReturnAddress) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp10(in *jlexer.Lexer, out *ReturnAddress) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -768,7 +977,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp8(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp8(out *jwriter.Writer, in ReturnAddress) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp10(out *jwriter.Writer, in ReturnAddress) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -784,7 +993,7 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp8(out *jw
 	}
 	out.RawByte('}')
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp9(in *jlexer.Lexer, out *ConfirmSpendParams) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp11(in *jlexer.Lexer, out *ConfirmSpendParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -846,7 +1055,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp9(in *jle
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp9(out *jwriter.Writer, in ConfirmSpendParams) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp11(out *jwriter.Writer, in ConfirmSpendParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -880,14 +1089,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp9(out *jw
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v ConfirmSpendParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp9(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp11(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *ConfirmSpendParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp9(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp11(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp10(in *jlexer.Lexer, out *AllowanceParams) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp12(in *jlexer.Lexer, out *AllowanceParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -920,7 +1129,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp10(in *jl
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp10(out *jwriter.Writer, in AllowanceParams) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp12(out *jwriter.Writer, in AllowanceParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -939,14 +1148,14 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp10(out *j
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v AllowanceParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp10(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp12(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *AllowanceParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp10(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp12(l, v)
 }
-func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp11(in *jlexer.Lexer, out *AccountInfo) {
+func tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp13(in *jlexer.Lexer, out *AccountInfo) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -979,7 +1188,7 @@ func tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp11(in *jl
 		in.Consumed()
 	}
 }
-func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp11(out *jwriter.Writer, in AccountInfo) {
+func tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp13(out *jwriter.Writer, in AccountInfo) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -998,10 +1207,10 @@ func tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp11(out *j
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v AccountInfo) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjsonA043f2bcEncodeBtcMappingContractContractMappingTinyjsonTmp11(w, v)
+	tinyjsonA043f2bcEncodeBchMappingContractContractMappingTinyjsonTmp13(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *AccountInfo) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjsonA043f2bcDecodeBtcMappingContractContractMappingTinyjsonTmp11(l, v)
+	tinyjsonA043f2bcDecodeBchMappingContractContractMappingTinyjsonTmp13(l, v)
 }
@@ -0,0 +1,92 @@
+package mapping
+
+import (
+	"bch-mapping-contract/contract/constants"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPendingDepositRoundTrip(t *testing.T) {
+	p := pendingDeposit{
+		mintRecord: mintRecord{Entry: testObservedEntry(t, "e", 2), Amount: 25000, UtxoId: 1500, Recipient: "hive:milo-hpr"},
+		ReadyAt:    106,
+		Height:     100,
+	}
+	data, err := marshalPendingDeposit(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unmarshalPendingDeposit(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != p {
+		t.Fatalf("got %+v, want %+v", *got, p)
+	}
+	if _, err := unmarshalPendingDeposit(append(data, 0)); err == nil {
+		t.Error("expected trailing bytes to fail")
+	}
+
+	// A held deposit carries the output it adds to the pool once credited.
+	held := p
+	held.UtxoId = 0
+	held.Utxo = &Utxo{TxId: strings.Repeat("e", 64), Vout: 2, Amount: 25000, PkScript: []byte{0x00, 0x20, 0x01}, Tag: []byte{0x02}}
+	data, err = marshalPendingDeposit(&held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = unmarshalPendingDeposit(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.mintRecord != held.mintRecord || got.Utxo == nil || !reflect.DeepEqual(*got.Utxo, *held.Utxo) {
+		t.Fatalf("got %+v, want %+v", *got, held)
+	}
+
+	queue := []pendingQueueEntry{{ReadyAt: 106, Entry: p.Entry}, {ReadyAt: 110, Entry: testObservedEntry(t, "f", 0)}}
+	gotQueue, err := unmarshalPendingQueue(marshalPendingQueue(queue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotQueue) != 2 || gotQueue[0] != queue[0] || gotQueue[1] != queue[1] {
+		t.Fatalf("got %+v, want %+v", gotQueue, queue)
+	}
+}
+
+func TestReadyDeposits(t *testing.T) {
+	a := pendingQueueEntry{ReadyAt: 105, Entry: testObservedEntry(t, "a", 0)}
+	b := pendingQueueEntry{ReadyAt: 106, Entry: testObservedEntry(t, "b", 0)}
+	c := pendingQueueEntry{ReadyAt: 110, Entry: testObservedEntry(t, "c", 0)}
+	queue := []pendingQueueEntry{a, b, c}
+
+	ready, rest := readyDeposits(queue, nil, 106, 10)
+	if len(ready) != 2 || ready[0] != a || ready[1] != b || len(rest) != 1 || rest[0] != c {
+		t.Fatalf("got ready %+v, rest %+v", ready, rest)
+	}
+
+	// The limit holds back deposits that are otherwise ready.
+	ready, rest = readyDeposits(queue, nil, 110, 2)
+	if len(ready) != 2 || len(rest) != 1 || rest[0] != c {
+		t.Fatalf("limit: got ready %+v, rest %+v", ready, rest)
+	}
+
+	// Deposits whose block was orphaned wait to be re-proven.
+	orphaned := []orphanedMint{{mintRecord: mintRecord{Entry: a.Entry}}}
+	ready, rest = readyDeposits(queue, orphaned, 106, 10)
+	if len(ready) != 1 || ready[0] != b || len(rest) != 2 {
+		t.Fatalf("orphaned: got ready %+v, rest %+v", ready, rest)
+	}
+}
+
+func TestCoinbaseMaturesAt(t *testing.T) {
+	if got := coinbaseMaturesAt(100, 150); got != 100+constants.CoinbaseMaturity {
+		t.Errorf("immature: got %d, want %d", got, 100+constants.CoinbaseMaturity)
+	}
+	if got := coinbaseMaturesAt(100, 99+constants.CoinbaseMaturity); got == 0 {
+		t.Error("one block short of maturity: expected a maturity height")
+	}
+	if got := coinbaseMaturesAt(100, 100+constants.CoinbaseMaturity); got != 0 {
+		t.Errorf("mature: got %d, want 0", got)
+	}
+}
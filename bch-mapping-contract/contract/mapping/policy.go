@@ -0,0 +1,56 @@
+package mapping
+
+import "bch-mapping-contract/contract/constants"
+
+// FeeUnit is the transaction size a fee rate is quoted per.
+type FeeUnit uint8
+
+const (
+	// FeePerByte rates are per serialized byte.
+	FeePerByte FeeUnit = iota
+	// FeePerKB rates are per started 1000 serialized bytes.
+	FeePerKB
+)
+
+// ChainPolicy is the relay policy of the network that withdrawal transactions
+// are built to meet. Amounts and fee rates are in the chain's base unit.
+type ChainPolicy struct {
+	FeeUnit FeeUnit
+	// DustLimit is the largest output value the network treats as dust. No
+	// output at or below it is created, and no withdrawal that small accepted.
+	DustLimit int64
+	// MinFeeRate and MaxFeeRate bound the oracle's base fee rate.
+	MinFeeRate int64
+	MaxFeeRate int64
+	// SplitThreshold is the change amount per change output, up to
+	// maxChangeOutputs, so later withdrawals have more UTXOs to draw on.
+	SplitThreshold int64
+	// VscFeeMin is the minimum VSC protocol fee, and VscFeeRateBps the fee in
+	// basis points (1 bps = 0.01%) of the amount withdrawn.
+	VscFeeMin     int64
+	VscFeeRateBps int64
+}
+
+// chainPolicy follows Bitcoin Cash Node's relay policy: BCH has no witness
+// discount, so fee rates are per serialized byte, and the dust limit is the
+// 546 sat of a P2PKH output.
+var chainPolicy = ChainPolicy{
+	FeeUnit:        FeePerByte,
+	DustLimit:      546,
+	MinFeeRate:     1,
+	MaxFeeRate:     constants.MaxBaseFeeRate,
+	SplitThreshold: 1000000, // 0.01 BCH
+}
+
+// isDust reports whether amount is too small for an output to relay.
+func (p *ChainPolicy) isDust(amount int64) bool {
+	return amount <= p.DustLimit
+}
+
+// fee returns the fee at rate for a transaction of size serialized bytes.
+func (p *ChainPolicy) fee(size, rate int64) (int64, error) {
+	if p.FeeUnit == FeePerKB {
+		size = (size + 999) / 1000
+	}
+	return safeMultiply64(size, rate)
+}
@@ -0,0 +1,36 @@
+package mapping
+
+import "testing"
+
+func TestChainPolicyFee(t *testing.T) {
+	cases := []struct {
+		unit FeeUnit
+		size int64
+		rate int64
+		want int64
+	}{
+		{FeePerByte, 226, 2, 452},
+		{FeePerKB, 226, 1000, 1000},
+		{FeePerKB, 1000, 1000, 1000},
+		{FeePerKB, 1001, 1000, 2000},
+	}
+	for _, c := range cases {
+		p := ChainPolicy{FeeUnit: c.unit}
+		got, err := p.fee(c.size, c.rate)
+		if err != nil {
+			t.Fatalf("fee(%d, %d): %v", c.size, c.rate, err)
+		}
+		if got != c.want {
+			t.Errorf("unit %d: fee(%d, %d) = %d, want %d", c.unit, c.size, c.rate, got, c.want)
+		}
+	}
+}
+
+func TestChainPolicyDust(t *testing.T) {
+	if !chainPolicy.isDust(chainPolicy.DustLimit) {
+		t.Error("an output at the dust limit should be dust")
+	}
+	if chainPolicy.isDust(chainPolicy.DustLimit + 1) {
+		t.Error("an output above the dust limit should not be dust")
+	}
+}
@@ -0,0 +1,69 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// SigHashForkID marks a signature as committing to the BCH replay-protected
+// digest. BCH nodes reject signatures without it.
+const SigHashForkID txscript.SigHashType = 0x40
+
+// SigHashAllForkID is the hash type of every signature the contract requests,
+// and the byte the broadcaster appends to each DER signature.
+const SigHashAllForkID = txscript.SigHashAll | SigHashForkID
+
+// calcSignatureHash returns the digest signed for input idx under
+// SIGHASH_ALL|FORKID. It is BIP143's algorithm applied to a legacy
+// transaction, with the fork ID (0 for BCH) in the hash type's upper bits.
+// scriptCode is the P2SH redeem script and amount the value of the spent output.
+func calcSignatureHash(scriptCode []byte, tx *wire.MsgTx, idx int, amount int64) []byte {
+	var buf bytes.Buffer
+	var tmp [8]byte
+
+	for _, in := range tx.TxIn {
+		buf.Write(in.PreviousOutPoint.Hash[:])
+		binary.LittleEndian.PutUint32(tmp[:4], in.PreviousOutPoint.Index)
+		buf.Write(tmp[:4])
+	}
+	hashPrevouts := chainhash.DoubleHashH(buf.Bytes())
+
+	buf.Reset()
+	for _, in := range tx.TxIn {
+		binary.LittleEndian.PutUint32(tmp[:4], in.Sequence)
+		buf.Write(tmp[:4])
+	}
+	hashSequence := chainhash.DoubleHashH(buf.Bytes())
+
+	buf.Reset()
+	for _, out := range tx.TxOut {
+		_ = wire.WriteTxOut(&buf, 0, 0, out)
+	}
+	hashOutputs := chainhash.DoubleHashH(buf.Bytes())
+
+	in := tx.TxIn[idx]
+	buf.Reset()
+	binary.LittleEndian.PutUint32(tmp[:4], uint32(tx.Version))
+	buf.Write(tmp[:4])
+	buf.Write(hashPrevouts[:])
+	buf.Write(hashSequence[:])
+	buf.Write(in.PreviousOutPoint.Hash[:])
+	binary.LittleEndian.PutUint32(tmp[:4], in.PreviousOutPoint.Index)
+	buf.Write(tmp[:4])
+	_ = wire.WriteVarBytes(&buf, 0, scriptCode)
+	binary.LittleEndian.PutUint64(tmp[:], uint64(amount))
+	buf.Write(tmp[:])
+	binary.LittleEndian.PutUint32(tmp[:4], in.Sequence)
+	buf.Write(tmp[:4])
+	buf.Write(hashOutputs[:])
+	binary.LittleEndian.PutUint32(tmp[:4], tx.LockTime)
+	buf.Write(tmp[:4])
+	binary.LittleEndian.PutUint32(tmp[:4], uint32(SigHashAllForkID))
+	buf.Write(tmp[:4])
+
+	return chainhash.DoubleHashB(buf.Bytes())
+}
@@ -0,0 +1,54 @@
+package mapping
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// The FORKID digest is BIP143's, so btcd's witness sighash with the same
+// hash type must agree with it.
+func TestCalcSignatureHashMatchesBIP143(t *testing.T) {
+	primary := mustDecodePub(t, "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	backup := mustDecodePub(t, "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	_, redeemScript, err := createP2SHAddressWithBackup(primary, backup, []byte("tag"), regtestNetwork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 3), nil, nil))
+	tx.TxIn[1].Sequence = 7
+	tx.AddTxOut(wire.NewTxOut(50_000, []byte{txscript.OP_TRUE}))
+	tx.AddTxOut(wire.NewTxOut(12_345, redeemScript[:23]))
+	tx.LockTime = 99
+
+	const amount = 70_000
+	fetcher := txscript.NewCannedPrevOutputFetcher(nil, amount)
+	for i := range tx.TxIn {
+		want, err := txscript.CalcWitnessSigHash(
+			redeemScript,
+			txscript.NewTxSigHashes(tx, fetcher),
+			SigHashAllForkID,
+			tx,
+			i,
+			amount,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := calcSignatureHash(redeemScript, tx, i, amount)
+		if !bytes.Equal(got, want) {
+			t.Errorf("input %d: got %x, want %x", i, got, want)
+		}
+	}
+
+	// A different amount commits to a different digest.
+	if bytes.Equal(calcSignatureHash(redeemScript, tx, 0, amount), calcSignatureHash(redeemScript, tx, 0, amount+1)) {
+		t.Error("digest does not commit to the spent amount")
+	}
+}
@@ -4,10 +4,29 @@ package mapping
 type SigningData struct {
 	Tx                []byte            `msg:"tx"`
 	UnsignedSigHashes []UnsignedSigHash `msg:"uh"`
+	// Payouts lists the withdrawals the spend pays, so that costs of the
+	// spend can be charged back to them.
+	Payouts []SpendPayout `msg:"po,omitempty"`
 }
 
 type UnsignedSigHash struct {
-	Index         uint32 `msg:"i"`
-	SigHash       []byte `msg:"hs"`
+	Index   uint32 `msg:"i"`
+	SigHash []byte `msg:"hs"`
+	// WitnessScript is the input's P2SH redeem script. BCH has no witness;
+	// the signature (with the SIGHASH_ALL|FORKID byte), OP_TRUE and this
+	// script form the input's scriptSig.
 	WitnessScript []byte `msg:"ws"`
+	// Amount is the value of the output the input spends. It is zero in
+	// spends recorded before it was stored.
+	Amount int64 `msg:"a,omitempty"`
+}
+
+// SpendPayout is a withdrawal paid by a spend: the account it was made from,
+// the amount sent, the id of its withdrawal record and the VSC fee it paid.
+// Id and VscFee are zero in spends recorded before they were stored.
+type SpendPayout struct {
+	From   string `msg:"f"`
+	Amount int64  `msg:"a"`
+	Id     uint64 `msg:"id,omitempty"`
+	VscFee int64  `msg:"vf,omitempty"`
 }
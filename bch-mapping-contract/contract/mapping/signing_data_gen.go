@@ -43,45 +43,29 @@ func (z *SigningData) DecodeMsg(dc *msgp.Reader) (err error) {
 				z.UnsignedSigHashes = make([]UnsignedSigHash, zb0002)
 			}
 			for za0001 := range z.UnsignedSigHashes {
-				var zb0003 uint32
-				zb0003, err = dc.ReadMapHeader()
+				err = z.UnsignedSigHashes[za0001].DecodeMsg(dc)
 				if err != nil {
 					err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
 					return
 				}
-				for zb0003 > 0 {
-					zb0003--
-					field, err = dc.ReadMapKeyPtr()
-					if err != nil {
-						err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "i":
-						z.UnsignedSigHashes[za0001].Index, err = dc.ReadUint32()
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "Index")
-							return
-						}
-					case "hs":
-						z.UnsignedSigHashes[za0001].SigHash, err = dc.ReadBytes(z.UnsignedSigHashes[za0001].SigHash)
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "SigHash")
-							return
-						}
-					case "ws":
-						z.UnsignedSigHashes[za0001].WitnessScript, err = dc.ReadBytes(z.UnsignedSigHashes[za0001].WitnessScript)
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "WitnessScript")
-							return
-						}
-					default:
-						err = dc.Skip()
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
-							return
-						}
-					}
+			}
+		case "po":
+			var zb0003 uint32
+			zb0003, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Payouts")
+				return
+			}
+			if cap(z.Payouts) >= int(zb0003) {
+				z.Payouts = (z.Payouts)[:zb0003]
+			} else {
+				z.Payouts = make([]SpendPayout, zb0003)
+			}
+			for za0002 := range z.Payouts {
+				err = z.Payouts[za0002].DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Payouts", za0002)
+					return
 				}
 			}
 		default:
@@ -97,58 +81,67 @@ func (z *SigningData) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *SigningData) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 2
-	// write "tx"
-	err = en.Append(0x82, 0xa2, 0x74, 0x78)
-	if err != nil {
-		return
-	}
-	err = en.WriteBytes(z.Tx)
-	if err != nil {
-		err = msgp.WrapError(err, "Tx")
-		return
+	// check for omitted fields
+	zb0001Len := uint32(3)
+	var zb0001Mask uint8 /* 3 bits */
+	_ = zb0001Mask
+	if z.Payouts == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4
 	}
-	// write "uh"
-	err = en.Append(0xa2, 0x75, 0x68)
+	// variable map header, size zb0001Len
+	err = en.Append(0x80 | uint8(zb0001Len))
 	if err != nil {
 		return
 	}
-	err = en.WriteArrayHeader(uint32(len(z.UnsignedSigHashes)))
-	if err != nil {
-		err = msgp.WrapError(err, "UnsignedSigHashes")
-		return
-	}
-	for za0001 := range z.UnsignedSigHashes {
-		// map header, size 3
-		// write "i"
-		err = en.Append(0x83, 0xa1, 0x69)
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// write "tx"
+		err = en.Append(0xa2, 0x74, 0x78)
 		if err != nil {
 			return
 		}
-		err = en.WriteUint32(z.UnsignedSigHashes[za0001].Index)
+		err = en.WriteBytes(z.Tx)
 		if err != nil {
-			err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "Index")
+			err = msgp.WrapError(err, "Tx")
 			return
 		}
-		// write "hs"
-		err = en.Append(0xa2, 0x68, 0x73)
+		// write "uh"
+		err = en.Append(0xa2, 0x75, 0x68)
 		if err != nil {
 			return
 		}
-		err = en.WriteBytes(z.UnsignedSigHashes[za0001].SigHash)
+		err = en.WriteArrayHeader(uint32(len(z.UnsignedSigHashes)))
 		if err != nil {
-			err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "SigHash")
+			err = msgp.WrapError(err, "UnsignedSigHashes")
 			return
 		}
-		// write "ws"
-		err = en.Append(0xa2, 0x77, 0x73)
-		if err != nil {
-			return
+		for za0001 := range z.UnsignedSigHashes {
+			err = z.UnsignedSigHashes[za0001].EncodeMsg(en)
+			if err != nil {
+				err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
+				return
+			}
 		}
-		err = en.WriteBytes(z.UnsignedSigHashes[za0001].WitnessScript)
-		if err != nil {
-			err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "WitnessScript")
-			return
+		if (zb0001Mask & 0x4) == 0 { // if not omitted
+			// write "po"
+			err = en.Append(0xa2, 0x70, 0x6f)
+			if err != nil {
+				return
+			}
+			err = en.WriteArrayHeader(uint32(len(z.Payouts)))
+			if err != nil {
+				err = msgp.WrapError(err, "Payouts")
+				return
+			}
+			for za0002 := range z.Payouts {
+				err = z.Payouts[za0002].EncodeMsg(en)
+				if err != nil {
+					err = msgp.WrapError(err, "Payouts", za0002)
+					return
+				}
+			}
 		}
 	}
 	return
@@ -157,24 +150,44 @@ func (z *SigningData) EncodeMsg(en *msgp.Writer) (err error) {
 // MarshalMsg implements msgp.Marshaler
 func (z *SigningData) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 2
-	// string "tx"
-	o = append(o, 0x82, 0xa2, 0x74, 0x78)
-	o = msgp.AppendBytes(o, z.Tx)
-	// string "uh"
-	o = append(o, 0xa2, 0x75, 0x68)
-	o = msgp.AppendArrayHeader(o, uint32(len(z.UnsignedSigHashes)))
-	for za0001 := range z.UnsignedSigHashes {
-		// map header, size 3
-		// string "i"
-		o = append(o, 0x83, 0xa1, 0x69)
-		o = msgp.AppendUint32(o, z.UnsignedSigHashes[za0001].Index)
-		// string "hs"
-		o = append(o, 0xa2, 0x68, 0x73)
-		o = msgp.AppendBytes(o, z.UnsignedSigHashes[za0001].SigHash)
-		// string "ws"
-		o = append(o, 0xa2, 0x77, 0x73)
-		o = msgp.AppendBytes(o, z.UnsignedSigHashes[za0001].WitnessScript)
+	// check for omitted fields
+	zb0001Len := uint32(3)
+	var zb0001Mask uint8 /* 3 bits */
+	_ = zb0001Mask
+	if z.Payouts == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4
+	}
+	// variable map header, size zb0001Len
+	o = append(o, 0x80|uint8(zb0001Len))
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// string "tx"
+		o = append(o, 0xa2, 0x74, 0x78)
+		o = msgp.AppendBytes(o, z.Tx)
+		// string "uh"
+		o = append(o, 0xa2, 0x75, 0x68)
+		o = msgp.AppendArrayHeader(o, uint32(len(z.UnsignedSigHashes)))
+		for za0001 := range z.UnsignedSigHashes {
+			o, err = z.UnsignedSigHashes[za0001].MarshalMsg(o)
+			if err != nil {
+				err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
+				return
+			}
+		}
+		if (zb0001Mask & 0x4) == 0 { // if not omitted
+			// string "po"
+			o = append(o, 0xa2, 0x70, 0x6f)
+			o = msgp.AppendArrayHeader(o, uint32(len(z.Payouts)))
+			for za0002 := range z.Payouts {
+				o, err = z.Payouts[za0002].MarshalMsg(o)
+				if err != nil {
+					err = msgp.WrapError(err, "Payouts", za0002)
+					return
+				}
+			}
+		}
 	}
 	return
 }
@@ -216,45 +229,29 @@ func (z *SigningData) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				z.UnsignedSigHashes = make([]UnsignedSigHash, zb0002)
 			}
 			for za0001 := range z.UnsignedSigHashes {
-				var zb0003 uint32
-				zb0003, bts, err = msgp.ReadMapHeaderBytes(bts)
+				bts, err = z.UnsignedSigHashes[za0001].UnmarshalMsg(bts)
 				if err != nil {
 					err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
 					return
 				}
-				for zb0003 > 0 {
-					zb0003--
-					field, bts, err = msgp.ReadMapKeyZC(bts)
-					if err != nil {
-						err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "i":
-						z.UnsignedSigHashes[za0001].Index, bts, err = msgp.ReadUint32Bytes(bts)
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "Index")
-							return
-						}
-					case "hs":
-						z.UnsignedSigHashes[za0001].SigHash, bts, err = msgp.ReadBytesBytes(bts, z.UnsignedSigHashes[za0001].SigHash)
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "SigHash")
-							return
-						}
-					case "ws":
-						z.UnsignedSigHashes[za0001].WitnessScript, bts, err = msgp.ReadBytesBytes(bts, z.UnsignedSigHashes[za0001].WitnessScript)
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001, "WitnessScript")
-							return
-						}
-					default:
-						bts, err = msgp.Skip(bts)
-						if err != nil {
-							err = msgp.WrapError(err, "UnsignedSigHashes", za0001)
-							return
-						}
-					}
+			}
+		case "po":
+			var zb0003 uint32
+			zb0003, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Payouts")
+				return
+			}
+			if cap(z.Payouts) >= int(zb0003) {
+				z.Payouts = (z.Payouts)[:zb0003]
+			} else {
+				z.Payouts = make([]SpendPayout, zb0003)
+			}
+			for za0002 := range z.Payouts {
+				bts, err = z.Payouts[za0002].UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Payouts", za0002)
+					return
 				}
 			}
 		default:
@@ -273,11 +270,238 @@ func (z *SigningData) UnmarshalMsg(bts []byte) (o []byte, err error) {
 func (z *SigningData) Msgsize() (s int) {
 	s = 1 + 3 + msgp.BytesPrefixSize + len(z.Tx) + 3 + msgp.ArrayHeaderSize
 	for za0001 := range z.UnsignedSigHashes {
-		s += 1 + 2 + msgp.Uint32Size + 3 + msgp.BytesPrefixSize + len(z.UnsignedSigHashes[za0001].SigHash) + 3 + msgp.BytesPrefixSize + len(z.UnsignedSigHashes[za0001].WitnessScript)
+		s += z.UnsignedSigHashes[za0001].Msgsize()
+	}
+	s += 3 + msgp.ArrayHeaderSize
+	for za0002 := range z.Payouts {
+		s += z.Payouts[za0002].Msgsize()
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *SpendPayout) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "f":
+			z.From, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "From")
+				return
+			}
+		case "a":
+			z.Amount, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Amount")
+				return
+			}
+		case "id":
+			z.Id, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "Id")
+				return
+			}
+		case "vf":
+			z.VscFee, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "VscFee")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *SpendPayout) EncodeMsg(en *msgp.Writer) (err error) {
+	// check for omitted fields
+	zb0001Len := uint32(4)
+	var zb0001Mask uint8 /* 4 bits */
+	_ = zb0001Mask
+	if z.Id == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x4
+	}
+	if z.VscFee == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x8
+	}
+	// variable map header, size zb0001Len
+	err = en.Append(0x80 | uint8(zb0001Len))
+	if err != nil {
+		return
+	}
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// write "f"
+		err = en.Append(0xa1, 0x66)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.From)
+		if err != nil {
+			err = msgp.WrapError(err, "From")
+			return
+		}
+		// write "a"
+		err = en.Append(0xa1, 0x61)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt64(z.Amount)
+		if err != nil {
+			err = msgp.WrapError(err, "Amount")
+			return
+		}
+		if (zb0001Mask & 0x4) == 0 { // if not omitted
+			// write "id"
+			err = en.Append(0xa2, 0x69, 0x64)
+			if err != nil {
+				return
+			}
+			err = en.WriteUint64(z.Id)
+			if err != nil {
+				err = msgp.WrapError(err, "Id")
+				return
+			}
+		}
+		if (zb0001Mask & 0x8) == 0 { // if not omitted
+			// write "vf"
+			err = en.Append(0xa2, 0x76, 0x66)
+			if err != nil {
+				return
+			}
+			err = en.WriteInt64(z.VscFee)
+			if err != nil {
+				err = msgp.WrapError(err, "VscFee")
+				return
+			}
+		}
 	}
 	return
 }
 
+// MarshalMsg implements msgp.Marshaler
+func (z *SpendPayout) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// check for omitted fields
+	zb0001Len := uint32(4)
+	var zb0001Mask uint8 /* 4 bits */
+	_ = zb0001Mask
+	if z.Id == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x4
+	}
+	if z.VscFee == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x8
+	}
+	// variable map header, size zb0001Len
+	o = append(o, 0x80|uint8(zb0001Len))
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// string "f"
+		o = append(o, 0xa1, 0x66)
+		o = msgp.AppendString(o, z.From)
+		// string "a"
+		o = append(o, 0xa1, 0x61)
+		o = msgp.AppendInt64(o, z.Amount)
+		if (zb0001Mask & 0x4) == 0 { // if not omitted
+			// string "id"
+			o = append(o, 0xa2, 0x69, 0x64)
+			o = msgp.AppendUint64(o, z.Id)
+		}
+		if (zb0001Mask & 0x8) == 0 { // if not omitted
+			// string "vf"
+			o = append(o, 0xa2, 0x76, 0x66)
+			o = msgp.AppendInt64(o, z.VscFee)
+		}
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *SpendPayout) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "f":
+			z.From, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "From")
+				return
+			}
+		case "a":
+			z.Amount, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Amount")
+				return
+			}
+		case "id":
+			z.Id, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Id")
+				return
+			}
+		case "vf":
+			z.VscFee, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "VscFee")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *SpendPayout) Msgsize() (s int) {
+	s = 1 + 2 + msgp.StringPrefixSize + len(z.From) + 2 + msgp.Int64Size + 3 + msgp.Uint64Size + 3 + msgp.Int64Size
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *UnsignedSigHash) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
@@ -314,6 +538,12 @@ func (z *UnsignedSigHash) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "WitnessScript")
 				return
 			}
+		case "a":
+			z.Amount, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Amount")
+				return
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -327,36 +557,64 @@ func (z *UnsignedSigHash) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *UnsignedSigHash) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 3
-	// write "i"
-	err = en.Append(0x83, 0xa1, 0x69)
-	if err != nil {
-		return
-	}
-	err = en.WriteUint32(z.Index)
-	if err != nil {
-		err = msgp.WrapError(err, "Index")
-		return
-	}
-	// write "hs"
-	err = en.Append(0xa2, 0x68, 0x73)
-	if err != nil {
-		return
+	// check for omitted fields
+	zb0001Len := uint32(4)
+	var zb0001Mask uint8 /* 4 bits */
+	_ = zb0001Mask
+	if z.Amount == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x8
 	}
-	err = en.WriteBytes(z.SigHash)
+	// variable map header, size zb0001Len
+	err = en.Append(0x80 | uint8(zb0001Len))
 	if err != nil {
-		err = msgp.WrapError(err, "SigHash")
 		return
 	}
-	// write "ws"
-	err = en.Append(0xa2, 0x77, 0x73)
-	if err != nil {
-		return
-	}
-	err = en.WriteBytes(z.WitnessScript)
-	if err != nil {
-		err = msgp.WrapError(err, "WitnessScript")
-		return
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// write "i"
+		err = en.Append(0xa1, 0x69)
+		if err != nil {
+			return
+		}
+		err = en.WriteUint32(z.Index)
+		if err != nil {
+			err = msgp.WrapError(err, "Index")
+			return
+		}
+		// write "hs"
+		err = en.Append(0xa2, 0x68, 0x73)
+		if err != nil {
+			return
+		}
+		err = en.WriteBytes(z.SigHash)
+		if err != nil {
+			err = msgp.WrapError(err, "SigHash")
+			return
+		}
+		// write "ws"
+		err = en.Append(0xa2, 0x77, 0x73)
+		if err != nil {
+			return
+		}
+		err = en.WriteBytes(z.WitnessScript)
+		if err != nil {
+			err = msgp.WrapError(err, "WitnessScript")
+			return
+		}
+		if (zb0001Mask & 0x8) == 0 { // if not omitted
+			// write "a"
+			err = en.Append(0xa1, 0x61)
+			if err != nil {
+				return
+			}
+			err = en.WriteInt64(z.Amount)
+			if err != nil {
+				err = msgp.WrapError(err, "Amount")
+				return
+			}
+		}
 	}
 	return
 }
@@ -364,16 +622,34 @@ func (z *UnsignedSigHash) EncodeMsg(en *msgp.Writer) (err error) {
 // MarshalMsg implements msgp.Marshaler
 func (z *UnsignedSigHash) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 3
-	// string "i"
-	o = append(o, 0x83, 0xa1, 0x69)
-	o = msgp.AppendUint32(o, z.Index)
-	// string "hs"
-	o = append(o, 0xa2, 0x68, 0x73)
-	o = msgp.AppendBytes(o, z.SigHash)
-	// string "ws"
-	o = append(o, 0xa2, 0x77, 0x73)
-	o = msgp.AppendBytes(o, z.WitnessScript)
+	// check for omitted fields
+	zb0001Len := uint32(4)
+	var zb0001Mask uint8 /* 4 bits */
+	_ = zb0001Mask
+	if z.Amount == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x8
+	}
+	// variable map header, size zb0001Len
+	o = append(o, 0x80|uint8(zb0001Len))
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// string "i"
+		o = append(o, 0xa1, 0x69)
+		o = msgp.AppendUint32(o, z.Index)
+		// string "hs"
+		o = append(o, 0xa2, 0x68, 0x73)
+		o = msgp.AppendBytes(o, z.SigHash)
+		// string "ws"
+		o = append(o, 0xa2, 0x77, 0x73)
+		o = msgp.AppendBytes(o, z.WitnessScript)
+		if (zb0001Mask & 0x8) == 0 { // if not omitted
+			// string "a"
+			o = append(o, 0xa1, 0x61)
+			o = msgp.AppendInt64(o, z.Amount)
+		}
+	}
 	return
 }
 
@@ -413,6 +689,12 @@ func (z *UnsignedSigHash) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "WitnessScript")
 				return
 			}
+		case "a":
+			z.Amount, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Amount")
+				return
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -427,6 +709,6 @@ func (z *UnsignedSigHash) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *UnsignedSigHash) Msgsize() (s int) {
-	s = 1 + 2 + msgp.Uint32Size + 3 + msgp.BytesPrefixSize + len(z.SigHash) + 3 + msgp.BytesPrefixSize + len(z.WitnessScript)
+	s = 1 + 2 + msgp.Uint32Size + 3 + msgp.BytesPrefixSize + len(z.SigHash) + 3 + msgp.BytesPrefixSize + len(z.WitnessScript) + 2 + msgp.Int64Size
 	return
 }
@@ -5,34 +5,25 @@ import (
 	ce "bch-mapping-contract/contract/contracterrors"
 	"bch-mapping-contract/sdk"
 	"bytes"
+	"slices"
 
-	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 )
 
-// constants in sats
-const dustThreshold = 546
-
-const splitThreshold = 1000000 // 0.01 BTC
 const maxChangeOutputs = 4
 
-// VscFeeMinSats is the minimum VSC protocol fee in satoshis.
-const VscFeeMinSats int64 = 0
-
-// VscFeeRateBps is the VSC protocol fee as basis points (1 bps = 0.01%).
-const VscFeeRateBps int64 = 0
-
 func calcVscFee(amount int64) (int64, error) {
-	if VscFeeMinSats == 0 && VscFeeRateBps == 0 {
+	minFee, rateBps := chainPolicy.VscFeeMin, chainPolicy.VscFeeRateBps
+	if minFee == 0 && rateBps == 0 {
 		return 0, nil
 	}
 	// divide first to avoid overflow on large amounts, then compensate for remainder
-	percentageFee := (amount/10000)*VscFeeRateBps + (amount%10000)*VscFeeRateBps/10000
-	finalFee := VscFeeMinSats
-	if percentageFee > VscFeeMinSats {
+	percentageFee := (amount/10000)*rateBps + (amount%10000)*rateBps/10000
+	finalFee := minFee
+	if percentageFee > minFee {
 		finalFee = percentageFee
 	}
 	if finalFee >= amount {
@@ -53,19 +44,26 @@ func getInputUtxos(registryEntries []uint16) ([]*Utxo, error) {
 	return result, nil
 }
 
-// estimateVSize returns the estimated vSize for given non-witness and witness data sizes.
-func estimateVSize(nonWitnessSize, witnessDataSize int64) int64 {
-	totalSize := nonWitnessSize + witnessDataSize
-	return (nonWitnessSize*3+totalSize+3)/4 + 2
+// scriptSigSize returns the size of the scriptSig spending the IF branch of a
+// P2SH redeem script: <sig> OP_TRUE <redeem_script>. The signature push is
+// 1 + 72 (max DER) + 1 (sighash byte), and the script push needs
+// OP_PUSHDATA1 once it is 76 bytes or longer.
+func scriptSigSize(redeemScriptLen int64) int64 {
+	pushSize := int64(1)
+	if redeemScriptLen >= txscript.OP_PUSHDATA1 {
+		pushSize = 2
+	}
+	return 74 + 1 + pushSize + redeemScriptLen
 }
 
-// clampedFeeRate returns the base fee rate clamped to MaxBaseFeeRate.
+// clampedFeeRate returns the base fee rate clamped to the chain policy's
+// fee rate bounds.
 func clampedFeeRate(rate int64) int64 {
-	if rate > constants.MaxBaseFeeRate {
-		return constants.MaxBaseFeeRate
+	if rate > chainPolicy.MaxFeeRate {
+		return chainPolicy.MaxFeeRate
 	}
-	if rate < 1 {
-		return 1
+	if rate < chainPolicy.MinFeeRate {
+		return chainPolicy.MinFeeRate
 	}
 	return rate
 }
@@ -80,20 +78,18 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 	// Base transaction overhead (version, locktime, etc.)
 	baseSize := int64(10)
 	// Input size: outpoint (36) + script sig length (1) + sequence (4)
-	inputSize := numInputs * 41
-	// Output size: value (8) + script length (1) + P2WSH script (34)
-	outputSize := int64(43) // 1 destination output
-
-	// Witness stack per input: <sig> <branch_selector> <witness_script>
-	// Serialized: item_count(1) + sig_len(1) + sig(72) + branch_len(1) + branch(1) + script_len(1) + script(N)
-	// Witness script is ~79 bytes for change UTXOs (no tag) or ~112 bytes for
-	// deposit UTXOs (with 32-byte tag). Use 112 as conservative upper bound
-	// to ensure fee estimate >= actual fee from calculateSegwitFee.
-	witnessDataSize := numInputs * (72 + 112 + 5)
+	// + scriptSig. The redeem script is ~78 bytes for change UTXOs (no tag)
+	// or ~111 bytes for deposit UTXOs (with 32-byte tag). Use 112 as a
+	// conservative upper bound to ensure fee estimate >= actual fee from
+	// calculateFee.
+	inputSize := numInputs * (41 + scriptSigSize(112))
+	// Output size: value (8) + script length (1) + P2PKH script (25), the
+	// larger of the P2PKH and P2SH scripts
+	outputSize := int64(34) // 1 destination output
 
 	// Compute base fee (no change outputs) first
-	nonWitnessSize := baseSize + inputSize + outputSize
-	baseFee, err := safeMultiply64(estimateVSize(nonWitnessSize, witnessDataSize), feeRate)
+	txSize := baseSize + inputSize + outputSize
+	baseFee, err := chainPolicy.fee(txSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 	}
@@ -103,14 +99,14 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 		availableChange = 0
 	}
 
-	if availableChange > dustThreshold {
-		numChangeOutputs := min(max(availableChange/splitThreshold, 1), maxChangeOutputs)
+	if !chainPolicy.isDust(availableChange) {
+		numChangeOutputs := min(max(availableChange/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
 
 		// Add change outputs one at a time, stopping when per-output amount is dust
 		addedOutputs := int64(0)
 		for i := int64(0); i < numChangeOutputs; i++ {
-			newNonWitness := nonWitnessSize + (addedOutputs+1)*43
-			newFee, err := safeMultiply64(estimateVSize(newNonWitness, witnessDataSize), feeRate)
+			newTxSize := txSize + (addedOutputs+1)*34
+			newFee, err := chainPolicy.fee(newTxSize, feeRate)
 			if err != nil {
 				return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 			}
@@ -118,15 +114,15 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 			if newAvailable < 0 {
 				newAvailable = 0
 			}
-			if newAvailable/(addedOutputs+1) <= dustThreshold {
+			if chainPolicy.isDust(newAvailable / (addedOutputs + 1)) {
 				break
 			}
 			addedOutputs++
-			nonWitnessSize = newNonWitness
+			txSize = newTxSize
 		}
 	}
 
-	fee, err := safeMultiply64(estimateVSize(nonWitnessSize, witnessDataSize), feeRate)
+	fee, err := chainPolicy.fee(txSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 	}
@@ -210,18 +206,18 @@ func (cs *ContractState) getInputUtxoIds(amount int64) ([]uint16, int64, error)
 	return nil, 0, ce.NewContractError(ce.ErrBalance, "total available balance insufficient to complete transaction")
 }
 
-func (cs *ContractState) calculateSegwitFee(baseSize int64, witnessScripts map[int][]byte) (int64, error) {
+// calculateFee returns the fee for a transaction of baseSize bytes without
+// its scriptSigs, once each input carries the scriptSig for its redeem script.
+// BCH has no witness discount, so the fee is charged on the full size.
+func (cs *ContractState) calculateFee(baseSize int64, redeemScripts map[int][]byte) (int64, error) {
 	feeRate := clampedFeeRate(cs.Supply.BaseFeeRate)
-	// Witness stack per input: <sig> <branch_selector> <witness_script>
-	// Serialized: item_count(1) + sig_len(1) + sig(72) + branch_len(1) + branch(1) + script_len(1) + script(N)
-	witnessDataSize := int64(0)
-	for _, witnessScript := range witnessScripts {
-		witnessDataSize += 72 + int64(len(witnessScript)) + 5
-	}
-	totalSize := baseSize + witnessDataSize
-	// +3 to round up, + 2 for has witness data flag
-	vSize := (baseSize*3+totalSize+3)/4 + 2
-	fee, err := safeMultiply64(vSize, feeRate)
+	totalSize := baseSize
+	for _, redeemScript := range redeemScripts {
+		sigScriptSize := scriptSigSize(int64(len(redeemScript)))
+		// the empty scriptSig's length byte is already in baseSize
+		totalSize += int64(wire.VarIntSerializeSize(uint64(sigScriptSize))) - 1 + sigScriptSize
+	}
+	fee, err := chainPolicy.fee(totalSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee calculation overflow")
 	}
@@ -240,42 +236,13 @@ func (cs *ContractState) buildSpendTransaction(
 ) (*wire.MsgTx, map[int][]byte, int64, error) {
 	tx := wire.NewMsgTx(wire.TxVersion)
 
-	// create all witness scripts now for better size estimation
-	witnessScripts := make(map[int][]byte)
-	for index, utxo := range inputs {
-		txHash, err := chainhash.NewHashFromStr(utxo.TxId)
-		if err != nil {
-			return nil, nil, 0, err
-		}
-
-		outPoint := wire.NewOutPoint(txHash, utxo.Vout)
-		txIn := wire.NewTxIn(outPoint, nil, nil)
-		tx.AddTxIn(txIn)
-
-		_, witnessScript, err := createP2WSHAddressWithBackup(
-			cs.PublicKeys.Primary,
-			cs.PublicKeys.Backup,
-			utxo.Tag, // already []byte
-			cs.NetworkParams,
-		)
-
-		if err != nil {
-			return nil, nil, 0, err
-		}
-		witnessScripts[index] = witnessScript
-	}
-
-	destAddr, err := btcutil.DecodeAddress(destAddress, cs.NetworkParams)
+	redeemScripts, err := cs.addSpendInputs(tx, inputs)
 	if err != nil {
-		return nil, nil, 0, ce.WrapContractError(
-			ce.ErrInput,
-			err,
-			"error decoding destination btc address ["+destAddress+"]",
-		)
+		return nil, nil, 0, err
 	}
 
 	// Create output script for destination
-	destScript, err := txscript.PayToAddrScript(destAddr)
+	destScript, err := cs.destinationScript(destAddress)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -284,7 +251,7 @@ func (cs *ContractState) buildSpendTransaction(
 	tx.AddTxOut(destTxOut)
 
 	baseSize := int64(tx.SerializeSize())
-	fee, err := cs.calculateSegwitFee(baseSize, witnessScripts)
+	fee, err := cs.calculateFee(baseSize, redeemScripts)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -298,8 +265,8 @@ func (cs *ContractState) buildSpendTransaction(
 	}
 
 	// Add change outputs if above dust, splitting across multiple outputs
-	if availableChange > dustThreshold {
-		changeAddressObj, err := btcutil.DecodeAddress(changeAddress, cs.NetworkParams)
+	if !chainPolicy.isDust(availableChange) {
+		changeAddressObj, err := DecodeAddress(changeAddress, cs.Network.Params)
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -309,13 +276,13 @@ func (cs *ContractState) buildSpendTransaction(
 		}
 		changeOutputSize := int64(wire.NewTxOut(int64(0), changeScript).SerializeSize())
 
-		numChangeOuputs := min(max(availableChange/splitThreshold, 1), maxChangeOutputs)
+		numChangeOuputs := min(max(availableChange/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
 
 		// Add change outputs one at a time, recalculating fee after each
 		addedOutputs := int64(0)
 		for range numChangeOuputs {
 			newBaseSize := baseSize + (addedOutputs+1)*changeOutputSize
-			newFee, err := cs.calculateSegwitFee(newBaseSize, witnessScripts)
+			newFee, err := cs.calculateFee(newBaseSize, redeemScripts)
 			if err != nil {
 				return nil, nil, 0, err
 			}
@@ -326,7 +293,7 @@ func (cs *ContractState) buildSpendTransaction(
 
 			// Check if adding this output still leaves enough for all outputs to be above dust
 			perOutput := newAvailable / (addedOutputs + 1)
-			if perOutput <= dustThreshold {
+			if chainPolicy.isDust(perOutput) {
 				break
 			}
 
@@ -350,7 +317,7 @@ func (cs *ContractState) buildSpendTransaction(
 		}
 	}
 
-	// Pentest finding BTC-C5: when availableChange ≤ dustThreshold the
+	// Pentest finding BTC-C5: when availableChange is dust the
 	// change output is omitted; the residual sats are implicitly paid
 	// to the miner. The size-based fee variable above does NOT capture
 	// that absorbed dust, so callers (HandleUnmap) under-decrement
@@ -365,37 +332,116 @@ func (cs *ContractState) buildSpendTransaction(
 	}
 	fee = totalInputsAmount - outSum
 
-	return tx, witnessScripts, fee, nil
+	return tx, redeemScripts, fee, nil
 }
 
-// signSpendTransaction computes witness sighashes and requests TSS signing
-// for each input. Call this only after all validation checks have passed.
-func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int][]byte) (*SigningData, error) {
-	unsignedSigHashes := make([]UnsignedSigHash, len(inputs))
-	for i, utxo := range inputs {
-		witnessScript := witnessScripts[i]
+// addSpendInputs adds inputs to tx and returns the redeem script of each,
+// by input index, created now for better size estimation.
+func (cs *ContractState) addSpendInputs(tx *wire.MsgTx, inputs []*Utxo) (map[int][]byte, error) {
+	redeemScripts := make(map[int][]byte)
+	for index, utxo := range inputs {
+		txHash, err := chainhash.NewHashFromStr(utxo.TxId)
+		if err != nil {
+			return nil, err
+		}
 
-		sigHashes := txscript.NewTxSigHashes(tx, txscript.NewCannedPrevOutputFetcher(utxo.PkScript, utxo.Amount))
+		outPoint := wire.NewOutPoint(txHash, utxo.Vout)
+		txIn := wire.NewTxIn(outPoint, nil, nil)
+		tx.AddTxIn(txIn)
 
-		sigHash, err := txscript.CalcWitnessSigHash(
-			witnessScript,
-			sigHashes,
-			txscript.SigHashAll,
-			tx,
-			i,
-			utxo.Amount,
+		_, redeemScript, err := createP2SHAddressWithBackup(
+			cs.PublicKeys.Primary,
+			cs.PublicKeys.Backup,
+			utxo.Tag, // already []byte
+			cs.Network,
 		)
 
 		if err != nil {
 			return nil, err
 		}
+		redeemScripts[index] = redeemScript
+	}
+	return redeemScripts, nil
+}
+
+// destinationScript returns the output script paying destAddress.
+func (cs *ContractState) destinationScript(destAddress string) ([]byte, error) {
+	destAddr, err := DecodeAddress(destAddress, cs.Network.Params)
+	if err != nil {
+		return nil, ce.WrapContractError(
+			ce.ErrInput,
+			err,
+			"error decoding destination bch address ["+destAddress+"]",
+		)
+	}
+	return txscript.PayToAddrScript(destAddr)
+}
+
+// recordSpend requests TSS signing for tx, moves its change outputs into the
+// unconfirmed pool, drops its inputs from the registry and stores the signing
+// data, with the withdrawals tx pays, under its txid. Call this only after
+// all validation checks have passed.
+func (cs *ContractState) recordSpend(
+	tx *wire.MsgTx,
+	inputUtxoIds []uint16,
+	inputUtxos []*Utxo,
+	redeemScripts map[int][]byte,
+	changeAddress string,
+	payouts []SpendPayout,
+) error {
+	signingData, err := signSpendTransaction(tx, inputUtxos, redeemScripts)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error signing spend transaction")
+	}
+	signingData.Payouts = payouts
+
+	unconfirmedUtxos, err := indexUnconfimedOutputs(tx, changeAddress, cs.Network.Params)
+	if err != nil {
+		return err
+	}
+	for _, utxo := range unconfirmedUtxos {
+		internalId, err := cs.allocateUnconfirmedId()
+		if err != nil {
+			return err
+		}
+		cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: internalId, Amount: utxo.Amount})
+		saveUtxo(internalId, utxo)
+	}
+
+	for _, inputId := range inputUtxoIds {
+		cs.UtxoList = slices.DeleteFunc(
+			cs.UtxoList,
+			func(entry UtxoRegistryEntry) bool { return entry.Id == inputId },
+		)
+		sdk.StateDeleteObject(getUtxoKey(inputId))
+	}
+
+	signingDataBytes, err := MarshalSigningData(signingData)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrJson, err, "error marshalling signing data")
+	}
+
+	sdk.StateSetObject(constants.TxSpendsPrefix+tx.TxID(), string(signingDataBytes))
+	cs.TxSpendsList = append(cs.TxSpendsList, tx.TxID())
+	return nil
+}
+
+// signSpendTransaction computes SIGHASH_ALL|FORKID sighashes and requests TSS
+// signing for each input. Call this only after all validation checks have passed.
+func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, redeemScripts map[int][]byte) (*SigningData, error) {
+	unsignedSigHashes := make([]UnsignedSigHash, len(inputs))
+	for i, utxo := range inputs {
+		redeemScript := redeemScripts[i]
+
+		sigHash := calcSignatureHash(redeemScript, tx, i, utxo.Amount)
 
 		sdk.TssSignKey(constants.TssKeyName, sigHash)
 
 		unsignedSigHashes[i] = UnsignedSigHash{
 			Index:         uint32(i),
 			SigHash:       sigHash,
-			WitnessScript: witnessScript,
+			WitnessScript: redeemScript,
+			Amount:        utxo.Amount,
 		}
 	}
 
@@ -412,20 +458,23 @@ func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int
 }
 
 func indexUnconfimedOutputs(tx *wire.MsgTx, changeAddress string, network *chaincfg.Params) ([]*Utxo, error) {
-	// 1 output will be to the destination, the others will be to change address
-	utxos := make([]*Utxo, len(tx.TxOut)-1)
+	// the outputs to the change address follow the one or more destinations
+	utxos := make([]*Utxo, 0, len(tx.TxOut)-1)
 
-	i := 0
 	for index, txOut := range tx.TxOut {
 		_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, network)
 		if err != nil {
 			return nil, err
 		}
-		// must be 1 because it's P2WSH
+		// must be 1 because it's P2SH or P2PKH
 		if len(addrs) != 1 {
 			return nil, ce.NewContractError(ce.ErrTransaction, "incorrect number of addresses for transaction output")
 		}
-		if addrs[0].EncodeAddress() == changeAddress {
+		address, err := EncodeAddress(addrs[0], network)
+		if err != nil {
+			return nil, ce.WrapContractError(ce.ErrTransaction, err, "error encoding transaction output address")
+		}
+		if address == changeAddress {
 			utxo := Utxo{
 				TxId:     tx.TxID(),
 				Vout:     uint32(index),
@@ -433,12 +482,7 @@ func indexUnconfimedOutputs(tx *wire.MsgTx, changeAddress string, network *chain
 				PkScript: txOut.PkScript,
 				Tag:      nil, // change outputs have no tag
 			}
-			if i < len(utxos) {
-				utxos[i] = &utxo
-				i++
-			} else {
-				utxos = append(utxos, &utxo)
-			}
+			utxos = append(utxos, &utxo)
 		}
 	}
 
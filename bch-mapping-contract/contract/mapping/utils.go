@@ -3,8 +3,8 @@ package mapping
 import (
 	"bch-mapping-contract/contract/constants"
 	ce "bch-mapping-contract/contract/contracterrors"
+	"bch-mapping-contract/contract/network"
 	"bch-mapping-contract/sdk"
-	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
@@ -20,14 +20,13 @@ import (
 	"github.com/btcsuite/btcd/wire"
 )
 
-func createP2WSHAddressWithBackup(
-	primaryPubKey CompressedPubKey, backupPubKey CompressedPubKey, tag []byte, network *chaincfg.Params,
+// createP2SHAddressWithBackup returns the CashAddr of the P2SH address for
+// the IF/CSV/ELSE script, and the redeem script itself. BCH has no segwit, so
+// the script is committed to by HASH160 rather than a P2WSH witness program.
+func createP2SHAddressWithBackup(
+	primaryPubKey CompressedPubKey, backupPubKey CompressedPubKey, tag []byte, net *network.Network,
 ) (string, []byte, error) {
-	csvBlocks := constants.BackupCSVBlocks
-
-	if network.Net != chaincfg.MainNetParams.Net {
-		csvBlocks = constants.TestnetBackupCSVBlocks
-	}
+	csvBlocks := net.BackupCSVBlocks
 
 	scriptBuilder := txscript.NewScriptBuilder()
 
@@ -64,25 +63,19 @@ func createP2WSHAddressWithBackup(
 		return "", nil, err
 	}
 
-	witnessProgram := sha256.Sum256(script)
-	addressWitnessScriptHash, err := btcutil.NewAddressWitnessScriptHash(witnessProgram[:], network)
-	if err != nil {
-		return "", nil, err
-	}
-
-	return addressWitnessScriptHash.EncodeAddress(), script, nil
+	return p2shAddress(script, net.Params)
 }
 
-func createP2WSHAddress(pubKeyHex string, tag []byte, network *chaincfg.Params) (string, []byte, error) {
+func createP2SHAddress(pubKeyHex string, tag []byte, network *chaincfg.Params) (string, []byte, error) {
 	pubKeyBytes, err := hex.DecodeString(pubKeyHex)
 	if err != nil {
 		return "", nil, err
 	}
 
-	return createSimpleP2WSHAddress(pubKeyBytes, tag, network)
+	return createSimpleP2SHAddress(pubKeyBytes, tag, network)
 }
 
-func createSimpleP2WSHAddress(pubKeyBytes []byte, tag []byte, network *chaincfg.Params) (string, []byte, error) {
+func createSimpleP2SHAddress(pubKeyBytes []byte, tag []byte, network *chaincfg.Params) (string, []byte, error) {
 	scriptBuilder := txscript.NewScriptBuilder()
 	if len(tag) > 0 {
 		scriptBuilder.AddData(pubKeyBytes)
@@ -98,12 +91,20 @@ func createSimpleP2WSHAddress(pubKeyBytes []byte, tag []byte, network *chaincfg.
 		return "", nil, err
 	}
 
-	witnessProgram := sha256.Sum256(script)
-	addressWitnessScriptHash, err := btcutil.NewAddressWitnessScriptHash(witnessProgram[:], network)
+	return p2shAddress(script, network)
+}
+
+// p2shAddress returns the CashAddr paying to a redeem script, along with the script.
+func p2shAddress(script []byte, network *chaincfg.Params) (string, []byte, error) {
+	addressScriptHash, err := btcutil.NewAddressScriptHash(script, network)
 	if err != nil {
 		return "", nil, err
 	}
-	return addressWitnessScriptHash.EncodeAddress(), script, nil
+	address, err := EncodeAddress(addressScriptHash, network)
+	if err != nil {
+		return "", nil, err
+	}
+	return address, script, nil
 }
 
 func checkAuth(env sdk.Env) error {
@@ -452,7 +453,10 @@ func senderLabel(inputs []*wire.TxIn, network *chaincfg.Params) string {
 		if err != nil || len(addrs) == 0 {
 			return "many"
 		}
-		addr := addrs[0].EncodeAddress()
+		addr, err := EncodeAddress(addrs[0], network)
+		if err != nil {
+			return "many"
+		}
 		if label == "" {
 			label = addr
 		} else if label != addr {
@@ -617,7 +621,11 @@ func loadObservedList(blockHeight uint32) []observedEntry {
 	if raw == nil || len(*raw) == 0 {
 		return nil
 	}
-	data := []byte(*raw)
+	return unpackObservedList([]byte(*raw))
+}
+
+// unpackObservedList splits packed observed entries.
+func unpackObservedList(data []byte) []observedEntry {
 	if len(data)%observedEntrySize != 0 {
 		return nil
 	}
@@ -640,11 +648,16 @@ func isObserved(list []observedEntry, entry observedEntry) bool {
 
 // saveObservedList writes the packed observed entries for a block height.
 func saveObservedList(blockHeight uint32, list []observedEntry) {
+	sdk.StateSetObject(observedBlockKey(blockHeight), string(packObservedList(list)))
+}
+
+// packObservedList concatenates observed entries.
+func packObservedList(list []observedEntry) []byte {
 	buf := make([]byte, len(list)*observedEntrySize)
 	for i, e := range list {
 		copy(buf[i*observedEntrySize:], e[:])
 	}
-	sdk.StateSetObject(observedBlockKey(blockHeight), string(buf))
+	return buf
 }
 
 // DeleteObservedList removes the observed tx list for a block height.
@@ -705,6 +718,20 @@ func getMaxUnmapPerBlock() int64 {
 	return v
 }
 
+// getMinConfirmations returns the minimum confirmation depth required of
+// proofs submitted to action, or DefaultMinConfirmations if none is set.
+func getMinConfirmations(action string) uint32 {
+	s := sdk.StateGetObject(constants.MinConfirmationsPrefix + action)
+	if s == nil || *s == "" {
+		return constants.DefaultMinConfirmations
+	}
+	v, err := strconv.ParseUint(*s, 10, 32)
+	if err != nil || v == 0 {
+		return constants.DefaultMinConfirmations
+	}
+	return uint32(v)
+}
+
 func loadUnmapAccumulator() (storedHeight uint64, accum int64) {
 	s := sdk.StateGetObject(constants.BlockUnmapAccKey)
 	if s == nil || len(*s) != 16 {
@@ -754,3 +781,19 @@ func checkAndUpdateUnmapRateLimit(blockHeight uint64, amount int64) error {
 	saveUnmapAccumulator(blockHeight, newAccum)
 	return nil
 }
+
+// releaseUnmapRateLimit gives amount back to the current Hive block's unmap
+// allowance when a queued withdrawal is refunded, so a refund does not keep
+// consuming the cap. It only frees allowance the block has used and never
+// lowers the accumulator below zero.
+func releaseUnmapRateLimit(blockHeight uint64, amount int64) {
+	storedHeight, accum := loadUnmapAccumulator()
+	if storedHeight != blockHeight || accum == 0 {
+		return
+	}
+	accum -= amount
+	if accum < 0 {
+		accum = 0
+	}
+	saveUnmapAccumulator(blockHeight, accum)
+}
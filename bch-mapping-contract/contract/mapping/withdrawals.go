@@ -0,0 +1,452 @@
+package mapping
+
+import (
+	"bch-mapping-contract/contract/constants"
+	ce "bch-mapping-contract/contract/contracterrors"
+	"bch-mapping-contract/sdk"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ---------------------------------------------------------------------------
+// Withdrawal queue
+//
+// With queued withdrawals enabled, unmap debits the sender and queues the
+// payout instead of building its own transaction. settleWithdrawals then
+// selects inputs once and pays every queued destination from a single
+// transaction, so only one signing round is needed. The miner fee is split
+// across the payouts in proportion to their amounts and taken out of each.
+// A payout that its share would leave as dust, or push over its max_fee, is
+// refunded instead.
+// ---------------------------------------------------------------------------
+
+const queuedWithdrawalFixedSize = 8 + 8 + 8 + 8 + 1 + 1
+
+type queuedWithdrawal struct {
+	Id     uint64 // id of its withdrawal record
+	From   string
+	To     string
+	Amount int64 // payout before its share of the miner fee
+	VscFee int64
+	MaxFee int64 // -1 when the sender set no max_fee
+}
+
+// debit returns what was taken from the sender's balance.
+func (w *queuedWithdrawal) debit() int64 {
+	return w.Amount + w.VscFee
+}
+
+func marshalWithdrawalQueue(queue []queuedWithdrawal) []byte {
+	var buf []byte
+	for _, w := range queue {
+		buf = binary.BigEndian.AppendUint64(buf, w.Id)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(w.Amount))
+		buf = binary.BigEndian.AppendUint64(buf, uint64(w.VscFee))
+		buf = binary.BigEndian.AppendUint64(buf, uint64(w.MaxFee))
+		buf = append(buf, byte(len(w.From)))
+		buf = append(buf, w.From...)
+		buf = append(buf, byte(len(w.To)))
+		buf = append(buf, w.To...)
+	}
+	return buf
+}
+
+func unmarshalWithdrawalQueue(data []byte) ([]queuedWithdrawal, error) {
+	var queue []queuedWithdrawal
+	for len(data) > 0 {
+		if len(data) < queuedWithdrawalFixedSize {
+			return nil, errors.New("truncated queued withdrawal")
+		}
+		w := queuedWithdrawal{
+			Id:     binary.BigEndian.Uint64(data[0:]),
+			Amount: int64(binary.BigEndian.Uint64(data[8:])),
+			VscFee: int64(binary.BigEndian.Uint64(data[16:])),
+			MaxFee: int64(binary.BigEndian.Uint64(data[24:])),
+		}
+		data = data[32:]
+		var ok bool
+		if w.From, data, ok = readShortString(data); !ok {
+			return nil, errors.New("truncated queued withdrawal sender")
+		}
+		if w.To, data, ok = readShortString(data); !ok {
+			return nil, errors.New("truncated queued withdrawal destination")
+		}
+		queue = append(queue, w)
+	}
+	return queue, nil
+}
+
+// readShortString reads a string prefixed with its 1-byte length.
+func readShortString(data []byte) (string, []byte, bool) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", nil, false
+	}
+	n := int(data[0])
+	return string(data[1 : 1+n]), data[1+n:], true
+}
+
+func loadWithdrawalQueue() ([]queuedWithdrawal, error) {
+	raw := sdk.StateGetObject(constants.WithdrawalQueueKey)
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	queue, err := unmarshalWithdrawalQueue([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding withdrawal queue")
+	}
+	return queue, nil
+}
+
+func saveWithdrawalQueue(queue []queuedWithdrawal) {
+	if len(queue) == 0 {
+		sdk.StateDeleteObject(constants.WithdrawalQueueKey)
+		return
+	}
+	sdk.StateSetObject(constants.WithdrawalQueueKey, string(marshalWithdrawalQueue(queue)))
+}
+
+// withdrawalsQueued reports whether the owner has enabled queued withdrawals.
+func withdrawalsQueued() bool {
+	s := sdk.StateGetObject(constants.WithdrawalQueueModeKey)
+	return s != nil && *s == "1"
+}
+
+// queueUnmap debits the sender and queues the withdrawal for the next
+// settleWithdrawals. amount and vscFee have already been validated by
+// HandleUnmap.
+func (cs *ContractState) queueUnmap(
+	env sdk.Env,
+	from string,
+	instructions *TransferParams,
+	amount, vscFee int64,
+) error {
+	if len(from) > 255 || len(instructions.To) > 255 {
+		return ce.NewContractError(ce.ErrInput, "address too long to queue")
+	}
+	// Reject an undecodable destination now, rather than at settlement.
+	if _, err := cs.destinationScript(instructions.To); err != nil {
+		return err
+	}
+
+	w := queuedWithdrawal{From: from, To: instructions.To, Amount: amount, VscFee: vscFee, MaxFee: -1}
+	if instructions.DeductFee {
+		w.Amount = amount - vscFee
+		if chainPolicy.isDust(w.Amount) {
+			return ce.NewContractError(ce.ErrBalance, "amount too small to cover fees")
+		}
+	}
+	if instructions.MaxFee != nil {
+		if vscFee > *instructions.MaxFee {
+			return ce.NewContractError(
+				ce.ErrTransaction,
+				"vsc fee "+strconv.FormatInt(vscFee, 10)+
+					" exceeds max_fee "+strconv.FormatInt(*instructions.MaxFee, 10),
+			)
+		}
+		w.MaxFee = *instructions.MaxFee
+	}
+	debit, err := safeAdd64(w.Amount, w.VscFee)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error computing final amount")
+	}
+
+	queue, err := loadWithdrawalQueue()
+	if err != nil {
+		return err
+	}
+	if len(queue) >= constants.MaxQueuedWithdrawals {
+		return ce.NewContractError(ce.ErrTransaction, "withdrawal queue is full")
+	}
+
+	if err := checkAndDeductBalance(env, from, debit); err != nil {
+		return err
+	}
+	if err := checkAndUpdateUnmapRateLimit(env.BlockHeight, debit); err != nil {
+		return err
+	}
+
+	if w.Id, err = nextWithdrawalId(); err != nil {
+		return err
+	}
+	saveWithdrawalQueue(append(queue, w))
+	sdk.Log(createQueueLog("queue", &w))
+	err = createWithdrawal(w.Id, &withdrawalRecord{
+		Status: WithdrawalRequested,
+		Debit:  debit,
+		From:   from,
+		To:     instructions.To,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The withdrawal leaves user balances now; the contract's holdings only
+	// when it is settled.
+	if cs.Supply.UserSupply, err = safeSubtract64(cs.Supply.UserSupply, debit); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing user supply")
+	}
+	if cs.Supply.FeeSupply, err = safeAdd64(cs.Supply.FeeSupply, vscFee); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing fee supply")
+	}
+	return nil
+}
+
+// HandleSettleWithdrawals pays up to MaxSettlePerCall queued withdrawals, in
+// queue order, from one transaction and returns its txid and the number of
+// withdrawals it pays. Withdrawals refunded along the way leave the queue
+// too. It returns an empty txid when nothing was paid.
+func (cs *ContractState) HandleSettleWithdrawals() (string, int, error) {
+	queue, err := loadWithdrawalQueue()
+	if err != nil || len(queue) == 0 {
+		return "", 0, err
+	}
+	batch := queue[:min(len(queue), constants.MaxSettlePerCall)]
+	rest := queue[len(batch):]
+
+	changeAddress, _, err := createP2SHAddressWithBackup(
+		cs.PublicKeys.Primary,
+		cs.PublicKeys.Backup,
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		return "", 0, ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
+	}
+
+	for {
+		if len(batch) == 0 {
+			saveWithdrawalQueue(rest)
+			return "", 0, nil
+		}
+		var total int64
+		for _, w := range batch {
+			if total, err = safeAdd64(total, w.Amount); err != nil {
+				return "", 0, ce.WrapContractError(ce.ErrArithmetic, err, "error summing queued withdrawals")
+			}
+		}
+
+		inputUtxoIds, totalInputAmt, err := cs.getInputUtxoIds(total)
+		if err != nil {
+			return "", 0, ce.Prepend(err, "error getting input utxos")
+		}
+		inputUtxos, err := getInputUtxos(inputUtxoIds)
+		if err != nil {
+			return "", 0, ce.Prepend(err, "error getting input utxos")
+		}
+
+		tx, redeemScripts, shares, err := cs.buildBatchTransaction(inputUtxos, totalInputAmt, batch, changeAddress)
+		if err != nil {
+			return "", 0, err
+		}
+
+		// Refund whatever the fee split made unpayable, then rebuild
+		// without it.
+		kept := batch[:0:0]
+		for i := range batch {
+			if chainPolicy.isDust(tx.TxOut[i].Value) ||
+				(batch[i].MaxFee >= 0 && batch[i].VscFee+shares[i] > batch[i].MaxFee) {
+				if err := cs.refundWithdrawal(&batch[i]); err != nil {
+					return "", 0, err
+				}
+				continue
+			}
+			kept = append(kept, batch[i])
+		}
+		if len(kept) < len(batch) {
+			batch = kept
+			continue
+		}
+
+		payouts := make([]SpendPayout, len(batch))
+		for i := range batch {
+			payouts[i] = SpendPayout{
+				From:   batch[i].From,
+				Amount: tx.TxOut[i].Value,
+				Id:     batch[i].Id,
+				VscFee: batch[i].VscFee,
+			}
+		}
+		if err := cs.recordSpend(tx, inputUtxoIds, inputUtxos, redeemScripts, changeAddress, payouts); err != nil {
+			return "", 0, err
+		}
+		err = updateWithdrawals(payouts, func(r *withdrawalRecord, p *SpendPayout) bool {
+			r.Status = WithdrawalSigningRequested
+			r.Sent = p.Amount
+			r.TxId = tx.TxID()
+			return true
+		})
+		if err != nil {
+			return "", 0, err
+		}
+
+		// The transaction spends everything but its change outputs, which
+		// follow the payouts.
+		outflow := totalInputAmt
+		for i := len(batch); i < len(tx.TxOut); i++ {
+			outflow -= tx.TxOut[i].Value
+		}
+		var vscFees, sent int64
+		for i := range batch {
+			vscFees += batch[i].VscFee
+			sent += tx.TxOut[i].Value
+		}
+		sdk.Log(createFeeLog(vscFees, outflow-sent))
+		for i := range batch {
+			sdk.Log(createUnmapLog(tx.TxID(), batch[i].From, batch[i].To, batch[i].debit(), tx.TxOut[i].Value))
+		}
+		if cs.Supply.ActiveSupply, err = safeSubtract64(cs.Supply.ActiveSupply, outflow+vscFees); err != nil {
+			return "", 0, ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing active supply")
+		}
+
+		saveWithdrawalQueue(rest)
+		return tx.TxID(), len(batch), nil
+	}
+}
+
+// buildBatchTransaction builds the transaction paying each withdrawal in
+// batch, in order, less its share of the miner fee. Any change follows the
+// payouts, split as for a single withdrawal. It returns each withdrawal's fee
+// share; a payout the share leaves at or below dust is not yet rejected.
+func (cs *ContractState) buildBatchTransaction(
+	inputs []*Utxo,
+	totalInputsAmount int64,
+	batch []queuedWithdrawal,
+	changeAddress string,
+) (*wire.MsgTx, map[int][]byte, []int64, error) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+
+	redeemScripts, err := cs.addSpendInputs(tx, inputs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var total int64
+	for _, w := range batch {
+		destScript, err := cs.destinationScript(w.To)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tx.AddTxOut(wire.NewTxOut(w.Amount, destScript))
+		total += w.Amount
+	}
+
+	// The fee comes out of the payouts, so the change is whatever the inputs
+	// hold beyond them.
+	change := totalInputsAmount - total
+	if !chainPolicy.isDust(change) {
+		changeScript, err := cs.destinationScript(changeAddress)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		numChangeOutputs := min(max(change/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
+		for numChangeOutputs > 1 && chainPolicy.isDust(change/numChangeOutputs) {
+			numChangeOutputs--
+		}
+		each := change / numChangeOutputs
+		tx.AddTxOut(wire.NewTxOut(each+change-each*numChangeOutputs, changeScript))
+		for range numChangeOutputs - 1 {
+			tx.AddTxOut(wire.NewTxOut(each, changeScript))
+		}
+	}
+
+	fee, err := cs.calculateFee(int64(tx.SerializeSize()), redeemScripts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	shares := feeShares(fee, batch)
+	for i := range batch {
+		tx.TxOut[i].Value -= shares[i]
+	}
+	return tx, redeemScripts, shares, nil
+}
+
+// feeShares splits fee across the withdrawals in proportion to their
+// amounts. The rounding remainder falls to the largest withdrawal.
+func feeShares(fee int64, batch []queuedWithdrawal) []int64 {
+	amounts := make([]int64, len(batch))
+	for i, w := range batch {
+		amounts[i] = w.Amount
+	}
+	return proRata(fee, amounts)
+}
+
+// proRata splits total in proportion to amounts. The rounding remainder
+// falls to the largest amount.
+func proRata(total int64, amounts []int64) []int64 {
+	var sum int64
+	largest := 0
+	for i, a := range amounts {
+		sum += a
+		if a > amounts[largest] {
+			largest = i
+		}
+	}
+	shares := make([]int64, len(amounts))
+	if sum <= 0 {
+		return shares
+	}
+	remainder := total
+	for i, a := range amounts {
+		// total*a/sum < 2^63 since a <= sum, so Div64 cannot overflow.
+		hi, lo := bits.Mul64(uint64(total), uint64(a))
+		q, _ := bits.Div64(hi, lo, uint64(sum))
+		shares[i] = int64(q)
+		remainder -= shares[i]
+	}
+	shares[largest] += remainder
+	return shares
+}
+
+// refundWithdrawal returns a queued withdrawal, VSC fee included, to the
+// sender's balance and releases it from the unmap rate limit.
+func (cs *ContractState) refundWithdrawal(w *queuedWithdrawal) error {
+	if err := incAccBalance(w.From, w.debit()); err != nil {
+		return ce.Prepend(err, "error refunding queued withdrawal")
+	}
+	releaseUnmapRateLimit(sdk.GetEnv().BlockHeight, w.debit())
+	var err error
+	if cs.Supply.UserSupply, err = safeAdd64(cs.Supply.UserSupply, w.debit()); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing user supply")
+	}
+	if cs.Supply.FeeSupply, err = safeSubtract64(cs.Supply.FeeSupply, w.VscFee); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing fee supply")
+	}
+	sdk.Log(createQueueLog("refund", w))
+	return updateWithdrawals([]SpendPayout{{Id: w.Id}}, func(r *withdrawalRecord, _ *SpendPayout) bool {
+		r.Status = WithdrawalFailed
+		return true
+	})
+}
+
+// createQueueLog records a change to a queued withdrawal: its sender,
+// destination, the amount debited and the payout before its share of the
+// miner fee. The type is "queue" when it is queued and "refund" when it is
+// returned to the sender. Settled withdrawals are logged as unmaps.
+func createQueueLog(kind string, w *queuedWithdrawal) string {
+	var b strings.Builder
+	b.Grow(160)
+	b.WriteString(kind)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("f")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(w.From)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("t")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(w.To)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("d")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendInt(buf[:0], w.debit(), 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("a")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.Write(strconv.AppendInt(buf[:0], w.Amount, 10))
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package mapping
+
+import (
+	"encoding/hex"
+	"slices"
+	"testing"
+)
+
+func TestWithdrawalQueueRoundTrip(t *testing.T) {
+	queue := []queuedWithdrawal{
+		{Id: 1, From: "hive:milo-hpr", To: "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", Amount: 25000, VscFee: 0, MaxFee: -1},
+		{Id: 7, From: "hive:vaultec", To: "1BoatSLRHtKNngkdXEeobR76b53LETtpyT", Amount: 1000000, VscFee: 50, MaxFee: 900},
+	}
+	data := marshalWithdrawalQueue(queue)
+	got, err := unmarshalWithdrawalQueue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, queue) {
+		t.Fatalf("got %+v, want %+v", got, queue)
+	}
+	if _, err := unmarshalWithdrawalQueue(data[:len(data)-1]); err == nil {
+		t.Error("expected a truncated queue to fail")
+	}
+}
+
+func TestFeeShares(t *testing.T) {
+	cases := []struct {
+		fee     int64
+		amounts []int64
+		want    []int64
+	}{
+		{300, []int64{100000}, []int64{300}},
+		{300, []int64{100000, 200000}, []int64{100, 200}},
+		// 1000/3 rounds down; the remainder falls to the largest payout.
+		{1000, []int64{50000, 50000, 60000}, []int64{312, 312, 376}},
+		{0, []int64{1000, 2000}, []int64{0, 0}},
+		// Large amounts must not overflow the intermediate product.
+		{1 << 40, []int64{1 << 50, 1 << 50}, []int64{1 << 39, 1 << 39}},
+	}
+	for _, c := range cases {
+		batch := make([]queuedWithdrawal, len(c.amounts))
+		for i, a := range c.amounts {
+			batch[i].Amount = a
+		}
+		if got := feeShares(c.fee, batch); !slices.Equal(got, c.want) {
+			t.Errorf("feeShares(%d, %v) = %v, want %v", c.fee, c.amounts, got, c.want)
+		}
+	}
+}
+
+func TestBatchTransactionChangeIndexed(t *testing.T) {
+	cs := newTestState(t, 1)
+	changeAddr, _, err := AddressWithBackup(
+		hex.EncodeToString(cs.PublicKeys.Primary[:]),
+		hex.EncodeToString(cs.PublicKeys.Backup[:]),
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		t.Fatalf("derive change address: %v", err)
+	}
+	batch := make([]queuedWithdrawal, 3)
+	for i := range batch {
+		batch[i] = queuedWithdrawal{To: regtestDestAddr(t), Amount: 100_000, MaxFee: -1}
+	}
+	const inputAmount = 10_000_000
+	tx, _, _, err := cs.buildBatchTransaction([]*Utxo{mkInput(t, inputAmount)}, inputAmount, batch, changeAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utxos, err := indexUnconfimedOutputs(tx, changeAddr, cs.Network.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != len(tx.TxOut)-len(batch) {
+		t.Fatalf("indexed %d change outputs, want %d", len(utxos), len(tx.TxOut)-len(batch))
+	}
+	for _, utxo := range utxos {
+		if utxo == nil || int(utxo.Vout) < len(batch) {
+			t.Fatalf("indexed %+v, want only the outputs after the payouts", utxo)
+		}
+	}
+}
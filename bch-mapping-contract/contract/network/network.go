@@ -0,0 +1,121 @@
+package network
+
+import (
+	"bch-mapping-contract/contract/constants"
+	ce "bch-mapping-contract/contract/contracterrors"
+	"bch-mapping-contract/sdk"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// Network describes the chain a contract is bridged to. It is fixed in state
+// by the first seedBlocks and read by every handler, so one test network
+// build can be deployed for any test network.
+type Network struct {
+	Name   string
+	Params *chaincfg.Params
+	// BackupCSVBlocks is the relative timelock on the backup key's spending
+	// path of deposit and change addresses.
+	BackupCSVBlocks int64
+	// Testnet lets the contract owner act as the oracle, re-register public
+	// keys, and replace more than two blocks at once.
+	Testnet bool
+	// OracleAddress may submit headers when no oracle set is configured.
+	OracleAddress string
+	// BlockRetention is the number of recent headers kept in state.
+	BlockRetention uint32
+}
+
+var networks = []*Network{
+	{
+		Name:            constants.Mainnet,
+		Params:          &chaincfg.MainNetParams,
+		BackupCSVBlocks: constants.BackupCSVBlocks,
+		OracleAddress:   constants.OracleAddress,
+		BlockRetention:  constants.MaxBlockRetention,
+	},
+	{
+		Name:            constants.Testnet,
+		Params:          &chaincfg.TestNet3Params,
+		BackupCSVBlocks: constants.TestnetBackupCSVBlocks,
+		Testnet:         true,
+		OracleAddress:   constants.OracleAddress,
+		BlockRetention:  constants.MaxBlockRetention,
+	},
+	{
+		Name:            constants.Regtest,
+		Params:          &chaincfg.RegressionNetParams,
+		BackupCSVBlocks: constants.TestnetBackupCSVBlocks,
+		Testnet:         true,
+		OracleAddress:   constants.OracleAddress,
+		BlockRetention:  constants.MaxBlockRetention,
+	},
+}
+
+// Lookup returns the network named name.
+func Lookup(name string) (*Network, bool) {
+	for _, n := range networks {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// Load returns the network fixed in state. Contracts seeded before the
+// network was stored use fallback, the build's default, which is mainnet
+// when empty.
+func Load(fallback string) (*Network, error) {
+	name := fallback
+	if stored := sdk.StateGetObject(constants.NetworkKey); stored != nil && *stored != "" {
+		name = *stored
+	}
+	if name == "" {
+		name = constants.Mainnet
+	}
+	n, ok := Lookup(name)
+	if !ok {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "unknown network "+name)
+	}
+	return n, nil
+}
+
+// Fix stores the network named name, or fallback when name is empty, unless
+// a network is already stored. The stored network cannot be changed, and
+// must be compatible with fallback, the build's default.
+func Fix(name string, fallback string) (*Network, error) {
+	stored := sdk.StateGetObject(constants.NetworkKey)
+	if stored != nil && *stored != "" {
+		if name != "" && name != *stored {
+			return nil, ce.NewContractError(ce.ErrInput, "network is already set to "+*stored)
+		}
+		return Load(fallback)
+	}
+	if fallback == "" {
+		fallback = constants.Mainnet
+	}
+	built, ok := Lookup(fallback)
+	if !ok {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "unknown network "+fallback)
+	}
+	if name == "" {
+		name = fallback
+	}
+	n, ok := Lookup(name)
+	if !ok {
+		return nil, ce.NewContractError(ce.ErrInput, "unknown network "+name)
+	}
+	if !compatible(n, built) {
+		return nil, ce.NewContractError(ce.ErrInput, "network "+name+" is not compatible with this build for "+built.Name)
+	}
+	sdk.StateSetObject(constants.NetworkKey, n.Name)
+	return n, nil
+}
+
+// compatible reports whether a build for built may be seeded as n. A mainnet
+// build only takes mainnet, so a deployment cannot be pinned to a test
+// network and pick up its privileges and short timelocks; a test network
+// build takes any test network.
+func compatible(n, built *Network) bool {
+	return n == built || (n.Testnet && built.Testnet)
+}
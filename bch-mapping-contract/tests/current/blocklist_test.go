@@ -4,6 +4,7 @@ import (
 	btcMapping "bch-mapping-contract"
 	"bch-mapping-contract/contract/constants"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -324,6 +325,36 @@ func TestAllOperations(t *testing.T) {
 		assert.Contains(t, r2.Ret, "4888517")
 	})
 
+	t.Run("AddBlocks_CompetingBranchNeedsMoreWork", func(t *testing.T) {
+		fcId := "forkchoice_blocklist"
+		w.ct.RegisterContract(fcId, testOwner, btcMapping.TestnetWasm)
+
+		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
+		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
+		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
+
+		w.ct.StateSet(fcId, constants.LastHeightKey, "4888515")
+		w.ct.StateSet(fcId, constants.BlockPrefix+"4888515", decodeHex(t, block4888515Hex))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(fcId, constants.SupplyKey, string(supply))
+
+		oracleCaller := "did:vsc:oracle:bch"
+		payload := `{"blocks":"` + block4888516Hex + block4888517Hex + `","latest_fee":1}`
+		r := callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
+		assert.NotEmpty(t, w.ct.StateGet(fcId, constants.ChainWorkPrefix+"4888517"))
+
+		// Resubmitting the tip adds nothing: it is already on the stored chain
+		// and must not be taken as a branch of its own.
+		payload = `{"blocks":"` + block4888517Hex + `","latest_fee":1}`
+		r = callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "resubmitted tip should be rejected")
+		assert.Contains(t, r.ErrMsg, "already stored")
+		assert.Equal(t, "4888517", w.ct.StateGet(fcId, constants.LastHeightKey))
+		assert.Empty(t, w.ct.StateGet(fcId, constants.StagedBranchKey))
+	})
+
 	// ========== ReplaceBlocks (multi-block reorg) ==========
 
 	t.Run("ReplaceBlocks_NonOwnerFails", func(t *testing.T) {
@@ -440,3 +471,146 @@ func TestAllOperations(t *testing.T) {
 		assert.False(t, r.Success, "transferFrom with insufficient balance should fail")
 	})
 }
+
+func TestSeedBlocksHeaderRun(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+
+	// Dropping the middle header breaks the run's linkage.
+	broken := lastBlockHeader + added.Blocks[160:]
+	r := callAction(t, w, "seedBlocks", `{"block_headers":"`+broken+`","block_height":`+lastBlockHeight+`}`, "")
+	assert.False(t, r.Success, "seedBlocks with unlinked headers should fail")
+
+	run := lastBlockHeader + added.Blocks
+	r = callAction(t, w, "seedBlocks", `{"block_headers":"`+run+`","block_height":`+lastBlockHeight+`}`, "")
+	require.True(t, r.Success, "seedBlocks with a header run should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.SeedHeightKey))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.BlockPrefix+"116088"))
+}
+
+func TestAddBlocksFutureDrift(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	// callAt calls as the owner in a Hive block with the given timestamp.
+	callAt := func(action, payload, timestamp string) test_utils.ContractTestCallResult {
+		self := basicSelf(t, testOwner)
+		self.Timestamp = timestamp
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self:       *self,
+			ContractId: testContractId,
+			Action:     action,
+			Payload:    json.RawMessage([]byte(payload)),
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     testOwner,
+		})
+	}
+
+	r := callAt("setMaxFutureDrift", "7200", "2025-12-30T00:00:00")
+	require.True(t, r.Success, "setMaxFutureDrift failed: %s %s", r.Err, r.ErrMsg)
+
+	// The headers were mined on 2025-12-30 between 22:49 and 23:10 UTC.
+	r = callAt("addBlocks", twoBlocksPayload, "2025-12-30T20:00:00")
+	assert.False(t, r.Success, "addBlocks with headers more than 2 hours ahead should fail")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = callAt("addBlocks", twoBlocksPayload, "2025-12-30T22:00:00")
+	require.True(t, r.Success, "addBlocks within the drift bound should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+}
+
+func TestAddBlocksOracleQuorum(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+	submit := func(blocks string, fee int, oracle string) test_utils.ContractTestCallResult {
+		payload := `{"blocks":"` + blocks + `","latest_fee":` + strconv.Itoa(fee) + `}`
+		return callAction(t, w, "addBlocks", payload, oracle)
+	}
+
+	r := callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "hive:oracle1")
+	assert.False(t, r.Success, "setOracles by a non-owner should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2"],"threshold":3}`, "")
+	assert.False(t, r.Success, "setOracles with a threshold above the set size should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "")
+	require.True(t, r.Success, "setOracles failed: %s %s", r.Err, r.ErrMsg)
+
+	r = submit(added.Blocks, 1, "did:vsc:oracle:bch")
+	assert.False(t, r.Success, "the single oracle address is not a member of the set")
+
+	r = submit(added.Blocks, 10, "hive:oracle1")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	// A different batch does not count towards the first.
+	r = submit(added.Blocks[:160], 10, "hive:oracle2")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = submit(added.Blocks, 30, "hive:oracle3")
+	require.True(t, r.Success, "quorum commit failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Contains(t, r.Ret, "base fee: 20")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle1"))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle3"))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+
+	// The single oracle address cannot change the headers around the quorum.
+	for _, action := range []string{"seedBlocks", "replaceBlock", "replaceBlocks", "prune"} {
+		r = callAction(t, w, action, added.Blocks[160:], "did:vsc:oracle:bch")
+		assert.False(t, r.Success, "%s by the single oracle address should fail with an oracle set", action)
+	}
+	r = callAction(t, w, "prune", "", "")
+	require.True(t, r.Success, "prune by the owner failed: %s %s", r.Err, r.ErrMsg)
+
+	r = callAction(t, w, "setOracles", `{"oracles":[],"threshold":0}`, "")
+	require.True(t, r.Success, "clearing the oracle set failed: %s %s", r.Err, r.ErrMsg)
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+}
+
+func TestAddBlocksFeeRateWindow(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+
+	r := callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[:160]+`","latest_fee":10}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 10")
+
+	// The median of 10 and 500 is 255, but one update moves the rate by at
+	// most a quarter.
+	r = callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[160:]+`","latest_fee":500}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 12")
+	assert.Len(t, ct.StateGet(testContractId, constants.FeeWindowKey), 16)
+}
@@ -13,7 +13,6 @@ import (
 	stateEngine "vsc-node/modules/state-processing"
 
 	"github.com/CosmWasm/tinyjson"
-	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
@@ -300,11 +299,11 @@ func TestMapMultipleOutputsSameTx(t *testing.T) {
 	const amount2 = int64(3000)
 
 	// Derive both deposit addresses
-	addr1, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction1, regtestParams())
+	addr1, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction1, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive addr1:", err)
 	}
-	addr2, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction2, regtestParams())
+	addr2, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction2, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive addr2:", err)
 	}
@@ -313,7 +312,7 @@ func TestMapMultipleOutputsSameTx(t *testing.T) {
 	tx := buildTestTx(t, addr1, amount1)
 
 	// Add second output to the same transaction
-	addr2Decoded, err := btcutil.DecodeAddress(addr2, regtestParams())
+	addr2Decoded, err := mapping.DecodeAddress(addr2, regtestParams())
 	if err != nil {
 		t.Fatal("failed to decode addr2:", err)
 	}
@@ -451,7 +450,7 @@ func TestUnmapExactBalance(t *testing.T) {
 
 	// After unmap, balance should be less than original
 	remainingBal := ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr")
-	// vscFee = 0 (VscFeeMinSats=0, VscFeeRateBps=0)
+	// vscFee = 0 (the chain policy has no VSC fee)
 	// So remaining should be 100000 - 50000 - btcFee = 50000 - btcFee
 	t.Logf("remaining balance after unmap: %q", remainingBal)
 	// Verify it decreased significantly
@@ -677,7 +676,7 @@ func TestUnmapSupplyUpdates(t *testing.T) {
 
 	t.Logf("supply after unmap: active=%d, user=%d, fee=%d", supply.ActiveSupply, supply.UserSupply, supply.FeeSupply)
 
-	// vscFee = 0 (VscFeeMinSats=0, VscFeeRateBps=0)
+	// vscFee = 0 (the chain policy has no VSC fee)
 	// ActiveSupply should decrease by (amount + btcFee)
 	assert.True(t, supply.ActiveSupply < balance, "active supply should decrease")
 	assert.True(t, supply.UserSupply < balance, "user supply should decrease")
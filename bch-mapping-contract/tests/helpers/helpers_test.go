@@ -3,9 +3,8 @@ package helpers
 import (
 	"bch-mapping-contract/contract/constants"
 	"bch-mapping-contract/contract/mapping"
+	"bch-mapping-contract/contract/network"
 	"testing"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
 func TestCreateAddress(t *testing.T) {
@@ -18,11 +17,12 @@ func TestCreateAddress(t *testing.T) {
 	const instruction = constants.DepositToKey + "=" + recipient
 	t.Log("instruction:", instruction)
 
+	net, _ := network.Lookup(constants.Testnet)
 	address, _, err := mapping.DepositAddress(
 		primaryTestnet,
 		backupTestnetDevnet,
 		instruction,
-		&chaincfg.TestNet4Params,
+		net,
 	)
 	// address, _, err := mapping.DepositAddress(primaryMainnet, backupMainnet, instruction, &chaincfg.MainNetParams)
 	if err != nil {
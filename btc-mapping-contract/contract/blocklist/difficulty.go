@@ -0,0 +1,265 @@
+package blocklist
+
+import (
+	"btc-mapping-contract/sdk"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"time"
+
+	"btc-mapping-contract/contract/constants"
+	ce "btc-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// maxTimeWarpSeconds is the BIP94 limit on how far the first block of an
+// epoch may be timestamped before its parent.
+const maxTimeWarpSeconds = 600
+
+// retargetAnchor is the part of an epoch's first header that the difficulty
+// calculation needs once the header itself has been pruned.
+type retargetAnchor struct {
+	Timestamp uint32
+	Bits      uint32
+}
+
+// anchorLookup returns the retarget anchor for the epoch starting at the
+// given height, or false if none is stored.
+type anchorLookup func(epochStart uint32) (retargetAnchor, bool)
+
+func newRetargetAnchor(header *wire.BlockHeader) retargetAnchor {
+	return retargetAnchor{Timestamp: uint32(header.Timestamp.Unix()), Bits: header.Bits}
+}
+
+// epochStart returns the height of the first block of the epoch containing height.
+func epochStart(height uint32) uint32 {
+	return height - height%constants.RetargetInterval
+}
+
+func loadRetargetAnchor(epochStart uint32) (retargetAnchor, bool) {
+	raw := sdk.StateGetObject(constants.RetargetAnchorPrefix + strconv.FormatUint(uint64(epochStart), 10))
+	if raw == nil || len(*raw) != 8 {
+		return retargetAnchor{}, false
+	}
+	b := []byte(*raw)
+	return retargetAnchor{
+		Timestamp: binary.BigEndian.Uint32(b[0:4]),
+		Bits:      binary.BigEndian.Uint32(b[4:8]),
+	}, true
+}
+
+func saveRetargetAnchor(epochStart uint32, header *wire.BlockHeader) {
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(header.Timestamp.Unix()))
+	binary.BigEndian.PutUint32(b[4:8], header.Bits)
+	sdk.StateSetObject(constants.RetargetAnchorPrefix+strconv.FormatUint(uint64(epochStart), 10), string(b[:]))
+}
+
+func deleteRetargetAnchor(epochStart uint32) {
+	sdk.StateDeleteObject(constants.RetargetAnchorPrefix + strconv.FormatUint(uint64(epochStart), 10))
+}
+
+// storeHeader writes the raw header at height and, when it opens a new
+// difficulty epoch, records the epoch's retarget anchor alongside it.
+func storeHeader(height uint32, header *wire.BlockHeader, raw []byte) {
+	sdk.StateSetObject(constants.BlockPrefix+strconv.FormatUint(uint64(height), 10), string(raw))
+	if height%constants.RetargetInterval == 0 {
+		saveRetargetAnchor(height, header)
+	}
+}
+
+// calcRequiredBits returns the compact target that a header at height with
+// the given timestamp must carry, given its parent header. It follows
+// Bitcoin Core's GetNextWorkRequired (and btcd's calcNextRequiredDifficulty),
+// reading the epoch's first header from the retarget anchors since only
+// MaxBlockRetention headers are kept in state.
+func calcRequiredBits(
+	params *chaincfg.Params,
+	height uint32,
+	prev *wire.BlockHeader,
+	timestamp time.Time,
+	anchors anchorLookup,
+) (uint32, error) {
+	if height == 0 {
+		return params.PowLimitBits, nil
+	}
+
+	if height%constants.RetargetInterval != 0 {
+		if !params.ReduceMinDifficulty {
+			return prev.Bits, nil
+		}
+
+		// Testnet: a block more than 20 minutes after its parent may be mined
+		// at the minimum difficulty.
+		if timestamp.After(prev.Timestamp.Add(params.MinDiffReductionTime)) {
+			return params.PowLimitBits, nil
+		}
+
+		// Otherwise it inherits the difficulty of the last block that was not
+		// mined under the min-difficulty exception. Every such block in an
+		// epoch carries the epoch's own bits, so the walk back ends at the
+		// epoch start whenever the parent is itself a min-difficulty block.
+		if (height-1)%constants.RetargetInterval == 0 || prev.Bits != params.PowLimitBits {
+			return prev.Bits, nil
+		}
+		anchor, ok := anchors(epochStart(height))
+		if !ok {
+			return 0, missingAnchorError(epochStart(height))
+		}
+		return anchor.Bits, nil
+	}
+
+	if height < constants.RetargetInterval {
+		return prev.Bits, nil
+	}
+	first := height - constants.RetargetInterval
+	anchor, ok := anchors(first)
+	if !ok {
+		return 0, missingAnchorError(first)
+	}
+
+	targetTimespan := int64(params.TargetTimespan / time.Second)
+	adjustmentFactor := params.RetargetAdjustmentFactor
+	minTimespan := targetTimespan / adjustmentFactor
+	maxTimespan := targetTimespan * adjustmentFactor
+
+	actualTimespan := prev.Timestamp.Unix() - int64(anchor.Timestamp)
+	adjustedTimespan := actualTimespan
+	if actualTimespan < minTimespan {
+		adjustedTimespan = minTimespan
+	} else if actualTimespan > maxTimespan {
+		adjustedTimespan = maxTimespan
+	}
+
+	// BIP94 (testnet4) retargets from the epoch's first block so that a
+	// min-difficulty block at the end of an epoch cannot reset the difficulty.
+	oldBits := prev.Bits
+	if params.EnforceBIP94 {
+		oldBits = anchor.Bits
+	}
+
+	newTarget := new(big.Int).Mul(blockchain.CompactToBig(oldBits), big.NewInt(adjustedTimespan))
+	newTarget.Div(newTarget, big.NewInt(targetTimespan))
+	if newTarget.Cmp(params.PowLimit) > 0 {
+		newTarget.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(newTarget), nil
+}
+
+// checkDifficulty rejects a header whose bits differ from the network's
+// required target at that height.
+func checkDifficulty(params *chaincfg.Params, height uint32, prev *wire.BlockHeader, header *wire.BlockHeader) error {
+	return checkDifficultyWith(params, height, prev, header, loadRetargetAnchor)
+}
+
+func checkDifficultyWith(
+	params *chaincfg.Params,
+	height uint32,
+	prev *wire.BlockHeader,
+	header *wire.BlockHeader,
+	anchors anchorLookup,
+) error {
+	// Regtest never retargets; its headers are only bounded by PowLimit,
+	// which CheckProofOfWork already enforces.
+	if params.PoWNoRetargeting {
+		return nil
+	}
+
+	// BIP94 time-warp protection: the first block of an epoch may not be
+	// timestamped more than 10 minutes before its parent.
+	if params.EnforceBIP94 && height%constants.RetargetInterval == 0 &&
+		header.Timestamp.Unix() < prev.Timestamp.Unix()-maxTimeWarpSeconds {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" timestamp violates time-warp rule",
+		)
+	}
+
+	expected, err := calcRequiredBits(params, height, prev, header.Timestamp, anchors)
+	if err != nil {
+		return err
+	}
+	if header.Bits != expected {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" has unexpected difficulty bits "+
+				strconv.FormatUint(uint64(header.Bits), 16)+", expected "+strconv.FormatUint(uint64(expected), 16),
+		)
+	}
+	return nil
+}
+
+func missingAnchorError(epochStart uint32) error {
+	return ce.NewContractError(
+		ce.ErrStateAccess,
+		"no retarget anchor for epoch starting at height "+strconv.FormatUint(uint64(epochStart), 10)+
+			", seed with epoch_header or call initRetarget",
+	)
+}
+
+// HandleInitRetarget stores the retarget anchor for the epoch containing the
+// current tip. It is used for contracts seeded before retargeting was
+// enforced, and must be given the header at the epoch's first height.
+func HandleInitRetarget(params SeedBlocksParams) (uint32, error) {
+	if params.BlockHeight%constants.RetargetInterval != 0 {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"block height must be the start of a difficulty epoch (multiple of "+
+				strconv.Itoa(constants.RetargetInterval)+")",
+		)
+	}
+	lastHeight, err := LastHeightFromState()
+	if err != nil {
+		return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+	}
+	if epochStart(lastHeight) != params.BlockHeight {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"epoch start does not match the current tip, expected height "+
+				strconv.FormatUint(uint64(epochStart(lastHeight)), 10),
+		)
+	}
+	if _, ok := loadRetargetAnchor(params.BlockHeight); ok {
+		return 0, ce.NewContractError(ce.ErrInput, "retarget anchor already set")
+	}
+
+	header, err := decodeHeaderHex(params.BlockHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the epoch-start header is still retained it must match the input.
+	stored := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatUint(uint64(params.BlockHeight), 10))
+	if stored != nil && *stored != "" {
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			return 0, ce.NewContractError(ce.ErrInput, "error encoding block header: "+err.Error())
+		}
+		if !bytes.Equal(buf.Bytes(), []byte(*stored)) {
+			return 0, ce.NewContractError(ce.ErrInput, "header does not match stored block at epoch start")
+		}
+	}
+
+	saveRetargetAnchor(params.BlockHeight, header)
+	return params.BlockHeight, nil
+}
+
+func decodeHeaderHex(headerHex string) (*wire.BlockHeader, error) {
+	headerBytes, err := hex.DecodeString(headerHex)
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrInvalidHex, err, "invalid block header hex")
+	}
+	if len(headerBytes) != 80 {
+		return nil, ce.NewContractError(ce.ErrInput, "expected exactly 80 bytes (one block header)")
+	}
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader(headerBytes), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+	}
+	return &header, nil
+}
@@ -0,0 +1,193 @@
+package blocklist
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"btc-mapping-contract/contract/constants"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// testNode implements blockchain.HeaderCtx so btcd's own contextual header
+// checks can serve as the reference for calcRequiredBits.
+type testNode struct {
+	height int32
+	header wire.BlockHeader
+	parent *testNode
+}
+
+func (n *testNode) Height() int32    { return n.height }
+func (n *testNode) Bits() uint32     { return n.header.Bits }
+func (n *testNode) Timestamp() int64 { return n.header.Timestamp.Unix() }
+func (n *testNode) Parent() blockchain.HeaderCtx {
+	if n.parent == nil {
+		return nil
+	}
+	return n.parent
+}
+func (n *testNode) RelativeAncestorCtx(distance int32) blockchain.HeaderCtx {
+	node := n
+	for i := int32(0); i < distance && node != nil; i++ {
+		node = node.parent
+	}
+	if node == nil {
+		return nil
+	}
+	return node
+}
+
+type testChainCtx struct{ params *chaincfg.Params }
+
+func (c testChainCtx) ChainParams() *chaincfg.Params { return c.params }
+func (c testChainCtx) BlocksPerRetarget() int32 {
+	return int32(c.params.TargetTimespan / c.params.TargetTimePerBlock)
+}
+func (c testChainCtx) MinRetargetTimespan() int64 {
+	return int64(c.params.TargetTimespan/time.Second) / c.params.RetargetAdjustmentFactor
+}
+func (c testChainCtx) MaxRetargetTimespan() int64 {
+	return int64(c.params.TargetTimespan/time.Second) * c.params.RetargetAdjustmentFactor
+}
+func (c testChainCtx) VerifyCheckpoint(int32, *chainhash.Hash) bool { return true }
+func (c testChainCtx) FindPreviousCheckpoint() (blockchain.HeaderCtx, error) {
+	return nil, nil
+}
+
+// buildChain extends a chain from an epoch start for two full epochs, asking
+// calcRequiredBits for each header's bits and checking btcd agrees that they
+// (and only they) are valid.
+func buildChain(t *testing.T, params *chaincfg.Params, startBits uint32, spacing func(i int) time.Duration) {
+	t.Helper()
+	const startHeight = 2016 * 100
+
+	anchors := map[uint32]retargetAnchor{}
+	lookup := func(h uint32) (retargetAnchor, bool) {
+		a, ok := anchors[h]
+		return a, ok
+	}
+
+	tip := &testNode{
+		height: startHeight,
+		header: wire.BlockHeader{Version: 0x20000000, Bits: startBits, Timestamp: time.Unix(1700000000, 0)},
+	}
+	anchors[startHeight] = retargetAnchor{Timestamp: uint32(tip.header.Timestamp.Unix()), Bits: startBits}
+	chainCtx := testChainCtx{params: params}
+
+	for i := 1; i <= 2*constants.RetargetInterval+10; i++ {
+		height := uint32(tip.height) + 1
+		header := wire.BlockHeader{
+			Version:   0x20000000,
+			PrevBlock: tip.header.BlockHash(),
+			Timestamp: tip.header.Timestamp.Add(spacing(i)),
+		}
+		bits, err := calcRequiredBits(params, height, &tip.header, header.Timestamp, lookup)
+		if err != nil {
+			t.Fatalf("height %d: %v", height, err)
+		}
+		header.Bits = bits
+
+		if err := blockchain.CheckBlockHeaderContext(&header, tip, blockchain.BFNone, chainCtx, true); err != nil {
+			t.Fatalf("height %d: btcd rejected bits %08x: %v", height, bits, err)
+		}
+		if err := checkDifficultyWith(params, height, &tip.header, &header, lookup); err != nil {
+			t.Fatalf("height %d: %v", height, err)
+		}
+
+		wrong := header
+		wrong.Bits = bits - 1
+		if err := checkDifficultyWith(params, height, &tip.header, &wrong, lookup); err == nil {
+			t.Fatalf("height %d: accepted wrong bits %08x", height, wrong.Bits)
+		}
+
+		if height%constants.RetargetInterval == 0 {
+			anchors[height] = retargetAnchor{Timestamp: uint32(header.Timestamp.Unix()), Bits: bits}
+		}
+		tip = &testNode{height: int32(height), header: header, parent: tip}
+	}
+}
+
+func TestRequiredBitsMainnetFastBlocks(t *testing.T) {
+	// Blocks every 2 minutes: the retarget is clamped to a 4x increase.
+	buildChain(t, &chaincfg.MainNetParams, 0x1703a30c, func(int) time.Duration { return 2 * time.Minute })
+}
+
+func TestRequiredBitsMainnetSlowBlocks(t *testing.T) {
+	// Blocks every 13 minutes: difficulty drops, capped at PowLimit.
+	buildChain(t, &chaincfg.MainNetParams, 0x1c00ffff, func(int) time.Duration { return 13 * time.Minute })
+}
+
+func TestRequiredBitsMainnetJitter(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	buildChain(t, &chaincfg.MainNetParams, 0x1703a30c, func(int) time.Duration {
+		return time.Duration(300+r.Intn(600)) * time.Second
+	})
+}
+
+// testnetSpacing mixes normal blocks with >20 minute gaps so that runs of
+// min-difficulty blocks appear mid-epoch and right before boundaries.
+func testnetSpacing(seed int64) func(int) time.Duration {
+	r := rand.New(rand.NewSource(seed))
+	return func(i int) time.Duration {
+		if i%constants.RetargetInterval > 2010 || r.Intn(5) == 0 {
+			return 21 * time.Minute
+		}
+		return time.Duration(60+r.Intn(600)) * time.Second
+	}
+}
+
+func TestRequiredBitsTestnet3(t *testing.T) {
+	buildChain(t, &chaincfg.TestNet3Params, 0x1a01b2c3, testnetSpacing(3))
+}
+
+func TestRequiredBitsTestnet4(t *testing.T) {
+	buildChain(t, &chaincfg.TestNet4Params, 0x1a01b2c3, testnetSpacing(4))
+}
+
+func TestRequiredBitsSignet(t *testing.T) {
+	// Signet retargets like mainnet but has its own, higher PowLimit.
+	buildChain(t, &chaincfg.SigNetParams, 0x1e0377ae, func(int) time.Duration { return 13 * time.Minute })
+}
+
+func TestRequiredBitsMissingAnchor(t *testing.T) {
+	none := func(uint32) (retargetAnchor, bool) { return retargetAnchor{}, false }
+	prev := wire.BlockHeader{Bits: 0x1703a30c, Timestamp: time.Unix(1700000000, 0)}
+
+	// Mid-epoch mainnet blocks only need the parent.
+	if _, err := calcRequiredBits(&chaincfg.MainNetParams, 2016*100+5, &prev, prev.Timestamp, none); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A boundary needs the previous epoch's anchor.
+	if _, err := calcRequiredBits(&chaincfg.MainNetParams, 2016*100, &prev, prev.Timestamp, none); err == nil {
+		t.Fatal("expected missing anchor error at epoch boundary")
+	}
+	// A testnet block following a min-difficulty block needs the current epoch's anchor.
+	minPrev := wire.BlockHeader{Bits: chaincfg.TestNet4Params.PowLimitBits, Timestamp: prev.Timestamp}
+	if _, err := calcRequiredBits(&chaincfg.TestNet4Params, 2016*100+5, &minPrev, prev.Timestamp, none); err == nil {
+		t.Fatal("expected missing anchor error after min-difficulty block")
+	}
+}
+
+func TestTimeWarpTestnet4(t *testing.T) {
+	prev := wire.BlockHeader{Bits: 0x1a01b2c3, Timestamp: time.Unix(1700000000, 0)}
+	anchors := func(uint32) (retargetAnchor, bool) {
+		return retargetAnchor{Timestamp: 1700000000 - 14*24*3600, Bits: 0x1a01b2c3}, true
+	}
+	header := wire.BlockHeader{Timestamp: prev.Timestamp.Add(-601 * time.Second)}
+	header.Bits, _ = calcRequiredBits(&chaincfg.TestNet4Params, 2016*100, &prev, header.Timestamp, anchors)
+	if err := checkDifficultyWith(&chaincfg.TestNet4Params, 2016*100, &prev, &header, anchors); err == nil {
+		t.Fatal("expected time-warp rejection")
+	}
+}
+
+func TestRegtestSkipsRetarget(t *testing.T) {
+	prev := wire.BlockHeader{Bits: 0x1d00ffff, Timestamp: time.Unix(1700000000, 0)}
+	header := wire.BlockHeader{Bits: 0x1d00ffff, Timestamp: prev.Timestamp.Add(21 * time.Minute)}
+	if err := checkDifficulty(&chaincfg.RegressionNetParams, 2016*100+1, &prev, &header); err != nil {
+		t.Fatalf("regtest should not enforce retargeting: %v", err)
+	}
+}
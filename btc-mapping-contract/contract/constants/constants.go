@@ -22,7 +22,8 @@ const BalancePrefix = "a" + DirPathDelimiter
 
 // ObservedBlockPrefix stores the list of observed txid:vout pairs for a given
 // block height. Key: "o-<height>", Value: packed 34-byte entries (32-byte txid
-// + 2-byte vout BE). Pruned alongside block headers during addBlocks.
+// + 2-byte vout BE). Kept when the block header is pruned, so a deposit proven
+// against the header archive cannot be minted twice.
 const ObservedBlockPrefix = "o" + DirPathDelimiter
 const UtxoPrefix = "u" + DirPathDelimiter
 const UtxoRegistryKey = "r"
@@ -31,10 +32,61 @@ const TxSpendsRegistryKey = "p"
 const TxSpendsPrefix = "d" + DirPathDelimiter
 const SupplyKey = "s"
 
+// MintJournalPrefix stores the deposits minted against a block height, so they
+// can be reversed if the block is orphaned. Key: "mj-<height>", Value: packed
+// mint records. Pruned alongside block headers.
+const MintJournalPrefix = "mj" + DirPathDelimiter
+
+// OrphanedMintsKey stores the mints from orphaned blocks awaiting a new proof.
+// Any not re-proven by their deadline height are clawed back.
+const OrphanedMintsKey = "om"
+
+// MintDeficitKey stores the total sats of orphaned mints that could not be
+// clawed back because the recipient no longer held the balance (decimal int64).
+const MintDeficitKey = "mdef"
+
+// MintReproveWindow is the number of blocks past a reorg within which a
+// deposit from an orphaned block may be re-proven before it is clawed back:
+// about an hour of blocks.
+const MintReproveWindow uint32 = 6
+
+// DepositFinalityKey stores the owner-set number of blocks a deposit waits
+// in the pending table before it is credited (decimal uint32). Unset or 0
+// credits deposits as soon as they are proven.
+const DepositFinalityKey = "dfin"
+
+// PendingDepositPrefix stores a proven deposit that is not yet spendable.
+// Key: "pd-<txid>:<vout>", Value: 4-byte BE ready height || 4-byte BE proof
+// height || packed mint record.
+const PendingDepositPrefix = "pd" + DirPathDelimiter
+
+// PendingDepositQueueKey lists the pending deposits in the order they were
+// proven. Value: packed 4-byte BE ready height || 34-byte observed entry.
+const PendingDepositQueueKey = "pdq"
+
+// MaxFinalizePerCall limits how many pending deposits are credited in a
+// single finalizeDeposits or addBlocks call.
+const MaxFinalizePerCall = 50
+
+// CoinbaseMaturity is the number of blocks the tip must be past a coinbase
+// before a deposit paid by it is credited, matching the chain's own rule for
+// spending coinbase outputs.
+const CoinbaseMaturity uint32 = 100
+
 const LastHeightKey = "h"
 const SeedHeightKey = "sh"
 const PruneFloorKey = "pf" // lowest unpruned block height, updated during pruning
 
+// MaxFutureDriftKey stores the owner-set number of seconds a header's
+// timestamp may be ahead of the Hive block time (decimal uint32). Unset or 0
+// skips the check.
+const MaxFutureDriftKey = "mfd"
+
+// HeaderMMRKey stores the header archive, a Merkle Mountain Range over the
+// hashes of pruned block headers. Value: 4-byte BE height of the first leaf ||
+// 8-byte BE leaf count || 32-byte peaks, largest mountain first.
+const HeaderMMRKey = "hmmr"
+
 // BTC-C3: per-Hive-block withdrawal rate limit. The accumulator tracks
 // total sats deducted by HandleUnmap within a single Hive L1 block;
 // when MaxUnmapPerBlock is positive, HandleUnmap rejects any unmap
@@ -48,10 +100,91 @@ const PruneFloorKey = "pf" // lowest unpruned block height, updated during pruni
 const DefaultMaxUnmapPerBlock int64 = 100_000_000 // 1 BTC in sats
 const MaxUnmapPerBlockKey = "muxb"
 
+// WithdrawalQueueModeKey stores whether unmaps are queued ("1") for
+// settleWithdrawals instead of each being sent in its own transaction.
+const WithdrawalQueueModeKey = "wqm"
+
+// WithdrawalQueueKey lists the queued withdrawals in the order they were
+// made. Value: packed entries of 8-byte BE withdrawal id || 8-byte BE payout
+// || 8-byte BE VSC fee || 8-byte BE max fee (-1 for none) || 1-byte length +
+// from || 1-byte length + destination.
+const WithdrawalQueueKey = "wq"
+
+// WithdrawalPrefix stores the record of each withdrawal, through which its
+// status can be queried. Key: "wd-<id>", Value: 1-byte status || 8-byte BE
+// amount deducted || 8-byte BE amount sent || 8-byte BE Hive height requested
+// || 8-byte BE Hive height last updated || 4-byte BE confirmation height ||
+// 1-byte length + from || 1-byte length + destination || 1-byte length +
+// txid.
+const WithdrawalPrefix = "wd" + DirPathDelimiter
+
+// WithdrawalLastIdKey stores the id of the latest withdrawal (decimal
+// uint64). Ids start at 1.
+const WithdrawalLastIdKey = "wdi"
+
+// WithdrawalTimeoutKey stores the owner-set number of Hive blocks a
+// withdrawal must go without a change of status before it can be cancelled
+// (decimal uint64). Unset uses DefaultWithdrawalTimeout, about a day.
+const WithdrawalTimeoutKey = "wto"
+const DefaultWithdrawalTimeout uint64 = 28800
+
+// CancelledSpendPrefix marks each version of a cancelled spend that may still
+// confirm, so that a late confirmation of it is reconciled. Key: "x-<txid>",
+// Value: 8-byte BE input total || 8-byte BE change || 1-byte version count ||
+// 2-byte BE input count || per version, 1-byte length + txid, 2-byte BE fee
+// charge count and per charge, 1-byte length + account and 8-byte BE amount ||
+// per input, 32-byte hash + 4-byte BE index || per withdrawal paid, 8-byte BE
+// id || 8-byte BE VSC fee.
+const CancelledSpendPrefix = "x" + DirPathDelimiter
+
+// CancelledInputPrefix indexes the cancelled spends that may still confirm by
+// the outputs they spend, so that a confirmed spend of one clears them. Key:
+// "xc-<txid>:<vout>", Value: comma-separated txids of the latest versions.
+const CancelledInputPrefix = "xc" + DirPathDelimiter
+
+// SpentInputPrefix marks an output a pending spend spends that cannot be
+// restored if that spend is cancelled: an input of a cancelled spend that
+// confirmed after all, or an orphaned deposit clawed back. Key:
+// "xi-<txid>:<vout>", Value: "1".
+const SpentInputPrefix = "xi" + DirPathDelimiter
+
+// MaxQueuedWithdrawals bounds the withdrawal queue, and MaxSettlePerCall how
+// many queued withdrawals one settleWithdrawals pays.
+const (
+	MaxQueuedWithdrawals = 100
+	MaxSettlePerCall     = 50
+)
+
+// CpfpPayerKey stores who pays the fee of a cpfp child transaction, and the
+// fee a bumpFee replacement adds:
+// CpfpPayerFees, the default, takes it from the protocol's fee supply, and
+// CpfpPayerWithdrawers from the withdrawals the stuck spend pays, in
+// proportion to their amounts.
+const CpfpPayerKey = "cpfpp"
+
+const (
+	CpfpPayerFees        = "fees"
+	CpfpPayerWithdrawers = "withdrawers"
+)
+
 // BlockUnmapAccKey stores the per-block unmap accumulator: 16 bytes
 // = uint64 BE Hive block height || uint64 BE accumulated sats.
 const BlockUnmapAccKey = "buac"
 
+// MinConfirmationsPrefix stores the owner-set minimum confirmation depth of
+// a proof-verifying action. Key: "mc-<action>", Value: decimal uint32. A
+// block at the tip has 1 confirmation; proofs against shallower blocks are
+// rejected. Unset actions use DefaultMinConfirmations, which accepts any
+// stored block.
+const MinConfirmationsPrefix = "mc" + DirPathDelimiter
+const DefaultMinConfirmations uint32 = 1
+
+// Actions with a configurable minimum confirmation depth.
+const (
+	MinConfirmationsMap          = "map"
+	MinConfirmationsConfirmSpend = "confirmSpend"
+)
+
 // Instruction URL search param keys
 const (
 	DepositToKey        = "deposit_to"
@@ -82,7 +215,7 @@ const MigrateVersionKey = "mv" // current migration version (decimal string)
 
 // LatestMigrateVersion is the newest migration version. Set this in init/seed
 // so freshly deployed contracts skip all migrations.
-const LatestMigrateVersion = "1"
+const LatestMigrateVersion = "2"
 
 // Old format constants (pre-migration)
 const (
@@ -90,11 +223,52 @@ const (
 )
 
 const OracleAddress = "did:vsc:oracle:btc"
+
+// OracleSetKey stores the owner-managed oracle set. Once set, addBlocks
+// applies a batch only after the threshold of members have submitted the
+// same headers. Value: 1-byte threshold || per oracle, 1-byte length ||
+// address.
+const OracleSetKey = "oset"
+
+// OracleSubmissionPrefix stores each oracle's latest staged batch.
+// Key: "osub-<address>", Value: 32-byte batch digest || 8-byte BE fee.
+const OracleSubmissionPrefix = "osub" + DirPathDelimiter
+
+// MaxOracles limits the size of the oracle set.
+const MaxOracles = 16
+
 const PrimaryPublicKeyStateKey = "pubkey"
 const BackupPublicKeyStateKey = "backupkey"
 
 const BlockPrefix = "b" + DirPathDelimiter
 
+// RetargetAnchorPrefix stores the context of a difficulty epoch's first
+// header, keyed by its height. Key: "e-<height>", Value: 8 bytes — uint32 BE
+// timestamp || uint32 BE bits. The epoch-start header itself is pruned long
+// before the next retarget (MaxBlockRetention < RetargetInterval), so this is
+// what the retarget calculation reads instead.
+const RetargetAnchorPrefix = "e" + DirPathDelimiter
+
+// ChainWorkPrefix stores the cumulative proof of work from the seed header
+// up to and including each stored header, keyed by height. Key: "w-<height>",
+// Value: big-endian unsigned integer. addBlocks compares it to choose between
+// competing branches, and prunes it alongside the headers.
+const ChainWorkPrefix = "w" + DirPathDelimiter
+
+// StagedBranchKey stores the competing branch addBlocks is collecting until it
+// carries more work than the stored chain. Value: uint32 BE fork height ||
+// uint32 BE branch tip height || big-endian cumulative chain work at the tip.
+const StagedBranchKey = "br"
+
+// StagedHeaderPrefix stores the raw headers of the staged branch, keyed by
+// height. Key: "bs-<height>", Value: 80-byte header. They never overwrite the
+// stored chain until the branch is adopted.
+const StagedHeaderPrefix = "bs" + DirPathDelimiter
+
+// RetargetInterval is the number of blocks per difficulty epoch (2 weeks of
+// 10-minute blocks) on every BTC network.
+const RetargetInterval = 2016
+
 // MaxBaseFeeRate caps the base fee rate at 500 sats/vbyte.
 // Pentest finding BTC-C6: the previous 1000 sat/vbyte ceiling
 // only protected against int overflow — within that range a
@@ -107,6 +281,18 @@ const BlockPrefix = "b" + DirPathDelimiter
 // during fee calculation; rates below 1 are clamped up to 1.
 const MaxBaseFeeRate int64 = 500
 
+// FeeWindowKey stores the most recent oracle fee observations, oldest first.
+// Value: packed 8-byte BE int64 fee rates, at most FeeWindowSize of them.
+const FeeWindowKey = "fwin"
+
+// FeeWindowSize is the number of fee observations whose median the base fee
+// rate follows.
+const FeeWindowSize = 12
+
+// MaxFeeRateStepPercent limits how far the base fee rate moves on one update,
+// as a percentage of its current value (at least 1 sat/vbyte).
+const MaxFeeRateStepPercent = 25
+
 // MaxBlockRetention is the number of recent block headers to keep.
 // Older headers are pruned during addBlocks to prevent unbounded state growth.
 // keep a week worth of headers to allow addresses to be registered after the fact
@@ -119,10 +305,37 @@ const MaxPrunePerCall = 50
 const (
 	Testnet3 string = "testnet3"
 	Testnet4 string = "testnet4"
+	Signet   string = "signet"
 	Mainnet  string = "mainnet"
 	Regtest  string = "regtest"
 )
 
-func IsTestnet(networkName string) bool {
-	return networkName == Testnet3 || networkName == Testnet4 || networkName == Regtest
+// NetworkKey stores the name of the network the contract is bridged to,
+// fixed by the first seedBlocks.
+const NetworkKey = "net"
+
+// Checkpoint pins the hash of a known block on a network.
+type Checkpoint struct {
+	Height uint32
+	Hash   string // block hash, big-endian hex as shown by block explorers
+}
+
+// Checkpoints are the compiled-in checkpoints per network, in ascending
+// height order. A seeded header run that covers a checkpoint height must match
+// it, and on mainnet a seed may not start below the last one.
+var Checkpoints = map[string][]Checkpoint{
+	Mainnet: {
+		{691719, "00000000000000000008a89e854d57e5667df88f1cdef6fde2fbca1de5b639ad"},
+		{724466, "000000000000000000052d314a259755ca65944e68df6b12a067ea8f1f5a7091"},
+		{751565, "00000000000000000009c97098b5295f7e5f183ac811fb5d1534040adb93cabd"},
+		{781565, "00000000000000000002b8c04999434c33b8e033f11a977b288f8411766ee61c"},
+		{800000, "00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054"},
+		{810000, "000000000000000000028028ca82b6aa81ce789e4eb9e0321b74c3cbaf405dd1"},
+	},
+	Testnet3: {
+		{1864000, "000000000000006433d1efec504c53ca332b64963c425395515b01977bd7b3b0"},
+		{2010000, "0000000000004ae2f3896ca8ecd41c460a35bf6184e145d91558cece1c688a76"},
+		{2143398, "00000000000163cfb1f97c4e4098a3692c8053ad9cab5ad9c86b338b5c00b8b7"},
+		{2344474, "0000000000000004877fa2d36316398528de4f347df2f8a96f76613a298ce060"},
+	},
 }
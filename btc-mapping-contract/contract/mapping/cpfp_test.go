@@ -0,0 +1,41 @@
+package mapping
+
+import (
+	"btc-mapping-contract/contract/constants"
+	"testing"
+)
+
+func TestChargeCpfpFeeFromFeeSupply(t *testing.T) {
+	cs := &ContractState{Supply: SystemSupply{FeeSupply: 100}}
+	if _, err := cs.chargeCpfpFee(constants.CpfpPayerFees, nil, 150, "aa"); err == nil {
+		t.Fatal("expected a fee above the fee supply to be rejected")
+	}
+	charges, err := cs.chargeCpfpFee(constants.CpfpPayerFees, nil, 60, "aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Supply.FeeSupply != 40 {
+		t.Fatalf("fee supply = %d, want 40", cs.Supply.FeeSupply)
+	}
+	if len(charges) != 1 || charges[0] != (FeeCharge{Amount: 60}) {
+		t.Fatalf("charges = %+v, want the fee supply charged 60", charges)
+	}
+
+	// The charge goes back to the fee supply when the bump does not confirm.
+	if err := cs.refundCharges(charges, "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if cs.Supply.FeeSupply != 100 {
+		t.Fatalf("fee supply = %d after the refund, want 100", cs.Supply.FeeSupply)
+	}
+}
+
+func TestChargeCpfpFeeNeedsWithdrawers(t *testing.T) {
+	cs := &ContractState{Supply: SystemSupply{FeeSupply: 100, UserSupply: 100}}
+	if _, err := cs.chargeCpfpFee(constants.CpfpPayerWithdrawers, nil, 10, "aa"); err == nil {
+		t.Fatal("expected a spend without recorded withdrawals to be rejected")
+	}
+	if cs.Supply.FeeSupply != 100 || cs.Supply.UserSupply != 100 {
+		t.Fatalf("supply changed: %+v", cs.Supply)
+	}
+}
@@ -1,9 +1,8 @@
 package mapping
 
 import (
+	"btc-mapping-contract/contract/network"
 	"crypto/sha256"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
 // AddressWithBackup derives the P2WSH address for the given keys and tag.
@@ -14,7 +13,7 @@ import (
 func AddressWithBackup(
 	primaryPubKeyHex, backupPubKeyHex string,
 	tag []byte,
-	network *chaincfg.Params,
+	net *network.Network,
 ) (address string, witnessScript []byte, err error) {
 	primaryPubKey, err := DecodeCompressedPubKey(primaryPubKeyHex)
 	if err != nil {
@@ -24,14 +23,14 @@ func AddressWithBackup(
 	if err != nil {
 		return "", nil, err
 	}
-	return createP2WSHAddressWithBackup(primaryPubKey, backupPubKey, tag, network)
+	return createP2WSHAddressWithBackup(primaryPubKey, backupPubKey, tag, net)
 }
 
 // DepositAddress derives the P2WSH deposit address for a given instruction string.
 // The tag is SHA256(instruction), matching the on-chain derivation in parseInstructions.
 func DepositAddress(
 	primaryPubKeyHex, backupPubKeyHex, instruction string,
-	network *chaincfg.Params,
+	net *network.Network,
 ) (address string, witnessScript []byte, err error) {
 	primaryPubKey, err := DecodeCompressedPubKey(primaryPubKeyHex)
 	if err != nil {
@@ -42,5 +41,5 @@ func DepositAddress(
 		return "", nil, err
 	}
 	sum := sha256.Sum256([]byte(instruction))
-	return createP2WSHAddressWithBackup(primaryPubKey, backupPubKey, sum[:], network)
+	return createP2WSHAddressWithBackup(primaryPubKey, backupPubKey, sum[:], net)
 }
@@ -0,0 +1,77 @@
+package mapping
+
+import (
+	"btc-mapping-contract/contract/blocklist"
+	"btc-mapping-contract/contract/constants"
+	ce "btc-mapping-contract/contract/contracterrors"
+	"btc-mapping-contract/sdk"
+	"encoding/binary"
+)
+
+// ---------------------------------------------------------------------------
+// Base fee rate
+//
+// Each addBlocks fee observation is clamped and kept in a window of the last
+// FeeWindowSize. The base fee rate used for withdrawals follows the median of
+// the window, moving by at most MaxFeeRateStepPercent of its current value per
+// update, so a single bad observation can neither spike nor crash it.
+// ---------------------------------------------------------------------------
+
+// UpdateBaseFeeRate records an oracle fee observation and moves the supply's
+// base fee rate towards the median of the window.
+func UpdateBaseFeeRate(supply *SystemSupply, observed int64) error {
+	window, err := loadFeeWindow()
+	if err != nil {
+		return err
+	}
+	window = pushFeeObservation(window, clampedFeeRate(observed))
+	saveFeeWindow(window)
+	supply.BaseFeeRate = nextBaseFeeRate(supply.BaseFeeRate, window)
+	return nil
+}
+
+// pushFeeObservation appends fee to the window, dropping the oldest
+// observations beyond FeeWindowSize.
+func pushFeeObservation(window []int64, fee int64) []int64 {
+	window = append(window, fee)
+	if len(window) > constants.FeeWindowSize {
+		window = window[len(window)-constants.FeeWindowSize:]
+	}
+	return window
+}
+
+// nextBaseFeeRate returns the median of window, limited to a step of
+// MaxFeeRateStepPercent from current. Only a rate never set takes the median
+// as is; a set rate is stepped even when the window has just been started.
+func nextBaseFeeRate(current int64, window []int64) int64 {
+	median := blocklist.MedianFee(window)
+	if current < 1 {
+		return median
+	}
+	step := max(current*constants.MaxFeeRateStepPercent/100, 1)
+	return clampedFeeRate(min(max(median, current-step), current+step))
+}
+
+func loadFeeWindow() ([]int64, error) {
+	raw := sdk.StateGetObject(constants.FeeWindowKey)
+	if raw == nil || *raw == "" {
+		return nil, nil
+	}
+	data := []byte(*raw)
+	if len(data)%8 != 0 {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "invalid fee window length")
+	}
+	window := make([]int64, len(data)/8)
+	for i := range window {
+		window[i] = int64(binary.BigEndian.Uint64(data[i*8:]))
+	}
+	return window, nil
+}
+
+func saveFeeWindow(window []int64) {
+	data := make([]byte, len(window)*8)
+	for i, fee := range window {
+		binary.BigEndian.PutUint64(data[i*8:], uint64(fee))
+	}
+	sdk.StateSetObject(constants.FeeWindowKey, string(data))
+}
@@ -1,11 +1,11 @@
 package mapping
 
 import (
+	"btc-mapping-contract/contract/blocklist"
 	"btc-mapping-contract/contract/constants"
 	"btc-mapping-contract/sdk"
 	"bytes"
 	"encoding/hex"
-	"slices"
 	"strconv"
 
 	"github.com/btcsuite/btcd/wire"
@@ -20,7 +20,8 @@ func (ms *MappingState) HandleMap(txData *VerificationRequest) error {
 	if err != nil {
 		return ce.WrapContractError(ce.ErrInvalidHex, err, "error decoding raw transaction hex")
 	}
-	if err := verifyTransaction(txData, rawTx); err != nil {
+	archived, err := verifyTransaction(txData, rawTx, constants.MinConfirmationsMap)
+	if err != nil {
 		return ce.Prepend(err, "error verifying tranasction")
 	}
 
@@ -30,6 +31,19 @@ func (ms *MappingState) HandleMap(txData *VerificationRequest) error {
 		return ce.WrapContractError(ce.ErrInput, err, "could not construct BTC transaction from input")
 	}
 
+	// coinbase outputs only become spendable once the coinbase has matured, so
+	// deposits paid by an immature one are held until then. The null prevout is
+	// checked rather than the index: the proof already ties a tx at index 0 to
+	// the block's coinbase.
+	maturesAt := uint32(0)
+	if isCoinbaseTx(&msgTx) {
+		lastHeight, err := blocklist.LastHeightFromState()
+		if err != nil {
+			return ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+		}
+		maturesAt = coinbaseMaturesAt(txData.BlockHeight, lastHeight)
+	}
+
 	// gets all outputs the address of which is specified in the deposit instructions
 	relevantOutputs, err := ms.indexOutputs(&msgTx)
 	if err != nil {
@@ -37,12 +51,20 @@ func (ms *MappingState) HandleMap(txData *VerificationRequest) error {
 	}
 
 	// removes this tx from utxo spends if present
-	if err := ms.updateUtxoSpends(msgTx.TxID()); err != nil {
+	if err := ms.updateUtxoSpends(msgTx.TxID(), txData.BlockHeight); err != nil {
 		return ce.Prepend(err, "error updating utxo spends")
 	}
+	// settles this tx if it is a cancelled spend that confirmed after all
+	if _, err := ms.reconcileCancelledSpend(&msgTx, txData.BlockHeight); err != nil {
+		return ce.Prepend(err, "error reconciling cancelled spend")
+	}
+	// forgets the cancelled spends this tx makes impossible
+	if err := forgetConflictingSpends(&msgTx); err != nil {
+		return ce.Prepend(err, "error clearing cancelled spends")
+	}
 
 	// TODO: return mapping results for each relevenat address as part of contract output, or at least log them
-	err = ms.processUtxos(relevantOutputs, senderLabel(msgTx.TxIn, ms.NetworkParams), txData.BlockHeight)
+	err = ms.processUtxos(relevantOutputs, senderLabel(msgTx.TxIn, ms.Network.Params), txData.BlockHeight, maturesAt, archived)
 	if err != nil {
 		return err
 	}
@@ -64,7 +86,7 @@ func (cs *ContractState) HandleUnmap(instructions *TransferParams) error {
 	if amount <= 0 {
 		return ce.NewContractError(ce.ErrInput, "amount must be positive")
 	}
-	if amount <= dustThreshold {
+	if chainPolicy.isDust(amount) {
 		return ce.NewContractError(ce.ErrInput, "amount below dust threshold")
 	}
 
@@ -102,6 +124,10 @@ func (cs *ContractState) HandleUnmap(instructions *TransferParams) error {
 		)
 	}
 
+	if withdrawalsQueued() {
+		return cs.queueUnmap(env, from, instructions, amount, vscFee)
+	}
+
 	// When deducting fees from amount, UTXOs need to cover (amount - vscFee),
 	// since sendAmount + btcFee = amount - vscFee.
 	utxoSelectionAmount := amount
@@ -126,7 +152,7 @@ func (cs *ContractState) HandleUnmap(instructions *TransferParams) error {
 		cs.PublicKeys.Primary,
 		cs.PublicKeys.Backup,
 		nil,
-		cs.NetworkParams,
+		cs.Network,
 	)
 	if err != nil {
 		return ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
@@ -142,7 +168,7 @@ func (cs *ContractState) HandleUnmap(instructions *TransferParams) error {
 			return err
 		}
 		sendAmount, err = safeSubtract64(utxoSelectionAmount, btcFeeEst)
-		if err != nil || sendAmount <= dustThreshold {
+		if err != nil || chainPolicy.isDust(sendAmount) {
 			return ce.NewContractError(ce.ErrBalance, "amount too small to cover fees")
 		}
 	}
@@ -201,41 +227,27 @@ func (cs *ContractState) HandleUnmap(instructions *TransferParams) error {
 	}
 
 	// All checks passed — now request TSS signing
-	signingData, err := signSpendTransaction(tx, inputUtxos, witnessScripts)
-	if err != nil {
-		return ce.WrapContractError(ce.ErrTransaction, err, "error signing spend transaction")
-	}
-
-	unconfirmedUtxos, err := indexUnconfimedOutputs(tx, changeAddress, cs.NetworkParams)
+	id, err := nextWithdrawalId()
 	if err != nil {
 		return err
 	}
-	for _, utxo := range unconfirmedUtxos {
-		internalId, err := cs.allocateUnconfirmedId()
-		if err != nil {
-			return err
-		}
-		cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: internalId, Amount: utxo.Amount})
-		saveUtxo(internalId, utxo)
-	}
-
-	for _, inputId := range inputUtxoIds {
-		cs.UtxoList = slices.DeleteFunc(
-			cs.UtxoList,
-			func(entry UtxoRegistryEntry) bool { return entry.Id == inputId },
-		)
-		sdk.StateDeleteObject(getUtxoKey(inputId))
+	payouts := []SpendPayout{{From: from, Amount: sendAmount, Id: id, VscFee: vscFee}}
+	if err := cs.recordSpend(tx, inputUtxoIds, inputUtxos, witnessScripts, changeAddress, payouts); err != nil {
+		return err
 	}
-
-	signingDataBytes, err := MarshalSigningData(signingData)
+	sdk.Log(createUnmapLog(tx.TxID(), from, instructions.To, finalAmt, sendAmount))
+	err = createWithdrawal(id, &withdrawalRecord{
+		Status: WithdrawalSigningRequested,
+		Debit:  finalAmt,
+		Sent:   sendAmount,
+		From:   from,
+		To:     instructions.To,
+		TxId:   tx.TxID(),
+	})
 	if err != nil {
-		return ce.WrapContractError(ce.ErrJson, err, "error marshalling signing data")
+		return err
 	}
 
-	sdk.StateSetObject(constants.TxSpendsPrefix+tx.TxID(), string(signingDataBytes))
-	cs.TxSpendsList = append(cs.TxSpendsList, tx.TxID())
-	sdk.Log(createUnmapLog(tx.TxID(), from, instructions.To, finalAmt, sendAmount))
-
 	// update supply
 	newActive, err := safeSubtract64(cs.Supply.ActiveSupply, finalAmt)
 	if err != nil {
@@ -315,7 +327,7 @@ func (cs *ContractState) HandleConfirmSpend(txData *VerificationRequest, indices
 	if err != nil {
 		return ce.WrapContractError(ce.ErrInput, err, "invalid raw tx hex")
 	}
-	if err := verifyTransaction(txData, rawTx); err != nil {
+	if _, err := verifyTransaction(txData, rawTx, constants.MinConfirmationsConfirmSpend); err != nil {
 		return ce.Prepend(err, "error verifying transaction")
 	}
 	var msgTx wire.MsgTx
@@ -332,6 +344,22 @@ func (cs *ContractState) HandleConfirmSpend(txData *VerificationRequest, indices
 		return ce.NewContractError(ce.ErrInput, "indices must be non-empty")
 	}
 
+	// A cancelled spend has no pending change left to promote; its
+	// confirmation is reconciled instead.
+	cancelled, err := cs.reconcileCancelledSpend(&msgTx, txData.BlockHeight)
+	if err != nil {
+		return err
+	}
+	if cancelled {
+		return forgetConflictingSpends(&msgTx)
+	}
+
+	// If an earlier version of a bumped spend confirmed, its change replaces
+	// the latest version's before it is promoted.
+	if err := cs.adoptSpendVersion(txId); err != nil {
+		return err
+	}
+
 	indexSet := make(map[uint32]struct{}, len(indices))
 	for _, idx := range indices {
 		indexSet[idx] = struct{}{}
@@ -371,17 +399,15 @@ func (cs *ContractState) HandleConfirmSpend(txData *VerificationRequest, indices
 		return ce.NewContractError(ce.ErrInput, "no unconfirmed outputs matched the provided indices")
 	}
 
-	// Clean up signing data for this tx if present.
-	sdk.StateDeleteObject(constants.TxSpendsPrefix + txId)
-	for i, val := range cs.TxSpendsList {
-		if val == txId {
-			cs.TxSpendsList[i] = cs.TxSpendsList[len(cs.TxSpendsList)-1]
-			cs.TxSpendsList = cs.TxSpendsList[:len(cs.TxSpendsList)-1]
-			break
-		}
+	if err := confirmWithdrawals(txId, txData.BlockHeight); err != nil {
+		return err
 	}
-
-	return nil
+	// Clean up signing data for this tx, and any other version of it, if
+	// present.
+	if err := cs.retireSpend(txId); err != nil {
+		return err
+	}
+	return forgetConflictingSpends(&msgTx)
 }
 
 // handles a transfer where funds are drawn from the caller
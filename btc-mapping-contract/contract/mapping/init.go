@@ -3,28 +3,15 @@ package mapping
 import (
 	"btc-mapping-contract/contract/constants"
 	ce "btc-mapping-contract/contract/contracterrors"
+	"btc-mapping-contract/contract/network"
 	"btc-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
 	"net/url"
 	"strings"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
-func IntializeContractState(publicKeys PublicKeys, networkMode string) (*ContractState, error) {
-	var networkParams *chaincfg.Params
-	switch networkMode {
-	case constants.Testnet3:
-		networkParams = &chaincfg.TestNet3Params
-	case constants.Testnet4:
-		networkParams = &chaincfg.TestNet4Params
-	case constants.Regtest:
-		networkParams = &chaincfg.RegressionNetParams
-	default:
-		networkParams = &chaincfg.MainNetParams
-	}
-
+func IntializeContractState(publicKeys PublicKeys, net *network.Network) (*ContractState, error) {
 	// Load UTXO registry (binary: 9 bytes/entry)
 	var utxos UtxoRegistry
 	utxoState := sdk.StateGetObject(constants.UtxoRegistryKey)
@@ -75,16 +62,16 @@ func IntializeContractState(publicKeys PublicKeys, networkMode string) (*Contrac
 		TxSpendsList:      txSpends,
 		Supply:            supply,
 		PublicKeys:        publicKeys,
-		NetworkParams:     networkParams,
+		Network:           net,
 	}, nil
 }
 
 func InitializeMappingState(
 	publicKeys PublicKeys,
-	networkMode string,
+	net *network.Network,
 	instructions ...string,
 ) (*MappingState, error) {
-	contractState, err := IntializeContractState(publicKeys, networkMode)
+	contractState, err := IntializeContractState(publicKeys, net)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +79,7 @@ func InitializeMappingState(
 	var registry map[string]*AddressMetadata
 	if len(instructions) > 0 {
 		var err error
-		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.NetworkParams)
+		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.Network)
 		if err != nil {
 			return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error unmarshalling address registry")
 		}
@@ -107,7 +94,7 @@ func InitializeMappingState(
 func (cs *ContractState) parseInstructions(
 	publicKeys PublicKeys,
 	instrs []string,
-	networkParams *chaincfg.Params,
+	net *network.Network,
 ) (map[string]*AddressMetadata, error) {
 	parsedInstructions := make([]url.Values, len(instrs))
 	registry := make(map[string]*AddressMetadata, len(instrs))
@@ -155,7 +142,7 @@ func (cs *ContractState) parseInstructions(
 				publicKeys.Primary,
 				publicKeys.Backup,
 				hashBytes,
-				networkParams,
+				net,
 			)
 			if err != nil {
 				return nil, err
@@ -0,0 +1,122 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+)
+
+func testObservedEntry(t *testing.T, fill string, vout uint32) observedEntry {
+	t.Helper()
+	entry, err := makeObservedEntry(strings.Repeat(fill, 64), vout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestMintJournalRoundTrip(t *testing.T) {
+	journal := []mintRecord{
+		{Entry: testObservedEntry(t, "a", 0), Amount: 10000, UtxoId: 1024, Recipient: "hive:milo-hpr"},
+		{Entry: testObservedEntry(t, "b", 3), Amount: 1, UtxoId: 65535, Recipient: ""},
+	}
+	data, err := marshalMintJournal(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unmarshalMintJournal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(journal) {
+		t.Fatalf("got %d records, want %d", len(got), len(journal))
+	}
+	for i := range journal {
+		if got[i] != journal[i] {
+			t.Errorf("record %d: got %+v, want %+v", i, got[i], journal[i])
+		}
+	}
+
+	if _, err := unmarshalMintJournal(data[:len(data)-1]); err == nil {
+		t.Error("expected truncated journal to fail")
+	}
+	long := mintRecord{Recipient: strings.Repeat("x", 256)}
+	if _, err := marshalMintJournal([]mintRecord{long}); err == nil {
+		t.Error("expected overlong recipient to fail")
+	}
+}
+
+func TestOrphanedMintsRoundTrip(t *testing.T) {
+	orphaned := []orphanedMint{
+		{
+			mintRecord: mintRecord{Entry: testObservedEntry(t, "c", 1), Amount: 500, UtxoId: 2000, Recipient: "hive:a"},
+			Height:     100,
+			Deadline:   106,
+		},
+		{
+			mintRecord: mintRecord{Entry: testObservedEntry(t, "d", 0), Amount: 700, UtxoId: 2001, Recipient: "hive:b"},
+			Height:     101,
+			Deadline:   107,
+		},
+	}
+	data, err := marshalOrphanedMints(orphaned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unmarshalOrphanedMints(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != orphaned[0] || got[1] != orphaned[1] {
+		t.Fatalf("got %+v, want %+v", got, orphaned)
+	}
+}
+
+func TestOrphanedMintQueue(t *testing.T) {
+	a := orphanedMint{mintRecord: mintRecord{Entry: testObservedEntry(t, "a", 0), Amount: 1}, Deadline: 106}
+	b := orphanedMint{mintRecord: mintRecord{Entry: testObservedEntry(t, "b", 0), Amount: 2}, Deadline: 108}
+
+	// The deadline height itself is still within the window.
+	expired, pending := splitExpiredMints([]orphanedMint{a, b}, 106)
+	if len(expired) != 0 || len(pending) != 2 {
+		t.Fatalf("at 106: got %d expired, %d pending", len(expired), len(pending))
+	}
+	expired, pending = splitExpiredMints([]orphanedMint{a, b}, 107)
+	if len(expired) != 1 || expired[0] != a || len(pending) != 1 || pending[0] != b {
+		t.Fatalf("at 107: got %+v expired, %+v pending", expired, pending)
+	}
+
+	rest, record, ok := takeOrphanedMint([]orphanedMint{a, b}, b.Entry)
+	if !ok || record != b.mintRecord || len(rest) != 1 || rest[0] != a {
+		t.Fatalf("take: got %+v, %+v, %v", rest, record, ok)
+	}
+	if _, _, ok := takeOrphanedMint(rest, b.Entry); ok {
+		t.Fatal("expected entry to be taken only once")
+	}
+}
+
+func TestIsUtxoOf(t *testing.T) {
+	entry := testObservedEntry(t, "a", 1)
+	for _, tc := range []struct {
+		name string
+		utxo Utxo
+		want bool
+	}{
+		{"same output", Utxo{TxId: strings.Repeat("a", 64), Vout: 1}, true},
+		{"other vout", Utxo{TxId: strings.Repeat("a", 64), Vout: 0}, false},
+		{"other tx", Utxo{TxId: strings.Repeat("b", 64), Vout: 1}, false},
+	} {
+		got, err := isUtxoOf(&tc.utxo, entry)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// A corrupt UTXO must fail rather than be kept while its mint is clawed
+	// back.
+	if _, err := isUtxoOf(&Utxo{TxId: "zz", Vout: 1}, entry); err == nil {
+		t.Error("expected an invalid utxo txid to fail")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 
+	"btc-mapping-contract/contract/blocklist"
 	"btc-mapping-contract/contract/constants"
 	ce "btc-mapping-contract/contract/contracterrors"
 )
@@ -62,7 +63,7 @@ func (ms *MappingState) indexOutputs(msgTx *wire.MsgTx) ([]Utxo, error) {
 	outputsForVsc := make([]Utxo, 0, len(ms.AddressRegistry))
 
 	for index, txOut := range msgTx.TxOut {
-		addr, ok, err := isForVscAcc(txOut, ms.AddressRegistry, ms.NetworkParams)
+		addr, ok, err := isForVscAcc(txOut, ms.AddressRegistry, ms.Network.Params)
 		if err != nil {
 			return nil, ce.WrapContractError(
 				ce.ErrInput,
@@ -93,8 +94,12 @@ func (ms *MappingState) indexOutputs(msgTx *wire.MsgTx) ([]Utxo, error) {
 // updateUtxoSpends checks whether txId is a known pending spend transaction.
 // If so, it confirms matching unconfirmed UTXOs by transitioning them from the
 // unconfirmed pool (IDs 0–63) to the confirmed pool (IDs 64–255), and removes
-// the signing data entry.
-func (cs *ContractState) updateUtxoSpends(txId string) error {
+// the signing data entry. The withdrawals it pays are marked confirmed at
+// blockHeight.
+func (cs *ContractState) updateUtxoSpends(txId string, blockHeight uint32) error {
+	if err := cs.adoptSpendVersion(txId); err != nil {
+		return err
+	}
 	utxoSpendJson := sdk.StateGetObject(constants.TxSpendsPrefix + txId)
 	if utxoSpendJson == nil || len(*utxoSpendJson) < 1 {
 		return nil
@@ -140,30 +145,49 @@ func (cs *ContractState) updateUtxoSpends(txId string) error {
 		}
 	}
 
-	sdk.StateDeleteObject(constants.TxSpendsPrefix + txId)
-	for i, val := range cs.TxSpendsList {
-		if val == txId {
-			// swap with the last element and shorten
-			cs.TxSpendsList[i] = cs.TxSpendsList[len(cs.TxSpendsList)-1]
-			cs.TxSpendsList = cs.TxSpendsList[:len(cs.TxSpendsList)-1]
-			break
-		}
+	if err := confirmWithdrawals(txId, blockHeight); err != nil {
+		return err
 	}
-	return nil
+	return cs.retireSpend(txId)
 }
 
-func (ms *MappingState) processUtxos(relevantUtxos []Utxo, from string, blockHeight uint32) error {
+// processUtxos credits the relevant outputs of a transaction proven at
+// blockHeight. A non-zero maturesAt holds deposits until the tip reaches that
+// height, as the transaction is an immature coinbase. Held deposits join the
+// UTXO pool when they are credited, and swaps are rejected until then. A
+// block already pruned is passed as archived, and its observed tx list is
+// kept in the header archive.
+func (ms *MappingState) processUtxos(relevantUtxos []Utxo, from string, blockHeight, maturesAt uint32, archived *archivedBlock) error {
 	totalMapped := int64(0)
 	env := sdk.GetEnv()
 	routerId := ""
 
 	// Load existing observed list for this block height (may already have entries
 	// from a prior map call against the same block).
-	observedList := loadObservedList(blockHeight)
+	var observedList []observedEntry
+	if archived != nil {
+		observedList = unpackObservedList(archived.observed)
+	} else {
+		observedList = loadObservedList(blockHeight)
+	}
+	journal, err := loadMintJournal(blockHeight)
+	if err != nil {
+		return err
+	}
+	orphaned, err := loadOrphanedMints()
+	if err != nil {
+		return err
+	}
+	journalChanged := false
+	orphanedChanged := false
+	finality := getDepositFinality()
+	holdDeposits := finality > 0 || maturesAt > 0
+	readyAt := max(blockHeight+finality, maturesAt)
+	var pending []pendingDeposit
 
 	// create new utxos entries for all of the relevant outputs in the incoming transaction
 	for _, utxo := range relevantUtxos {
-		_, addrs, _, err := txscript.ExtractPkScriptAddrs(utxo.PkScript, ms.NetworkParams)
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(utxo.PkScript, ms.Network.Params)
 		if err != nil {
 			return ce.WrapContractError(ce.ErrInput, err, "error extracting pkscript address")
 		}
@@ -176,29 +200,73 @@ func (ms *MappingState) processUtxos(relevantUtxos []Utxo, from string, blockHei
 			if err != nil {
 				return ce.WrapContractError(ce.ErrInput, err, "error creating observed entry")
 			}
+			// A deposit from an orphaned block that is proven again has already
+			// been minted; it only moves back into the journal.
+			if rest, record, ok := takeOrphanedMint(orphaned, entry); ok {
+				orphaned = rest
+				orphanedChanged = true
+				journal = append(journal, record)
+				journalChanged = true
+				if !isObserved(observedList, entry) {
+					observedList = append(observedList, entry)
+				}
+				if err := reprovePendingDeposit(entry, blockHeight, readyAt); err != nil {
+					return err
+				}
+				continue
+			}
 			if isObserved(observedList, entry) {
 				continue
 			}
 
-			utxoInternalId, err := ms.allocateConfirmedId()
-			if err != nil {
-				return err
+			// A held deposit's output joins the pool only once it is
+			// credited, so that no withdrawal can spend it before then.
+			held := metadata.Type == MapDeposit && holdDeposits
+			var utxoInternalId uint16
+			if !held {
+				utxoInternalId, err = ms.allocateConfirmedId()
+				if err != nil {
+					return err
+				}
+				ms.UtxoList = append(ms.UtxoList, UtxoRegistryEntry{Id: utxoInternalId, Amount: utxo.Amount})
+				saveUtxo(utxoInternalId, &utxo)
 			}
-			ms.UtxoList = append(ms.UtxoList, UtxoRegistryEntry{Id: utxoInternalId, Amount: utxo.Amount})
-			saveUtxo(utxoInternalId, &utxo)
 
 			// Mark observed
 			observedList = append(observedList, entry)
 
 			sdk.Log(createMapLog(from, metadata.Recipient, utxo.Amount))
+			// mintedTo is the account holding the minted amount, which a
+			// clawback debits if the block is orphaned.
+			mintedTo := metadata.Recipient
 			switch metadata.Type {
 			case MapDeposit:
+				// with a deposit finality set or an immature coinbase, the
+				// deposit is credited by FinalizeDeposits once the tip is far
+				// enough past this block
+				if holdDeposits {
+					break
+				}
 				// increment balance for recipient account (vsc account not btc account)
 				// alread verified that this addresss is valid on VSC
 				if err := incAccBalance(metadata.Recipient, utxo.Amount); err != nil {
 					return ce.Prepend(err, "error crediting deposit balance")
 				}
 			case MapSwap:
+				// A swap cannot be clawed back from its recipient, so it only
+				// executes once a deposit would be credited.
+				if holdDeposits {
+					lastHeight, err := blocklist.LastHeightFromState()
+					if err != nil {
+						return ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+					}
+					if lastHeight < readyAt {
+						return ce.NewContractError(
+							ce.ErrInput,
+							"swaps cannot be mapped before height "+strconv.FormatUint(uint64(readyAt), 10),
+						)
+					}
+				}
 
 				// get router id and check it only if there is a swap in the tx
 				if routerId == "" {
@@ -272,11 +340,31 @@ func (ms *MappingState) processUtxos(relevantUtxos []Utxo, from string, blockHei
 					if swapResult.AmountOut == "" || swapResult.AmountOut == "0" {
 						return ce.NewContractError(ce.ErrInput, "swap returned zero amount out")
 					}
+					// The recipient was paid in the swap's output asset; the
+					// minted amount went to the router.
+					mintedTo = routerAddr
 				}
 			default:
 				// should never happen
 				continue
 			}
+			journal = append(journal, mintRecord{
+				Entry:     entry,
+				Amount:    utxo.Amount,
+				UtxoId:    utxoInternalId,
+				Recipient: mintedTo,
+			})
+			journalChanged = true
+			if held {
+				pending = append(pending, pendingDeposit{
+					mintRecord: journal[len(journal)-1],
+					ReadyAt:    readyAt,
+					Height:     blockHeight,
+					Utxo:       &utxo,
+				})
+				// pending deposits enter the supply when they are credited
+				continue
+			}
 			// This increments in all cases, since BTC is always mapped onto VSC
 			totalMapped, err = safeAdd64(totalMapped, utxo.Amount)
 			if err != nil {
@@ -286,9 +374,27 @@ func (ms *MappingState) processUtxos(relevantUtxos []Utxo, from string, blockHei
 	}
 
 	// Persist the observed list for this block height
-	if len(observedList) > 0 {
+	if archived != nil {
+		if err := archived.saveObservedList(observedList); err != nil {
+			return err
+		}
+	} else if len(observedList) > 0 {
 		saveObservedList(blockHeight, observedList)
 	}
+	// A pruned block is past any reorg, so its mints need no journal.
+	if journalChanged && !blocklist.IsPruned(blockHeight) {
+		if err := saveMintJournal(blockHeight, journal); err != nil {
+			return err
+		}
+	}
+	if orphanedChanged {
+		if err := saveOrphanedMints(orphaned); err != nil {
+			return err
+		}
+	}
+	if err := addPendingDeposits(pending); err != nil {
+		return err
+	}
 
 	if totalMapped != 0 {
 		newActive, err := safeAdd64(ms.Supply.ActiveSupply, totalMapped)
@@ -0,0 +1,39 @@
+package mapping
+
+import "testing"
+
+func TestChainPolicyFee(t *testing.T) {
+	cases := []struct {
+		unit       FeeUnit
+		nonWitness int64
+		witness    int64
+		rate       int64
+		want       int64
+	}{
+		// (100*3 + 200 + 3)/4 + 2 = 127 vbytes
+		{FeePerVByte, 100, 100, 2, 254},
+		{FeePerByte, 100, 100, 2, 400},
+		{FeePerKB, 100, 100, 1000, 1000},
+		{FeePerKB, 600, 401, 1000, 2000},
+		{FeePerKB, 1000, 0, 1000, 1000},
+	}
+	for _, c := range cases {
+		p := ChainPolicy{FeeUnit: c.unit}
+		got, err := p.fee(c.nonWitness, c.witness, c.rate)
+		if err != nil {
+			t.Fatalf("fee(%d, %d, %d): %v", c.nonWitness, c.witness, c.rate, err)
+		}
+		if got != c.want {
+			t.Errorf("unit %d: fee(%d, %d, %d) = %d, want %d", c.unit, c.nonWitness, c.witness, c.rate, got, c.want)
+		}
+	}
+}
+
+func TestChainPolicyDust(t *testing.T) {
+	if !chainPolicy.isDust(chainPolicy.DustLimit) {
+		t.Error("an output at the dust limit should be dust")
+	}
+	if chainPolicy.isDust(chainPolicy.DustLimit + 1) {
+		t.Error("an output above the dust limit should not be dust")
+	}
+}
@@ -1,9 +1,8 @@
 package mapping
 
 import (
+	"btc-mapping-contract/contract/network"
 	"net/url"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
 //tinyjson:json
@@ -19,6 +18,15 @@ type VerificationRequest struct {
 	RawTxHex       string `json:"raw_tx_hex"`
 	MerkleProofHex string `json:"merkle_proof_hex"` // array of byte arrays, each of which is guaranteed 32 bytes
 	TxIndex        uint32 `json:"tx_index"`         // position of the tx in the block
+	// proof of the block's coinbase, pinning the merkle tree depth; required
+	// unless the block has a single transaction or tx is the coinbase
+	CoinbaseTxHex    string `json:"coinbase_tx_hex,omitempty"`
+	CoinbaseProofHex string `json:"coinbase_merkle_proof_hex,omitempty"`
+	// optional header of a block already pruned, the observed tx list archived
+	// with it, and the proof of both in the header archive
+	ArchivalHeaderHex   string `json:"archival_header_hex,omitempty"`
+	ArchiveProofHex     string `json:"archive_proof_hex,omitempty"`
+	ArchivedObservedHex string `json:"archived_observed_hex,omitempty"`
 }
 
 type Deposit struct {
@@ -117,7 +125,7 @@ type ContractState struct {
 	TxSpendsList      TxSpendsRegistry
 	Supply            SystemSupply
 	PublicKeys        PublicKeys
-	NetworkParams     *chaincfg.Params
+	Network           *network.Network
 }
 
 type MappingState struct {
@@ -196,3 +204,25 @@ type ConfirmSpendParams struct {
 	TxData  *VerificationRequest `json:"tx_data"`
 	Indices []uint32             `json:"indices"`
 }
+
+//tinyjson:json
+type SetMinConfirmationsParams struct {
+	Action        string `json:"action"`
+	Confirmations uint32 `json:"confirmations"`
+}
+
+// WithdrawalInfo is the status of a withdrawal returned by getWithdrawal.
+//
+//tinyjson:json
+type WithdrawalInfo struct {
+	Id          uint64 `json:"id"`
+	Status      string `json:"status"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Deducted    string `json:"deducted"`
+	Sent        string `json:"sent"`
+	TxId        string `json:"tx_id,omitempty"`
+	RequestedAt uint64 `json:"requested_at"` // hive block height
+	UpdatedAt   uint64 `json:"updated_at"`   // hive block height
+	ConfirmedAt uint32 `json:"confirmed_at,omitempty"`
+}
@@ -5,6 +5,7 @@ import (
 	ce "btc-mapping-contract/contract/contracterrors"
 	"btc-mapping-contract/sdk"
 	"bytes"
+	"slices"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -13,26 +14,22 @@ import (
 	"github.com/btcsuite/btcd/wire"
 )
 
-// constants in sats
-const dustThreshold = 546
-
-const splitThreshold = 1000000 // 0.01 BTC
 const maxChangeOutputs = 4
 
-// VscFeeMinSats is the minimum VSC protocol fee in satoshis.
-const VscFeeMinSats int64 = 0
-
-// VscFeeRateBps is the VSC protocol fee as basis points (1 bps = 0.01%).
-const VscFeeRateBps int64 = 0
+// rbfSequence is the input sequence number of withdrawal transactions. It
+// signals BIP125 replaceability, so bumpFee can replace a spend stuck at a
+// stale fee rate.
+const rbfSequence = wire.MaxTxInSequenceNum - 2
 
 func calcVscFee(amount int64) (int64, error) {
-	if VscFeeMinSats == 0 && VscFeeRateBps == 0 {
+	minFee, rateBps := chainPolicy.VscFeeMin, chainPolicy.VscFeeRateBps
+	if minFee == 0 && rateBps == 0 {
 		return 0, nil
 	}
 	// divide first to avoid overflow on large amounts, then compensate for remainder
-	percentageFee := (amount/10000)*VscFeeRateBps + (amount%10000)*VscFeeRateBps/10000
-	finalFee := VscFeeMinSats
-	if percentageFee > VscFeeMinSats {
+	percentageFee := (amount/10000)*rateBps + (amount%10000)*rateBps/10000
+	finalFee := minFee
+	if percentageFee > minFee {
 		finalFee = percentageFee
 	}
 	if finalFee >= amount {
@@ -59,13 +56,14 @@ func estimateVSize(nonWitnessSize, witnessDataSize int64) int64 {
 	return (nonWitnessSize*3+totalSize+3)/4 + 2
 }
 
-// clampedFeeRate returns the base fee rate clamped to MaxBaseFeeRate.
+// clampedFeeRate returns the base fee rate clamped to the chain policy's
+// fee rate bounds.
 func clampedFeeRate(rate int64) int64 {
-	if rate > constants.MaxBaseFeeRate {
-		return constants.MaxBaseFeeRate
+	if rate > chainPolicy.MaxFeeRate {
+		return chainPolicy.MaxFeeRate
 	}
-	if rate < 1 {
-		return 1
+	if rate < chainPolicy.MinFeeRate {
+		return chainPolicy.MinFeeRate
 	}
 	return rate
 }
@@ -93,7 +91,7 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 
 	// Compute base fee (no change outputs) first
 	nonWitnessSize := baseSize + inputSize + outputSize
-	baseFee, err := safeMultiply64(estimateVSize(nonWitnessSize, witnessDataSize), feeRate)
+	baseFee, err := chainPolicy.fee(nonWitnessSize, witnessDataSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 	}
@@ -103,14 +101,14 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 		availableChange = 0
 	}
 
-	if availableChange > dustThreshold {
-		numChangeOutputs := min(max(availableChange/splitThreshold, 1), maxChangeOutputs)
+	if !chainPolicy.isDust(availableChange) {
+		numChangeOutputs := min(max(availableChange/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
 
 		// Add change outputs one at a time, stopping when per-output amount is dust
 		addedOutputs := int64(0)
 		for i := int64(0); i < numChangeOutputs; i++ {
 			newNonWitness := nonWitnessSize + (addedOutputs+1)*43
-			newFee, err := safeMultiply64(estimateVSize(newNonWitness, witnessDataSize), feeRate)
+			newFee, err := chainPolicy.fee(newNonWitness, witnessDataSize, feeRate)
 			if err != nil {
 				return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 			}
@@ -118,7 +116,7 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 			if newAvailable < 0 {
 				newAvailable = 0
 			}
-			if newAvailable/(addedOutputs+1) <= dustThreshold {
+			if chainPolicy.isDust(newAvailable / (addedOutputs + 1)) {
 				break
 			}
 			addedOutputs++
@@ -126,7 +124,7 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 		}
 	}
 
-	fee, err := safeMultiply64(estimateVSize(nonWitnessSize, witnessDataSize), feeRate)
+	fee, err := chainPolicy.fee(nonWitnessSize, witnessDataSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 	}
@@ -211,17 +209,19 @@ func (cs *ContractState) getInputUtxoIds(amount int64) ([]uint16, int64, error)
 }
 
 func (cs *ContractState) calculateSegwitFee(baseSize int64, witnessScripts map[int][]byte) (int64, error) {
-	feeRate := clampedFeeRate(cs.Supply.BaseFeeRate)
+	return segwitFee(baseSize, witnessScripts, clampedFeeRate(cs.Supply.BaseFeeRate))
+}
+
+// segwitFee returns the fee at feeRate for a transaction of baseSize bytes
+// without its witnesses, once each input carries the witness for its script.
+func segwitFee(baseSize int64, witnessScripts map[int][]byte, feeRate int64) (int64, error) {
 	// Witness stack per input: <sig> <branch_selector> <witness_script>
 	// Serialized: item_count(1) + sig_len(1) + sig(72) + branch_len(1) + branch(1) + script_len(1) + script(N)
 	witnessDataSize := int64(0)
 	for _, witnessScript := range witnessScripts {
 		witnessDataSize += 72 + int64(len(witnessScript)) + 5
 	}
-	totalSize := baseSize + witnessDataSize
-	// +3 to round up, + 2 for has witness data flag
-	vSize := (baseSize*3+totalSize+3)/4 + 2
-	fee, err := safeMultiply64(vSize, feeRate)
+	fee, err := chainPolicy.fee(baseSize, witnessDataSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee calculation overflow")
 	}
@@ -240,42 +240,13 @@ func (cs *ContractState) buildSpendTransaction(
 ) (*wire.MsgTx, map[int][]byte, int64, error) {
 	tx := wire.NewMsgTx(wire.TxVersion)
 
-	// create all witness scripts now for better size estimation
-	witnessScripts := make(map[int][]byte)
-	for index, utxo := range inputs {
-		txHash, err := chainhash.NewHashFromStr(utxo.TxId)
-		if err != nil {
-			return nil, nil, 0, err
-		}
-
-		outPoint := wire.NewOutPoint(txHash, utxo.Vout)
-		txIn := wire.NewTxIn(outPoint, nil, nil)
-		tx.AddTxIn(txIn)
-
-		_, witnessScript, err := createP2WSHAddressWithBackup(
-			cs.PublicKeys.Primary,
-			cs.PublicKeys.Backup,
-			utxo.Tag, // already []byte
-			cs.NetworkParams,
-		)
-
-		if err != nil {
-			return nil, nil, 0, err
-		}
-		witnessScripts[index] = witnessScript
-	}
-
-	destAddr, err := btcutil.DecodeAddress(destAddress, cs.NetworkParams)
+	witnessScripts, err := cs.addSpendInputs(tx, inputs)
 	if err != nil {
-		return nil, nil, 0, ce.WrapContractError(
-			ce.ErrInput,
-			err,
-			"error decoding destination btc address ["+destAddress+"]",
-		)
+		return nil, nil, 0, err
 	}
 
 	// Create output script for destination
-	destScript, err := txscript.PayToAddrScript(destAddr)
+	destScript, err := cs.destinationScript(destAddress)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -298,8 +269,8 @@ func (cs *ContractState) buildSpendTransaction(
 	}
 
 	// Add change outputs if above dust, splitting across multiple outputs
-	if availableChange > dustThreshold {
-		changeAddressObj, err := btcutil.DecodeAddress(changeAddress, cs.NetworkParams)
+	if !chainPolicy.isDust(availableChange) {
+		changeAddressObj, err := btcutil.DecodeAddress(changeAddress, cs.Network.Params)
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -309,7 +280,7 @@ func (cs *ContractState) buildSpendTransaction(
 		}
 		changeOutputSize := int64(wire.NewTxOut(int64(0), changeScript).SerializeSize())
 
-		numChangeOuputs := min(max(availableChange/splitThreshold, 1), maxChangeOutputs)
+		numChangeOuputs := min(max(availableChange/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
 
 		// Add change outputs one at a time, recalculating fee after each
 		addedOutputs := int64(0)
@@ -326,7 +297,7 @@ func (cs *ContractState) buildSpendTransaction(
 
 			// Check if adding this output still leaves enough for all outputs to be above dust
 			perOutput := newAvailable / (addedOutputs + 1)
-			if perOutput <= dustThreshold {
+			if chainPolicy.isDust(perOutput) {
 				break
 			}
 
@@ -350,7 +321,7 @@ func (cs *ContractState) buildSpendTransaction(
 		}
 	}
 
-	// Pentest finding BTC-C5: when availableChange ≤ dustThreshold the
+	// Pentest finding BTC-C5: when availableChange is dust the
 	// change output is omitted; the residual sats are implicitly paid
 	// to the miner. The size-based fee variable above does NOT capture
 	// that absorbed dust, so callers (HandleUnmap) under-decrement
@@ -368,6 +339,119 @@ func (cs *ContractState) buildSpendTransaction(
 	return tx, witnessScripts, fee, nil
 }
 
+// addSpendInputs adds inputs to tx and returns the witness script of each,
+// by input index, created now for better size estimation.
+func (cs *ContractState) addSpendInputs(tx *wire.MsgTx, inputs []*Utxo) (map[int][]byte, error) {
+	witnessScripts := make(map[int][]byte)
+	for index, utxo := range inputs {
+		txHash, err := chainhash.NewHashFromStr(utxo.TxId)
+		if err != nil {
+			return nil, err
+		}
+
+		outPoint := wire.NewOutPoint(txHash, utxo.Vout)
+		txIn := wire.NewTxIn(outPoint, nil, nil)
+		txIn.Sequence = rbfSequence
+		tx.AddTxIn(txIn)
+
+		_, witnessScript, err := createP2WSHAddressWithBackup(
+			cs.PublicKeys.Primary,
+			cs.PublicKeys.Backup,
+			utxo.Tag, // already []byte
+			cs.Network,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		witnessScripts[index] = witnessScript
+	}
+	return witnessScripts, nil
+}
+
+// destinationScript returns the output script paying destAddress.
+func (cs *ContractState) destinationScript(destAddress string) ([]byte, error) {
+	destAddr, err := btcutil.DecodeAddress(destAddress, cs.Network.Params)
+	if err != nil {
+		return nil, ce.WrapContractError(
+			ce.ErrInput,
+			err,
+			"error decoding destination btc address ["+destAddress+"]",
+		)
+	}
+	return txscript.PayToAddrScript(destAddr)
+}
+
+// recordSpend requests TSS signing for tx, moves its change outputs into the
+// unconfirmed pool, drops its inputs from the registry and stores the signing
+// data, with the withdrawals tx pays, under its txid. Call this only after
+// all validation checks have passed.
+func (cs *ContractState) recordSpend(
+	tx *wire.MsgTx,
+	inputUtxoIds []uint16,
+	inputUtxos []*Utxo,
+	witnessScripts map[int][]byte,
+	changeAddress string,
+	payouts []SpendPayout,
+) error {
+	signingData, err := signSpendTransaction(tx, inputUtxos, witnessScripts)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error signing spend transaction")
+	}
+	signingData.Payouts = payouts
+
+	if _, err := cs.addUnconfirmedChange(tx, changeAddress); err != nil {
+		return err
+	}
+
+	for _, inputId := range inputUtxoIds {
+		cs.UtxoList = slices.DeleteFunc(
+			cs.UtxoList,
+			func(entry UtxoRegistryEntry) bool { return entry.Id == inputId },
+		)
+		sdk.StateDeleteObject(getUtxoKey(inputId))
+	}
+
+	signingDataBytes, err := MarshalSigningData(signingData)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrJson, err, "error marshalling signing data")
+	}
+
+	sdk.StateSetObject(constants.TxSpendsPrefix+tx.TxID(), string(signingDataBytes))
+	cs.TxSpendsList = append(cs.TxSpendsList, tx.TxID())
+	return nil
+}
+
+// addUnconfirmedChange adds the change outputs of tx to the unconfirmed pool
+// and returns their total.
+func (cs *ContractState) addUnconfirmedChange(tx *wire.MsgTx, changeAddress string) (int64, error) {
+	unconfirmedUtxos, err := indexUnconfimedOutputs(tx, changeAddress, cs.Network.Params)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, utxo := range unconfirmedUtxos {
+		internalId, err := cs.allocateUnconfirmedId()
+		if err != nil {
+			return 0, err
+		}
+		cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: internalId, Amount: utxo.Amount})
+		saveUtxo(internalId, utxo)
+		total += utxo.Amount
+	}
+	return total, nil
+}
+
+// addChangeOutputs adds change to tx split evenly across count outputs paying
+// changeScript, the rounding remainder going to the first.
+func addChangeOutputs(tx *wire.MsgTx, change, count int64, changeScript []byte) {
+	each := change / count
+	tx.AddTxOut(wire.NewTxOut(each+change-each*count, changeScript))
+	for range count - 1 {
+		tx.AddTxOut(wire.NewTxOut(each, changeScript))
+	}
+}
+
 // signSpendTransaction computes witness sighashes and requests TSS signing
 // for each input. Call this only after all validation checks have passed.
 func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int][]byte) (*SigningData, error) {
@@ -407,6 +491,7 @@ func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int
 			Index:         uint32(i),
 			SigHash:       sigHash,
 			WitnessScript: witnessScript,
+			Amount:        utxo.Amount,
 		}
 	}
 
@@ -423,10 +508,9 @@ func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int
 }
 
 func indexUnconfimedOutputs(tx *wire.MsgTx, changeAddress string, network *chaincfg.Params) ([]*Utxo, error) {
-	// 1 output will be to the destination, the others will be to change address
-	utxos := make([]*Utxo, len(tx.TxOut)-1)
+	// the outputs to the change address follow the one or more destinations
+	utxos := make([]*Utxo, 0, len(tx.TxOut)-1)
 
-	i := 0
 	for index, txOut := range tx.TxOut {
 		_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, network)
 		if err != nil {
@@ -444,12 +528,7 @@ func indexUnconfimedOutputs(tx *wire.MsgTx, changeAddress string, network *chain
 				PkScript: txOut.PkScript,
 				Tag:      nil, // change outputs have no tag
 			}
-			if i < len(utxos) {
-				utxos[i] = &utxo
-				i++
-			} else {
-				utxos = append(utxos, &utxo)
-			}
+			utxos = append(utxos, &utxo)
 		}
 	}
 
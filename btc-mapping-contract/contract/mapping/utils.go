@@ -3,6 +3,7 @@ package mapping
 import (
 	"btc-mapping-contract/contract/constants"
 	ce "btc-mapping-contract/contract/contracterrors"
+	"btc-mapping-contract/contract/network"
 	"btc-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
@@ -21,13 +22,9 @@ import (
 )
 
 func createP2WSHAddressWithBackup(
-	primaryPubKey CompressedPubKey, backupPubKey CompressedPubKey, tag []byte, network *chaincfg.Params,
+	primaryPubKey CompressedPubKey, backupPubKey CompressedPubKey, tag []byte, net *network.Network,
 ) (string, []byte, error) {
-	csvBlocks := constants.BackupCSVBlocks
-
-	if network.Net != chaincfg.MainNetParams.Net {
-		csvBlocks = constants.TestnetBackupCSVBlocks
-	}
+	csvBlocks := net.BackupCSVBlocks
 
 	scriptBuilder := txscript.NewScriptBuilder()
 
@@ -65,7 +62,7 @@ func createP2WSHAddressWithBackup(
 	}
 
 	witnessProgram := sha256.Sum256(script)
-	addressWitnessScriptHash, err := btcutil.NewAddressWitnessScriptHash(witnessProgram[:], network)
+	addressWitnessScriptHash, err := btcutil.NewAddressWitnessScriptHash(witnessProgram[:], net.Params)
 	if err != nil {
 		return "", nil, err
 	}
@@ -617,7 +614,11 @@ func loadObservedList(blockHeight uint32) []observedEntry {
 	if raw == nil || len(*raw) == 0 {
 		return nil
 	}
-	data := []byte(*raw)
+	return unpackObservedList([]byte(*raw))
+}
+
+// unpackObservedList splits packed observed entries.
+func unpackObservedList(data []byte) []observedEntry {
 	if len(data)%observedEntrySize != 0 {
 		return nil
 	}
@@ -640,11 +641,16 @@ func isObserved(list []observedEntry, entry observedEntry) bool {
 
 // saveObservedList writes the packed observed entries for a block height.
 func saveObservedList(blockHeight uint32, list []observedEntry) {
+	sdk.StateSetObject(observedBlockKey(blockHeight), string(packObservedList(list)))
+}
+
+// packObservedList concatenates observed entries.
+func packObservedList(list []observedEntry) []byte {
 	buf := make([]byte, len(list)*observedEntrySize)
 	for i, e := range list {
 		copy(buf[i*observedEntrySize:], e[:])
 	}
-	sdk.StateSetObject(observedBlockKey(blockHeight), string(buf))
+	return buf
 }
 
 // DeleteObservedList removes the observed tx list for a block height.
@@ -705,6 +711,20 @@ func getMaxUnmapPerBlock() int64 {
 	return v
 }
 
+// getMinConfirmations returns the minimum confirmation depth required of
+// proofs submitted to action, or DefaultMinConfirmations if none is set.
+func getMinConfirmations(action string) uint32 {
+	s := sdk.StateGetObject(constants.MinConfirmationsPrefix + action)
+	if s == nil || *s == "" {
+		return constants.DefaultMinConfirmations
+	}
+	v, err := strconv.ParseUint(*s, 10, 32)
+	if err != nil || v == 0 {
+		return constants.DefaultMinConfirmations
+	}
+	return uint32(v)
+}
+
 func loadUnmapAccumulator() (storedHeight uint64, accum int64) {
 	s := sdk.StateGetObject(constants.BlockUnmapAccKey)
 	if s == nil || len(*s) != 16 {
@@ -754,3 +774,19 @@ func checkAndUpdateUnmapRateLimit(blockHeight uint64, amount int64) error {
 	saveUnmapAccumulator(blockHeight, newAccum)
 	return nil
 }
+
+// releaseUnmapRateLimit gives amount back to the current Hive block's unmap
+// allowance when a queued withdrawal is refunded, so a refund does not keep
+// consuming the cap. It only frees allowance the block has used and never
+// lowers the accumulator below zero.
+func releaseUnmapRateLimit(blockHeight uint64, amount int64) {
+	storedHeight, accum := loadUnmapAccumulator()
+	if storedHeight != blockHeight || accum == 0 {
+		return
+	}
+	accum -= amount
+	if accum < 0 {
+		accum = 0
+	}
+	saveUnmapAccumulator(blockHeight, accum)
+}
@@ -0,0 +1,63 @@
+package network
+
+import (
+	"testing"
+
+	"btc-mapping-contract/contract/constants"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *chaincfg.Params
+		testnet bool
+		csv     int64
+	}{
+		{constants.Mainnet, &chaincfg.MainNetParams, false, constants.BackupCSVBlocks},
+		{constants.Testnet3, &chaincfg.TestNet3Params, true, constants.TestnetBackupCSVBlocks},
+		{constants.Testnet4, &chaincfg.TestNet4Params, true, constants.TestnetBackupCSVBlocks},
+		{constants.Signet, &chaincfg.SigNetParams, true, constants.TestnetBackupCSVBlocks},
+		{constants.Regtest, &chaincfg.RegressionNetParams, true, constants.TestnetBackupCSVBlocks},
+	}
+	for _, tt := range tests {
+		n, ok := Lookup(tt.name)
+		if !ok {
+			t.Fatalf("%s: not found", tt.name)
+		}
+		if n.Params != tt.params || n.Testnet != tt.testnet || n.BackupCSVBlocks != tt.csv {
+			t.Errorf("%s: got params %s, testnet %v, csv %d", tt.name, n.Params.Name, n.Testnet, n.BackupCSVBlocks)
+		}
+	}
+	if _, ok := Lookup("testnet"); ok {
+		t.Error("unknown network name should not be found")
+	}
+}
+
+func TestFixCompatible(t *testing.T) {
+	tests := []struct {
+		name, fallback string
+		want           string
+	}{
+		{"", "", constants.Mainnet},
+		{constants.Mainnet, "", constants.Mainnet},
+		{constants.Regtest, "", ""},
+		{constants.Testnet4, constants.Mainnet, ""},
+		{"", constants.Testnet4, constants.Testnet4},
+		{constants.Regtest, constants.Testnet4, constants.Regtest},
+		{constants.Mainnet, constants.Testnet4, ""},
+	}
+	for _, tt := range tests {
+		n, err := Fix(tt.name, tt.fallback)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Fix(%q, %q) = %s, want an error", tt.name, tt.fallback, n.Name)
+			}
+			continue
+		}
+		if err != nil || n.Name != tt.want {
+			t.Errorf("Fix(%q, %q) = %v, %v, want %s", tt.name, tt.fallback, n, err, tt.want)
+		}
+	}
+}
@@ -15,6 +15,7 @@ var (
 	DevWasm      []byte
 	Testnet4Wasm []byte
 	Testnet3Wasm []byte
+	SignetWasm   []byte
 	RegtestWasm  []byte
 )
 
@@ -22,6 +23,7 @@ func init() {
 	DevWasm, _ = loadWasmFile("dev.wasm")
 	Testnet4Wasm, _ = loadWasmFile("testnet4.wasm")
 	Testnet3Wasm, _ = loadWasmFile("testnet3.wasm")
+	SignetWasm, _ = loadWasmFile("signet.wasm")
 	RegtestWasm, _ = loadWasmFile("regtest.wasm")
 }
 
@@ -49,7 +49,7 @@ func TestBTCC2_RepeatedTxInReplacedBlockNoDoubleMint(t *testing.T) {
 	// instruction. The contract derives the same address from Instructions,
 	// so the output is recognised as a relevant deposit.
 	depositAddr, _, err := mapping.DepositAddress(
-		TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams(),
+		TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork(),
 	)
 	require.NoError(t, err, "derive deposit address")
 	tx := buildTestTx(t, depositAddr, amount)
@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"btc-mapping-contract/contract/constants"
 	"btc-mapping-contract/contract/mapping"
+	"btc-mapping-contract/contract/network"
 
 	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcutil"
@@ -29,6 +31,11 @@ func regtestParams() *chaincfg.Params {
 	return &chaincfg.RegressionNetParams
 }
 
+func regtestNetwork() *network.Network {
+	net, _ := network.Lookup(constants.Regtest)
+	return net
+}
+
 // encodeBalance encodes amount using the same compact big-endian binary
 // format as setAccBal, so the value can be seeded directly into contract state.
 func encodeBalance(t *testing.T, amount int64) string {
@@ -151,7 +158,8 @@ func buildTestTx(t *testing.T, toAddress string, amount int64) *wire.MsgTx {
 	t.Helper()
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.AddTxIn(&wire.TxIn{
-		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{}, Index: 0xffffffff},
+		// any non-null outpoint; a null one would make this a coinbase
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0},
 		SignatureScript:  []byte{0x00},
 		Sequence:         wire.MaxTxInSequenceNum,
 	})
@@ -182,7 +190,7 @@ func serializeTx(t *testing.T, tx *wire.MsgTx) string {
 // matching the on-chain address derivation.
 func depositUtxoBinary(t *testing.T, txId string, vout uint32, amount int64, instruction string) string {
 	t.Helper()
-	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams())
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive deposit address:", err)
 	}
@@ -210,7 +218,7 @@ func depositUtxoBinary(t *testing.T, txId string, vout uint32, amount int64, ins
 func changeUtxoBinary(t *testing.T, txId string, vout uint32, amount int64) string {
 	t.Helper()
 	// nil tag → change address path (OP_CHECKSIGVERIFY + OP_DATA_0)
-	address, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestParams())
+	address, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive change address:", err)
 	}
@@ -246,7 +254,7 @@ type MapTestFixture struct {
 // empty proof, TxIndex = 0) so the on-chain Merkle verification trivially passes.
 func buildMapFixture(t *testing.T, instruction string, amount int64, blockHeight uint32) MapTestFixture {
 	t.Helper()
-	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams())
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive deposit address:", err)
 	}
@@ -278,7 +286,7 @@ type ConfirmSpendFixture struct {
 // empty proof, TxIndex = 0) so the on-chain Merkle verification trivially passes.
 func buildConfirmSpendFixture(t *testing.T, blockHeight uint32) ConfirmSpendFixture {
 	t.Helper()
-	changeAddr, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestParams())
+	changeAddr, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive change address:", err)
 	}
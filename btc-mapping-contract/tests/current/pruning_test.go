@@ -105,6 +105,38 @@ func TestPruning(t *testing.T) {
 		assert.NotEmpty(t, w.ct.StateGet(id, constants.BlockPrefix+lastBlockHeight), "seed block should be preserved")
 	})
 
+	t.Run("AddBlocks_PrunesObservedLists", func(t *testing.T) {
+		id := "prune_observed"
+		ct.RegisterContract(id, testOwner, ContractWasm)
+		seedViaAction(t, w, id)
+
+		tip := seedHeight + 2
+		retainFrom := tip - constants.MaxBlockRetention + 1
+		startHeight := retainFrom - 10
+		observed := buildObservedList(t, observedParam{
+			"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", 0,
+		})
+		for h := startHeight; h < seedHeight; h++ {
+			w.ct.StateSet(id, constants.BlockPrefix+strconv.Itoa(h), "fake_header")
+		}
+		w.ct.StateSet(id, constants.ObservedBlockPrefix+strconv.Itoa(startHeight), observed)
+		w.ct.StateSet(id, constants.ObservedBlockPrefix+strconv.Itoa(retainFrom-1), observed)
+		w.ct.StateSet(id, constants.ObservedBlockPrefix+lastBlockHeight, observed)
+		w.ct.StateSet(id, constants.PruneFloorKey, strconv.Itoa(startHeight))
+
+		r := callActionOnContract(t, w, id, "addBlocks", twoBlocksPayload, oracleCaller)
+		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
+
+		// Pruned blocks lose their observed lists with their headers
+		assert.Empty(t, w.ct.StateGet(id, constants.ObservedBlockPrefix+strconv.Itoa(startHeight)),
+			"observed list at startHeight should be pruned")
+		assert.Empty(t, w.ct.StateGet(id, constants.ObservedBlockPrefix+strconv.Itoa(retainFrom-1)),
+			"observed list below retainFrom should be pruned")
+		// Blocks in the retention window keep theirs
+		assert.Equal(t, observed, w.ct.StateGet(id, constants.ObservedBlockPrefix+lastBlockHeight),
+			"observed list of the seed block should be preserved")
+	})
+
 	t.Run("AddBlocks_NoPruningWithoutFloor", func(t *testing.T) {
 		id := "prune_nofloor"
 		ct.RegisterContract(id, testOwner, ContractWasm)
@@ -15,7 +15,98 @@ var (
 	_ tinyjson.Marshaler
 )
 
-func tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp(in *jlexer.Lexer, out *SeedBlocksParams) {
+func tinyjson2d7d9c89DecodeDashMappingContractContractBlocklistTinyjsonTmp(in *jlexer.Lexer, out *SetOraclesParams) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "oracles":
+			if in.IsNull() {
+				in.Skip()
+				out.Oracles = nil
+			} else {
+				in.Delim('[')
+				if out.Oracles == nil {
+					if !in.IsDelim(']') {
+						out.Oracles = make([]string, 0, 4)
+					} else {
+						out.Oracles = []string{}
+					}
+				} else {
+					out.Oracles = (out.Oracles)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 string
+					v1 = string(in.String())
+					out.Oracles = append(out.Oracles, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "threshold":
+			out.Threshold = uint32(in.Uint32())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func tinyjson2d7d9c89EncodeDashMappingContractContractBlocklistTinyjsonTmp(out *jwriter.Writer, in SetOraclesParams) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"oracles\":"
+		out.RawString(prefix[1:])
+		if in.Oracles == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Oracles {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v3))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"threshold\":"
+		out.RawString(prefix)
+		out.Uint32(uint32(in.Threshold))
+	}
+	out.RawByte('}')
+}
+
+// MarshalTinyJSON supports tinyjson.Marshaler interface
+func (v SetOraclesParams) MarshalTinyJSON(w *jwriter.Writer) {
+	tinyjson2d7d9c89EncodeDashMappingContractContractBlocklistTinyjsonTmp(w, v)
+}
+
+// UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
+func (v *SetOraclesParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
+	tinyjson2d7d9c89DecodeDashMappingContractContractBlocklistTinyjsonTmp(l, v)
+}
+func tinyjson2d7d9c89DecodeDashMappingContractContractBlocklistTinyjsonTmp1(in *jlexer.Lexer, out *SeedBlocksParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -38,6 +129,12 @@ func tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp(in *jl
 			out.BlockHeader = string(in.String())
 		case "block_height":
 			out.BlockHeight = uint32(in.Uint32())
+		case "parent_headers":
+			out.ParentHeaders = string(in.String())
+		case "block_headers":
+			out.BlockHeaders = string(in.String())
+		case "network":
+			out.Network = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -48,7 +145,7 @@ func tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp(in *jl
 		in.Consumed()
 	}
 }
-func tinyjson2d7d9c89EncodeBtcMappingContractContractBlocklistTinyjsonTmp(out *jwriter.Writer, in SeedBlocksParams) {
+func tinyjson2d7d9c89EncodeDashMappingContractContractBlocklistTinyjsonTmp1(out *jwriter.Writer, in SeedBlocksParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -62,19 +159,34 @@ func tinyjson2d7d9c89EncodeBtcMappingContractContractBlocklistTinyjsonTmp(out *j
 		out.RawString(prefix)
 		out.Uint32(uint32(in.BlockHeight))
 	}
+	if in.ParentHeaders != "" {
+		const prefix string = ",\"parent_headers\":"
+		out.RawString(prefix)
+		out.String(string(in.ParentHeaders))
+	}
+	if in.BlockHeaders != "" {
+		const prefix string = ",\"block_headers\":"
+		out.RawString(prefix)
+		out.String(string(in.BlockHeaders))
+	}
+	if in.Network != "" {
+		const prefix string = ",\"network\":"
+		out.RawString(prefix)
+		out.String(string(in.Network))
+	}
 	out.RawByte('}')
 }
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v SeedBlocksParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjson2d7d9c89EncodeBtcMappingContractContractBlocklistTinyjsonTmp(w, v)
+	tinyjson2d7d9c89EncodeDashMappingContractContractBlocklistTinyjsonTmp1(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *SeedBlocksParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp(l, v)
+	tinyjson2d7d9c89DecodeDashMappingContractContractBlocklistTinyjsonTmp1(l, v)
 }
-func tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp1(in *jlexer.Lexer, out *AddBlocksParams) {
+func tinyjson2d7d9c89DecodeDashMappingContractContractBlocklistTinyjsonTmp2(in *jlexer.Lexer, out *AddBlocksParams) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -107,7 +219,7 @@ func tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp1(in *j
 		in.Consumed()
 	}
 }
-func tinyjson2d7d9c89EncodeBtcMappingContractContractBlocklistTinyjsonTmp1(out *jwriter.Writer, in AddBlocksParams) {
+func tinyjson2d7d9c89EncodeDashMappingContractContractBlocklistTinyjsonTmp2(out *jwriter.Writer, in AddBlocksParams) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -126,10 +238,10 @@ func tinyjson2d7d9c89EncodeBtcMappingContractContractBlocklistTinyjsonTmp1(out *
 
 // MarshalTinyJSON supports tinyjson.Marshaler interface
 func (v AddBlocksParams) MarshalTinyJSON(w *jwriter.Writer) {
-	tinyjson2d7d9c89EncodeBtcMappingContractContractBlocklistTinyjsonTmp1(w, v)
+	tinyjson2d7d9c89EncodeDashMappingContractContractBlocklistTinyjsonTmp2(w, v)
 }
 
 // UnmarshalTinyJSON supports tinyjson.Unmarshaler interface
 func (v *AddBlocksParams) UnmarshalTinyJSON(l *jlexer.Lexer) {
-	tinyjson2d7d9c89DecodeBtcMappingContractContractBlocklistTinyjsonTmp1(l, v)
+	tinyjson2d7d9c89DecodeDashMappingContractContractBlocklistTinyjsonTmp2(l, v)
 }
@@ -0,0 +1,167 @@
+package blocklist
+
+import (
+	"bytes"
+	"dash-mapping-contract/sdk"
+	"math/big"
+	"strconv"
+
+	"dash-mapping-contract/contract/constants"
+	ce "dash-mapping-contract/contract/contracterrors"
+	"dash-mapping-contract/contract/network"
+	"dash-mapping-contract/contract/x11"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// blockHash returns a Dash block hash, the X11 hash of the 80-byte header.
+// Unlike Bitcoin it is also the PoW hash, and it is what PrevBlock commits
+// to. Transaction ids and merkle roots stay double-SHA256.
+func blockHash(header *wire.BlockHeader) chainhash.Hash {
+	var buf bytes.Buffer
+	buf.Grow(wire.MaxBlockHeaderPayload)
+	_ = header.Serialize(&buf)
+	return chainhash.Hash(x11.Sum(buf.Bytes()))
+}
+
+// checkProofOfWork is blockchain.CheckProofOfWork with the X11 block hash in
+// place of the double-SHA256 one.
+func checkProofOfWork(params *network.PowParams, header *wire.BlockHeader) error {
+	target := blockchain.CompactToBig(header.Bits)
+	if target.Sign() <= 0 {
+		return ce.NewContractError(ce.ErrInput, "block target difficulty is too low")
+	}
+	if target.Cmp(params.PowLimit) > 0 {
+		return ce.NewContractError(ce.ErrInput, "block target difficulty is higher than max")
+	}
+	hash := blockHash(header)
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return ce.NewContractError(ce.ErrInput, "block X11 hash is higher than expected max")
+	}
+	return nil
+}
+
+// headerLookup returns the stored header at height, or false if there is none.
+type headerLookup func(height uint32) (*wire.BlockHeader, bool)
+
+func loadHeader(height uint32) (*wire.BlockHeader, bool) {
+	raw := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatUint(uint64(height), 10))
+	if raw == nil || *raw == "" {
+		return nil, false
+	}
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader([]byte(*raw)), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, false
+	}
+	return &header, true
+}
+
+// calcRequiredBits returns the compact target that a header at height with
+// the given timestamp must carry, given its parent header. It follows Dash
+// Core's GetNextWorkRequired after Dark Gravity Wave v3 activated.
+func calcRequiredBits(
+	params *network.PowParams,
+	height uint32,
+	prev *wire.BlockHeader,
+	timestamp int64,
+	headers headerLookup,
+) (uint32, error) {
+	if height < params.DGWHeight || height <= params.DGWPastBlocks {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" predates Dark Gravity Wave (height "+
+				strconv.FormatUint(uint64(params.DGWHeight), 10)+")",
+		)
+	}
+
+	// Testnet: a block more than 2 hours after its parent may be mined at
+	// the minimum difficulty, and one more than 4 spacings after it at a
+	// tenth of the parent's difficulty.
+	if params.AllowMinDifficulty {
+		if timestamp > prev.Timestamp.Unix()+2*60*60 {
+			return params.PowLimitBits, nil
+		}
+		if timestamp > prev.Timestamp.Unix()+4*params.TargetSpacing {
+			newTarget := blockchain.CompactToBig(prev.Bits)
+			newTarget.Mul(newTarget, big.NewInt(10))
+			if newTarget.Cmp(params.PowLimit) > 0 {
+				return params.PowLimitBits, nil
+			}
+			return blockchain.BigToCompact(newTarget), nil
+		}
+	}
+
+	// Dash Core's running "average" weights the newest target oddly, as
+	// (avg*n + target)/(n+1) for the nth block back; it is reproduced
+	// exactly rather than as a true mean.
+	first := prev
+	avg := blockchain.CompactToBig(prev.Bits)
+	for n := uint32(2); n <= params.DGWPastBlocks; n++ {
+		h, ok := headers(height - n)
+		if !ok {
+			return 0, ce.NewContractError(
+				ce.ErrStateAccess,
+				"no block header at height "+strconv.FormatUint(uint64(height-n), 10)+
+					" to retarget from, seed with parent_headers",
+			)
+		}
+		avg.Mul(avg, big.NewInt(int64(n)))
+		avg.Add(avg, blockchain.CompactToBig(h.Bits))
+		avg.Div(avg, big.NewInt(int64(n)+1))
+		first = h
+	}
+
+	timespan := int64(params.DGWPastBlocks) * params.TargetSpacing
+	actual := prev.Timestamp.Unix() - first.Timestamp.Unix()
+	if actual < timespan/3 {
+		actual = timespan / 3
+	} else if actual > timespan*3 {
+		actual = timespan * 3
+	}
+
+	newTarget := avg.Mul(avg, big.NewInt(actual))
+	newTarget.Div(newTarget, big.NewInt(timespan))
+	if newTarget.Cmp(params.PowLimit) > 0 {
+		newTarget.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(newTarget), nil
+}
+
+// checkHeader verifies a header's X11 proof of work and that its bits are the
+// ones the network requires at height.
+func checkHeader(params *network.PowParams, height uint32, prev *wire.BlockHeader, header *wire.BlockHeader) error {
+	return checkHeaderWith(params, height, prev, header, loadHeader)
+}
+
+func checkHeaderWith(
+	params *network.PowParams,
+	height uint32,
+	prev *wire.BlockHeader,
+	header *wire.BlockHeader,
+	headers headerLookup,
+) error {
+	heightStr := strconv.FormatUint(uint64(height), 10)
+	if err := checkProofOfWork(params, header); err != nil {
+		return ce.Prepend(err, "block "+heightStr+" failed PoW check")
+	}
+
+	// Regtest never retargets; its headers are only bounded by PowLimit.
+	if params.NoRetargeting {
+		return nil
+	}
+
+	expected, err := calcRequiredBits(params, height, prev, header.Timestamp.Unix(), headers)
+	if err != nil {
+		return err
+	}
+	if header.Bits != expected {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+heightStr+" has unexpected difficulty bits "+
+				strconv.FormatUint(uint64(header.Bits), 16)+", expected "+strconv.FormatUint(uint64(expected), 16),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"dash-mapping-contract/contract/constants"
+	"dash-mapping-contract/contract/network"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// testPowParams returns a copy of the named network's PoW params, which a
+// test may change freely.
+func testPowParams(name string) *network.PowParams {
+	n, _ := network.Lookup(name)
+	params := *n.Pow
+	return &params
+}
+
+// Dash mainnet genesis, hash 00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6.
+const dashGenesisHeader = "010000000000000000000000000000000000000000000000000000000000000000000000c762a6567f3cc092f0684bb62b7e00a84890b990f07cc71a6bb58d64b98e02e0022ddb52f0ff0f1ec23fb901"
+
+const regtestBits = 0x207fffff
+
+// history returns the parent of a block at height, and a lookup for the
+// blocks below it. The block n back from height is spaced n-1 intervals
+// before the parent and carries bits(n).
+func history(t *testing.T, height uint32, prevTime, spacing int64, bits func(n uint32) uint32) (*wire.BlockHeader, headerLookup) {
+	prev := &wire.BlockHeader{Bits: bits(1), Timestamp: time.Unix(prevTime, 0)}
+	return prev, func(h uint32) (*wire.BlockHeader, bool) {
+		n := height - h
+		if h >= height || n > 24 {
+			t.Fatalf("unexpected header lookup %d for height %d", h, height)
+		}
+		return &wire.BlockHeader{Bits: bits(n), Timestamp: time.Unix(prevTime-int64(n-1)*spacing, 0)}, true
+	}
+}
+
+func constBits(bits uint32) func(uint32) uint32 {
+	return func(uint32) uint32 { return bits }
+}
+
+func noHeaders(uint32) (*wire.BlockHeader, bool) { return nil, false }
+
+func TestBlockHashGenesis(t *testing.T) {
+	raw, _ := hex.DecodeString(dashGenesisHeader)
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader(raw), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		t.Fatal(err)
+	}
+	got := blockHash(&header)
+	if got.String() != "00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6" {
+		t.Fatalf("got %s", got)
+	}
+	if err := checkProofOfWork(testPowParams(constants.Mainnet), &header); err != nil {
+		t.Fatal(err)
+	}
+	header.Nonce++
+	if err := checkProofOfWork(testPowParams(constants.Mainnet), &header); err == nil {
+		t.Fatal("expected PoW failure")
+	}
+}
+
+func TestRequiredBitsDarkGravityWave(t *testing.T) {
+	params := testPowParams(constants.Mainnet)
+	const height = 2000000
+	const prevTime = 1700000000
+
+	cases := []struct {
+		name    string
+		spacing int64
+		bits    func(uint32) uint32
+		want    uint32
+	}{
+		// 24 blocks span only 23 intervals, so on-target blocks still raise
+		// the difficulty slightly.
+		{"on target", 150, constBits(0x1b0404cb), 0x1b03d9ed},
+		{"slow blocks", 300, constBits(0x1b0404cb), 0x1b07b3da},
+		{"fast blocks clamped to 1/3", 0, constBits(0x1b0404cb), 0x1b0156ee},
+		{"slow blocks clamped to 3x", 5000, constBits(0x1b0404cb), 0x1b0c0e61},
+		{"capped at PowLimit", 5000, constBits(0x1e0fffff), 0x1e0fffff},
+		// A true mean would be about 0x1b0606cb * 3450/3600; Dash Core's
+		// running average gives the newest target more weight.
+		{"mixed targets use Dash Core's weighting", 150, func(n uint32) uint32 {
+			if n%2 == 1 {
+				return 0x1b0404cb
+			}
+			return 0x1b0808cb
+		}, 0x1b05b2ce},
+	}
+	for _, c := range cases {
+		prev, headers := history(t, height, prevTime, c.spacing, c.bits)
+		got, err := calcRequiredBits(params, height, prev, prevTime+150, headers)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %08x, want %08x", c.name, got, c.want)
+		}
+	}
+
+	prev, headers := history(t, height, prevTime, 150, constBits(0x1b0404cb))
+
+	// Mainnet never allows min-difficulty blocks.
+	got, err := calcRequiredBits(params, height, prev, prevTime+3*60*60, headers)
+	if err != nil || got != 0x1b03d9ed {
+		t.Fatalf("got %08x, %v; want 1b03d9ed", got, err)
+	}
+
+	// All 24 past blocks must be stored.
+	partial := func(h uint32) (*wire.BlockHeader, bool) {
+		if h < height-10 {
+			return nil, false
+		}
+		return headers(h)
+	}
+	if _, err := calcRequiredBits(params, height, prev, prevTime+150, partial); err == nil {
+		t.Fatal("expected missing header error")
+	}
+
+	// Heights before DGW are not supported.
+	if _, err := calcRequiredBits(params, params.DGWHeight-1, prev, prevTime+150, noHeaders); err == nil {
+		t.Fatal("expected pre-DGW height to be rejected")
+	}
+}
+
+func TestRequiredBitsTestnetMinDifficulty(t *testing.T) {
+	params := testPowParams(constants.Testnet)
+	const height = 1000000
+	const prevTime = 1700000000
+	prev, headers := history(t, height, prevTime, 150, constBits(0x1c0404cb))
+
+	cases := []struct {
+		name  string
+		delay int64
+		want  uint32
+	}{
+		{"more than 2 hours after the parent", 2*60*60 + 1, params.PowLimitBits},
+		{"more than 4 spacings after the parent", 601, 0x1c282fee},
+		{"otherwise DGW", 600, 0x1c03d9ed},
+	}
+	for _, c := range cases {
+		got, err := calcRequiredBits(params, height, prev, prevTime+c.delay, headers)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %08x, want %08x", c.name, got, c.want)
+		}
+	}
+
+	// Ten times an easy parent's target is capped at the limit.
+	easy := &wire.BlockHeader{Bits: 0x1e0404cb, Timestamp: time.Unix(prevTime, 0)}
+	got, err := calcRequiredBits(params, height, easy, prevTime+601, noHeaders)
+	if err != nil || got != params.PowLimitBits {
+		t.Fatalf("got %08x, %v; want PowLimitBits", got, err)
+	}
+}
+
+// mineX11 bumps the header's nonce until its X11 hash meets its bits.
+func mineX11(header *wire.BlockHeader) {
+	target := blockchain.CompactToBig(header.Bits)
+	for {
+		hash := blockHash(header)
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			return
+		}
+		header.Nonce++
+	}
+}
+
+func TestCheckHeaderEnforcesBits(t *testing.T) {
+	// Mainnet rules at regtest's PowLimit, so headers are cheap to mine.
+	params := testPowParams(constants.Mainnet)
+	params.PowLimit = testPowParams(constants.Regtest).PowLimit
+	params.PowLimitBits = regtestBits
+	const height = 2000000
+	const prevTime = 1700000000
+
+	header := &wire.BlockHeader{
+		Version:    4,
+		PrevBlock:  chainhash.HashH([]byte("prev")),
+		MerkleRoot: chainhash.HashH([]byte("merkle")),
+		Timestamp:  time.Unix(prevTime+150, 0),
+		Bits:       regtestBits,
+	}
+	mineX11(header)
+
+	// Slow blocks keep the target at the limit.
+	prev, slow := history(t, height, prevTime, 600, constBits(regtestBits))
+	if err := checkHeaderWith(params, height, prev, header, slow); err != nil {
+		t.Fatal(err)
+	}
+	// Fast blocks lower it, so the header's bits are stale.
+	prev, fast := history(t, height, prevTime, 60, constBits(regtestBits))
+	if err := checkHeaderWith(params, height, prev, header, fast); err == nil {
+		t.Fatal("expected unexpected-bits failure")
+	}
+}
+
+func TestRegtestSkipsRetarget(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	header := &wire.BlockHeader{
+		Version:    4,
+		PrevBlock:  chainhash.HashH([]byte("prev")),
+		MerkleRoot: chainhash.HashH([]byte("merkle")),
+		Timestamp:  time.Unix(1700000000, 0),
+		Bits:       regtestBits,
+	}
+	mineX11(header)
+
+	prev := &wire.BlockHeader{Bits: 0x1b0404cb, Timestamp: header.Timestamp}
+	if err := checkHeaderWith(params, 1, prev, header, noHeaders); err != nil {
+		t.Fatalf("regtest should only check PoW: %v", err)
+	}
+
+	// PoW is still checked.
+	header.Nonce++
+	for checkProofOfWork(params, header) == nil {
+		header.Nonce++
+	}
+	if err := checkHeaderWith(params, 1, prev, header, noHeaders); err == nil {
+		t.Fatal("expected PoW failure")
+	}
+}
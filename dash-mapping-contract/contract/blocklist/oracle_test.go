@@ -0,0 +1,34 @@
+package blocklist
+
+import "testing"
+
+func TestMedianFee(t *testing.T) {
+	cases := []struct {
+		fees []int64
+		want int64
+	}{
+		{[]int64{7}, 7},
+		{[]int64{900, 3, 5}, 5},
+		{[]int64{4, 1, 10, 6}, 5},
+		{[]int64{2, 3}, 2},
+		{[]int64{1, 1, 1000, 1000, 1000}, 1000},
+	}
+	for _, c := range cases {
+		if got := MedianFee(c.fees); got != c.want {
+			t.Errorf("MedianFee(%v) = %d, want %d", c.fees, got, c.want)
+		}
+	}
+}
+
+func TestBatchDigest(t *testing.T) {
+	a, b := BlockHeaderBytes{1}, BlockHeaderBytes{2}
+	if batchDigest([]BlockHeaderBytes{a, b}) != batchDigest([]BlockHeaderBytes{a, b}) {
+		t.Error("same headers should give the same digest")
+	}
+	if batchDigest([]BlockHeaderBytes{a, b}) == batchDigest([]BlockHeaderBytes{b, a}) {
+		t.Error("reordered headers should give a different digest")
+	}
+	if batchDigest([]BlockHeaderBytes{a}) == batchDigest([]BlockHeaderBytes{a, b}) {
+		t.Error("a prefix of a batch should give a different digest")
+	}
+}
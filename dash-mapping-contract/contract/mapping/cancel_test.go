@@ -0,0 +1,69 @@
+package mapping
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestCancelledSpendRoundTrip(t *testing.T) {
+	c := &cancelledSpend{
+		InputTotal: 1230000,
+		Change:     45000,
+		Versions: []string{
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+			"0e3e2357e806b6cdb1f70b54c3a3a17b6714ee1f0e68bebb44a74b1efd512098",
+		},
+		Inputs: []wire.OutPoint{
+			{Hash: chainhash.Hash{0x01}, Index: 0},
+			{Hash: chainhash.Hash{0x02}, Index: 7},
+		},
+		Payouts: []cancelledPayout{{Id: 3, VscFee: 150}, {Id: 4, VscFee: 0}},
+	}
+	data, err := marshalCancelledSpend(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unmarshalCancelledSpend(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Fatalf("got %+v, want %+v", got, c)
+	}
+	for _, n := range []int{1, cancelledSpendFixedSize + 10, len(data) - 1} {
+		if _, err := unmarshalCancelledSpend(data[:n]); err == nil {
+			t.Errorf("expected %d of %d bytes to fail", n, len(data))
+		}
+	}
+}
+
+func TestWitnessScriptTag(t *testing.T) {
+	primary, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x11}, 32))
+	backup, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x22}, 32))
+	var keys PublicKeys
+	copy(keys.Primary[:], primary.PubKey().SerializeCompressed())
+	copy(keys.Backup[:], backup.PubKey().SerializeCompressed())
+
+	for _, tag := range [][]byte{bytes.Repeat([]byte{0xab}, 32), nil} {
+		_, script, err := createScriptAddressWithBackup(keys.Primary, keys.Backup, tag, regtestNetwork())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := witnessScriptTag(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, tag) {
+			t.Errorf("got tag %x, want %x", got, tag)
+		}
+	}
+
+	if _, err := witnessScriptTag([]byte{0x51}); err == nil {
+		t.Error("expected a foreign script to be rejected")
+	}
+}
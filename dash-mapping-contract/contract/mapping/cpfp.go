@@ -0,0 +1,266 @@
+package mapping
+
+import (
+	"bytes"
+	"dash-mapping-contract/contract/constants"
+	ce "dash-mapping-contract/contract/contracterrors"
+	"dash-mapping-contract/sdk"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ---------------------------------------------------------------------------
+// Child-pays-for-parent
+//
+// cpfp accelerates a pending spend without replacing it. A child transaction
+// spends one of the spend's change outputs back to the change address and
+// pays enough fee that the two together meet the current fee rate. The child
+// is recorded like any other spend. Who pays its fee is an owner setting; see
+// constants.CpfpPayerKey.
+// ---------------------------------------------------------------------------
+
+// loadSigningData returns the signing data of the pending spend txId, or nil
+// if there is none.
+func loadSigningData(txId string) (*SigningData, error) {
+	raw := sdk.StateGetObject(constants.TxSpendsPrefix + txId)
+	if raw == nil || len(*raw) < 1 {
+		return nil, nil
+	}
+	sd, err := UnmarshalSigningData([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrJson, err, "error unmarshalling signing data")
+	}
+	return sd, nil
+}
+
+// unconfirmedChangeOf returns the registry indices of the unconfirmed UTXOs
+// created by txId, and their total.
+func (cs *ContractState) unconfirmedChangeOf(txId string) ([]int, int64, error) {
+	var indices []int
+	var total int64
+	for i, entry := range cs.UtxoList {
+		if entry.Id >= constants.UtxoConfirmedPoolStart {
+			continue
+		}
+		utxo, err := loadUtxo(entry.Id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if utxo.TxId == txId {
+			indices = append(indices, i)
+			total += entry.Amount
+		}
+	}
+	return indices, total, nil
+}
+
+// cpfpPayer returns who pays the fee of cpfp children.
+func cpfpPayer() string {
+	payer := sdk.StateGetObject(constants.CpfpPayerKey)
+	if payer == nil || *payer == "" {
+		return constants.CpfpPayerFees
+	}
+	return *payer
+}
+
+// HandleCpfp spends the largest change output of the pending spend txId that
+// is still in the pool in a child transaction lifting both to the current fee
+// rate, and returns the child's txid.
+func (cs *ContractState) HandleCpfp(txId string) (string, error) {
+	sd, err := loadSigningData(txId)
+	if err != nil {
+		return "", err
+	}
+	if sd == nil {
+		return "", ce.NewContractError(ce.ErrInput, "no pending spend "+txId)
+	}
+
+	var parent wire.MsgTx
+	if err := parent.Deserialize(bytes.NewReader(sd.Tx)); err != nil {
+		return "", ce.WrapContractError(ce.ErrTransaction, err, "error deserializing pending spend")
+	}
+	parentScripts := make(map[int][]byte, len(sd.UnsignedSigHashes))
+	var parentFee int64
+	for _, h := range sd.UnsignedSigHashes {
+		if h.Amount <= 0 {
+			return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" was recorded without its input amounts")
+		}
+		parentScripts[int(h.Index)] = h.WitnessScript
+		parentFee += h.Amount
+	}
+	for _, out := range parent.TxOut {
+		parentFee -= out.Value
+	}
+	parentTarget, err := cs.calculateSegwitFee(int64(parent.SerializeSize()), parentScripts)
+	if err != nil {
+		return "", err
+	}
+	if parentFee >= parentTarget {
+		return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" already pays the current fee rate")
+	}
+
+	pooled, _, err := cs.unconfirmedChangeOf(txId)
+	if err != nil {
+		return "", err
+	}
+	if len(pooled) == 0 {
+		return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" has no unspent change")
+	}
+	largest := pooled[0]
+	for _, i := range pooled[1:] {
+		if cs.UtxoList[i].Amount > cs.UtxoList[largest].Amount {
+			largest = i
+		}
+	}
+	utxoId := cs.UtxoList[largest].Id
+	utxo, err := loadUtxo(utxoId)
+	if err != nil {
+		return "", err
+	}
+
+	changeAddress, _, err := createScriptAddressWithBackup(
+		cs.PublicKeys.Primary,
+		cs.PublicKeys.Backup,
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		return "", ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
+	}
+	changeScript, err := cs.destinationScript(changeAddress)
+	if err != nil {
+		return "", err
+	}
+
+	child := wire.NewMsgTx(wire.TxVersion)
+	childScripts, err := cs.addSpendInputs(child, []*Utxo{utxo})
+	if err != nil {
+		return "", err
+	}
+	child.AddTxOut(wire.NewTxOut(utxo.Amount, changeScript))
+	childTarget, err := cs.calculateSegwitFee(int64(child.SerializeSize()), childScripts)
+	if err != nil {
+		return "", err
+	}
+	// The child pays its own fee at the target rate and what the spend is
+	// short of it.
+	fee := childTarget + parentTarget - parentFee
+	child.TxOut[0].Value -= fee
+	if chainPolicy.isDust(child.TxOut[0].Value) {
+		return "", ce.NewContractError(
+			ce.ErrBalance,
+			"change of spend "+txId+" ("+strconv.FormatInt(utxo.Amount, 10)+
+				") cannot cover a fee of "+strconv.FormatInt(fee, 10),
+		)
+	}
+	childTxId := child.TxID()
+
+	payer := cpfpPayer()
+	if err := cs.chargeCpfpFee(payer, sd.Payouts, fee, childTxId); err != nil {
+		return "", err
+	}
+	if err := cs.recordSpend(child, []uint16{utxoId}, []*Utxo{utxo}, childScripts, changeAddress, nil); err != nil {
+		return "", err
+	}
+	if cs.Supply.ActiveSupply, err = safeSubtract64(cs.Supply.ActiveSupply, fee); err != nil {
+		return "", ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing active supply")
+	}
+	sdk.Log(createCpfpLog(childTxId, txId, fee, payer))
+	return childTxId, nil
+}
+
+// chargeCpfpFee takes fee from the protocol fee supply, or from the accounts
+// of payouts in proportion to their amounts, as payer selects.
+func (cs *ContractState) chargeCpfpFee(payer string, payouts []SpendPayout, fee int64, txId string) error {
+	var err error
+	if payer != constants.CpfpPayerWithdrawers {
+		if cs.Supply.FeeSupply < fee {
+			return ce.NewContractError(
+				ce.ErrBalance,
+				"fee supply "+strconv.FormatInt(cs.Supply.FeeSupply, 10)+
+					" insufficient for cpfp fee "+strconv.FormatInt(fee, 10),
+			)
+		}
+		if cs.Supply.FeeSupply, err = safeSubtract64(cs.Supply.FeeSupply, fee); err != nil {
+			return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing fee supply")
+		}
+		return nil
+	}
+
+	if len(payouts) == 0 {
+		return ce.NewContractError(ce.ErrInput, "spend has no recorded withdrawals to charge")
+	}
+	amounts := make([]int64, len(payouts))
+	for i, p := range payouts {
+		amounts[i] = p.Amount
+	}
+	for i, share := range proRata(fee, amounts) {
+		if share == 0 {
+			continue
+		}
+		bal := getAccBal(payouts[i].From)
+		if bal < share {
+			return ce.NewContractError(
+				ce.ErrBalance,
+				"account ["+payouts[i].From+"] balance "+strconv.FormatInt(bal, 10)+
+					" insufficient for cpfp fee share "+strconv.FormatInt(share, 10),
+			)
+		}
+		setAccBal(payouts[i].From, bal-share)
+		sdk.Log(createChargeLog(txId, payouts[i].From, share))
+	}
+	if cs.Supply.UserSupply, err = safeSubtract64(cs.Supply.UserSupply, fee); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing user supply")
+	}
+	return nil
+}
+
+// createCpfpLog records a cpfp child: its txid, the txid of the spend it
+// accelerates, its fee and who paid it.
+func createCpfpLog(txId, parent string, fee int64, payer string) string {
+	var b strings.Builder
+	b.Grow(200)
+	b.WriteString("cpfp")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("id")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(txId)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("p")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(parent)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("b")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendInt(buf[:0], fee, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("c")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(payer)
+	return b.String()
+}
+
+// createChargeLog records a withdrawer's share of a cpfp child's fee, taken
+// from their balance.
+func createChargeLog(txId, from string, amount int64) string {
+	var b strings.Builder
+	b.Grow(160)
+	b.WriteString("charge")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("id")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(txId)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("f")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(from)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("d")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendInt(buf[:0], amount, 10))
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package mapping
+
+import (
+	"dash-mapping-contract/contract/constants"
+	"testing"
+)
+
+func TestChargeCpfpFeeFromFeeSupply(t *testing.T) {
+	cs := &ContractState{Supply: SystemSupply{FeeSupply: 100}}
+	if err := cs.chargeCpfpFee(constants.CpfpPayerFees, nil, 150, "aa"); err == nil {
+		t.Fatal("expected a fee above the fee supply to be rejected")
+	}
+	if err := cs.chargeCpfpFee(constants.CpfpPayerFees, nil, 60, "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if cs.Supply.FeeSupply != 40 {
+		t.Fatalf("fee supply = %d, want 40", cs.Supply.FeeSupply)
+	}
+}
+
+func TestChargeCpfpFeeNeedsWithdrawers(t *testing.T) {
+	cs := &ContractState{Supply: SystemSupply{FeeSupply: 100, UserSupply: 100}}
+	if err := cs.chargeCpfpFee(constants.CpfpPayerWithdrawers, nil, 10, "aa"); err == nil {
+		t.Fatal("expected a spend without recorded withdrawals to be rejected")
+	}
+	if cs.Supply.FeeSupply != 100 || cs.Supply.UserSupply != 100 {
+		t.Fatalf("supply changed: %+v", cs.Supply)
+	}
+}
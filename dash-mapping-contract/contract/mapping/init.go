@@ -3,55 +3,15 @@ package mapping
 import (
 	"dash-mapping-contract/contract/constants"
 	ce "dash-mapping-contract/contract/contracterrors"
+	"dash-mapping-contract/contract/network"
 	"dash-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
 	"net/url"
 	"strings"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
-// Dash-specific network params for address validation. Dash uses different
-// address version bytes than Bitcoin. Bech32HRPSegwit is set for internal
-// P2WSH tracking only (Dash has no native segwit).
-func dashTestNetParams() *chaincfg.Params {
-	p := chaincfg.TestNet3Params
-	p.PubKeyHashAddrID = 0x8c // 'y' prefix
-	p.ScriptHashAddrID = 0x13 // '8'/'9' prefix
-	p.Bech32HRPSegwit = "tdash"
-	return &p
-}
-
-func dashMainNetParams() *chaincfg.Params {
-	p := chaincfg.MainNetParams
-	p.PubKeyHashAddrID = 0x4c // 'X' prefix
-	p.ScriptHashAddrID = 0x10 // '7' prefix
-	p.Bech32HRPSegwit = "dash"
-	return &p
-}
-
-func dashRegTestParams() *chaincfg.Params {
-	// Dash regtest reuses Dash testnet base58 prefixes. Bech32HRPSegwit is
-	// left at the inherited "bcrt" since Dash has no native segwit and the
-	// regtest test harness uses bcrt1... destination addresses.
-	p := chaincfg.RegressionNetParams
-	p.PubKeyHashAddrID = 0x8c // 'y' prefix
-	p.ScriptHashAddrID = 0x13 // '8'/'9' prefix
-	return &p
-}
-
-func IntializeContractState(publicKeys PublicKeys, networkMode string) (*ContractState, error) {
-	var networkParams *chaincfg.Params
-	switch networkMode {
-	case constants.Testnet:
-		networkParams = dashTestNetParams()
-	case constants.Regtest:
-		networkParams = dashRegTestParams()
-	default:
-		networkParams = dashMainNetParams()
-	}
-
+func IntializeContractState(publicKeys PublicKeys, net *network.Network) (*ContractState, error) {
 	// Load UTXO registry (binary: 9 bytes/entry)
 	var utxos UtxoRegistry
 	utxoState := sdk.StateGetObject(constants.UtxoRegistryKey)
@@ -102,16 +62,16 @@ func IntializeContractState(publicKeys PublicKeys, networkMode string) (*Contrac
 		TxSpendsList:      txSpends,
 		Supply:            supply,
 		PublicKeys:        publicKeys,
-		NetworkParams:     networkParams,
+		Network:           net,
 	}, nil
 }
 
 func InitializeMappingState(
 	publicKeys PublicKeys,
-	networkMode string,
+	net *network.Network,
 	instructions ...string,
 ) (*MappingState, error) {
-	contractState, err := IntializeContractState(publicKeys, networkMode)
+	contractState, err := IntializeContractState(publicKeys, net)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +79,7 @@ func InitializeMappingState(
 	var registry map[string]*AddressMetadata
 	if len(instructions) > 0 {
 		var err error
-		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.NetworkParams)
+		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.Network)
 		if err != nil {
 			return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error unmarshalling address registry")
 		}
@@ -134,7 +94,7 @@ func InitializeMappingState(
 func (cs *ContractState) parseInstructions(
 	publicKeys PublicKeys,
 	instrs []string,
-	networkParams *chaincfg.Params,
+	net *network.Network,
 ) (map[string]*AddressMetadata, error) {
 	parsedInstructions := make([]url.Values, len(instrs))
 	registry := make(map[string]*AddressMetadata, len(instrs))
@@ -178,11 +138,11 @@ func (cs *ContractState) parseInstructions(
 			hasher := sha256.New()
 			hasher.Write([]byte(instr))
 			hashBytes := hasher.Sum(nil)
-			address, _, err := createP2WSHAddressWithBackup(
+			address, _, err := createScriptAddressWithBackup(
 				publicKeys.Primary,
 				publicKeys.Backup,
 				hashBytes,
-				networkParams,
+				net,
 			)
 			if err != nil {
 				return nil, err
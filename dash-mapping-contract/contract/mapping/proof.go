@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"dash-mapping-contract/contract/blocklist"
 	"dash-mapping-contract/contract/constants"
 	ce "dash-mapping-contract/contract/contracterrors"
 	"dash-mapping-contract/sdk"
@@ -13,32 +14,225 @@ import (
 )
 
 // verifyTransaction checks that rawTxBytes is included in the block at
-// req.BlockHeight via Merkle proof. The contract does not enforce a minimum
-// confirmation depth — that responsibility belongs to the oracle, which only
-// submits headers after sufficient confirmations (see CLAUDE.md § Security Model).
-func verifyTransaction(req *VerificationRequest, rawTxBytes []byte) error {
+// req.BlockHeight via Merkle proof, and that the block is buried at least as
+// deep as the owner-configured minimum confirmations for action.
+// A block already pruned is returned, so its archived observed tx list can
+// be used and updated.
+func verifyTransaction(req *VerificationRequest, rawTxBytes []byte, action string) (*archivedBlock, error) {
+	lastHeight, err := blocklist.LastHeightFromState()
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+	}
+	if err := checkConfirmations(req.BlockHeight, lastHeight, getMinConfirmations(action)); err != nil {
+		return nil, err
+	}
+
+	blockHeader, archived, err := provenBlockHeader(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(rawTxBytes)); err != nil {
+		return nil, err
+	}
+	if tx.SerializeSizeStripped() == merkleNodeSize {
+		return nil, ce.NewContractError(ce.ErrInput, "64-byte transactions cannot be proven, they are indistinguishable from a merkle node")
+	}
+
+	merkleProof, err := merkleProofFromHex(req.MerkleProofHex)
+	if err != nil {
+		return nil, err
+	}
+
+	calculatedHash := tx.TxHash()
+
+	if !verifyMerkleProof(calculatedHash, req.TxIndex, merkleProof, blockHeader.MerkleRoot) {
+		return nil, ce.NewContractError(ce.ErrInput, "transaction cannot be validated, failed to reconstruct proof")
+	}
+	if err := checkMerkleDepth(req, tx, len(merkleProof), blockHeader.MerkleRoot); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// checkMerkleDepth pins the depth of a proof of tx against the block's
+// coinbase, so that an inner node cannot be proven as a transaction. A proof
+// without siblings needs no coinbase, as tx is then the only transaction of
+// the block, and neither does a proof of the coinbase itself.
+func checkMerkleDepth(req *VerificationRequest, tx *wire.MsgTx, depth int, merkleRoot chainhash.Hash) error {
+	if depth == 0 || (req.TxIndex == 0 && isCoinbaseTx(tx)) {
+		return nil
+	}
+	if req.CoinbaseTxHex == "" || req.CoinbaseProofHex == "" {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"a block of more than one transaction requires the coinbase tx and its merkle proof",
+		)
+	}
+	return verifyCoinbaseProof(req.CoinbaseTxHex, req.CoinbaseProofHex, req.TxIndex, depth, merkleRoot)
+}
+
+// provenBlockHeader returns the header at req.BlockHeight from contract state
+// or, once it has been pruned, the archival header in req after checking it
+// against the header archive, along with the archived block.
+func provenBlockHeader(req *VerificationRequest) (*wire.BlockHeader, *archivedBlock, error) {
 	// block header from contract state (stored as raw 80 bytes)
 	rawHeaderStr := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatUint(uint64(req.BlockHeight), 10))
-	rawHeaderBytes := []byte(*rawHeaderStr)
+	if rawHeaderStr == nil || *rawHeaderStr == "" {
+		archived, err := archivedBlockHeader(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		return archived.header, archived, nil
+	}
+	var blockHeader wire.BlockHeader
+	if err := blockHeader.BtcDecode(bytes.NewReader([]byte(*rawHeaderStr)), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, nil, ce.WrapContractError(ce.ErrInput, err, "error decoding block header")
+	}
+	return &blockHeader, nil, nil
+}
+
+// archivedBlock is a pruned block a transaction was proven in. Its observed
+// tx list is kept in its header archive leaf rather than in contract state.
+type archivedBlock struct {
+	height   uint32
+	header   *wire.BlockHeader
+	observed []byte
+	proof    []chainhash.Hash
+}
+
+// archivedBlockHeader decodes the archival header and observed tx list in req
+// and checks them against the header archive at req.BlockHeight.
+func archivedBlockHeader(req *VerificationRequest) (*archivedBlock, error) {
+	if req.ArchivalHeaderHex == "" {
+		return nil, ce.NewContractError(
+			ce.ErrInput,
+			"no block header at height "+strconv.FormatUint(uint64(req.BlockHeight), 10)+
+				", an archival header and proof are required",
+		)
+	}
+	rawHeaderBytes, err := hex.DecodeString(req.ArchivalHeaderHex)
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrInput, err, "error decoding archival header hex")
+	}
+	if len(rawHeaderBytes) != 80 {
+		return nil, ce.NewContractError(ce.ErrInput, "expected an 80-byte archival header")
+	}
 	var blockHeader wire.BlockHeader
 	if err := blockHeader.BtcDecode(bytes.NewReader(rawHeaderBytes), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
-		return ce.WrapContractError(ce.ErrInput, err, "error decoding block header")
+		return nil, ce.WrapContractError(ce.ErrInput, err, "error decoding block header")
+	}
+	observed, err := hex.DecodeString(req.ArchivedObservedHex)
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrInput, err, "error decoding archived observed hex")
 	}
+	archiveProof, err := merkleProofFromHex(req.ArchiveProofHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := blocklist.VerifyArchivedHeader(req.BlockHeight, &blockHeader, observed, archiveProof); err != nil {
+		return nil, err
+	}
+	return &archivedBlock{
+		height:   req.BlockHeight,
+		header:   &blockHeader,
+		observed: observed,
+		proof:    archiveProof,
+	}, nil
+}
 
-	tx := wire.NewMsgTx(wire.TxVersion)
-	if err := tx.Deserialize(bytes.NewReader(rawTxBytes)); err != nil {
+// saveObservedList archives list as the observed tx list of the block, in
+// place of the one it was proven with.
+func (a *archivedBlock) saveObservedList(list []observedEntry) error {
+	packed := packObservedList(list)
+	if bytes.Equal(packed, a.observed) {
+		return nil
+	}
+	if err := blocklist.UpdateArchivedObserved(a.height, a.header, a.observed, packed, a.proof); err != nil {
 		return err
 	}
+	a.observed = packed
+	return nil
+}
 
-	merkleProof, err := merkleProofFromHex(req.MerkleProofHex)
+// merkleNodeSize is the size of an inner merkle node preimage, two 32-byte
+// hashes. A transaction of the same stripped size could be passed off as one.
+const merkleNodeSize = 64
+
+// verifyCoinbaseProof checks a proof of the block's coinbase at index 0. Every
+// leaf of the merkle tree sits at the same depth, so a valid coinbase proof
+// pins the depth the transaction's proof must have, and with it the range its
+// index can take.
+func verifyCoinbaseProof(
+	coinbaseTxHex string,
+	coinbaseProofHex string,
+	txIndex uint32,
+	depth int,
+	merkleRoot chainhash.Hash,
+) error {
+	rawCoinbase, err := hex.DecodeString(coinbaseTxHex)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error decoding coinbase tx hex")
+	}
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	if err := coinbase.Deserialize(bytes.NewReader(rawCoinbase)); err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error deserializing coinbase tx")
+	}
+	if !isCoinbaseTx(coinbase) {
+		return ce.NewContractError(ce.ErrInput, "coinbase tx does not spend the null outpoint")
+	}
+	if coinbase.SerializeSizeStripped() == merkleNodeSize {
+		return ce.NewContractError(ce.ErrInput, "64-byte transactions cannot be proven, they are indistinguishable from a merkle node")
+	}
+	coinbaseProof, err := merkleProofFromHex(coinbaseProofHex)
 	if err != nil {
 		return err
 	}
+	if !verifyMerkleProof(coinbase.TxHash(), 0, coinbaseProof, merkleRoot) {
+		return ce.NewContractError(ce.ErrInput, "coinbase cannot be validated, failed to reconstruct proof")
+	}
+	if len(coinbaseProof) != depth {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"proof depth "+strconv.Itoa(depth)+" does not match the block's merkle depth "+
+				strconv.Itoa(len(coinbaseProof)),
+		)
+	}
+	if uint64(txIndex) >= 1<<uint(depth) {
+		return ce.NewContractError(ce.ErrInput, "tx index is out of range for the block's merkle depth")
+	}
+	return nil
+}
 
-	calculatedHash := tx.TxHash()
+// isCoinbaseTx reports whether tx has the single null-outpoint input of a
+// coinbase.
+func isCoinbaseTx(tx *wire.MsgTx) bool {
+	if len(tx.TxIn) != 1 {
+		return false
+	}
+	prev := tx.TxIn[0].PreviousOutPoint
+	return prev.Index == wire.MaxPrevOutIndex && prev.Hash == (chainhash.Hash{})
+}
 
-	if !verifyMerkleProof(calculatedHash, req.TxIndex, merkleProof, blockHeader.MerkleRoot) {
-		return ce.NewContractError(ce.ErrInput, "transaction cannot be validated, failed to reconstruct proof")
+// checkConfirmations rejects a proof against a block with fewer than min
+// confirmations, counting the tip block itself as one.
+func checkConfirmations(blockHeight, lastHeight, min uint32) error {
+	if blockHeight > lastHeight {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block height "+strconv.FormatUint(uint64(blockHeight), 10)+" is above the last stored height "+
+				strconv.FormatUint(uint64(lastHeight), 10),
+		)
+	}
+	confirmations := lastHeight - blockHeight + 1
+	if confirmations < min {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block at height "+strconv.FormatUint(uint64(blockHeight), 10)+" has "+
+				strconv.FormatUint(uint64(confirmations), 10)+" confirmations, "+
+				strconv.FormatUint(uint64(min), 10)+" required",
+		)
 	}
 	return nil
 }
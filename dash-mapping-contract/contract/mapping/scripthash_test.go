@@ -0,0 +1,104 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"dash-mapping-contract/contract/constants"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// Dash has no segwit, so deposit and change addresses must be P2SH.
+func TestScriptAddressIsP2SH(t *testing.T) {
+	if constants.ScriptHashMode != constants.ScriptHashP2SH {
+		t.Fatalf("Dash must use P2SH, got %s", constants.ScriptHashMode)
+	}
+	cs := newTestState(t, 1)
+	tag := bytes.Repeat([]byte{0xab}, 32)
+	address, script, err := createScriptAddressWithBackup(cs.PublicKeys.Primary, cs.PublicKeys.Backup, tag, cs.Network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := btcutil.DecodeAddress(address, cs.Network.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2sh, ok := addr.(*btcutil.AddressScriptHash)
+	if !ok {
+		t.Fatalf("got %T, want P2SH", addr)
+	}
+	if !bytes.Equal(p2sh.Hash160()[:], btcutil.Hash160(script)) {
+		t.Fatal("address does not commit to the redeem script")
+	}
+}
+
+// The fee must cover the transaction once every input carries its scriptSig
+// with the largest low-S signature (71-byte DER plus the sighash byte).
+func TestP2SHFeeCoversScriptSigs(t *testing.T) {
+	const feeRate = 3
+	cs := newTestState(t, feeRate)
+	changeAddr, _, err := AddressWithBackup(
+		hex.EncodeToString(cs.PublicKeys.Primary[:]),
+		hex.EncodeToString(cs.PublicKeys.Backup[:]),
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deposit := mkInput(t, 600_000)
+	deposit.Tag = bytes.Repeat([]byte{0xab}, 32)
+	inputs := []*Utxo{deposit, mkInput(t, 700_000)}
+	tx, scripts, fee, err := cs.buildSpendTransaction(inputs, 1_300_000, regtestDestAddr(t), changeAddr, 400_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	estimate, err := cs.estimateFee(int64(len(inputs)), 400_000, 1_300_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, in := range tx.TxIn {
+		in.SignatureScript, err = txscript.NewScriptBuilder().
+			AddData(make([]byte, 72)).
+			AddOp(txscript.OP_TRUE).
+			AddData(scripts[i]).
+			Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tx.HasWitness() {
+		t.Fatal("P2SH spends must not carry a witness")
+	}
+	want := int64(tx.SerializeSize()) * feeRate
+	if fee != want {
+		t.Errorf("fee %d, want %d for %d bytes", fee, want, tx.SerializeSize())
+	}
+	if estimate < fee {
+		t.Errorf("estimate %d below actual fee %d", estimate, fee)
+	}
+}
+
+// Witness destinations cannot be paid on a chain without segwit.
+func TestBuildSpendRejectsSegwitDestination(t *testing.T) {
+	cs := newTestState(t, 1)
+	witness, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20), &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeAddr, _, err := createScriptAddressWithBackup(cs.PublicKeys.Primary, cs.PublicKeys.Backup, nil, cs.Network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err = cs.buildSpendTransaction([]*Utxo{mkInput(t, 100_000)}, 100_000, witness.EncodeAddress(), changeAddr, 50_000)
+	if err == nil {
+		t.Fatal("expected segwit destination to be rejected")
+	}
+}
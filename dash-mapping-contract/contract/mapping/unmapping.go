@@ -5,6 +5,7 @@ import (
 	ce "dash-mapping-contract/contract/contracterrors"
 	"dash-mapping-contract/sdk"
 	"bytes"
+	"slices"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -13,26 +14,17 @@ import (
 	"github.com/btcsuite/btcd/wire"
 )
 
-// constants in sats
-const dustThreshold = 546
-
-const splitThreshold = 1000000 // 0.01 BTC
 const maxChangeOutputs = 4
 
-// VscFeeMinSats is the minimum VSC protocol fee in satoshis.
-const VscFeeMinSats int64 = 0
-
-// VscFeeRateBps is the VSC protocol fee as basis points (1 bps = 0.01%).
-const VscFeeRateBps int64 = 0
-
 func calcVscFee(amount int64) (int64, error) {
-	if VscFeeMinSats == 0 && VscFeeRateBps == 0 {
+	minFee, rateBps := chainPolicy.VscFeeMin, chainPolicy.VscFeeRateBps
+	if minFee == 0 && rateBps == 0 {
 		return 0, nil
 	}
 	// divide first to avoid overflow on large amounts, then compensate for remainder
-	percentageFee := (amount/10000)*VscFeeRateBps + (amount%10000)*VscFeeRateBps/10000
-	finalFee := VscFeeMinSats
-	if percentageFee > VscFeeMinSats {
+	percentageFee := (amount/10000)*rateBps + (amount%10000)*rateBps/10000
+	finalFee := minFee
+	if percentageFee > minFee {
 		finalFee = percentageFee
 	}
 	if finalFee >= amount {
@@ -59,13 +51,42 @@ func estimateVSize(nonWitnessSize, witnessDataSize int64) int64 {
 	return (nonWitnessSize*3+totalSize+3)/4 + 2
 }
 
-// clampedFeeRate returns the base fee rate clamped to MaxBaseFeeRate.
+// spendDataSize returns the bytes an input spending the IF branch of a script
+// of scriptLen bytes adds beyond its outpoint, sequence and empty script
+// length. In P2WSH mode it is the witness:
+// item_count(1) + sig_len(1) + sig(72) + branch_len(1) + branch(1) + script_len(1) + script(N).
+// In P2SH mode it is the scriptSig <sig> OP_TRUE <script>, whose script push
+// needs OP_PUSHDATA1 from 76 bytes.
+func spendDataSize(scriptLen int64) int64 {
+	if constants.ScriptHashMode == constants.ScriptHashP2SH {
+		pushSize := int64(1)
+		if scriptLen >= txscript.OP_PUSHDATA1 {
+			pushSize = 2
+		}
+		sigScriptSize := 1 + 72 + 1 + pushSize + scriptLen
+		// the empty scriptSig's length byte is already counted
+		return int64(wire.VarIntSerializeSize(uint64(sigScriptSize))) - 1 + sigScriptSize
+	}
+	return 72 + scriptLen + 5
+}
+
+// scriptOutputSize is the size of a deposit or change output: value (8) +
+// script length (1) + P2WSH script (34) or P2SH script (23).
+func scriptOutputSize() int64 {
+	if constants.ScriptHashMode == constants.ScriptHashP2SH {
+		return 32
+	}
+	return 43
+}
+
+// clampedFeeRate returns the base fee rate clamped to the chain policy's
+// fee rate bounds.
 func clampedFeeRate(rate int64) int64 {
-	if rate > constants.MaxBaseFeeRate {
-		return constants.MaxBaseFeeRate
+	if rate > chainPolicy.MaxFeeRate {
+		return chainPolicy.MaxFeeRate
 	}
-	if rate < 1 {
-		return 1
+	if rate < chainPolicy.MinFeeRate {
+		return chainPolicy.MinFeeRate
 	}
 	return rate
 }
@@ -81,19 +102,22 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 	baseSize := int64(10)
 	// Input size: outpoint (36) + script sig length (1) + sequence (4)
 	inputSize := numInputs * 41
-	// Output size: value (8) + script length (1) + P2WSH script (34)
+	// Output size: value (8) + script length (1) + P2WSH script (34). In P2SH
+	// mode the largest destination script is P2PKH (25).
 	outputSize := int64(43) // 1 destination output
+	if constants.ScriptHashMode == constants.ScriptHashP2SH {
+		outputSize = 34
+	}
 
-	// Witness stack per input: <sig> <branch_selector> <witness_script>
-	// Serialized: item_count(1) + sig_len(1) + sig(72) + branch_len(1) + branch(1) + script_len(1) + script(N)
-	// Witness script is ~79 bytes for change UTXOs (no tag) or ~112 bytes for
-	// deposit UTXOs (with 32-byte tag). Use 112 as conservative upper bound
-	// to ensure fee estimate >= actual fee from calculateSegwitFee.
-	witnessDataSize := numInputs * (72 + 112 + 5)
+	// Witness or scriptSig per input, see spendDataSize. The script is ~79
+	// bytes for change UTXOs (no tag) or ~112 bytes for deposit UTXOs (with
+	// 32-byte tag). Use 112 as conservative upper bound to ensure fee
+	// estimate >= actual fee from calculateSegwitFee.
+	witnessDataSize := numInputs * spendDataSize(112)
 
 	// Compute base fee (no change outputs) first
 	nonWitnessSize := baseSize + inputSize + outputSize
-	baseFee, err := safeMultiply64(estimateVSize(nonWitnessSize, witnessDataSize), feeRate)
+	baseFee, err := chainPolicy.fee(nonWitnessSize, witnessDataSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 	}
@@ -103,14 +127,14 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 		availableChange = 0
 	}
 
-	if availableChange > dustThreshold {
-		numChangeOutputs := min(max(availableChange/splitThreshold, 1), maxChangeOutputs)
+	if !chainPolicy.isDust(availableChange) {
+		numChangeOutputs := min(max(availableChange/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
 
 		// Add change outputs one at a time, stopping when per-output amount is dust
 		addedOutputs := int64(0)
 		for i := int64(0); i < numChangeOutputs; i++ {
-			newNonWitness := nonWitnessSize + (addedOutputs+1)*43
-			newFee, err := safeMultiply64(estimateVSize(newNonWitness, witnessDataSize), feeRate)
+			newNonWitness := nonWitnessSize + (addedOutputs+1)*scriptOutputSize()
+			newFee, err := chainPolicy.fee(newNonWitness, witnessDataSize, feeRate)
 			if err != nil {
 				return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 			}
@@ -118,7 +142,7 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 			if newAvailable < 0 {
 				newAvailable = 0
 			}
-			if newAvailable/(addedOutputs+1) <= dustThreshold {
+			if chainPolicy.isDust(newAvailable / (addedOutputs + 1)) {
 				break
 			}
 			addedOutputs++
@@ -126,7 +150,7 @@ func (cs *ContractState) estimateFee(numInputs int64, amount, inputAmount int64)
 		}
 	}
 
-	fee, err := safeMultiply64(estimateVSize(nonWitnessSize, witnessDataSize), feeRate)
+	fee, err := chainPolicy.fee(nonWitnessSize, witnessDataSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee estimation overflow")
 	}
@@ -210,18 +234,17 @@ func (cs *ContractState) getInputUtxoIds(amount int64) ([]uint16, int64, error)
 	return nil, 0, ce.NewContractError(ce.ErrBalance, "total available balance insufficient to complete transaction")
 }
 
+// calculateSegwitFee returns the fee for a transaction of baseSize bytes
+// without its witnesses or scriptSigs, once each input carries the spend data
+// for its script. The chain policy decides
+// whether witness data is discounted.
 func (cs *ContractState) calculateSegwitFee(baseSize int64, witnessScripts map[int][]byte) (int64, error) {
 	feeRate := clampedFeeRate(cs.Supply.BaseFeeRate)
-	// Witness stack per input: <sig> <branch_selector> <witness_script>
-	// Serialized: item_count(1) + sig_len(1) + sig(72) + branch_len(1) + branch(1) + script_len(1) + script(N)
 	witnessDataSize := int64(0)
 	for _, witnessScript := range witnessScripts {
-		witnessDataSize += 72 + int64(len(witnessScript)) + 5
+		witnessDataSize += spendDataSize(int64(len(witnessScript)))
 	}
-	totalSize := baseSize + witnessDataSize
-	// +3 to round up, + 2 for has witness data flag
-	vSize := (baseSize*3+totalSize+3)/4 + 2
-	fee, err := safeMultiply64(vSize, feeRate)
+	fee, err := chainPolicy.fee(baseSize, witnessDataSize, feeRate)
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrArithmetic, err, "fee calculation overflow")
 	}
@@ -240,42 +263,13 @@ func (cs *ContractState) buildSpendTransaction(
 ) (*wire.MsgTx, map[int][]byte, int64, error) {
 	tx := wire.NewMsgTx(wire.TxVersion)
 
-	// create all witness scripts now for better size estimation
-	witnessScripts := make(map[int][]byte)
-	for index, utxo := range inputs {
-		txHash, err := chainhash.NewHashFromStr(utxo.TxId)
-		if err != nil {
-			return nil, nil, 0, err
-		}
-
-		outPoint := wire.NewOutPoint(txHash, utxo.Vout)
-		txIn := wire.NewTxIn(outPoint, nil, nil)
-		tx.AddTxIn(txIn)
-
-		_, witnessScript, err := createP2WSHAddressWithBackup(
-			cs.PublicKeys.Primary,
-			cs.PublicKeys.Backup,
-			utxo.Tag, // already []byte
-			cs.NetworkParams,
-		)
-
-		if err != nil {
-			return nil, nil, 0, err
-		}
-		witnessScripts[index] = witnessScript
-	}
-
-	destAddr, err := btcutil.DecodeAddress(destAddress, cs.NetworkParams)
+	witnessScripts, err := cs.addSpendInputs(tx, inputs)
 	if err != nil {
-		return nil, nil, 0, ce.WrapContractError(
-			ce.ErrInput,
-			err,
-			"error decoding destination btc address ["+destAddress+"]",
-		)
+		return nil, nil, 0, err
 	}
 
 	// Create output script for destination
-	destScript, err := txscript.PayToAddrScript(destAddr)
+	destScript, err := cs.destinationScript(destAddress)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -298,8 +292,8 @@ func (cs *ContractState) buildSpendTransaction(
 	}
 
 	// Add change outputs if above dust, splitting across multiple outputs
-	if availableChange > dustThreshold {
-		changeAddressObj, err := btcutil.DecodeAddress(changeAddress, cs.NetworkParams)
+	if !chainPolicy.isDust(availableChange) {
+		changeAddressObj, err := btcutil.DecodeAddress(changeAddress, cs.Network.Params)
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -309,7 +303,7 @@ func (cs *ContractState) buildSpendTransaction(
 		}
 		changeOutputSize := int64(wire.NewTxOut(int64(0), changeScript).SerializeSize())
 
-		numChangeOuputs := min(max(availableChange/splitThreshold, 1), maxChangeOutputs)
+		numChangeOuputs := min(max(availableChange/chainPolicy.SplitThreshold, 1), maxChangeOutputs)
 
 		// Add change outputs one at a time, recalculating fee after each
 		addedOutputs := int64(0)
@@ -326,7 +320,7 @@ func (cs *ContractState) buildSpendTransaction(
 
 			// Check if adding this output still leaves enough for all outputs to be above dust
 			perOutput := newAvailable / (addedOutputs + 1)
-			if perOutput <= dustThreshold {
+			if chainPolicy.isDust(perOutput) {
 				break
 			}
 
@@ -350,7 +344,7 @@ func (cs *ContractState) buildSpendTransaction(
 		}
 	}
 
-	// Pentest finding BTC-C5: when availableChange ≤ dustThreshold the
+	// Pentest finding BTC-C5: when availableChange is dust the
 	// change output is omitted; the residual sats are implicitly paid
 	// to the miner. The size-based fee variable above does NOT capture
 	// that absorbed dust, so callers (HandleUnmap) under-decrement
@@ -368,23 +362,132 @@ func (cs *ContractState) buildSpendTransaction(
 	return tx, witnessScripts, fee, nil
 }
 
-// signSpendTransaction computes witness sighashes and requests TSS signing
-// for each input. Call this only after all validation checks have passed.
+// addSpendInputs adds inputs to tx and returns the witness script of each,
+// by input index, created now for better size estimation.
+func (cs *ContractState) addSpendInputs(tx *wire.MsgTx, inputs []*Utxo) (map[int][]byte, error) {
+	witnessScripts := make(map[int][]byte)
+	for index, utxo := range inputs {
+		txHash, err := chainhash.NewHashFromStr(utxo.TxId)
+		if err != nil {
+			return nil, err
+		}
+
+		outPoint := wire.NewOutPoint(txHash, utxo.Vout)
+		txIn := wire.NewTxIn(outPoint, nil, nil)
+		tx.AddTxIn(txIn)
+
+		_, witnessScript, err := createScriptAddressWithBackup(
+			cs.PublicKeys.Primary,
+			cs.PublicKeys.Backup,
+			utxo.Tag, // already []byte
+			cs.Network,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		witnessScripts[index] = witnessScript
+	}
+	return witnessScripts, nil
+}
+
+// destinationScript returns the output script paying destAddress. In P2SH
+// mode it rejects segwit destinations.
+func (cs *ContractState) destinationScript(destAddress string) ([]byte, error) {
+	destAddr, err := btcutil.DecodeAddress(destAddress, cs.Network.Params)
+	if err != nil {
+		return nil, ce.WrapContractError(
+			ce.ErrInput,
+			err,
+			"error decoding destination btc address ["+destAddress+"]",
+		)
+	}
+	if constants.ScriptHashMode == constants.ScriptHashP2SH {
+		switch destAddr.(type) {
+		case *btcutil.AddressPubKeyHash, *btcutil.AddressScriptHash:
+		default:
+			return nil, ce.NewContractError(
+				ce.ErrInput,
+				"destination address ["+destAddress+"] must be P2PKH or P2SH, Dash has no segwit",
+			)
+		}
+	}
+	return txscript.PayToAddrScript(destAddr)
+}
+
+// recordSpend requests TSS signing for tx, moves its change outputs into the
+// unconfirmed pool, drops its inputs from the registry and stores the signing
+// data, with the withdrawals tx pays, under its txid. Call this only after
+// all validation checks have passed.
+func (cs *ContractState) recordSpend(
+	tx *wire.MsgTx,
+	inputUtxoIds []uint16,
+	inputUtxos []*Utxo,
+	witnessScripts map[int][]byte,
+	changeAddress string,
+	payouts []SpendPayout,
+) error {
+	signingData, err := signSpendTransaction(tx, inputUtxos, witnessScripts)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error signing spend transaction")
+	}
+	signingData.Payouts = payouts
+
+	unconfirmedUtxos, err := indexUnconfimedOutputs(tx, changeAddress, cs.Network.Params)
+	if err != nil {
+		return err
+	}
+	for _, utxo := range unconfirmedUtxos {
+		internalId, err := cs.allocateUnconfirmedId()
+		if err != nil {
+			return err
+		}
+		cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: internalId, Amount: utxo.Amount})
+		saveUtxo(internalId, utxo)
+	}
+
+	for _, inputId := range inputUtxoIds {
+		cs.UtxoList = slices.DeleteFunc(
+			cs.UtxoList,
+			func(entry UtxoRegistryEntry) bool { return entry.Id == inputId },
+		)
+		sdk.StateDeleteObject(getUtxoKey(inputId))
+	}
+
+	signingDataBytes, err := MarshalSigningData(signingData)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrJson, err, "error marshalling signing data")
+	}
+
+	sdk.StateSetObject(constants.TxSpendsPrefix+tx.TxID(), string(signingDataBytes))
+	cs.TxSpendsList = append(cs.TxSpendsList, tx.TxID())
+	return nil
+}
+
+// signSpendTransaction computes sighashes and requests TSS signing for each
+// input: legacy sighashes over the redeem script in P2SH mode, witness
+// sighashes otherwise. Call this only after all validation checks have passed.
 func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int][]byte) (*SigningData, error) {
+	scriptSig := constants.ScriptHashMode == constants.ScriptHashP2SH
 	unsignedSigHashes := make([]UnsignedSigHash, len(inputs))
 	for i, utxo := range inputs {
 		witnessScript := witnessScripts[i]
 
-		sigHashes := txscript.NewTxSigHashes(tx, txscript.NewCannedPrevOutputFetcher(utxo.PkScript, utxo.Amount))
-
-		sigHash, err := txscript.CalcWitnessSigHash(
-			witnessScript,
-			sigHashes,
-			txscript.SigHashAll,
-			tx,
-			i,
-			utxo.Amount,
-		)
+		var sigHash []byte
+		var err error
+		if scriptSig {
+			sigHash, err = txscript.CalcSignatureHash(witnessScript, txscript.SigHashAll, tx, i)
+		} else {
+			sigHashes := txscript.NewTxSigHashes(tx, txscript.NewCannedPrevOutputFetcher(utxo.PkScript, utxo.Amount))
+			sigHash, err = txscript.CalcWitnessSigHash(
+				witnessScript,
+				sigHashes,
+				txscript.SigHashAll,
+				tx,
+				i,
+				utxo.Amount,
+			)
+		}
 
 		if err != nil {
 			return nil, err
@@ -396,6 +499,7 @@ func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int
 			Index:         uint32(i),
 			SigHash:       sigHash,
 			WitnessScript: witnessScript,
+			Amount:        utxo.Amount,
 		}
 	}
 
@@ -408,20 +512,20 @@ func signSpendTransaction(tx *wire.MsgTx, inputs []*Utxo, witnessScripts map[int
 	return &SigningData{
 		Tx:                buf.Bytes(),
 		UnsignedSigHashes: unsignedSigHashes,
+		ScriptSig:         scriptSig,
 	}, nil
 }
 
 func indexUnconfimedOutputs(tx *wire.MsgTx, changeAddress string, network *chaincfg.Params) ([]*Utxo, error) {
-	// 1 output will be to the destination, the others will be to change address
-	utxos := make([]*Utxo, len(tx.TxOut)-1)
+	// the outputs to the change address follow the one or more destinations
+	utxos := make([]*Utxo, 0, len(tx.TxOut)-1)
 
-	i := 0
 	for index, txOut := range tx.TxOut {
 		_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, network)
 		if err != nil {
 			return nil, err
 		}
-		// must be 1 because it's P2WSH
+		// must be 1 because it's P2SH, P2WSH or P2PKH
 		if len(addrs) != 1 {
 			return nil, ce.NewContractError(ce.ErrTransaction, "incorrect number of addresses for transaction output")
 		}
@@ -433,12 +537,7 @@ func indexUnconfimedOutputs(tx *wire.MsgTx, changeAddress string, network *chain
 				PkScript: txOut.PkScript,
 				Tag:      nil, // change outputs have no tag
 			}
-			if i < len(utxos) {
-				utxos[i] = &utxo
-				i++
-			} else {
-				utxos = append(utxos, &utxo)
-			}
+			utxos = append(utxos, &utxo)
 		}
 	}
 
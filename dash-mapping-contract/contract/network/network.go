@@ -0,0 +1,195 @@
+package network
+
+import (
+	"dash-mapping-contract/contract/constants"
+	ce "dash-mapping-contract/contract/contracterrors"
+	"dash-mapping-contract/sdk"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// Network describes the chain a contract is bridged to. It is fixed in state
+// by the first seedBlocks and read by every handler, so one test network
+// build can be deployed for any test network.
+type Network struct {
+	Name   string
+	Params *chaincfg.Params
+	// BackupCSVBlocks is the relative timelock on the backup key's spending
+	// path of deposit and change addresses.
+	BackupCSVBlocks int64
+	// Testnet lets the contract owner act as the oracle, re-register public
+	// keys, and replace more than two blocks at once.
+	Testnet bool
+	// OracleAddress may submit headers when no oracle set is configured.
+	OracleAddress string
+	// BlockRetention is the number of recent headers kept in state.
+	BlockRetention uint32
+	// Pow holds the consensus values that validate the network's headers.
+	Pow *PowParams
+}
+
+// PowParams holds the Dash consensus values needed to validate headers.
+// btcsuite's chaincfg only knows Bitcoin's, so they are defined here.
+type PowParams struct {
+	PowLimit      *big.Int
+	PowLimitBits  uint32
+	NoRetargeting bool
+	// Dark Gravity Wave retargets every block from the last DGWPastBlocks
+	// blocks towards TargetSpacing seconds. Only heights from DGWHeight on
+	// are supported.
+	TargetSpacing int64
+	DGWHeight     uint32
+	DGWPastBlocks uint32
+	// AllowMinDifficulty relaxes the target for blocks that come long after
+	// their parent.
+	AllowMinDifficulty bool
+}
+
+var (
+	// 0x00000fffff000...000, bits 0x1e0fffff
+	mainPowLimit, _ = new(big.Int).SetString("0fffff000000000000000000000000000000000000000000000000000000", 16)
+	// 2^255 - 1, bits 0x207fffff
+	regtestPowLimit = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+)
+
+var networks = []*Network{
+	{
+		Name:            constants.Mainnet,
+		Params:          dashMainNetParams(),
+		BackupCSVBlocks: constants.BackupCSVBlocks,
+		OracleAddress:   constants.OracleAddress,
+		BlockRetention:  constants.MaxBlockRetention,
+		Pow: &PowParams{
+			PowLimit:      mainPowLimit,
+			PowLimitBits:  0x1e0fffff,
+			TargetSpacing: 150,
+			DGWHeight:     34140,
+			DGWPastBlocks: 24,
+		},
+	},
+	{
+		Name:            constants.Testnet,
+		Params:          dashTestNetParams(),
+		BackupCSVBlocks: constants.TestnetBackupCSVBlocks,
+		Testnet:         true,
+		OracleAddress:   constants.OracleAddress,
+		BlockRetention:  constants.MaxBlockRetention,
+		Pow: &PowParams{
+			PowLimit:           mainPowLimit,
+			PowLimitBits:       0x1e0fffff,
+			TargetSpacing:      150,
+			DGWHeight:          4002,
+			DGWPastBlocks:      24,
+			AllowMinDifficulty: true,
+		},
+	},
+	{
+		Name:            constants.Regtest,
+		Params:          dashRegTestParams(),
+		BackupCSVBlocks: constants.TestnetBackupCSVBlocks,
+		Testnet:         true,
+		OracleAddress:   constants.OracleAddress,
+		BlockRetention:  constants.MaxBlockRetention,
+		Pow: &PowParams{
+			PowLimit:      regtestPowLimit,
+			PowLimitBits:  0x207fffff,
+			NoRetargeting: true,
+			TargetSpacing: 150,
+			DGWPastBlocks: 24,
+		},
+	},
+}
+
+// Dash-specific network params for address validation. Dash uses different
+// address version bytes than Bitcoin. Dash has no segwit, so deposit and change
+// addresses are P2SH and bech32 destinations are rejected.
+func dashTestNetParams() *chaincfg.Params {
+	p := chaincfg.TestNet3Params
+	p.PubKeyHashAddrID = 0x8c // 'y' prefix
+	p.ScriptHashAddrID = 0x13 // '8'/'9' prefix
+	return &p
+}
+
+func dashMainNetParams() *chaincfg.Params {
+	p := chaincfg.MainNetParams
+	p.PubKeyHashAddrID = 0x4c // 'X' prefix
+	p.ScriptHashAddrID = 0x10 // '7' prefix
+	return &p
+}
+
+func dashRegTestParams() *chaincfg.Params {
+	// Dash regtest reuses Dash testnet base58 prefixes.
+	p := chaincfg.RegressionNetParams
+	p.PubKeyHashAddrID = 0x8c // 'y' prefix
+	p.ScriptHashAddrID = 0x13 // '8'/'9' prefix
+	return &p
+}
+
+// Lookup returns the network named name.
+func Lookup(name string) (*Network, bool) {
+	for _, n := range networks {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// Load returns the network fixed in state. Contracts seeded before the
+// network was stored use fallback, the build's default, which is mainnet
+// when empty.
+func Load(fallback string) (*Network, error) {
+	name := fallback
+	if stored := sdk.StateGetObject(constants.NetworkKey); stored != nil && *stored != "" {
+		name = *stored
+	}
+	if name == "" {
+		name = constants.Mainnet
+	}
+	n, ok := Lookup(name)
+	if !ok {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "unknown network "+name)
+	}
+	return n, nil
+}
+
+// Fix stores the network named name, or fallback when name is empty, unless
+// a network is already stored. The stored network cannot be changed, and
+// must be compatible with fallback, the build's default.
+func Fix(name string, fallback string) (*Network, error) {
+	stored := sdk.StateGetObject(constants.NetworkKey)
+	if stored != nil && *stored != "" {
+		if name != "" && name != *stored {
+			return nil, ce.NewContractError(ce.ErrInput, "network is already set to "+*stored)
+		}
+		return Load(fallback)
+	}
+	if fallback == "" {
+		fallback = constants.Mainnet
+	}
+	built, ok := Lookup(fallback)
+	if !ok {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "unknown network "+fallback)
+	}
+	if name == "" {
+		name = fallback
+	}
+	n, ok := Lookup(name)
+	if !ok {
+		return nil, ce.NewContractError(ce.ErrInput, "unknown network "+name)
+	}
+	if !compatible(n, built) {
+		return nil, ce.NewContractError(ce.ErrInput, "network "+name+" is not compatible with this build for "+built.Name)
+	}
+	sdk.StateSetObject(constants.NetworkKey, n.Name)
+	return n, nil
+}
+
+// compatible reports whether a build for built may be seeded as n. A mainnet
+// build only takes mainnet, so a deployment cannot be pinned to a test
+// network and pick up its privileges and short timelocks; a test network
+// build takes any test network.
+func compatible(n, built *Network) bool {
+	return n == built || (n.Testnet && built.Testnet)
+}
@@ -0,0 +1,73 @@
+package x11
+
+// Groestl, SHAvite-3 and ECHO are built on the AES S-box and round function.
+
+var aesSbox = func() (s [256]byte) {
+	// Multiplicative inverse in GF(2^8) followed by the affine transform.
+	var inv [256]byte
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if gmul(byte(a), byte(b)) == 1 {
+				inv[a] = byte(b)
+				break
+			}
+		}
+	}
+	for a := range s {
+		x := inv[a]
+		y := x
+		for i := 1; i < 5; i++ {
+			y ^= x<<i | x>>(8-i)
+		}
+		s[a] = y ^ 0x63
+	}
+	return s
+}()
+
+// gmul multiplies in GF(2^8) modulo the AES polynomial.
+func gmul(a, b byte) byte {
+	var r byte
+	for b != 0 {
+		if b&1 != 0 {
+			r ^= a
+		}
+		a = xtime(a)
+		b >>= 1
+	}
+	return r
+}
+
+func xtime(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+// mixColumn applies AES MixColumns to one column.
+func mixColumn(a0, a1, a2, a3 byte) (byte, byte, byte, byte) {
+	return xtime(a0) ^ xtime(a1) ^ a1 ^ a2 ^ a3,
+		a0 ^ xtime(a1) ^ xtime(a2) ^ a2 ^ a3,
+		a0 ^ a1 ^ xtime(a2) ^ xtime(a3) ^ a3,
+		xtime(a0) ^ a0 ^ a1 ^ a2 ^ xtime(a3)
+}
+
+// aesRound applies SubBytes, ShiftRows, MixColumns and AddRoundKey to a
+// column-major state (byte i at row i%4, column i/4). A nil key skips
+// AddRoundKey.
+func aesRound(s *[16]byte, key *[16]byte) {
+	var u [16]byte
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			u[r+4*c] = aesSbox[s[r+4*((c+r)%4)]]
+		}
+	}
+	for c := 0; c < 4; c++ {
+		s[4*c], s[4*c+1], s[4*c+2], s[4*c+3] = mixColumn(u[4*c], u[4*c+1], u[4*c+2], u[4*c+3])
+	}
+	if key != nil {
+		for i := range s {
+			s[i] ^= key[i]
+		}
+	}
+}
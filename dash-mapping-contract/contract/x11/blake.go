@@ -0,0 +1,110 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// BLAKE-512 (SHA-3 finalist, 16 rounds).
+
+var blakeC = [16]uint64{
+	0x243F6A8885A308D3, 0x13198A2E03707344, 0xA4093822299F31D0, 0x082EFA98EC4E6C89,
+	0x452821E638D01377, 0xBE5466CF34E90C6C, 0xC0AC29B7C97C50DD, 0x3F84D5B5B5470917,
+	0x9216D5D98979FB1B, 0xD1310BA698DFB5AC, 0x2FFD72DBD01ADFB7, 0xB8E1AFED6A267E96,
+	0xBA7C9045F12C7F99, 0x24A19947B3916CF7, 0x0801F2E2858EFC16, 0x636920D871574E69,
+}
+
+var blakeSigma = [10][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+var blakeIV = [8]uint64{
+	0x6A09E667F3BCC908, 0xBB67AE8584CAA73B, 0x3C6EF372FE94F82B, 0xA54FF53A5F1D36F1,
+	0x510E527FADE682D1, 0x9B05688C2B3E6C1F, 0x1F83D9ABFB41BD6B, 0x5BE0CD19137E2179,
+}
+
+func blakeCompress(h *[8]uint64, block []byte, t uint64) {
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.BigEndian.Uint64(block[i*8:])
+	}
+	var v [16]uint64
+	copy(v[:8], h[:])
+	copy(v[8:12], blakeC[:4])
+	v[12] = t ^ blakeC[4]
+	v[13] = t ^ blakeC[5]
+	v[14] = blakeC[6]
+	v[15] = blakeC[7]
+
+	g := func(s *[16]uint8, i, a, b, c, d int) {
+		x, y := s[2*i], s[2*i+1]
+		v[a] += v[b] + (m[x] ^ blakeC[y])
+		v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -25)
+		v[a] += v[b] + (m[y] ^ blakeC[x])
+		v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -11)
+	}
+	for r := 0; r < 16; r++ {
+		s := &blakeSigma[r%10]
+		g(s, 0, 0, 4, 8, 12)
+		g(s, 1, 1, 5, 9, 13)
+		g(s, 2, 2, 6, 10, 14)
+		g(s, 3, 3, 7, 11, 15)
+		g(s, 4, 0, 5, 10, 15)
+		g(s, 5, 1, 6, 11, 12)
+		g(s, 6, 2, 7, 8, 13)
+		g(s, 7, 3, 4, 9, 14)
+	}
+	for i := range h {
+		h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+func blake512(msg []byte) [64]byte {
+	h := blakeIV
+	bitLen := uint64(len(msg)) * 8
+
+	pos := 0
+	for ; len(msg)-pos >= 128; pos += 128 {
+		blakeCompress(&h, msg[pos:pos+128], uint64(pos+128)*8)
+	}
+
+	// The counter of a block holding no message bits is zero.
+	rem := msg[pos:]
+	var buf [128]byte
+	copy(buf[:], rem)
+	buf[len(rem)] = 0x80
+	if len(rem) <= 111 {
+		t := bitLen
+		if len(rem) == 0 {
+			t = 0
+		}
+		buf[111] |= 1
+		binary.BigEndian.PutUint64(buf[120:], bitLen)
+		blakeCompress(&h, buf[:], t)
+	} else {
+		blakeCompress(&h, buf[:], bitLen)
+		buf = [128]byte{}
+		buf[111] = 1
+		binary.BigEndian.PutUint64(buf[120:], bitLen)
+		blakeCompress(&h, buf[:], 0)
+	}
+
+	var out [64]byte
+	for i, x := range h {
+		binary.BigEndian.PutUint64(out[i*8:], x)
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Blue Midnight Wish (BMW-512).
+
+func bmwS0(x uint64) uint64 {
+	return x>>1 ^ x<<3 ^ bits.RotateLeft64(x, 4) ^ bits.RotateLeft64(x, 37)
+}
+func bmwS1(x uint64) uint64 {
+	return x>>1 ^ x<<2 ^ bits.RotateLeft64(x, 13) ^ bits.RotateLeft64(x, 43)
+}
+func bmwS2(x uint64) uint64 {
+	return x>>2 ^ x<<1 ^ bits.RotateLeft64(x, 19) ^ bits.RotateLeft64(x, 53)
+}
+func bmwS3(x uint64) uint64 {
+	return x>>2 ^ x<<2 ^ bits.RotateLeft64(x, 28) ^ bits.RotateLeft64(x, 59)
+}
+func bmwS4(x uint64) uint64 { return x>>1 ^ x }
+func bmwS5(x uint64) uint64 { return x>>2 ^ x }
+
+// bmwW lists, for each W_i of f0, the indices of M^H summed into it and
+// whether each is subtracted.
+var bmwW = [16][5]struct {
+	idx uint8
+	neg bool
+}{
+	{{5, false}, {7, true}, {10, false}, {13, false}, {14, false}},
+	{{6, false}, {8, true}, {11, false}, {14, false}, {15, true}},
+	{{0, false}, {7, false}, {9, false}, {12, true}, {15, false}},
+	{{0, false}, {1, true}, {8, false}, {10, true}, {13, false}},
+	{{1, false}, {2, false}, {9, false}, {11, true}, {14, true}},
+	{{3, false}, {2, true}, {10, false}, {12, true}, {15, false}},
+	{{4, false}, {0, true}, {3, true}, {11, true}, {13, false}},
+	{{1, false}, {4, true}, {5, true}, {12, true}, {14, true}},
+	{{2, false}, {5, true}, {6, true}, {13, false}, {15, true}},
+	{{0, false}, {3, true}, {6, false}, {7, true}, {14, false}},
+	{{8, false}, {1, true}, {4, true}, {7, true}, {15, false}},
+	{{8, false}, {0, true}, {2, true}, {5, true}, {9, false}},
+	{{1, false}, {3, false}, {6, true}, {9, true}, {10, false}},
+	{{2, false}, {4, false}, {7, false}, {10, false}, {11, false}},
+	{{3, false}, {5, true}, {8, false}, {11, true}, {12, true}},
+	{{12, false}, {4, true}, {6, true}, {9, true}, {13, false}},
+}
+
+func bmwCompress(h *[16]uint64, m *[16]uint64) {
+	var q [32]uint64
+	for i := 0; i < 16; i++ {
+		var w uint64
+		for _, e := range bmwW[i] {
+			if e.neg {
+				w -= m[e.idx] ^ h[e.idx]
+			} else {
+				w += m[e.idx] ^ h[e.idx]
+			}
+		}
+		switch i % 5 {
+		case 0:
+			w = bmwS0(w)
+		case 1:
+			w = bmwS1(w)
+		case 2:
+			w = bmwS2(w)
+		case 3:
+			w = bmwS3(w)
+		case 4:
+			w = bmwS4(w)
+		}
+		q[i] = w + h[(i+1)%16]
+	}
+
+	addElement := func(j int) uint64 {
+		a := j - 16
+		rm := func(k int) uint64 { return bits.RotateLeft64(m[k%16], k%16+1) }
+		return (rm(a) + rm(a+3) - rm(a+10) + uint64(j)*0x0555555555555555) ^ h[(a+7)%16]
+	}
+	for j := 16; j < 18; j++ {
+		var s uint64
+		for k := 0; k < 16; k += 4 {
+			s += bmwS1(q[j-16+k]) + bmwS2(q[j-15+k]) + bmwS3(q[j-14+k]) + bmwS0(q[j-13+k])
+		}
+		q[j] = s + addElement(j)
+	}
+	for j := 18; j < 32; j++ {
+		s := q[j-16] + bits.RotateLeft64(q[j-15], 5) +
+			q[j-14] + bits.RotateLeft64(q[j-13], 11) +
+			q[j-12] + bits.RotateLeft64(q[j-11], 27) +
+			q[j-10] + bits.RotateLeft64(q[j-9], 32) +
+			q[j-8] + bits.RotateLeft64(q[j-7], 37) +
+			q[j-6] + bits.RotateLeft64(q[j-5], 43) +
+			q[j-4] + bits.RotateLeft64(q[j-3], 53) +
+			bmwS4(q[j-2]) + bmwS5(q[j-1])
+		q[j] = s + addElement(j)
+	}
+
+	var xl, xh uint64
+	for i := 16; i < 24; i++ {
+		xl ^= q[i]
+	}
+	xh = xl
+	for i := 24; i < 32; i++ {
+		xh ^= q[i]
+	}
+
+	h[0] = (xh<<5 ^ q[16]>>5 ^ m[0]) + (xl ^ q[24] ^ q[0])
+	h[1] = (xh>>7 ^ q[17]<<8 ^ m[1]) + (xl ^ q[25] ^ q[1])
+	h[2] = (xh>>5 ^ q[18]<<5 ^ m[2]) + (xl ^ q[26] ^ q[2])
+	h[3] = (xh>>1 ^ q[19]<<5 ^ m[3]) + (xl ^ q[27] ^ q[3])
+	h[4] = (xh>>3 ^ q[20] ^ m[4]) + (xl ^ q[28] ^ q[4])
+	h[5] = (xh<<6 ^ q[21]>>6 ^ m[5]) + (xl ^ q[29] ^ q[5])
+	h[6] = (xh>>4 ^ q[22]<<6 ^ m[6]) + (xl ^ q[30] ^ q[6])
+	h[7] = (xh>>11 ^ q[23]<<2 ^ m[7]) + (xl ^ q[31] ^ q[7])
+	h[8] = bits.RotateLeft64(h[4], 9) + (xh ^ q[24] ^ m[8]) + (xl<<8 ^ q[23] ^ q[8])
+	h[9] = bits.RotateLeft64(h[5], 10) + (xh ^ q[25] ^ m[9]) + (xl>>6 ^ q[16] ^ q[9])
+	h[10] = bits.RotateLeft64(h[6], 11) + (xh ^ q[26] ^ m[10]) + (xl<<6 ^ q[17] ^ q[10])
+	h[11] = bits.RotateLeft64(h[7], 12) + (xh ^ q[27] ^ m[11]) + (xl<<4 ^ q[18] ^ q[11])
+	h[12] = bits.RotateLeft64(h[0], 13) + (xh ^ q[28] ^ m[12]) + (xl>>3 ^ q[19] ^ q[12])
+	h[13] = bits.RotateLeft64(h[1], 14) + (xh ^ q[29] ^ m[13]) + (xl>>4 ^ q[20] ^ q[13])
+	h[14] = bits.RotateLeft64(h[2], 15) + (xh ^ q[30] ^ m[14]) + (xl>>7 ^ q[21] ^ q[14])
+	h[15] = bits.RotateLeft64(h[3], 16) + (xh ^ q[31] ^ m[15]) + (xl>>2 ^ q[22] ^ q[15])
+}
+
+func bmw512(msg []byte) [64]byte {
+	var h [16]uint64
+	for i := range h {
+		h[i] = 0x8081828384858687 + uint64(i)*0x0808080808080808
+	}
+
+	// Pad with 0x80, then zeros up to the 64-bit little-endian bit length.
+	n := (len(msg) + 1 + 8 + 127) / 128 * 128
+	padded := make([]byte, n)
+	copy(padded, msg)
+	padded[len(msg)] = 0x80
+	binary.LittleEndian.PutUint64(padded[n-8:], uint64(len(msg))*8)
+
+	var m [16]uint64
+	for off := 0; off < n; off += 128 {
+		for i := range m {
+			m[i] = binary.LittleEndian.Uint64(padded[off+i*8:])
+		}
+		bmwCompress(&h, &m)
+	}
+
+	// Final compression keyed by the constant, with the chaining value as
+	// the message.
+	var f [16]uint64
+	for i := range f {
+		f[i] = 0xaaaaaaaaaaaaaaa0 + uint64(i)
+	}
+	bmwCompress(&f, &h)
+
+	var out [64]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], f[i+8])
+	}
+	return out
+}
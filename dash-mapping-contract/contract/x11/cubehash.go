@@ -0,0 +1,75 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// CubeHash16/32-512.
+
+func cubehashRounds(x *[32]uint32, n int) {
+	for ; n > 0; n-- {
+		for i := 0; i < 16; i++ {
+			x[i+16] += x[i]
+			x[i] = bits.RotateLeft32(x[i], 7)
+		}
+		for i := 0; i < 8; i++ {
+			x[i], x[i+8] = x[i+8], x[i]
+		}
+		for i := 0; i < 16; i++ {
+			x[i] ^= x[i+16]
+		}
+		for i := 16; i < 32; i++ {
+			if i&2 == 0 {
+				x[i], x[i^2] = x[i^2], x[i]
+			}
+		}
+		for i := 0; i < 16; i++ {
+			x[i+16] += x[i]
+			x[i] = bits.RotateLeft32(x[i], 11)
+		}
+		for i := 0; i < 16; i++ {
+			if i&4 == 0 {
+				x[i], x[i^4] = x[i^4], x[i]
+			}
+		}
+		for i := 0; i < 16; i++ {
+			x[i] ^= x[i+16]
+		}
+		for i := 16; i < 32; i++ {
+			if i&1 == 0 {
+				x[i], x[i^1] = x[i^1], x[i]
+			}
+		}
+	}
+}
+
+var cubehashIV = func() (x [32]uint32) {
+	x[0], x[1], x[2] = 64, 32, 16
+	cubehashRounds(&x, 160)
+	return x
+}()
+
+func cubehash512(msg []byte) [64]byte {
+	x := cubehashIV
+
+	n := (len(msg) + 32) / 32 * 32
+	padded := make([]byte, n)
+	copy(padded, msg)
+	padded[len(msg)] = 0x80
+
+	for off := 0; off < n; off += 32 {
+		for i := 0; i < 8; i++ {
+			x[i] ^= binary.LittleEndian.Uint32(padded[off+i*4:])
+		}
+		cubehashRounds(&x, 16)
+	}
+	x[31] ^= 1
+	cubehashRounds(&x, 160)
+
+	var out [64]byte
+	for i := 0; i < 16; i++ {
+		binary.LittleEndian.PutUint32(out[i*4:], x[i])
+	}
+	return out
+}
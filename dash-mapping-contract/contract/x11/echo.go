@@ -0,0 +1,86 @@
+package x11
+
+import "encoding/binary"
+
+// ECHO-512. The state is sixteen 128-bit words, each an AES state.
+
+func echoCompress(v *[8][16]byte, block []byte, counter uint64) {
+	var w [16][16]byte
+	for i := 0; i < 8; i++ {
+		w[i] = v[i]
+		copy(w[8+i][:], block[i*16:])
+	}
+
+	var zero, key [16]byte
+	k := counter
+	for r := 0; r < 10; r++ {
+		// BigSubWords: two AES rounds per word, the first keyed by the
+		// 128-bit little-endian counter.
+		for i := range w {
+			binary.LittleEndian.PutUint64(key[:], k)
+			aesRound(&w[i], &key)
+			aesRound(&w[i], &zero)
+			k++
+		}
+		// BigShiftRows: word i sits at row i%4, column i/4.
+		t := w
+		for c := 0; c < 4; c++ {
+			for row := 0; row < 4; row++ {
+				w[row+4*c] = t[row+4*((c+row)%4)]
+			}
+		}
+		// BigMixColumns: MixColumns across the four words of each column,
+		// byte by byte.
+		for c := 0; c < 4; c++ {
+			a, b, cc, d := &w[4*c], &w[4*c+1], &w[4*c+2], &w[4*c+3]
+			for n := 0; n < 16; n++ {
+				a[n], b[n], cc[n], d[n] = mixColumn(a[n], b[n], cc[n], d[n])
+			}
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		for n := 0; n < 16; n++ {
+			v[i][n] ^= block[i*16+n] ^ w[i][n] ^ w[i+8][n]
+		}
+	}
+}
+
+func echo512(msg []byte) [64]byte {
+	var v [8][16]byte
+	for i := range v {
+		binary.LittleEndian.PutUint16(v[i][:], 512)
+	}
+	bitLen := uint64(len(msg)) * 8
+
+	pos := 0
+	for ; len(msg)-pos >= 128; pos += 128 {
+		echoCompress(&v, msg[pos:pos+128], uint64(pos+128)*8)
+	}
+
+	// Pad with 0x80, then the 16-bit digest size and the 128-bit
+	// little-endian bit length. The counter of a block holding no message
+	// bits is zero.
+	rem := msg[pos:]
+	counter := bitLen
+	if len(rem) == 0 {
+		counter = 0
+	}
+	var buf [128]byte
+	copy(buf[:], rem)
+	buf[len(rem)] = 0x80
+	if len(rem)+1 > 110 {
+		echoCompress(&v, buf[:], counter)
+		buf = [128]byte{}
+		counter = 0
+	}
+	binary.LittleEndian.PutUint16(buf[110:], 512)
+	binary.LittleEndian.PutUint64(buf[112:], bitLen)
+	echoCompress(&v, buf[:], counter)
+
+	var out [64]byte
+	for i := 0; i < 4; i++ {
+		copy(out[i*16:], v[i][:])
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package x11
+
+import "encoding/binary"
+
+// Groestl-512.
+
+var (
+	groestlShiftP = [8]int{0, 1, 2, 3, 4, 5, 6, 11}
+	groestlShiftQ = [8]int{1, 3, 5, 11, 0, 2, 4, 6}
+	groestlMix    = [8]byte{2, 2, 3, 4, 5, 3, 5, 7}
+)
+
+// groestlMul[k][x] is groestlMix[k]*x in GF(2^8).
+var groestlMul = func() (t [8][256]byte) {
+	for k := range t {
+		for x := range t[k] {
+			t[k][x] = gmul(groestlMix[k], byte(x))
+		}
+	}
+	return t
+}()
+
+// groestlPerm applies P (or Q) to a 1024-bit state laid out column-major:
+// byte i is at row i%8, column i/8.
+func groestlPerm(state *[128]byte, q bool) {
+	var a, t [8][16]byte
+	for j := 0; j < 16; j++ {
+		for i := 0; i < 8; i++ {
+			a[i][j] = state[j*8+i]
+		}
+	}
+	shift := &groestlShiftP
+	if q {
+		shift = &groestlShiftQ
+	}
+	for r := 0; r < 14; r++ {
+		// AddRoundConstant
+		if q {
+			for i := 0; i < 8; i++ {
+				for j := 0; j < 16; j++ {
+					a[i][j] ^= 0xff
+				}
+			}
+			for j := 0; j < 16; j++ {
+				a[7][j] ^= byte(j<<4) ^ byte(r)
+			}
+		} else {
+			for j := 0; j < 16; j++ {
+				a[0][j] ^= byte(j<<4) ^ byte(r)
+			}
+		}
+		// SubBytes and ShiftBytes
+		for i := 0; i < 8; i++ {
+			for j := 0; j < 16; j++ {
+				t[i][j] = aesSbox[a[i][(j+shift[i])%16]]
+			}
+		}
+		// MixBytes
+		for j := 0; j < 16; j++ {
+			for i := 0; i < 8; i++ {
+				var v byte
+				for k := 0; k < 8; k++ {
+					v ^= groestlMul[(k-i+8)%8][t[k][j]]
+				}
+				a[i][j] = v
+			}
+		}
+	}
+	for j := 0; j < 16; j++ {
+		for i := 0; i < 8; i++ {
+			state[j*8+i] = a[i][j]
+		}
+	}
+}
+
+func groestl512(msg []byte) [64]byte {
+	var h [128]byte
+	h[126] = 2
+
+	// Pad with 0x80, then zeros up to the 64-bit big-endian block count.
+	n := (len(msg) + 1 + 8 + 127) / 128 * 128
+	padded := make([]byte, n)
+	copy(padded, msg)
+	padded[len(msg)] = 0x80
+	binary.BigEndian.PutUint64(padded[n-8:], uint64(n/128))
+
+	var p, q [128]byte
+	for off := 0; off < n; off += 128 {
+		for i := range p {
+			p[i] = h[i] ^ padded[off+i]
+		}
+		copy(q[:], padded[off:off+128])
+		groestlPerm(&p, false)
+		groestlPerm(&q, true)
+		for i := range h {
+			h[i] ^= p[i] ^ q[i]
+		}
+	}
+
+	p = h
+	groestlPerm(&p, false)
+	var out [64]byte
+	for i := range out {
+		out[i] = p[64+i] ^ h[64+i]
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package x11
+
+import "encoding/binary"
+
+// JH-512, following the nibble-oriented description of the specification
+// rather than the bitsliced reference code. It is slower, but X11 only
+// hashes a couple of blocks per header.
+
+var (
+	jhS0 = [16]byte{9, 0, 4, 11, 13, 12, 3, 15, 1, 10, 2, 6, 7, 5, 8, 14}
+	jhS1 = [16]byte{3, 12, 6, 13, 5, 7, 1, 9, 15, 2, 0, 4, 11, 10, 14, 8}
+)
+
+// jhRoundConstants holds the 42 round constants of E8, 256 bits each, most
+// significant bit first. C_0 is the fractional part of sqrt(2) and each
+// following constant is R6 of the previous one with all-zero constants.
+var jhRoundConstants = func() (c [42][32]byte) {
+	el := [64]byte{
+		0x6, 0xa, 0x0, 0x9, 0xe, 0x6, 0x6, 0x7, 0xf, 0x3, 0xb, 0xc, 0xc, 0x9, 0x0, 0x8,
+		0xb, 0x2, 0xf, 0xb, 0x1, 0x3, 0x6, 0x6, 0xe, 0xa, 0x9, 0x5, 0x7, 0xd, 0x3, 0xe,
+		0x3, 0xa, 0xd, 0xe, 0xc, 0x1, 0x7, 0x5, 0x1, 0x2, 0x7, 0x7, 0x5, 0x0, 0x9, 0x9,
+		0xd, 0xa, 0x2, 0xf, 0x5, 0x9, 0x0, 0xb, 0x0, 0x6, 0x6, 0x7, 0x3, 0x2, 0x2, 0xa,
+	}
+	for r := range c {
+		for i := range c[r] {
+			c[r][i] = el[2*i]<<4 | el[2*i+1]
+		}
+		jhRound(el[:], nil)
+	}
+	return c
+}()
+
+// jhMul2 multiplies by x in GF(2^4) modulo x^4 + x + 1.
+func jhMul2(a byte) byte {
+	a <<= 1
+	if a&0x10 != 0 {
+		a ^= 0x13
+	}
+	return a
+}
+
+// jhSL[sel][a<<4|b] is the S-box layer and linear transform L applied to
+// the nibble pair (a, b), with the S-boxes chosen by the two bits of sel.
+var jhSL = func() (t [4][256]byte) {
+	for sel := range t {
+		sa, sb := &jhS0, &jhS0
+		if sel&2 != 0 {
+			sa = &jhS1
+		}
+		if sel&1 != 0 {
+			sb = &jhS1
+		}
+		for x := range t[sel] {
+			a, b := sa[x>>4], sb[x&15]
+			d := b ^ jhMul2(a)
+			t[sel][x] = (a^jhMul2(d))<<4 | d
+		}
+	}
+	return t
+}()
+
+// jhRound is the round function R_d on 2^d nibbles: S-boxes selected by the
+// round constant bits (S0 throughout if c is nil), the linear transform L on
+// adjacent pairs, then the permutation P_d = phi . P' . pi.
+func jhRound(x []byte, c []byte) {
+	n := len(x)
+	for i := 0; i < n; i += 2 {
+		var sel byte
+		if c != nil {
+			sel = c[i/8] >> (6 - i%8) & 3
+		}
+		y := jhSL[sel][x[i]<<4|x[i+1]]
+		x[i], x[i+1] = y>>4, y&15
+	}
+	for i := 0; i < n; i += 4 {
+		x[i+2], x[i+3] = x[i+3], x[i+2]
+	}
+	var t [256]byte
+	copy(t[:n], x)
+	for i := 0; i < n/2; i++ {
+		x[i] = t[2*i]
+		x[i+n/2] = t[2*i+1]
+	}
+	for i := n / 2; i < n; i += 2 {
+		x[i], x[i+1] = x[i+1], x[i]
+	}
+}
+
+func jhBit(h *[128]byte, i int) byte { return h[i/8] >> (7 - i%8) & 1 }
+
+// jhE8 applies the bijective function E8 to the 1024-bit state.
+func jhE8(h *[128]byte) {
+	// Grouping: nibble i takes bits i, i+256, i+512 and i+768, and the two
+	// halves are interleaved.
+	var q [256]byte
+	for i := 0; i < 128; i++ {
+		q[2*i] = jhBit(h, i)<<3 | jhBit(h, i+256)<<2 | jhBit(h, i+512)<<1 | jhBit(h, i+768)
+		j := i + 128
+		q[2*i+1] = jhBit(h, j)<<3 | jhBit(h, j+256)<<2 | jhBit(h, j+512)<<1 | jhBit(h, j+768)
+	}
+	for r := range jhRoundConstants {
+		jhRound(q[:], jhRoundConstants[r][:])
+	}
+
+	*h = [128]byte{}
+	for i := 0; i < 256; i++ {
+		var t byte
+		if i < 128 {
+			t = q[2*i]
+		} else {
+			t = q[2*(i-128)+1]
+		}
+		for k := 0; k < 4; k++ {
+			bit := i + 256*k
+			h[bit/8] |= (t >> (3 - k) & 1) << (7 - bit%8)
+		}
+	}
+}
+
+// jhF8 is the compression function: the block is XORed into the first half
+// of the state before E8 and into the second half after it.
+func jhF8(h *[128]byte, block []byte) {
+	for i := 0; i < 64; i++ {
+		h[i] ^= block[i]
+	}
+	jhE8(h)
+	for i := 0; i < 64; i++ {
+		h[64+i] ^= block[i]
+	}
+}
+
+var jhIV = func() (h [128]byte) {
+	h[0], h[1] = 0x02, 0x00 // output size 512, big-endian
+	jhF8(&h, make([]byte, 64))
+	return h
+}()
+
+func jh512(msg []byte) [64]byte {
+	h := jhIV
+
+	// Pad with 0x80 and at least 383 zero bits, then the 128-bit big-endian
+	// bit length. A block-aligned message gets exactly one padding block.
+	n := len(msg)/64*64 + 128
+	if len(msg)%64 == 0 {
+		n -= 64
+	}
+	padded := make([]byte, n)
+	copy(padded, msg)
+	padded[len(msg)] = 0x80
+	binary.BigEndian.PutUint64(padded[n-8:], uint64(len(msg))*8)
+
+	for off := 0; off < n; off += 64 {
+		jhF8(&h, padded[off:off+64])
+	}
+
+	var out [64]byte
+	copy(out[:], h[64:])
+	return out
+}
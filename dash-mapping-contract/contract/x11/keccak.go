@@ -0,0 +1,76 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Keccak-512 as submitted to the SHA-3 competition (0x01 padding, not the
+// FIPS 202 0x06 domain separator).
+
+const keccakRate = 72
+
+var keccakRC = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRot is indexed [x][y].
+var keccakRot = [5][5]int{
+	{0, 36, 3, 41, 18},
+	{1, 44, 10, 45, 2},
+	{62, 6, 43, 15, 61},
+	{28, 55, 25, 21, 56},
+	{27, 20, 39, 8, 14},
+}
+
+// keccakF1600 permutes the state a, indexed a[x+5*y].
+func keccakF1600(a *[25]uint64) {
+	var c, d [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d[x] = c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+		}
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y]^d[x], keccakRot[x][y])
+			}
+		}
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				a[x+5*y] = b[x+5*y] ^ (^b[(x+1)%5+5*y] & b[(x+2)%5+5*y])
+			}
+		}
+		a[0] ^= keccakRC[round]
+	}
+}
+
+func keccak512(msg []byte) [64]byte {
+	n := (len(msg) + keccakRate) / keccakRate * keccakRate
+	padded := make([]byte, n)
+	copy(padded, msg)
+	padded[len(msg)] = 0x01
+	padded[n-1] |= 0x80
+
+	var a [25]uint64
+	for off := 0; off < n; off += keccakRate {
+		for i := 0; i < keccakRate/8; i++ {
+			a[i] ^= binary.LittleEndian.Uint64(padded[off+i*8:])
+		}
+		keccakF1600(&a)
+	}
+
+	var out [64]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], a[i])
+	}
+	return out
+}
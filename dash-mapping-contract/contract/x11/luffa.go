@@ -0,0 +1,155 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Luffa-512: five 256-bit lanes, each permuted by eight steps of Q_j.
+
+var luffaIV = [5][8]uint32{
+	{0x6d251e69, 0x44b051e0, 0x4eaa6fb4, 0xdbf78465, 0x6e292011, 0x90152df4, 0xee058139, 0xdef610bb},
+	{0xc3b44b95, 0xd9d2f256, 0x70eee9a0, 0xde099fa3, 0x5d9b0557, 0x8fc944b3, 0xcf1ccf0e, 0x746cd581},
+	{0xf7efc89d, 0x5dba5781, 0x04016ce5, 0xad659c05, 0x0306194f, 0x666d1836, 0x24aa230a, 0x8b264ae7},
+	{0x858075d5, 0x36d79cce, 0xe571f7d7, 0x204b1f67, 0x35870c6a, 0x57e9e923, 0x14bcb808, 0x7cde72ce},
+	{0x6c68e9be, 0x5ec41e22, 0xc825b7c7, 0xaffb4363, 0xf5df3999, 0x0fc688f1, 0xb07224cc, 0x03e86cea},
+}
+
+// luffaRC holds the step constants of each lane, for words 0 and 4.
+var luffaRC = [5][2][8]uint32{
+	{
+		{0x303994a6, 0xc0e65299, 0x6cc33a12, 0xdc56983e, 0x1e00108f, 0x7800423d, 0x8f5b7882, 0x96e1db12},
+		{0xe0337818, 0x441ba90d, 0x7f34d442, 0x9389217f, 0xe5a8bce6, 0x5274baf4, 0x26889ba7, 0x9a226e9d},
+	},
+	{
+		{0xb6de10ed, 0x70f47aae, 0x0707a3d4, 0x1c1e8f51, 0x707a3d45, 0xaeb28562, 0xbaca1589, 0x40a46f3e},
+		{0x01685f3d, 0x05a17cf4, 0xbd09caca, 0xf4272b28, 0x144ae5cc, 0xfaa7ae2b, 0x2e48f1c1, 0xb923c704},
+	},
+	{
+		{0xfc20d9d2, 0x34552e25, 0x7ad8818f, 0x8438764a, 0xbb6de032, 0xedb780c8, 0xd9847356, 0xa2c78434},
+		{0xe25e72c1, 0xe623bb72, 0x5c58a4a4, 0x1e38e2e7, 0x78e38b9d, 0x27586719, 0x36eda57f, 0x703aace7},
+	},
+	{
+		{0xb213afa5, 0xc84ebe95, 0x4e608a22, 0x56d858fe, 0x343b138f, 0xd0ec4e3d, 0x2ceb4882, 0xb3ad2208},
+		{0xe028c9bf, 0x44756f91, 0x7e8fce32, 0x956548be, 0xfe191be2, 0x3cb226e5, 0x5944a28e, 0xa1c4c355},
+	},
+	{
+		{0xf0d2e9e3, 0xac11d7fa, 0x1bcb66f2, 0x6f2d9bc9, 0x78602649, 0x8edae952, 0x3b6ba548, 0xedae9520},
+		{0x5090d577, 0x2d1925ab, 0xb46496ac, 0xd1925ab0, 0x29131ab6, 0x0fc053c3, 0x3f014f0c, 0xfc053c31},
+	},
+}
+
+func luffaSubCrumb(a0, a1, a2, a3 uint32) (uint32, uint32, uint32, uint32) {
+	tmp := a0
+	a0 |= a1
+	a2 ^= a3
+	a1 = ^a1
+	a0 ^= a3
+	a3 &= tmp
+	a1 ^= a3
+	a3 ^= a2
+	a2 &= a0
+	a0 = ^a0
+	a2 ^= a1
+	a1 |= a3
+	tmp ^= a1
+	a3 ^= a2
+	a2 &= a1
+	a1 ^= a0
+	return tmp, a1, a2, a3
+}
+
+func luffaMixWord(u, v uint32) (uint32, uint32) {
+	v ^= u
+	u = bits.RotateLeft32(u, 2) ^ v
+	v = bits.RotateLeft32(v, 14) ^ u
+	u = bits.RotateLeft32(u, 10) ^ v
+	v = bits.RotateLeft32(v, 1)
+	return u, v
+}
+
+// luffaM2 multiplies a 256-bit word by 2 in Luffa's GF(2^8)^32 ring.
+func luffaM2(s [8]uint32) [8]uint32 {
+	t := s[7]
+	return [8]uint32{t, s[0] ^ t, s[1], s[2] ^ t, s[3] ^ t, s[4], s[5], s[6]}
+}
+
+func luffaXor(a, b [8]uint32) [8]uint32 {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
+
+// luffaMI5 is the message injection for five lanes.
+func luffaMI5(v *[5][8]uint32, m [8]uint32) {
+	a := luffaM2(luffaXor(luffaXor(luffaXor(v[0], v[1]), luffaXor(v[2], v[3])), v[4]))
+	for j := range v {
+		v[j] = luffaXor(a, v[j])
+	}
+
+	b := luffaXor(luffaM2(v[0]), v[1])
+	v[1] = luffaXor(luffaM2(v[1]), v[2])
+	v[2] = luffaXor(luffaM2(v[2]), v[3])
+	v[3] = luffaXor(luffaM2(v[3]), v[4])
+	v[4] = luffaXor(luffaM2(v[4]), v[0])
+
+	v[0] = luffaXor(luffaM2(b), v[4])
+	v[4] = luffaXor(luffaM2(v[4]), v[3])
+	v[3] = luffaXor(luffaM2(v[3]), v[2])
+	v[2] = luffaXor(luffaM2(v[2]), v[1])
+	v[1] = luffaXor(luffaM2(v[1]), b)
+
+	for j := range v {
+		v[j] = luffaXor(v[j], m)
+		m = luffaM2(m)
+	}
+}
+
+// luffaP5 tweaks and permutes each lane.
+func luffaP5(v *[5][8]uint32) {
+	for j := range v {
+		x := &v[j]
+		for i := 4; i < 8; i++ {
+			x[i] = bits.RotateLeft32(x[i], j)
+		}
+		for r := 0; r < 8; r++ {
+			x[0], x[1], x[2], x[3] = luffaSubCrumb(x[0], x[1], x[2], x[3])
+			x[5], x[6], x[7], x[4] = luffaSubCrumb(x[5], x[6], x[7], x[4])
+			for k := 0; k < 4; k++ {
+				x[k], x[k+4] = luffaMixWord(x[k], x[k+4])
+			}
+			x[0] ^= luffaRC[j][0][r]
+			x[4] ^= luffaRC[j][1][r]
+		}
+	}
+}
+
+func luffa512(msg []byte) [64]byte {
+	v := luffaIV
+
+	n := (len(msg) + 32) / 32 * 32
+	padded := make([]byte, n)
+	copy(padded, msg)
+	padded[len(msg)] = 0x80
+
+	var m [8]uint32
+	for off := 0; off < n; off += 32 {
+		for i := range m {
+			m[i] = binary.BigEndian.Uint32(padded[off+i*4:])
+		}
+		luffaMI5(&v, m)
+		luffaP5(&v)
+	}
+
+	// Two blank rounds, each outputting 256 bits.
+	var out [64]byte
+	for k := 0; k < 2; k++ {
+		luffaMI5(&v, [8]uint32{})
+		luffaP5(&v)
+		for i := 0; i < 8; i++ {
+			binary.BigEndian.PutUint32(out[k*32+i*4:], v[0][i]^v[1][i]^v[2][i]^v[3][i]^v[4][i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package x11
+
+import "encoding/binary"
+
+// SHAvite-3-512.
+
+var shaviteIV = [16]uint32{
+	0x72FCCDD8, 0x79CA4727, 0x128A077B, 0x40D55AEC, 0xD1901A06, 0x430AE307, 0xB29F5CD1, 0xDF07FBFC,
+	0x8E45D73D, 0x681AB538, 0xBDE86578, 0xDD577E47, 0xE275EADE, 0x502D9FCD, 0xB9357178, 0x022A4B9A,
+}
+
+// shaviteAES runs one keyless AES round on four little-endian words.
+func shaviteAES(w [4]uint32) [4]uint32 {
+	var s [16]byte
+	for i, x := range w {
+		binary.LittleEndian.PutUint32(s[i*4:], x)
+	}
+	aesRound(&s, nil)
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint32(s[i*4:])
+	}
+	return w
+}
+
+// shaviteF is the four-round AES-based Feistel function keyed by k.
+func shaviteF(x [4]uint32, k []uint32) [4]uint32 {
+	for r := 0; r < 4; r++ {
+		for i := range x {
+			x[i] ^= k[r*4+i]
+		}
+		x = shaviteAES(x)
+	}
+	return x
+}
+
+func shaviteCompress(h *[16]uint32, block []byte, counter uint64) {
+	c := [4]uint32{uint32(counter), uint32(counter >> 32), 0, 0}
+
+	var p [4][4]uint32
+	for i := range p {
+		copy(p[i][:], h[i*4:i*4+4])
+	}
+	var rk [32]uint32
+	for i := range rk {
+		rk[i] = binary.LittleEndian.Uint32(block[i*4:])
+	}
+
+	// The counter is injected into one key block in rounds 1, 5, 9 and 13.
+	inject := func(round int) (int, [4]uint32) {
+		switch round {
+		case 1:
+			return 0, [4]uint32{c[0], c[1], c[2], ^c[3]}
+		case 5:
+			return 1, [4]uint32{c[3], c[2], c[1], ^c[0]}
+		case 9:
+			return 7, [4]uint32{c[2], c[3], c[0], ^c[1]}
+		case 13:
+			return 6, [4]uint32{c[1], c[0], c[3], ^c[2]}
+		}
+		return -1, [4]uint32{}
+	}
+
+	// Round i updates logical blocks A and C, which rotate through the four
+	// physical blocks.
+	round := func(i int) {
+		a, b, cc, d := (4-i%4)%4, (5-i%4)%4, (6-i%4)%4, (7-i%4)%4
+		fb := shaviteF(p[b], rk[0:16])
+		fd := shaviteF(p[d], rk[16:32])
+		for k := 0; k < 4; k++ {
+			p[a][k] ^= fb[k]
+			p[cc][k] ^= fd[k]
+		}
+	}
+
+	round(0)
+	for i := 1; i < 14; i++ {
+		if i%2 == 1 {
+			// Nonlinear expansion: each block is an AES round of itself
+			// rotated by one word, chained with the previous new block.
+			injectAt, injectWords := inject(i)
+			var next [32]uint32
+			prev := [4]uint32{rk[28], rk[29], rk[30], rk[31]}
+			for t := 0; t < 8; t++ {
+				nb := shaviteAES([4]uint32{rk[4*t+1], rk[4*t+2], rk[4*t+3], rk[4*t]})
+				for k := range nb {
+					nb[k] ^= prev[k]
+					if t == injectAt {
+						nb[k] ^= injectWords[k]
+					}
+				}
+				copy(next[4*t:], nb[:])
+				prev = nb
+			}
+			rk = next
+		} else {
+			// Linear expansion: rk[i] = rk[i-32] ^ rk[i-7].
+			for k := 0; k < 32; k++ {
+				if k >= 7 {
+					rk[k] ^= rk[k-7]
+				} else {
+					rk[k] ^= rk[k+25]
+				}
+			}
+		}
+		round(i)
+	}
+
+	// After 14 rounds the logical order is back to physical 2, 3, 0, 1.
+	for i := 0; i < 4; i++ {
+		for k := 0; k < 4; k++ {
+			h[i*4+k] ^= p[(i+2)%4][k]
+		}
+	}
+}
+
+func shavite512(msg []byte) [64]byte {
+	h := shaviteIV
+	bitLen := uint64(len(msg)) * 8
+
+	pos := 0
+	for ; len(msg)-pos >= 128; pos += 128 {
+		shaviteCompress(&h, msg[pos:pos+128], uint64(pos+128)*8)
+	}
+
+	// Pad with 0x80, then the 128-bit little-endian bit length and the
+	// 16-bit digest size. The counter of a block holding no message bits is
+	// zero.
+	rem := msg[pos:]
+	counter := bitLen
+	if len(rem) == 0 {
+		counter = 0
+	}
+	var buf [128]byte
+	copy(buf[:], rem)
+	buf[len(rem)] = 0x80
+	if len(rem)+1 > 110 {
+		shaviteCompress(&h, buf[:], counter)
+		buf = [128]byte{}
+		counter = 0
+	}
+	binary.LittleEndian.PutUint64(buf[110:], bitLen)
+	binary.LittleEndian.PutUint16(buf[126:], 512)
+	shaviteCompress(&h, buf[:], counter)
+
+	var out [64]byte
+	for i, x := range h {
+		binary.LittleEndian.PutUint32(out[i*4:], x)
+	}
+	return out
+}
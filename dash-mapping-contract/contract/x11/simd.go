@@ -0,0 +1,153 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SIMD-512. The message expansion's number-theoretic transform is computed
+// directly rather than with an FFT.
+
+var simdIV = [32]uint32{
+	0x0BA16B95, 0x72F999AD, 0x9FECC2AE, 0xBA3264FC, 0x5E894929, 0x8E9F30E5, 0x2F1DAA37, 0xF0F2C558,
+	0xAC506643, 0xA90635A5, 0xE25B878B, 0xAAB7878F, 0x88817F7A, 0x0A02892B, 0x559A7550, 0x598F657E,
+	0x7EEF60A1, 0x6B70E3E8, 0x9C1714D1, 0xB958E2A8, 0xAB02675E, 0xED1C014F, 0xCD8D65BB, 0xFDB7A257,
+	0x09254899, 0xD699C7BC, 0x9019B6DC, 0x2B9022E4, 0x8FA14956, 0x21BF9BD3, 0xB94D0943, 0x6FFDDC22,
+}
+
+var (
+	simdPerm = [7]int{1, 6, 2, 3, 5, 7, 4}
+	simdRot  = [4][4]int{{3, 23, 17, 27}, {28, 19, 22, 7}, {29, 9, 15, 5}, {4, 13, 10, 25}}
+	// simdSB selects which 16 transformed values feed each step's message
+	// words.
+	simdSB = [4][8]int{
+		{4, 6, 0, 2, 7, 5, 3, 1},
+		{15, 11, 12, 8, 9, 13, 10, 14},
+		{17, 18, 23, 20, 22, 21, 16, 19},
+		{30, 24, 25, 31, 27, 29, 28, 26},
+	}
+	// simdOff holds, per round, the offsets of the two paired values and
+	// the multiplier (185 or 233) applied to them.
+	simdOff = [4][3]int32{{0, 1, 185}, {0, 1, 185}, {-256, -128, 233}, {-383, -255, 233}}
+)
+
+// simdPow returns base^i mod 257 for i in [0, 256).
+func simdPow(base int32) (p [256]int32) {
+	p[0] = 1
+	for i := 1; i < 256; i++ {
+		p[i] = p[i-1] * base % 257
+	}
+	return p
+}
+
+var (
+	simdPow41  = simdPow(41)
+	simdPow163 = simdPow(163)
+	simdPow40  = simdPow(40)
+)
+
+func simdIf(x, y, z uint32) uint32  { return (y^z)&x ^ z }
+func simdMaj(x, y, z uint32) uint32 { return x&y | (x|y)&z }
+
+// simdExpand derives the 32 steps' message words from a block.
+func simdExpand(block []byte, final bool) (w [32][8]uint32) {
+	var q [256]int32
+	for i := range q {
+		// At most 128 * 255 * 256, so reducing once at the end cannot
+		// overflow.
+		var v int32
+		for j := 0; j < 128; j++ {
+			v += int32(block[j]) * simdPow41[i*j&255]
+		}
+		v += simdPow163[i]
+		if final {
+			v += simdPow40[i]
+		}
+		v %= 257
+		if v > 128 {
+			v -= 257
+		}
+		q[i] = v
+	}
+	for r := 0; r < 4; r++ {
+		o1, o2, mm := simdOff[r][0], simdOff[r][1], simdOff[r][2]
+		for k := 0; k < 8; k++ {
+			sb := int32(simdSB[r][k])
+			for t := int32(0); t < 8; t++ {
+				l := q[16*sb+2*t+o1]
+				h := q[16*sb+2*t+o2]
+				w[8*r+k][t] = uint32(l*mm)&0xffff + uint32(h*mm)<<16
+			}
+		}
+	}
+	return w
+}
+
+func simdStep(s *[4][8]uint32, w *[8]uint32, maj bool, r, sh, pp int) {
+	var ta, na [8]uint32
+	for j := range ta {
+		ta[j] = bits.RotateLeft32(s[0][j], r)
+	}
+	for j := range na {
+		var f uint32
+		if maj {
+			f = simdMaj(s[0][j], s[1][j], s[2][j])
+		} else {
+			f = simdIf(s[0][j], s[1][j], s[2][j])
+		}
+		na[j] = bits.RotateLeft32(s[3][j]+w[j]+f, sh) + ta[j^pp]
+	}
+	s[3], s[2], s[1], s[0] = s[2], s[1], ta, na
+}
+
+func simdCompress(h *[32]uint32, block []byte, final bool) {
+	w := simdExpand(block, final)
+
+	var s [4][8]uint32
+	for i := 0; i < 32; i++ {
+		s[i/8][i%8] = h[i] ^ binary.LittleEndian.Uint32(block[i*4:])
+	}
+	n := 0
+	for r := 0; r < 4; r++ {
+		p := &simdRot[r]
+		for k := 0; k < 8; k++ {
+			simdStep(&s, &w[8*r+k], k >= 4, p[k%4], p[(k+1)%4], simdPerm[n%7])
+			n++
+		}
+	}
+	// Feed-forward: four more steps with the old chaining value as message.
+	p := &simdRot[3]
+	for k := 0; k < 4; k++ {
+		var fw [8]uint32
+		copy(fw[:], h[8*k:8*k+8])
+		simdStep(&s, &fw, false, p[k%4], p[(k+1)%4], simdPerm[n%7])
+		n++
+	}
+	for i := 0; i < 32; i++ {
+		h[i] = s[i/8][i%8]
+	}
+}
+
+func simd512(msg []byte) [64]byte {
+	h := simdIV
+
+	pos := 0
+	for ; len(msg)-pos >= 128; pos += 128 {
+		simdCompress(&h, msg[pos:pos+128], false)
+	}
+	if pos < len(msg) {
+		var buf [128]byte
+		copy(buf[:], msg[pos:])
+		simdCompress(&h, buf[:], false)
+	}
+	// The final block holds only the 64-bit little-endian bit length.
+	var buf [128]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(msg))*8)
+	simdCompress(&h, buf[:], true)
+
+	var out [64]byte
+	for i := 0; i < 16; i++ {
+		binary.LittleEndian.PutUint32(out[i*4:], h[i])
+	}
+	return out
+}
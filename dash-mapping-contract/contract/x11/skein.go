@@ -0,0 +1,108 @@
+package x11
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Skein-512-512 (version 1.3).
+
+var threefishRot = [8][4]int{
+	{46, 36, 19, 37}, {33, 27, 14, 42}, {17, 49, 36, 39}, {44, 9, 54, 56},
+	{39, 30, 34, 24}, {13, 50, 10, 17}, {25, 29, 39, 43}, {8, 35, 56, 22},
+}
+
+var threefishPerm = [8]int{2, 1, 4, 7, 6, 5, 0, 3}
+
+const (
+	skeinTypeMsg = 48
+	skeinTypeOut = 63
+)
+
+// skeinIV is the chaining value after the configuration UBI for a 512-bit
+// output.
+var skeinIV = [8]uint64{
+	0x4903ADFF749C51CE, 0x0D95DE399746DF03, 0x8FD1934127C79BCE, 0x9A255629FF352CB1,
+	0x5DB62599DF6CA7B0, 0xEABE394CA9D5C3F4, 0x991112C71A75B523, 0xAE18A40B660FCC33,
+}
+
+func threefish512(key *[8]uint64, t0, t1 uint64, block *[8]uint64) [8]uint64 {
+	var k [9]uint64
+	copy(k[:8], key[:])
+	k[8] = 0x1BD11BDAA9FC1A22
+	for i := 0; i < 8; i++ {
+		k[8] ^= key[i]
+	}
+	t := [3]uint64{t0, t1, t0 ^ t1}
+
+	v := *block
+	inject := func(s int) {
+		for i := 0; i < 8; i++ {
+			v[i] += k[(s+i)%9]
+		}
+		v[5] += t[s%3]
+		v[6] += t[(s+1)%3]
+		v[7] += uint64(s)
+	}
+	for d := 0; d < 72; d++ {
+		if d%4 == 0 {
+			inject(d / 4)
+		}
+		for j := 0; j < 4; j++ {
+			v[2*j] += v[2*j+1]
+			v[2*j+1] = bits.RotateLeft64(v[2*j+1], threefishRot[d%8][j]) ^ v[2*j]
+		}
+		w := v
+		for i := range v {
+			v[i] = w[threefishPerm[i]]
+		}
+	}
+	inject(18)
+	return v
+}
+
+// skeinUBI chains msg through Threefish with tweaks of the given type.
+func skeinUBI(g *[8]uint64, msg []byte, typ uint64) {
+	blocks := (len(msg) + 63) / 64
+	if blocks == 0 {
+		blocks = 1
+	}
+	var pos uint64
+	for b := 0; b < blocks; b++ {
+		var buf [64]byte
+		chunk := msg[64*b:]
+		if len(chunk) > 64 {
+			chunk = chunk[:64]
+		}
+		copy(buf[:], chunk)
+		pos += uint64(len(chunk))
+
+		t1 := typ << 56
+		if b == 0 {
+			t1 |= 1 << 62
+		}
+		if b == blocks-1 {
+			t1 |= 1 << 63
+		}
+		var m [8]uint64
+		for i := range m {
+			m[i] = binary.LittleEndian.Uint64(buf[i*8:])
+		}
+		e := threefish512(g, pos, t1, &m)
+		for i := range g {
+			g[i] = e[i] ^ m[i]
+		}
+	}
+}
+
+func skein512(msg []byte) [64]byte {
+	g := skeinIV
+	skeinUBI(&g, msg, skeinTypeMsg)
+	skeinUBI(&g, make([]byte, 8), skeinTypeOut)
+
+	var out [64]byte
+	for i, x := range g {
+		binary.LittleEndian.PutUint64(out[i*8:], x)
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+// Package x11 implements Dash's X11 proof-of-work hash: eleven SHA-3
+// candidates chained together, each hashing the previous 512-bit digest.
+// It is pure Go without assembly or unsafe so that it builds under TinyGo.
+package x11
+
+// chain is the order of the hash functions after BLAKE.
+var chain = [...]func([]byte) [64]byte{
+	bmw512,
+	groestl512,
+	skein512,
+	jh512,
+	keccak512,
+	luffa512,
+	cubehash512,
+	shavite512,
+	simd512,
+	echo512,
+}
+
+// Sum returns the X11 hash of data: the first 256 bits of the final ECHO
+// digest. For a block header it is in the same byte order as a block hash
+// (interpret as little-endian to compare against the target).
+func Sum(data []byte) [32]byte {
+	h := blake512(data)
+	for _, f := range chain {
+		h = f(h[:])
+	}
+	var out [32]byte
+	copy(out[:], h[:32])
+	return out
+}
@@ -0,0 +1,80 @@
+package x11
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestEmptyVectors(t *testing.T) {
+	cases := []struct {
+		name string
+		f    func([]byte) [64]byte
+		want string
+	}{
+		{"blake", blake512, "a8cfbbd73726062df0c6864dda65defe58ef0cc52a5625090fa17601e1eecd1b628e94f396ae402a00acc9eab77b4d4c2e852aaaa25a636d80af3fc7913ef5b8"},
+		{"bmw", bmw512, "6a725655c42bc8a2a20549dd5a233a6a2beb01616975851fd122504e604b46af7d96697d0b6333db1d1709d6df328d2a6c786551b0cce2255e8c7332b4819c0e"},
+		{"groestl", groestl512, "6d3ad29d279110eef3adbd66de2a0345a77baede1557f5d099fce0c03d6dc2ba8e6d4a6633dfbd66053c20faa87d1a11f39a7fbe4a6c2f009801370308fc4ad8"},
+		{"skein", skein512, "bc5b4c50925519c290cc634277ae3d6257212395cba733bbad37a4af0fa06af41fca7903d06564fea7a2d3730dbdb80c1f85562dfcc070334ea4d1d9e72cba7a"},
+		{"jh", jh512, "90ecf2f76f9d2c8017d979ad5ab96b87d58fc8fc4b83060f3f900774faa2c8fabe69c5f4ff1ec2b61d6b316941cedee117fb04b1f4c5bc1b919ae841c50eec4f"},
+		{"keccak", keccak512, "0eab42de4c3ceb9235fc91acffe746b29c29a8c366b7c60e4e67c466f36a4304c00fa9caf9d87976ba469bcbe06713b435f091ef2769fb160cdab33d3670680e"},
+		{"luffa", luffa512, "6e7de4501189b3ca58f3ac114916654bbcd4922024b4cc1cd764acfe8ab4b7805df133eab345ffdb1c414564c924f48e0a301824e2ac4c34bd4efde2e43da90e"},
+		{"cubehash", cubehash512, "4a1d00bbcfcb5a9562fb981e7f7db3350fe2658639d948b9d57452c22328bb32f468b072208450bad5ee178271408be0b16e5633ac8a1e3cf9864cfbfc8e043a"},
+		{"shavite", shavite512, "a485c1b2578459d1efc5dddd840bb0b4a650ac82fe68f58c4442ccda747da006b2d1dc6b4a4eb7d84ff91e1f466fef429d259acd995dddcad16fa545c7a6e5ba"},
+		{"simd", simd512, "51a5af7e243cd9a5989f7792c880c4c3168c3d60c4518725fe5757d1f7a69c6366977eaba7905ce2da5d7cfd07773725f0935b55f3efb954996689a49b6d29e0"},
+		{"echo", echo512, "158f58cc79d300a9aa292515049275d051a28ab931726d0ec44bdd9faef4a702c36db9e7922fff077402236465833c5cc76af4efc352b4b44c7fa15aa0ef234e"},
+	}
+	for _, c := range cases {
+		got := c.f(nil)
+		if hex.EncodeToString(got[:]) != c.want {
+			t.Errorf("%s: got %x", c.name, got)
+		}
+	}
+}
+
+func TestKeccakMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 300; n += 7 {
+		msg := make([]byte, n)
+		r.Read(msg)
+		ref := sha3.NewLegacyKeccak512()
+		ref.Write(msg)
+		got := keccak512(msg)
+		if hex.EncodeToString(got[:]) != hex.EncodeToString(ref.Sum(nil)) {
+			t.Fatalf("mismatch for %d bytes", n)
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "51b572209083576ea221c27e62b4e22063257571ccb6cc3dc3cd17eb67584eba"},
+		{"text", hex.EncodeToString([]byte("The great experiment continues.")), "4da3b7c5ff698c6546564ebc72204f31885cd87b75b2b3ca5a93b5d75db85b8c"},
+		// Dash mainnet and testnet genesis headers, hashes in display order
+		// 00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6 and
+		// 00000bafbc94add76cb75e2ec92894837288a481e5c005f6563d91623bf8bc2c.
+		{
+			"mainnet genesis",
+			"010000000000000000000000000000000000000000000000000000000000000000000000c762a6567f3cc092f0684bb62b7e00a84890b990f07cc71a6bb58d64b98e02e0022ddb52f0ff0f1ec23fb901",
+			"b67a40f3cd5804437a108f105533739c37e6229bc1adcab385140b59fd0f0000",
+		},
+		{
+			"testnet genesis",
+			"010000000000000000000000000000000000000000000000000000000000000000000000c762a6567f3cc092f0684bb62b7e00a84890b990f07cc71a6bb58d64b98e02e0dee1e352f0ff0f1ec3c927e6",
+			"2cbcf83b62913d56f605c0e581a48872839428c92e5eb76cd7ad94bcaf0b0000",
+		},
+	}
+	for _, c := range cases {
+		data, _ := hex.DecodeString(c.data)
+		got := Sum(data)
+		if hex.EncodeToString(got[:]) != c.want {
+			t.Errorf("%s: got %x", c.name, got)
+		}
+	}
+}
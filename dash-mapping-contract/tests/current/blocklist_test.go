@@ -1,9 +1,10 @@
 package current_test
 
 import (
-	btcMapping "dash-mapping-contract"
 	"dash-mapping-contract/contract/constants"
+	"encoding/hex"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -18,12 +19,19 @@ import (
 const testContractId = "mapping_contract"
 const testOwner = "hive:milo-hpr"
 
-// Valid Bitcoin testnet4 block headers (116087 → 116088 → 116089).
+// The Dash mainnet genesis followed by two X11-mined regtest headers. The
+// regtest build does not retarget, so they are stored at an arbitrary height.
 const lastBlockHeight = "116087"
 
-const lastBlockHeader = "00c0a520165303733ee5b0561d46da9dcce685fd12a807d64472931c46d5920c00000000c96f929654fc44fb69783b6cc4f2340ad85de5b10c5047836561901299ed23d162525469ffff001dda00dd53"
+const (
+	dashGenesisHeader = "010000000000000000000000000000000000000000000000000000000000000000000000c762a6567f3cc092f0684bb62b7e00a84890b990f07cc71a6bb58d64b98e02e0022ddb52f0ff0f1ec23fb901"
+	dashRegtestBlock1 = "00000020b67a40f3cd5804437a108f105533739c37e6229bc1adcab385140b59fd0f00006e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d00f15365ffff7f2000000000"
+	dashRegtestBlock2 = "0000002041c7d27d4a7fb05ae291e29ca68d6de4a9fbc5a695dbd02f6d112be1c2bee2314bf5122f344554c53bde2ebb8cd2b7e3d1600ad631c385a5d7cce23c7785459a96f15365ffff7f2001000000"
+)
+
+const lastBlockHeader = dashGenesisHeader
 
-const twoBlocksPayload = `{"blocks":"00c0fa213b04801d1b66efcf8f41290a675777893f5c6ac158a585654263ba0900000000fdf6162d92eee3af012f1ddab30a401bb371a0da32371d185fc25eb3655fd6d013575469ffff001db80220f80000002002883f9d7847a35a0d371cd11bf95c0f9d252ed41f46dde04172bf0c000000003d2af3ae86b3638665e6214df4dc12712fd7486348c3c319cedb3c69bc8a4ddac45b5469ffff001d1adfdc74","latest_fee":1}`
+const twoBlocksPayload = `{"blocks":"` + dashRegtestBlock1 + dashRegtestBlock2 + `","latest_fee":1}`
 
 type ctWrapper struct {
 	ct *test_utils.ContractTest
@@ -273,55 +281,96 @@ func TestAllOperations(t *testing.T) {
 	// ========== AddBlocks round-trip (block sequence bug) ==========
 	// Reproduces the testnet bug: after addBlocks stores block headers as raw
 	// bytes, the next addBlocks must read them back and verify chain continuity.
-	// Uses real BTC testnet3 block headers at heights 4888515 → 4888516 → 4888517.
+	// Uses the Dash genesis → regtest block 1 → regtest block 2 fixtures.
 
 	t.Run("AddBlocks_RoundTrip_ChainContinuity", func(t *testing.T) {
 		rtId := "roundtrip_blocklist"
-		// Use testnet3 WASM since we're testing with real testnet3 block headers
-		w.ct.RegisterContract(rtId, testOwner, btcMapping.TestnetWasm)
-
-		// Real BTC testnet3 block headers fetched from btcd RPC.
-		// hash(4888515) = 00000000f570586692e115a950b812f359b27ac1441c91b91223163843504515
-		// hash(4888516) = 000000000923f265408b69969242b68fbec135b24761ac2f51cd60b53855dc1c
-		// hash(4888517) = 000000002e42abbf29b8d01a647c0b42521966edb88e87e11c80720de1135a39
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
-
-		// Seed with block 4888515 stored as raw bytes (matching what the
-		// contract itself does in HandleAddBlocks line 124-126).
-		seedRaw := decodeHex(t, block4888515Hex)
-		w.ct.StateSet(rtId, constants.LastHeightKey, "4888515")
-		w.ct.StateSet(rtId, constants.BlockPrefix+"4888515", seedRaw)
+		w.ct.RegisterContract(rtId, testOwner, ContractWasm)
+
+		// Block hashes are X11, not double-SHA256:
+		// hash(genesis) = 00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6
+		// hash(block 1) = 31e2bec2e12b116d2fd0db95a6c5fba9e46d8da69ce291e25ab07f4a7dd2c741
+		// hash(block 2) = 1e88ded99a197322169106764d1c0f446cf21ef81434d3fcf0e1f1013defcbf8
+
+		// Seed with the genesis stored as raw bytes (matching what the
+		// contract itself does in HandleAddBlocks).
+		seedRaw := decodeHex(t, dashGenesisHeader)
+		w.ct.StateSet(rtId, constants.LastHeightKey, "0")
+		w.ct.StateSet(rtId, constants.BlockPrefix+"0", seedRaw)
 		// Supply: 4x int64 BE = 32 zero bytes for all-zero supply with base_fee=1
 		supply := make([]byte, 32)
 		supply[31] = 1 // base_fee_rate = 1
 		w.ct.StateSet(rtId, constants.SupplyKey, string(supply))
 
 		// Debug: verify the stored seed is readable
-		stored := w.ct.StateGet(rtId, constants.BlockPrefix+"4888515")
+		stored := w.ct.StateGet(rtId, constants.BlockPrefix+"0")
 		t.Logf("Stored seed length: %d, expected: 80", len(stored))
 		t.Logf("Stored seed hex: %x", []byte(stored)[:min(20, len(stored))])
 		t.Logf("Stored height: %s", w.ct.StateGet(rtId, constants.LastHeightKey))
 		t.Logf("Stored supply length: %d", len(w.ct.StateGet(rtId, constants.SupplyKey)))
 
-		// First addBlocks: submit block 4888516.
+		// First addBlocks: submit block 1.
 		// Use oracle DID as caller (always allowed) to bypass auth issues in test
 		oracleCaller := "did:vsc:oracle:dash"
-		payload1 := `{"blocks":"` + block4888516Hex + `","latest_fee":0}`
+		payload1 := `{"blocks":"` + dashRegtestBlock1 + `","latest_fee":0}`
 		r1 := callActionOnContract(t, w, rtId, "addBlocks", payload1, oracleCaller)
 		t.Logf("r1: success=%v err=%q errMsg=%q ret=%q", r1.Success, r1.Err, r1.ErrMsg, r1.Ret)
-		require.True(t, r1.Success, "first addBlocks (4888516) should succeed: %s %s", r1.Err, r1.ErrMsg)
-		assert.Contains(t, r1.Ret, "4888516")
+		require.True(t, r1.Success, "first addBlocks (1) should succeed: %s %s", r1.Err, r1.ErrMsg)
+		assert.Contains(t, r1.Ret, "last height: 1")
 
-		// Second addBlocks: submit block 4888517.
+		// Second addBlocks: submit block 2.
 		// This reads back the raw bytes stored by the first call.
-		// If the raw byte round-trip corrupts the header, BlockHash()
+		// If the raw byte round-trip corrupts the header, its X11 hash
 		// will differ and we get "block sequence incorrect".
-		payload2 := `{"blocks":"` + block4888517Hex + `","latest_fee":0}`
+		payload2 := `{"blocks":"` + dashRegtestBlock2 + `","latest_fee":0}`
 		r2 := callActionOnContract(t, w, rtId, "addBlocks", payload2, oracleCaller)
-		require.True(t, r2.Success, "second addBlocks (4888517) should succeed: %s %s", r2.Err, r2.ErrMsg)
-		assert.Contains(t, r2.Ret, "4888517")
+		require.True(t, r2.Success, "second addBlocks (2) should succeed: %s %s", r2.Err, r2.ErrMsg)
+		assert.Contains(t, r2.Ret, "last height: 2")
+	})
+
+	t.Run("AddBlocks_BadPoWFails", func(t *testing.T) {
+		badId := "bad_pow_blocklist"
+		w.ct.RegisterContract(badId, testOwner, ContractWasm)
+		w.ct.StateSet(badId, constants.LastHeightKey, "1")
+		w.ct.StateSet(badId, constants.BlockPrefix+"1", decodeHex(t, dashRegtestBlock1))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(badId, constants.SupplyKey, string(supply))
+		oracleCaller := "did:vsc:oracle:dash"
+
+		// Block 2 with its nonce changed still links by PrevBlock but its X11
+		// hash no longer meets the target.
+		tampered := []byte(decodeHex(t, dashRegtestBlock2))
+		tampered[76]--
+		payload := `{"blocks":"` + hex.EncodeToString(tampered) + `","latest_fee":1}`
+		r := callActionOnContract(t, w, badId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "addBlocks with an unmined header should fail")
+	})
+
+	t.Run("AddBlocks_CompetingBranchNeedsMoreWork", func(t *testing.T) {
+		fcId := "forkchoice_blocklist"
+		w.ct.RegisterContract(fcId, testOwner, ContractWasm)
+
+		w.ct.StateSet(fcId, constants.LastHeightKey, "0")
+		w.ct.StateSet(fcId, constants.BlockPrefix+"0", decodeHex(t, dashGenesisHeader))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(fcId, constants.SupplyKey, string(supply))
+
+		oracleCaller := "did:vsc:oracle:dash"
+		payload := `{"blocks":"` + dashRegtestBlock1 + dashRegtestBlock2 + `","latest_fee":1}`
+		r := callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
+		assert.NotEmpty(t, w.ct.StateGet(fcId, constants.ChainWorkPrefix+"2"))
+
+		// Resubmitting the tip adds nothing: it is already on the stored chain
+		// and must not be taken as a branch of its own.
+		payload = `{"blocks":"` + dashRegtestBlock2 + `","latest_fee":1}`
+		r = callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "resubmitted tip should be rejected")
+		assert.Contains(t, r.ErrMsg, "already stored")
+		assert.Equal(t, "2", w.ct.StateGet(fcId, constants.LastHeightKey))
+		assert.Empty(t, w.ct.StateGet(fcId, constants.StagedBranchKey))
 	})
 
 	// ========== ReplaceBlocks (multi-block reorg) ==========
@@ -342,61 +391,54 @@ func TestAllOperations(t *testing.T) {
 	})
 
 	t.Run("ReplaceBlocks_MultiBlock_Success", func(t *testing.T) {
-		// Set up a 3-block chain: 4888515 → 4888516 → 4888517
+		// Set up a 3-block chain: genesis → block 1 → block 2
 		rbId := "replaceblocks_test"
-		w.ct.RegisterContract(rbId, testOwner, btcMapping.TestnetWasm)
-
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
+		w.ct.RegisterContract(rbId, testOwner, ContractWasm)
 
-		// Seed with block 4888515
-		seedRaw := decodeHex(t, block4888515Hex)
-		w.ct.StateSet(rbId, constants.LastHeightKey, "4888515")
-		w.ct.StateSet(rbId, constants.BlockPrefix+"4888515", seedRaw)
+		// Seed with the genesis
+		seedRaw := decodeHex(t, dashGenesisHeader)
+		w.ct.StateSet(rbId, constants.LastHeightKey, "0")
+		w.ct.StateSet(rbId, constants.BlockPrefix+"0", seedRaw)
 		supply := make([]byte, 32)
 		supply[31] = 1
 		w.ct.StateSet(rbId, constants.SupplyKey, string(supply))
 
-		// Add blocks 4888516 and 4888517
+		// Add blocks 1 and 2
 		oracleCaller := "did:vsc:oracle:dash"
-		payload := `{"blocks":"` + block4888516Hex + block4888517Hex + `","latest_fee":1}`
+		payload := `{"blocks":"` + dashRegtestBlock1 + dashRegtestBlock2 + `","latest_fee":1}`
 		r := callActionOnContract(t, w, rbId, "addBlocks", payload, oracleCaller)
 		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
-		assert.Contains(t, r.Ret, "4888517")
+		assert.Contains(t, r.Ret, "last height: 2")
 
-		// Now replace both blocks 4888516 and 4888517 with themselves (same canonical headers).
+		// Now replace both blocks 1 and 2 with themselves (same canonical headers).
 		// This simulates a 2-block reorg where the canonical chain happens to match.
 		// The key test is that the chaining validation passes for multi-block replacement.
-		replacePayload := block4888516Hex + block4888517Hex
+		replacePayload := dashRegtestBlock1 + dashRegtestBlock2
 		r2 := callActionOnContract(t, w, rbId, "replaceBlocks", replacePayload, "")
 		require.True(t, r2.Success, "replaceBlocks (2 blocks) should succeed: %s %s", r2.Err, r2.ErrMsg)
 		assert.Contains(t, r2.Ret, "replaced 2 blocks")
-		assert.Contains(t, r2.Ret, "4888517")
+		assert.Contains(t, r2.Ret, "tip at height: 2")
 	})
 
 	t.Run("ReplaceBlocks_SingleBlock_DelegatesToReplaceBlock", func(t *testing.T) {
 		// Single-header replaceBlocks should delegate to HandleReplaceBlock
 		rbId2 := "replaceblocks_single"
-		w.ct.RegisterContract(rbId2, testOwner, btcMapping.TestnetWasm)
+		w.ct.RegisterContract(rbId2, testOwner, ContractWasm)
 
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-
-		seedRaw := decodeHex(t, block4888515Hex)
-		raw516 := decodeHex(t, block4888516Hex)
-		w.ct.StateSet(rbId2, constants.LastHeightKey, "4888516")
-		w.ct.StateSet(rbId2, constants.BlockPrefix+"4888515", seedRaw)
-		w.ct.StateSet(rbId2, constants.BlockPrefix+"4888516", raw516)
+		seedRaw := decodeHex(t, dashRegtestBlock1)
+		raw2 := decodeHex(t, dashRegtestBlock2)
+		w.ct.StateSet(rbId2, constants.LastHeightKey, "2")
+		w.ct.StateSet(rbId2, constants.BlockPrefix+"1", seedRaw)
+		w.ct.StateSet(rbId2, constants.BlockPrefix+"2", raw2)
 		supply := make([]byte, 32)
 		supply[31] = 1
 		w.ct.StateSet(rbId2, constants.SupplyKey, string(supply))
 
 		// Replace just the tip (single block)
-		replacePayload := block4888516Hex
+		replacePayload := dashRegtestBlock2
 		r := callActionOnContract(t, w, rbId2, "replaceBlocks", replacePayload, "")
 		require.True(t, r.Success, "single-block replaceBlocks should succeed: %s %s", r.Err, r.ErrMsg)
-		assert.Contains(t, r.Ret, "4888516")
+		assert.Contains(t, r.Ret, "height: 2")
 	})
 
 	// ========== Unmap ==========
@@ -440,3 +482,141 @@ func TestAllOperations(t *testing.T) {
 		assert.False(t, r.Success, "transferFrom with insufficient balance should fail")
 	})
 }
+
+func TestSeedBlocksHeaderRun(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+
+	// Out of order, the headers do not link.
+	broken := dashRegtestBlock2 + dashRegtestBlock1
+	r := callAction(t, w, "seedBlocks", `{"block_headers":"`+broken+`","block_height":`+lastBlockHeight+`}`, "")
+	assert.False(t, r.Success, "seedBlocks with unlinked headers should fail")
+
+	run := dashRegtestBlock1 + dashRegtestBlock2
+	r = callAction(t, w, "seedBlocks", `{"block_headers":"`+run+`","block_height":`+lastBlockHeight+`}`, "")
+	require.True(t, r.Success, "seedBlocks with a header run should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116088")
+	assert.Equal(t, "116088", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.SeedHeightKey))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.BlockPrefix+"116087"))
+}
+
+func TestAddBlocksFutureDrift(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	// callAt calls as the owner in a Hive block with the given timestamp.
+	callAt := func(action, payload, timestamp string) test_utils.ContractTestCallResult {
+		self := basicSelf(t, testOwner)
+		self.Timestamp = timestamp
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self:       *self,
+			ContractId: testContractId,
+			Action:     action,
+			Payload:    json.RawMessage([]byte(payload)),
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     testOwner,
+		})
+	}
+
+	r := callAt("setMaxFutureDrift", "7200", "2023-11-14T00:00:00")
+	require.True(t, r.Success, "setMaxFutureDrift failed: %s %s", r.Err, r.ErrMsg)
+
+	// The headers were mined on 2023-11-14 between 22:13 and 22:16 UTC.
+	r = callAt("addBlocks", twoBlocksPayload, "2023-11-14T20:00:00")
+	assert.False(t, r.Success, "addBlocks with headers more than 2 hours ahead should fail")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = callAt("addBlocks", twoBlocksPayload, "2023-11-14T22:00:00")
+	require.True(t, r.Success, "addBlocks within the drift bound should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+}
+
+func TestAddBlocksOracleQuorum(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+	submit := func(blocks string, fee int, oracle string) test_utils.ContractTestCallResult {
+		payload := `{"blocks":"` + blocks + `","latest_fee":` + strconv.Itoa(fee) + `}`
+		return callAction(t, w, "addBlocks", payload, oracle)
+	}
+
+	r := callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "hive:oracle1")
+	assert.False(t, r.Success, "setOracles by a non-owner should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2"],"threshold":3}`, "")
+	assert.False(t, r.Success, "setOracles with a threshold above the set size should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "")
+	require.True(t, r.Success, "setOracles failed: %s %s", r.Err, r.ErrMsg)
+
+	r = submit(added.Blocks, 1, "did:vsc:oracle:dash")
+	assert.False(t, r.Success, "the single oracle address is not a member of the set")
+
+	r = submit(added.Blocks, 10, "hive:oracle1")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	// A different batch does not count towards the first.
+	r = submit(added.Blocks[:160], 10, "hive:oracle2")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = submit(added.Blocks, 30, "hive:oracle3")
+	require.True(t, r.Success, "quorum commit failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Contains(t, r.Ret, "base fee: 20")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle1"))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle3"))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+
+	// The single oracle address cannot change the headers around the quorum.
+	for _, action := range []string{"seedBlocks", "replaceBlock", "replaceBlocks", "prune"} {
+		r = callAction(t, w, action, added.Blocks[160:], "did:vsc:oracle:dash")
+		assert.False(t, r.Success, "%s by the single oracle address should fail with an oracle set", action)
+	}
+	r = callAction(t, w, "prune", "", "")
+	require.True(t, r.Success, "prune by the owner failed: %s %s", r.Err, r.ErrMsg)
+
+	r = callAction(t, w, "setOracles", `{"oracles":[],"threshold":0}`, "")
+	require.True(t, r.Success, "clearing the oracle set failed: %s %s", r.Err, r.ErrMsg)
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+}
+
+func TestAddBlocksFeeRateWindow(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+
+	r := callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[:160]+`","latest_fee":10}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 10")
+
+	// The median of 10 and 500 is 255, but one update moves the rate by at
+	// most a quarter.
+	r = callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[160:]+`","latest_fee":500}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 12")
+	assert.Len(t, ct.StateGet(testContractId, constants.FeeWindowKey), 16)
+}
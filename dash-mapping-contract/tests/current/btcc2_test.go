@@ -49,7 +49,7 @@ func TestBTCC2_RepeatedTxInReplacedBlockNoDoubleMint(t *testing.T) {
 	// instruction. The contract derives the same address from Instructions,
 	// so the output is recognised as a relevant deposit.
 	depositAddr, _, err := mapping.DepositAddress(
-		TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams(),
+		TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork(),
 	)
 	require.NoError(t, err, "derive deposit address")
 	tx := buildTestTx(t, depositAddr, amount)
@@ -59,14 +59,14 @@ func TestBTCC2_RepeatedTxInReplacedBlockNoDoubleMint(t *testing.T) {
 	// Anchor header at H-1 (regtest PoW; merkle root irrelevant, never mapped).
 	seedTs := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	seed := buildRegtestHeader(chainhash.Hash{}, chainhash.Hash{}, seedTs)
-	seedHash := seed.BlockHash()
+	seedHash := blockHash(seed)
 
 	// Two distinct blocks at the tip height H, both chaining to the anchor and
 	// both committing to the same deposit tx (MerkleRoot == txHash). Different
 	// timestamps → different block hashes → a genuine reorg replacement.
 	blockOrig := buildRegtestHeader(seedHash, txHash, seedTs.Add(10*time.Minute))
 	blockReplace := buildRegtestHeader(seedHash, txHash, seedTs.Add(20*time.Minute))
-	require.NotEqual(t, blockOrig.BlockHash(), blockReplace.BlockHash(),
+	require.NotEqual(t, blockHash(blockOrig), blockHash(blockReplace),
 		"original and replacement blocks must be distinct to simulate a reorg")
 
 	ct := test_utils.NewContractTest()
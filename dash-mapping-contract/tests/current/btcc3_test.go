@@ -200,3 +200,47 @@ func TestBTCC3_SetMaxUnmapPerBlockRejectsNegative(t *testing.T) {
 	r := callActionOnContract(t, w, contractId, "setMaxUnmapPerBlock", "-1", "hive:milo-hpr")
 	assert.False(t, r.Success, "negative cap must be rejected")
 }
+
+// A queued withdrawal that is cancelled gives its debit back to the current
+// Hive block's allowance, so the refunded amount can be unmapped again in
+// the same block.
+func TestBTCC3_RefundReleasesUnmapCap(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+
+	const contractId = "btcc3_refund"
+	const sender = "hive:milo-hpr"
+	btcc3SetupContract(t, &ct, contractId, 20000, 5000, 5000, 5000, 5000)
+
+	w := &ctWrapper{ct: &ct}
+	for _, c := range [][2]string{
+		{"setMaxUnmapPerBlock", "6000"},
+		{"setWithdrawalQueue", "true"},
+		{"setWithdrawalTimeout", "1"},
+	} {
+		r := callActionOnContract(t, w, contractId, c[0], c[1], sender)
+		require.True(t, r.Success, "%s failed: %s %s", c[0], r.Err, r.ErrMsg)
+	}
+
+	r := btcc3Unmap(t, &ct, contractId, 4500, "blockA")
+	require.True(t, r.Success, "queued unmap failed: %s %s", r.Err, r.ErrMsg)
+	ct.IncrementBlocks(2)
+
+	r = btcc3Unmap(t, &ct, contractId, 4500, "blockB")
+	require.True(t, r.Success, "queued unmap failed: %s %s", r.Err, r.ErrMsg)
+	_, before := decodeAccumulator(ct.StateGet(contractId, constants.BlockUnmapAccKey))
+	require.GreaterOrEqual(t, before, int64(4500))
+	assert.False(t, btcc3Unmap(t, &ct, contractId, 4500, "blockB").Success, "the cap is used up")
+
+	// Only the first withdrawal has waited out the timeout; cancelling it
+	// frees the allowance the second one used.
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", "2", sender)
+	assert.False(t, r.Success, "a withdrawal queued this block cannot be cancelled yet")
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", "1", sender)
+	require.True(t, r.Success, "cancelWithdrawal failed: %s %s", r.Err, r.ErrMsg)
+	_, after := decodeAccumulator(ct.StateGet(contractId, constants.BlockUnmapAccKey))
+	assert.Less(t, after, before, "the refund must release its debit from the cap")
+
+	r = btcc3Unmap(t, &ct, contractId, 4500, "blockB")
+	require.True(t, r.Success, "unmap after the refund should fit under the cap: %s %s", r.Err, r.ErrMsg)
+}
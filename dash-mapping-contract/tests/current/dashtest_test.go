@@ -9,7 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"dash-mapping-contract/contract/constants"
 	"dash-mapping-contract/contract/mapping"
+	"dash-mapping-contract/contract/network"
+	"dash-mapping-contract/contract/x11"
 
 	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcutil"
@@ -25,8 +28,18 @@ const (
 	TestBackupPubKeyHex  = "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
 )
 
+// regtestParams mirrors the contract's Dash regtest params: Bitcoin's regtest
+// with Dash testnet base58 prefixes.
 func regtestParams() *chaincfg.Params {
-	return &chaincfg.RegressionNetParams
+	p := chaincfg.RegressionNetParams
+	p.PubKeyHashAddrID = 0x8c // 'y' prefix
+	p.ScriptHashAddrID = 0x13 // '8'/'9' prefix
+	return &p
+}
+
+func regtestNetwork() *network.Network {
+	net, _ := network.Lookup(constants.Regtest)
+	return net
 }
 
 // encodeBalance encodes amount using the same compact big-endian binary
@@ -84,15 +97,15 @@ func decodeHex(t *testing.T, s string) string {
 	return string(b)
 }
 
-// regtestDestAddress returns a P2WPKH address derived from TestBackupPubKeyHex
-// on the regtest network (bcrt1q...).
+// regtestDestAddress returns a P2PKH address derived from TestBackupPubKeyHex
+// on the regtest network (y...).
 func regtestDestAddress(t *testing.T) string {
 	t.Helper()
 	pubKeyBytes, err := hex.DecodeString(TestBackupPubKeyHex)
 	if err != nil {
 		t.Fatal("invalid test backup public key hex:", err)
 	}
-	addr, err := btcutil.NewAddressWitnessPubKeyHash(
+	addr, err := btcutil.NewAddressPubKeyHash(
 		btcutil.Hash160(pubKeyBytes),
 		regtestParams(),
 	)
@@ -102,9 +115,20 @@ func regtestDestAddress(t *testing.T) string {
 	return addr.EncodeAddress()
 }
 
+// blockHash returns the Dash block hash of a header: X11 of its 80 bytes,
+// which is both the PoW hash and what the next header's PrevBlock links to.
+func blockHash(h *wire.BlockHeader) chainhash.Hash {
+	var buf bytes.Buffer
+	if err := h.Serialize(&buf); err != nil {
+		panic(err)
+	}
+	return chainhash.Hash(x11.Sum(buf.Bytes()))
+}
+
 // buildRegtestHeader creates a valid regtest block header by mining for a nonce
-// that satisfies the compact target 0x207fffff (hash must be ≤ 7fffff000...0).
-// On average this needs ~2 iterations since ~50% of random hashes pass.
+// whose X11 hash satisfies the compact target 0x207fffff (hash must be
+// ≤ 7fffff000...0). On average this needs ~2 iterations since ~50% of random
+// hashes pass.
 func buildRegtestHeader(prevBlock, merkleRoot chainhash.Hash, ts time.Time) *wire.BlockHeader {
 	h := &wire.BlockHeader{
 		Version:    1,
@@ -116,7 +140,7 @@ func buildRegtestHeader(prevBlock, merkleRoot chainhash.Hash, ts time.Time) *wir
 	}
 	target := blockchain.CompactToBig(0x207fffff)
 	for {
-		hash := h.BlockHash()
+		hash := blockHash(h)
 		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
 			return h
 		}
@@ -151,7 +175,8 @@ func buildTestTx(t *testing.T, toAddress string, amount int64) *wire.MsgTx {
 	t.Helper()
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.AddTxIn(&wire.TxIn{
-		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{}, Index: 0xffffffff},
+		// any non-null outpoint; a null one would make this a coinbase
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0},
 		SignatureScript:  []byte{0x00},
 		Sequence:         wire.MaxTxInSequenceNum,
 	})
@@ -182,7 +207,7 @@ func serializeTx(t *testing.T, tx *wire.MsgTx) string {
 // matching the on-chain address derivation.
 func depositUtxoBinary(t *testing.T, txId string, vout uint32, amount int64, instruction string) string {
 	t.Helper()
-	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams())
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive deposit address:", err)
 	}
@@ -210,7 +235,7 @@ func depositUtxoBinary(t *testing.T, txId string, vout uint32, amount int64, ins
 func changeUtxoBinary(t *testing.T, txId string, vout uint32, amount int64) string {
 	t.Helper()
 	// nil tag → change address path (OP_CHECKSIGVERIFY + OP_DATA_0)
-	address, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestParams())
+	address, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive change address:", err)
 	}
@@ -246,7 +271,7 @@ type MapTestFixture struct {
 // empty proof, TxIndex = 0) so the on-chain Merkle verification trivially passes.
 func buildMapFixture(t *testing.T, instruction string, amount int64, blockHeight uint32) MapTestFixture {
 	t.Helper()
-	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams())
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive deposit address:", err)
 	}
@@ -278,7 +303,7 @@ type ConfirmSpendFixture struct {
 // empty proof, TxIndex = 0) so the on-chain Merkle verification trivially passes.
 func buildConfirmSpendFixture(t *testing.T, blockHeight uint32) ConfirmSpendFixture {
 	t.Helper()
-	changeAddr, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestParams())
+	changeAddr, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive change address:", err)
 	}
@@ -326,7 +351,7 @@ func buildHeaderChain(t *testing.T, seedTime time.Time, count int) (string, stri
 	var chainBuf bytes.Buffer
 	prev := seed
 	for i := 0; i < count; i++ {
-		prevHash := prev.BlockHash()
+		prevHash := blockHash(prev)
 		next := buildRegtestHeader(prevHash, chainhash.Hash{}, prev.Timestamp.Add(10*time.Minute))
 		chainBuf.WriteString(serializeHeader(t, next))
 		prev = next
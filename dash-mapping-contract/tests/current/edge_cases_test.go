@@ -300,11 +300,11 @@ func TestMapMultipleOutputsSameTx(t *testing.T) {
 	const amount2 = int64(3000)
 
 	// Derive both deposit addresses
-	addr1, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction1, regtestParams())
+	addr1, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction1, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive addr1:", err)
 	}
-	addr2, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction2, regtestParams())
+	addr2, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction2, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive addr2:", err)
 	}
@@ -451,7 +451,7 @@ func TestUnmapExactBalance(t *testing.T) {
 
 	// After unmap, balance should be less than original
 	remainingBal := ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr")
-	// vscFee = 0 (VscFeeMinSats=0, VscFeeRateBps=0)
+	// vscFee = 0 (the chain policy has no VSC fee)
 	// So remaining should be 100000 - 50000 - btcFee = 50000 - btcFee
 	t.Logf("remaining balance after unmap: %q", remainingBal)
 	// Verify it decreased significantly
@@ -677,7 +677,7 @@ func TestUnmapSupplyUpdates(t *testing.T) {
 
 	t.Logf("supply after unmap: active=%d, user=%d, fee=%d", supply.ActiveSupply, supply.UserSupply, supply.FeeSupply)
 
-	// vscFee = 0 (VscFeeMinSats=0, VscFeeRateBps=0)
+	// vscFee = 0 (the chain policy has no VSC fee)
 	// ActiveSupply should decrease by (amount + btcFee)
 	assert.True(t, supply.ActiveSupply < balance, "active supply should decrease")
 	assert.True(t, supply.UserSupply < balance, "user supply should decrease")
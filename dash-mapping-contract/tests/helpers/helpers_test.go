@@ -3,9 +3,8 @@ package helpers
 import (
 	"dash-mapping-contract/contract/constants"
 	"dash-mapping-contract/contract/mapping"
+	"dash-mapping-contract/contract/network"
 	"testing"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
 func TestCreateAddress(t *testing.T) {
@@ -15,9 +14,9 @@ func TestCreateAddress(t *testing.T) {
 	const recipient = "hive:milo-hpr"
 	const instruction = constants.DepositToKey + "=" + recipient
 	t.Log("instruction:", instruction)
-	var network = chaincfg.TestNet4Params
+	net, _ := network.Lookup(constants.Testnet)
 
-	address, _, err := mapping.DepositAddress(primary, backup, instruction, &network)
+	address, _, err := mapping.DepositAddress(primary, backup, instruction, net)
 	if err != nil {
 		t.Fatal("error creating address:", err)
 	}
@@ -0,0 +1,256 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+
+	ce "doge-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Since height 371337 Dogecoin blocks are merged-mined: the work is done on a
+// parent chain (usually Litecoin) whose coinbase commits to the Dogecoin block
+// hash. Such headers set the AuxPoW version bit and are followed on the wire
+// by the proof linking them to the parent header. This mirrors Dogecoin Core's
+// CAuxPow.
+
+const (
+	auxpowVersionFlag = 1 << 8
+	auxpowChainIDBits = 16
+	// dogeChainID is Dogecoin's merged-mining chain ID, carried in the upper
+	// 16 bits of the block version.
+	dogeChainID = 0x0062
+	// maxChainMerkleBranch is Dogecoin Core's limit on the chain merkle
+	// branch, i.e. at most 2^30 merged-mined chains.
+	maxChainMerkleBranch = 30
+	// maxCoinbaseMerkleBranch bounds the parent block's transaction tree,
+	// well beyond any real block.
+	maxCoinbaseMerkleBranch = 32
+)
+
+// mergedMiningHeader marks the chain merkle root in the parent coinbase.
+var mergedMiningHeader = []byte{0xfa, 0xbe, 'm', 'm'}
+
+// AuxPow is the merged-mining proof that follows an AuxPoW header.
+type AuxPow struct {
+	// CoinbaseTx is the parent block's coinbase, which commits to the chain
+	// merkle root.
+	CoinbaseTx *wire.MsgTx
+	// CoinbaseBranch links CoinbaseTx to the parent header's merkle root.
+	CoinbaseBranch []chainhash.Hash
+	CoinbaseIndex  int32
+	// ChainBranch links the Dogecoin block hash to the chain merkle root.
+	ChainBranch  []chainhash.Hash
+	ChainIndex   int32
+	ParentHeader BlockHeaderBytes
+}
+
+// HeaderSubmission is one header as submitted to addBlocks or replaceBlocks:
+// the 80-byte base header, which is what gets stored, and the AuxPoW proof
+// for merged-mined blocks.
+type HeaderSubmission struct {
+	Base   BlockHeaderBytes
+	AuxPow *AuxPow
+}
+
+func isAuxpowVersion(version int32) bool {
+	return version&auxpowVersionFlag != 0
+}
+
+func versionChainID(version int32) int32 {
+	return version >> auxpowChainIDBits
+}
+
+// isLegacyVersion reports whether a header predates merged mining. Dogecoin
+// also treats its stray version 2 blocks as legacy.
+func isLegacyVersion(version int32) bool {
+	return version == 1 || version == 2
+}
+
+// DivideHeaderList splits the hex-encoded submission into headers. Each entry
+// is an 80-byte header followed, if its version has the AuxPoW bit set, by its
+// serialized AuxPoW, exactly as Dogecoin Core serializes block headers.
+func DivideHeaderList(blocksHex *string) ([]HeaderSubmission, error) {
+	blockBytes, err := hex.DecodeString(*blocksHex)
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrInvalidHex, err, "error decoding block headers hex")
+	}
+
+	r := bytes.NewReader(blockBytes)
+	var headers []HeaderSubmission
+	for r.Len() > 0 {
+		header, err := readHeaderSubmission(r)
+		if err != nil {
+			return nil, ce.Prepend(err, "header at index "+strconv.Itoa(len(headers)))
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+func readHeaderSubmission(r *bytes.Reader) (HeaderSubmission, error) {
+	var header HeaderSubmission
+	if _, err := io.ReadFull(r, header.Base[:]); err != nil {
+		return header, ce.NewContractError(ce.ErrInput, "incorrect block length")
+	}
+	version := int32(binary.LittleEndian.Uint32(header.Base[0:4]))
+	if !isAuxpowVersion(version) {
+		return header, nil
+	}
+
+	auxpow, err := readAuxPow(r)
+	if err != nil {
+		return header, ce.Prepend(err, "error decoding auxpow")
+	}
+	header.AuxPow = auxpow
+	return header, nil
+}
+
+func readAuxPow(r *bytes.Reader) (*AuxPow, error) {
+	var aux AuxPow
+	aux.CoinbaseTx = new(wire.MsgTx)
+	if err := aux.CoinbaseTx.BtcDecode(r, 0, wire.BaseEncoding); err != nil {
+		return nil, ce.NewContractError(ce.ErrInput, "invalid parent coinbase: "+err.Error())
+	}
+	// The parent block hash is serialized but unused by consensus.
+	var parentHash chainhash.Hash
+	if _, err := io.ReadFull(r, parentHash[:]); err != nil {
+		return nil, ce.NewContractError(ce.ErrInput, "truncated parent block hash")
+	}
+	var err error
+	aux.CoinbaseBranch, aux.CoinbaseIndex, err = readMerkleBranch(r, maxCoinbaseMerkleBranch)
+	if err != nil {
+		return nil, ce.Prepend(err, "coinbase branch")
+	}
+	aux.ChainBranch, aux.ChainIndex, err = readMerkleBranch(r, maxChainMerkleBranch)
+	if err != nil {
+		return nil, ce.Prepend(err, "chain branch")
+	}
+	if _, err := io.ReadFull(r, aux.ParentHeader[:]); err != nil {
+		return nil, ce.NewContractError(ce.ErrInput, "truncated parent header")
+	}
+	return &aux, nil
+}
+
+func readMerkleBranch(r *bytes.Reader, maxLen uint64) ([]chainhash.Hash, int32, error) {
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, 0, ce.NewContractError(ce.ErrInput, "truncated merkle branch")
+	}
+	if n > maxLen {
+		return nil, 0, ce.NewContractError(ce.ErrInput, "merkle branch too long")
+	}
+	branch := make([]chainhash.Hash, n)
+	for i := range branch {
+		if _, err := io.ReadFull(r, branch[i][:]); err != nil {
+			return nil, 0, ce.NewContractError(ce.ErrInput, "truncated merkle branch")
+		}
+	}
+	var index [4]byte
+	if _, err := io.ReadFull(r, index[:]); err != nil {
+		return nil, 0, ce.NewContractError(ce.ErrInput, "truncated merkle branch index")
+	}
+	return branch, int32(binary.LittleEndian.Uint32(index[:])), nil
+}
+
+// checkMerkleBranch folds hash up the branch, taking the side at each level
+// from the low bit of index.
+func checkMerkleBranch(hash chainhash.Hash, branch []chainhash.Hash, index int32) chainhash.Hash {
+	if index == -1 {
+		return chainhash.Hash{}
+	}
+	var buf [64]byte
+	for _, sibling := range branch {
+		if index&1 != 0 {
+			copy(buf[:32], sibling[:])
+			copy(buf[32:], hash[:])
+		} else {
+			copy(buf[:32], hash[:])
+			copy(buf[32:], sibling[:])
+		}
+		hash = chainhash.DoubleHashH(buf[:])
+		index >>= 1
+	}
+	return hash
+}
+
+// expectedChainIndex is the slot a chain must occupy in a chain merkle tree of
+// the given height, so that one parent block cannot commit to two different
+// blocks of the same chain.
+func expectedChainIndex(nonce uint32, chainID int32, height int) int32 {
+	rand := nonce
+	rand = rand*1103515245 + 12345
+	rand += uint32(chainID)
+	rand = rand*1103515245 + 12345
+	return int32(rand % (1 << uint(height)))
+}
+
+// check verifies that the proof commits to auxBlockHash for chainID: the
+// coinbase is in the parent block, and its script carries the chain merkle
+// root at the slot reserved for chainID.
+func (a *AuxPow) check(auxBlockHash chainhash.Hash, chainID int32, strictChainID bool) error {
+	if a.CoinbaseIndex != 0 {
+		return ce.NewContractError(ce.ErrInput, "auxpow coinbase is not the first transaction of the parent block")
+	}
+	parentVersion := int32(binary.LittleEndian.Uint32(a.ParentHeader[0:4]))
+	if strictChainID && versionChainID(parentVersion) == chainID {
+		return ce.NewContractError(ce.ErrInput, "auxpow parent has our chain ID")
+	}
+	if len(a.ChainBranch) > maxChainMerkleBranch {
+		return ce.NewContractError(ce.ErrInput, "auxpow chain merkle branch too long")
+	}
+
+	rootHash := checkMerkleBranch(auxBlockHash, a.ChainBranch, a.ChainIndex)
+	// The coinbase carries the root in display (big-endian) byte order.
+	root := make([]byte, chainhash.HashSize)
+	for i := range root {
+		root[i] = rootHash[chainhash.HashSize-1-i]
+	}
+
+	parentMerkleRoot := chainhash.Hash(a.ParentHeader[36:68])
+	if checkMerkleBranch(a.CoinbaseTx.TxHash(), a.CoinbaseBranch, a.CoinbaseIndex) != parentMerkleRoot {
+		return ce.NewContractError(ce.ErrInput, "auxpow merkle root incorrect")
+	}
+
+	if len(a.CoinbaseTx.TxIn) == 0 {
+		return ce.NewContractError(ce.ErrInput, "auxpow coinbase has no inputs")
+	}
+	script := a.CoinbaseTx.TxIn[0].SignatureScript
+
+	pc := bytes.Index(script, root)
+	if pc < 0 {
+		return ce.NewContractError(ce.ErrInput, "auxpow missing chain merkle root in parent coinbase")
+	}
+	if head := bytes.Index(script, mergedMiningHeader); head >= 0 {
+		// Only one chain merkle root may be committed, directly after the
+		// single merged-mining header.
+		if bytes.Index(script[head+1:], mergedMiningHeader) >= 0 {
+			return ce.NewContractError(ce.ErrInput, "multiple merged mining headers in coinbase")
+		}
+		if head+len(mergedMiningHeader) != pc {
+			return ce.NewContractError(ce.ErrInput, "merged mining header is not just before chain merkle root")
+		}
+	} else if pc > 20 {
+		// Without the header, the root must start early in the coinbase.
+		return ce.NewContractError(ce.ErrInput, "auxpow chain merkle root must start in the first 20 bytes of the parent coinbase")
+	}
+
+	pc += len(root)
+	if len(script)-pc < 8 {
+		return ce.NewContractError(ce.ErrInput, "auxpow missing chain merkle tree size and nonce in parent coinbase")
+	}
+	size := binary.LittleEndian.Uint32(script[pc : pc+4])
+	if size != 1<<uint(len(a.ChainBranch)) {
+		return ce.NewContractError(ce.ErrInput, "auxpow merkle branch size does not match parent coinbase")
+	}
+	nonce := binary.LittleEndian.Uint32(script[pc+4 : pc+8])
+	if a.ChainIndex != expectedChainIndex(nonce, chainID, len(a.ChainBranch)) {
+		return ce.NewContractError(ce.ErrInput, "auxpow wrong chain index")
+	}
+	return nil
+}
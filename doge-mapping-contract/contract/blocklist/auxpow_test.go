@@ -0,0 +1,265 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"doge-mapping-contract/contract/constants"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	auxpowTestVersion = dogeChainID<<auxpowChainIDBits | auxpowVersionFlag | 4
+	regtestBits       = 0x207fffff
+)
+
+// mineScrypt bumps the nonce of raw (an 80-byte header) until its scrypt hash
+// meets bits.
+func mineScrypt(raw []byte, bits uint32) {
+	target := blockchain.CompactToBig(bits)
+	for {
+		powHash := chainhash.Hash(scryptHash(raw))
+		if blockchain.HashToBig(&powHash).Cmp(target) <= 0 {
+			return
+		}
+		binary.LittleEndian.PutUint32(raw[76:], binary.LittleEndian.Uint32(raw[76:])+1)
+	}
+}
+
+func headerBytes(t *testing.T, h *wire.BlockHeader) BlockHeaderBytes {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := h.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return BlockHeaderBytes(buf.Bytes())
+}
+
+// auxpowScript is a parent coinbase script committing to root for a chain
+// merkle tree of the given height.
+func auxpowScript(root chainhash.Hash, height int, nonce uint32) []byte {
+	script := []byte{0x03, 0x40, 0x42, 0x0f}
+	script = append(script, mergedMiningHeader...)
+	for i := chainhash.HashSize - 1; i >= 0; i-- {
+		script = append(script, root[i])
+	}
+	script = binary.LittleEndian.AppendUint32(script, 1<<uint(height))
+	return binary.LittleEndian.AppendUint32(script, nonce)
+}
+
+// buildAuxPow returns a proof merge-mining child at a chain merkle tree of
+// the given height, with the parent mined at regtest difficulty. script
+// overrides the coinbase script if set.
+func buildAuxPow(t *testing.T, child *wire.BlockHeader, height int, script func(root chainhash.Hash) []byte) *AuxPow {
+	t.Helper()
+	const nonce = 7
+	aux := &AuxPow{ChainIndex: expectedChainIndex(nonce, dogeChainID, height)}
+	for i := 0; i < height; i++ {
+		aux.ChainBranch = append(aux.ChainBranch, chainhash.HashH([]byte{byte(i)}))
+	}
+	root := checkMerkleBranch(child.BlockHash(), aux.ChainBranch, aux.ChainIndex)
+	if script == nil {
+		script = func(root chainhash.Hash) []byte { return auxpowScript(root, height, nonce) }
+	}
+
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0xffffffff), script(root), nil))
+	coinbase.AddTxOut(wire.NewTxOut(50, []byte{0x51}))
+	aux.CoinbaseTx = coinbase
+	aux.CoinbaseBranch = []chainhash.Hash{chainhash.HashH([]byte("sibling"))}
+
+	parent := &wire.BlockHeader{
+		Version:    0x20000000,
+		MerkleRoot: checkMerkleBranch(coinbase.TxHash(), aux.CoinbaseBranch, 0),
+		Timestamp:  child.Timestamp,
+		Bits:       child.Bits,
+	}
+	aux.ParentHeader = headerBytes(t, parent)
+	mineScrypt(aux.ParentHeader[:], child.Bits)
+	return aux
+}
+
+func serializeAuxPow(t *testing.T, aux *AuxPow) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := aux.CoinbaseTx.SerializeNoWitness(&buf); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(make([]byte, chainhash.HashSize))
+	for _, b := range []struct {
+		branch []chainhash.Hash
+		index  int32
+	}{{aux.CoinbaseBranch, aux.CoinbaseIndex}, {aux.ChainBranch, aux.ChainIndex}} {
+		if err := wire.WriteVarInt(&buf, 0, uint64(len(b.branch))); err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range b.branch {
+			buf.Write(h[:])
+		}
+		buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(b.index)))
+	}
+	buf.Write(aux.ParentHeader[:])
+	return buf.Bytes()
+}
+
+func auxpowTestHeader() *wire.BlockHeader {
+	return &wire.BlockHeader{
+		Version:    auxpowTestVersion,
+		PrevBlock:  chainhash.HashH([]byte("prev")),
+		MerkleRoot: chainhash.HashH([]byte("merkle")),
+		Timestamp:  time.Unix(1700000000, 0),
+		Bits:       regtestBits,
+	}
+}
+
+func TestDivideHeaderListAuxPow(t *testing.T) {
+	child := auxpowTestHeader()
+	aux := buildAuxPow(t, child, 2, nil)
+	base := headerBytes(t, child)
+
+	blocks := dogeGenesisHeader + hex.EncodeToString(base[:]) + hex.EncodeToString(serializeAuxPow(t, aux)) + dogeGenesisHeader
+	headers, err := DivideHeaderList(&blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 3 {
+		t.Fatalf("got %d headers, want 3", len(headers))
+	}
+	if headers[0].AuxPow != nil || headers[2].AuxPow != nil {
+		t.Fatal("legacy headers should not carry an auxpow")
+	}
+	got := headers[1]
+	if got.Base != base || got.AuxPow == nil {
+		t.Fatal("auxpow header not parsed")
+	}
+	if got.AuxPow.ParentHeader != aux.ParentHeader || got.AuxPow.ChainIndex != aux.ChainIndex ||
+		len(got.AuxPow.ChainBranch) != 2 || got.AuxPow.CoinbaseTx.TxHash() != aux.CoinbaseTx.TxHash() {
+		t.Fatal("auxpow fields do not round-trip")
+	}
+
+	// An AuxPoW-versioned header without its proof, or a truncated proof,
+	// cannot be parsed.
+	for _, bad := range []string{
+		hex.EncodeToString(base[:]),
+		blocks[:len(blocks)-len(dogeGenesisHeader)-2],
+		dogeGenesisHeader[:158],
+	} {
+		if _, err := DivideHeaderList(&bad); err == nil {
+			t.Errorf("expected parse error for %d hex chars", len(bad))
+		}
+	}
+}
+
+func TestCheckAuxPowProofOfWork(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	child := auxpowTestHeader()
+
+	for _, height := range []int{0, 1, 3} {
+		submission := &HeaderSubmission{Base: headerBytes(t, child), AuxPow: buildAuxPow(t, child, height, nil)}
+		if err := checkAuxPowProofOfWork(params, 1, child, submission); err != nil {
+			t.Fatalf("chain tree height %d: %v", height, err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(h *wire.BlockHeader, aux *AuxPow)
+		script func(root chainhash.Hash) []byte
+	}{
+		{name: "proof for another block", mutate: func(h *wire.BlockHeader, _ *AuxPow) { h.Nonce++ }},
+		{name: "wrong chain index", mutate: func(_ *wire.BlockHeader, aux *AuxPow) { aux.ChainIndex ^= 1 }},
+		{name: "coinbase not first", mutate: func(_ *wire.BlockHeader, aux *AuxPow) { aux.CoinbaseIndex = 1 }},
+		{name: "coinbase not in parent", mutate: func(_ *wire.BlockHeader, aux *AuxPow) {
+			aux.CoinbaseBranch[0][0]++
+		}},
+		{name: "parent with our chain ID", mutate: func(_ *wire.BlockHeader, aux *AuxPow) {
+			binary.LittleEndian.PutUint32(aux.ParentHeader[0:4], auxpowTestVersion)
+		}},
+		{name: "parent PoW", mutate: func(_ *wire.BlockHeader, aux *AuxPow) {
+			// Walk the nonce until the parent hash misses the target.
+			target := blockchain.CompactToBig(regtestBits)
+			for {
+				binary.LittleEndian.PutUint32(aux.ParentHeader[76:], binary.LittleEndian.Uint32(aux.ParentHeader[76:])+1)
+				powHash := chainhash.Hash(scryptHash(aux.ParentHeader[:]))
+				if blockchain.HashToBig(&powHash).Cmp(target) > 0 {
+					return
+				}
+			}
+		}},
+		{name: "header not before root", script: func(root chainhash.Hash) []byte {
+			s := auxpowScript(root, 2, 7)
+			return append(s[:8:8], append([]byte{0x00}, s[8:]...)...)
+		}},
+		{name: "two merged mining headers", script: func(root chainhash.Hash) []byte {
+			return append(auxpowScript(root, 2, 7), mergedMiningHeader...)
+		}},
+		{name: "wrong tree size", script: func(root chainhash.Hash) []byte {
+			s := auxpowScript(root, 2, 7)
+			binary.LittleEndian.PutUint32(s[len(s)-8:], 8)
+			return s
+		}},
+		{name: "late headerless root", script: func(root chainhash.Hash) []byte {
+			s := append(make([]byte, 21), auxpowScript(root, 2, 7)[8:]...)
+			return s
+		}},
+	}
+	for _, c := range cases {
+		header := auxpowTestHeader()
+		aux := buildAuxPow(t, header, 2, c.script)
+		if c.mutate != nil {
+			c.mutate(header, aux)
+		}
+		submission := &HeaderSubmission{Base: headerBytes(t, header), AuxPow: aux}
+		if err := checkAuxPowProofOfWork(params, 1, header, submission); err == nil {
+			t.Errorf("%s: expected failure", c.name)
+		}
+	}
+
+	// A headerless root early in the coinbase is still accepted.
+	header := auxpowTestHeader()
+	aux := buildAuxPow(t, header, 2, func(root chainhash.Hash) []byte {
+		return append([]byte{0x03, 0x40, 0x42, 0x0f}, auxpowScript(root, 2, 7)[8:]...)
+	})
+	if err := checkAuxPowProofOfWork(params, 1, header, &HeaderSubmission{Base: headerBytes(t, header), AuxPow: aux}); err != nil {
+		t.Fatalf("legacy commitment: %v", err)
+	}
+}
+
+func TestCheckAuxPowVersionRules(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	params.AuxpowHeight = 100
+
+	// Legacy headers are checked on their own hash until merged mining
+	// activates, and rejected after.
+	legacy := auxpowTestHeader()
+	legacy.Version = 1
+	raw := headerBytes(t, legacy)
+	mineScrypt(raw[:], regtestBits)
+	legacy.Nonce = binary.LittleEndian.Uint32(raw[76:])
+	if err := checkAuxPowProofOfWork(params, 99, legacy, &HeaderSubmission{Base: raw}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkAuxPowProofOfWork(params, 100, legacy, &HeaderSubmission{Base: raw}); err == nil {
+		t.Fatal("expected legacy header to be rejected after activation")
+	}
+
+	// Non-legacy headers must carry Dogecoin's chain ID.
+	foreign := auxpowTestHeader()
+	foreign.Version = 0x00010104
+	aux := buildAuxPow(t, foreign, 0, nil)
+	if err := checkAuxPowProofOfWork(params, 100, foreign, &HeaderSubmission{Base: headerBytes(t, foreign), AuxPow: aux}); err == nil {
+		t.Fatal("expected wrong chain ID to be rejected")
+	}
+
+	// The AuxPoW bit and the proof must agree.
+	child := auxpowTestHeader()
+	if err := checkAuxPowProofOfWork(params, 100, child, &HeaderSubmission{Base: headerBytes(t, child)}); err == nil {
+		t.Fatal("expected missing auxpow to be rejected")
+	}
+}
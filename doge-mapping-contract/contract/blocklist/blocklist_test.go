@@ -0,0 +1,87 @@
+package blocklist
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"doge-mapping-contract/contract/constants"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// minedRegtestRun returns n linked legacy regtest headers, each with a nonce
+// that meets the regtest scrypt proof of work, and their submissions.
+func minedRegtestRun(t *testing.T, n int) ([]wire.BlockHeader, []HeaderSubmission) {
+	t.Helper()
+	headers := make([]wire.BlockHeader, n)
+	submissions := make([]HeaderSubmission, n)
+	prev := chainhash.Hash{}
+	for i := range headers {
+		headers[i] = wire.BlockHeader{
+			Version:   1,
+			PrevBlock: prev,
+			Timestamp: time.Unix(1700000000+int64(i)*60, 0),
+			Bits:      regtestBits,
+		}
+		raw := headerBytes(t, &headers[i])
+		mineScrypt(raw[:], regtestBits)
+		submissions[i] = HeaderSubmission{Base: raw}
+		headers[i].Nonce = binary.LittleEndian.Uint32(raw[76:])
+		prev = headers[i].BlockHash()
+	}
+	return headers, submissions
+}
+
+func TestCheckSeedRun(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	headers, submissions := minedRegtestRun(t, 5)
+	const first = 100
+
+	// The first header may serve as the parent of the rest.
+	if err := checkSeedRun(params, nil, true, first, nil, headers, submissions); err != nil {
+		t.Fatalf("valid run: %v", err)
+	}
+	if err := checkSeedRun(params, nil, true, first+1, &headers[0], headers[1:], submissions[1:]); err != nil {
+		t.Fatalf("valid run with parent: %v", err)
+	}
+	if err := checkSeedRun(params, nil, true, first+2, &headers[0], headers[2:], submissions[2:]); err == nil {
+		t.Error("expected run not chaining to its parent to fail")
+	}
+
+	broken := append([]wire.BlockHeader(nil), headers...)
+	brokenSubs := append([]HeaderSubmission(nil), submissions...)
+	broken[3].PrevBlock = chainhash.Hash{1}
+	brokenSubs[3] = HeaderSubmission{Base: headerBytes(t, &broken[3])}
+	if err := checkSeedRun(params, nil, true, first, nil, broken, brokenSubs); err == nil {
+		t.Error("expected broken linkage to fail")
+	}
+
+	weak := append([]wire.BlockHeader(nil), headers...)
+	weakSubs := append([]HeaderSubmission(nil), submissions...)
+	weak[4].Bits = 0x1e0fffff
+	weakSubs[4] = HeaderSubmission{Base: headerBytes(t, &weak[4])}
+	if err := checkSeedRun(params, nil, true, first, nil, weak, weakSubs); err == nil {
+		t.Error("expected header without proof of work to fail")
+	}
+
+	match := []constants.Checkpoint{{Height: first + 2, Hash: headers[2].BlockHash().String()}}
+	if err := checkSeedRun(params, match, false, first, nil, headers, submissions); err != nil {
+		t.Errorf("matching checkpoint: %v", err)
+	}
+	mismatch := []constants.Checkpoint{{Height: first + 2, Hash: headers[1].BlockHash().String()}}
+	if err := checkSeedRun(params, mismatch, false, first, nil, headers, submissions); err == nil {
+		t.Error("expected checkpoint mismatch to fail")
+	}
+
+	// Only networks that refuse old seeds reject a run below the last
+	// checkpoint.
+	later := []constants.Checkpoint{{Height: first + 10, Hash: headers[0].BlockHash().String()}}
+	if err := checkSeedRun(params, later, true, first, nil, headers, submissions); err == nil {
+		t.Error("expected seed below the last checkpoint to fail")
+	}
+	if err := checkSeedRun(params, later, false, first, nil, headers, submissions); err != nil {
+		t.Errorf("seed below checkpoint on a test network: %v", err)
+	}
+}
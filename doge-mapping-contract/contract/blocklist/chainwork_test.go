@@ -0,0 +1,76 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// linkedHeaders returns a lookup over a chain of headers at heights
+// from..to, each committing to the hash of the one below it.
+func linkedHeaders(from, to uint32) headerLookup {
+	chain := make(map[uint32]*wire.BlockHeader)
+	prev := chainhash.Hash{}
+	for h := from; h <= to; h++ {
+		header := &wire.BlockHeader{
+			Version:   4,
+			PrevBlock: prev,
+			Timestamp: time.Unix(1700000000+int64(h)*600, 0),
+			Bits:      0x1d00ffff,
+		}
+		chain[h] = header
+		prev = header.BlockHash()
+	}
+	return func(height uint32) (*wire.BlockHeader, bool) {
+		header, ok := chain[height]
+		return header, ok
+	}
+}
+
+func TestFindForkPoint(t *testing.T) {
+	headers := linkedHeaders(100, 120)
+	hashAt := func(height uint32) *chainhash.Hash {
+		header, _ := headers(height)
+		hash := header.BlockHash()
+		return &hash
+	}
+
+	height, header, ok := findForkPoint(120, 100, hashAt(105), headers)
+	if !ok || height != 105 {
+		t.Fatalf("got %d, %v; want 105", height, ok)
+	}
+	if header.BlockHash() != *hashAt(105) {
+		t.Fatal("returned header does not match the fork point")
+	}
+
+	// The floor is inclusive.
+	if height, _, ok := findForkPoint(120, 100, hashAt(100), headers); !ok || height != 100 {
+		t.Fatalf("got %d, %v; want 100", height, ok)
+	}
+	// Nothing below the floor is considered.
+	if _, _, ok := findForkPoint(120, 106, hashAt(105), headers); ok {
+		t.Fatal("expected fork below the floor to be rejected")
+	}
+	// The tip itself is not a fork point; extending it is the normal path.
+	if _, _, ok := findForkPoint(120, 100, hashAt(120), headers); ok {
+		t.Fatal("expected the tip not to be searched")
+	}
+	// Unknown hashes are not found.
+	unknown := chainhash.HashH([]byte("unknown"))
+	if _, _, ok := findForkPoint(120, 100, &unknown, headers); ok {
+		t.Fatal("expected unknown hash not to be found")
+	}
+
+	// The search stops at the first missing header.
+	gapped := func(height uint32) (*wire.BlockHeader, bool) {
+		if height == 110 {
+			return nil, false
+		}
+		return headers(height)
+	}
+	if _, _, ok := findForkPoint(120, 100, hashAt(105), gapped); ok {
+		t.Fatal("expected search to stop at a missing header")
+	}
+}
@@ -0,0 +1,172 @@
+package blocklist
+
+import (
+	"bytes"
+	"doge-mapping-contract/sdk"
+	"math/big"
+	"strconv"
+
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+	"doge-mapping-contract/contract/network"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// checkProofOfWork is blockchain.CheckProofOfWork with the scrypt hash of
+// powHeader in place of the double-SHA256 block hash.
+func checkProofOfWork(params *network.PowParams, powHeader []byte, bits uint32) error {
+	target := blockchain.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return ce.NewContractError(ce.ErrInput, "block target difficulty is too low")
+	}
+	if target.Cmp(params.PowLimit) > 0 {
+		return ce.NewContractError(ce.ErrInput, "block target difficulty is higher than max")
+	}
+	powHash := chainhash.Hash(scryptHash(powHeader))
+	if blockchain.HashToBig(&powHash).Cmp(target) > 0 {
+		return ce.NewContractError(ce.ErrInput, "block scrypt hash is higher than expected max")
+	}
+	return nil
+}
+
+// checkAuxPowProofOfWork follows Dogecoin Core's CheckAuxPowProofOfWork: a
+// legacy header is checked on its own scrypt hash, a merged-mined one on its
+// AuxPoW commitment and the parent header's scrypt hash, both against the
+// header's own bits.
+func checkAuxPowProofOfWork(params *network.PowParams, height uint32, header *wire.BlockHeader, submission *HeaderSubmission) error {
+	if isLegacyVersion(header.Version) {
+		if height >= params.AuxpowHeight {
+			return ce.NewContractError(ce.ErrInput, "legacy block not allowed after merged mining activation")
+		}
+	} else if params.StrictChainID && versionChainID(header.Version) != dogeChainID {
+		return ce.NewContractError(ce.ErrInput, "block does not have Dogecoin's chain ID")
+	}
+
+	if submission.AuxPow == nil {
+		if isAuxpowVersion(header.Version) {
+			return ce.NewContractError(ce.ErrInput, "no auxpow on block with auxpow version")
+		}
+		return checkProofOfWork(params, submission.Base[:], header.Bits)
+	}
+	if !isAuxpowVersion(header.Version) {
+		return ce.NewContractError(ce.ErrInput, "auxpow on block with non-auxpow version")
+	}
+	if err := submission.AuxPow.check(header.BlockHash(), versionChainID(header.Version), params.StrictChainID); err != nil {
+		return err
+	}
+	return checkProofOfWork(params, submission.AuxPow.ParentHeader[:], header.Bits)
+}
+
+// headerLookup returns the stored header at height, or false if there is none.
+type headerLookup func(height uint32) (*wire.BlockHeader, bool)
+
+func loadHeader(height uint32) (*wire.BlockHeader, bool) {
+	raw := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatUint(uint64(height), 10))
+	if raw == nil || *raw == "" {
+		return nil, false
+	}
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader([]byte(*raw)), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, false
+	}
+	return &header, true
+}
+
+// calcRequiredBits returns the compact target that a header at height with
+// the given timestamp must carry, given its parent header. It follows
+// Dogecoin Core's GetNextWorkRequired after DigiShield, which retargets every
+// block from the time between the parent and grandparent.
+func calcRequiredBits(
+	params *network.PowParams,
+	height uint32,
+	prev *wire.BlockHeader,
+	timestamp int64,
+	headers headerLookup,
+) (uint32, error) {
+	if height < params.DigishieldHeight || height < 2 {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" predates DigiShield (height "+
+				strconv.FormatUint(uint64(params.DigishieldHeight), 10)+")",
+		)
+	}
+
+	// Testnet: a block more than 2 minutes after its parent may be mined at
+	// the minimum difficulty.
+	if params.AllowMinDifficulty && height-1 >= params.MinDifficultyHeight &&
+		timestamp > prev.Timestamp.Unix()+2*params.TargetSpacing {
+		return params.PowLimitBits, nil
+	}
+
+	first, ok := headers(height - 2)
+	if !ok {
+		return 0, ce.NewContractError(
+			ce.ErrStateAccess,
+			"no block header at height "+strconv.FormatUint(uint64(height-2), 10)+
+				" to retarget from, seed with parent_header",
+		)
+	}
+
+	// Amplitude filter: only an eighth of the deviation from the target
+	// spacing is applied, bounded to -25%/+50%. Integer division truncates
+	// toward zero in both Go and C++.
+	timespan := params.TargetSpacing
+	actual := prev.Timestamp.Unix() - first.Timestamp.Unix()
+	modulated := timespan + (actual-timespan)/8
+	minTimespan := timespan - timespan/4
+	maxTimespan := timespan + timespan/2
+	if modulated < minTimespan {
+		modulated = minTimespan
+	} else if modulated > maxTimespan {
+		modulated = maxTimespan
+	}
+
+	newTarget := blockchain.CompactToBig(prev.Bits)
+	newTarget.Mul(newTarget, big.NewInt(modulated))
+	newTarget.Div(newTarget, big.NewInt(timespan))
+	if newTarget.Cmp(params.PowLimit) > 0 {
+		newTarget.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(newTarget), nil
+}
+
+// checkHeader verifies a header's proof of work, including its AuxPoW, and
+// that its bits are the ones the network requires at height.
+func checkHeader(params *network.PowParams, height uint32, prev *wire.BlockHeader, header *wire.BlockHeader, submission *HeaderSubmission) error {
+	return checkHeaderWith(params, height, prev, header, submission, loadHeader)
+}
+
+func checkHeaderWith(
+	params *network.PowParams,
+	height uint32,
+	prev *wire.BlockHeader,
+	header *wire.BlockHeader,
+	submission *HeaderSubmission,
+	headers headerLookup,
+) error {
+	heightStr := strconv.FormatUint(uint64(height), 10)
+	if err := checkAuxPowProofOfWork(params, height, header, submission); err != nil {
+		return ce.Prepend(err, "block "+heightStr+" failed PoW check")
+	}
+
+	// Regtest never retargets; its headers are only bounded by PowLimit.
+	if params.NoRetargeting {
+		return nil
+	}
+
+	expected, err := calcRequiredBits(params, height, prev, header.Timestamp.Unix(), headers)
+	if err != nil {
+		return err
+	}
+	if header.Bits != expected {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+heightStr+" has unexpected difficulty bits "+
+				strconv.FormatUint(uint64(header.Bits), 16)+", expected "+strconv.FormatUint(uint64(expected), 16),
+		)
+	}
+	return nil
+}
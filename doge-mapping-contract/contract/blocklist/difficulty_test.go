@@ -0,0 +1,153 @@
+package blocklist
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"doge-mapping-contract/contract/constants"
+	"doge-mapping-contract/contract/network"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// testPowParams returns a copy of the named network's PoW params, which a
+// test may change freely.
+func testPowParams(name string) *network.PowParams {
+	n, _ := network.Lookup(name)
+	params := *n.Pow
+	return &params
+}
+
+func grandparentAt(t *testing.T, want uint32, timestamp int64) headerLookup {
+	return func(height uint32) (*wire.BlockHeader, bool) {
+		if height != want {
+			t.Fatalf("unexpected header lookup %d, want %d", height, want)
+		}
+		return &wire.BlockHeader{Timestamp: time.Unix(timestamp, 0)}, true
+	}
+}
+
+func noHeaders(uint32) (*wire.BlockHeader, bool) { return nil, false }
+
+func TestRequiredBitsDigishield(t *testing.T) {
+	params := testPowParams(constants.Mainnet)
+	const height = 5000000
+	const prevTime = 1700000000
+
+	cases := []struct {
+		name   string
+		actual int64
+		bits   uint32
+		want   uint32
+	}{
+		{"on target", 60, 0x1b0404cb, 0x1b0404cb},
+		{"fast block", 0, 0x1b0404cb, 0x1b038cc4},
+		{"deviation below 8s truncates to zero", 53, 0x1b0404cb, 0x1b0404cb},
+		{"deviation of 8s", 52, 0x1b0404cb, 0x1b03f3a5},
+		{"slow block", 120, 0x1b0404cb, 0x1b047cd1},
+		{"out-of-order timestamps clamped to -25%", -100, 0x1b0404cb, 0x1b030398},
+		{"very slow block clamped to +50%", 1000, 0x1b0404cb, 0x1b060730},
+		{"capped at PowLimit", 1000, 0x1e0fffff, 0x1e0fffff},
+	}
+	for _, c := range cases {
+		prev := &wire.BlockHeader{Bits: c.bits, Timestamp: time.Unix(prevTime, 0)}
+		got, err := calcRequiredBits(params, height, prev, prevTime+60, grandparentAt(t, height-2, prevTime-c.actual))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %08x, want %08x", c.name, got, c.want)
+		}
+	}
+
+	prev := &wire.BlockHeader{Bits: 0x1b0404cb, Timestamp: time.Unix(prevTime, 0)}
+
+	// Mainnet never allows min-difficulty blocks.
+	got, err := calcRequiredBits(params, height, prev, prevTime+3600, grandparentAt(t, height-2, prevTime-60))
+	if err != nil || got != 0x1b0404cb {
+		t.Fatalf("got %08x, %v; want 1b0404cb", got, err)
+	}
+
+	// The grandparent must be stored.
+	if _, err := calcRequiredBits(params, height, prev, prevTime+60, noHeaders); err == nil {
+		t.Fatal("expected missing header error")
+	}
+
+	// Heights before DigiShield are not supported.
+	if _, err := calcRequiredBits(params, params.DigishieldHeight-1, prev, prevTime+60, noHeaders); err == nil {
+		t.Fatal("expected pre-DigiShield height to be rejected")
+	}
+}
+
+func TestRequiredBitsTestnetMinDifficulty(t *testing.T) {
+	params := testPowParams(constants.Testnet)
+	const prevTime = 1700000000
+	prev := &wire.BlockHeader{Bits: 0x1c0404cb, Timestamp: time.Unix(prevTime, 0)}
+
+	// More than 2 minutes after the parent: min difficulty.
+	got, err := calcRequiredBits(params, params.MinDifficultyHeight+1, prev, prevTime+121, noHeaders)
+	if err != nil || got != params.PowLimitBits {
+		t.Fatalf("got %08x, %v; want PowLimitBits", got, err)
+	}
+	// Otherwise DigiShield applies as usual.
+	got, err = calcRequiredBits(params, params.MinDifficultyHeight+1, prev, prevTime+120,
+		grandparentAt(t, params.MinDifficultyHeight-1, prevTime-60))
+	if err != nil || got != 0x1c0404cb {
+		t.Fatalf("got %08x, %v; want 1c0404cb", got, err)
+	}
+	// Before the rule activated, slow blocks are retargeted normally.
+	got, err = calcRequiredBits(params, params.MinDifficultyHeight, prev, prevTime+121,
+		grandparentAt(t, params.MinDifficultyHeight-2, prevTime-60))
+	if err != nil || got != 0x1c0404cb {
+		t.Fatalf("got %08x, %v; want 1c0404cb", got, err)
+	}
+}
+
+func TestCheckHeaderEnforcesBits(t *testing.T) {
+	// Mainnet rules at regtest's PowLimit, so headers are cheap to mine.
+	params := testPowParams(constants.Mainnet)
+	params.PowLimit = testPowParams(constants.Regtest).PowLimit
+	params.PowLimitBits = regtestBits
+	const height = 5000000
+
+	header := auxpowTestHeader()
+	submission := &HeaderSubmission{Base: headerBytes(t, header), AuxPow: buildAuxPow(t, header, 1, nil)}
+	prev := &wire.BlockHeader{Bits: regtestBits, Timestamp: header.Timestamp.Add(-time.Minute)}
+
+	// A slow parent keeps the target at the limit.
+	slow := grandparentAt(t, height-2, prev.Timestamp.Unix()-600)
+	if err := checkHeaderWith(params, height, prev, header, submission, slow); err != nil {
+		t.Fatal(err)
+	}
+	// A fast parent lowers it, so the header's bits are stale.
+	fast := grandparentAt(t, height-2, prev.Timestamp.Unix())
+	if err := checkHeaderWith(params, height, prev, header, submission, fast); err == nil {
+		t.Fatal("expected unexpected-bits failure")
+	}
+}
+
+func TestRegtestSkipsRetarget(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	header := auxpowTestHeader()
+	header.Version = 1
+	raw := headerBytes(t, header)
+	mineScrypt(raw[:], regtestBits)
+	header.Nonce = binary.LittleEndian.Uint32(raw[76:])
+
+	prev := &wire.BlockHeader{Bits: 0x1b0404cb, Timestamp: header.Timestamp}
+	if err := checkHeaderWith(params, 1, prev, header, &HeaderSubmission{Base: raw}, noHeaders); err != nil {
+		t.Fatalf("regtest should only check PoW: %v", err)
+	}
+
+	// PoW is still checked.
+	raw[76]++
+	header.Nonce++
+	for checkProofOfWork(params, raw[:], regtestBits) == nil {
+		raw[76]++
+		header.Nonce++
+	}
+	if err := checkHeaderWith(params, 1, prev, header, &HeaderSubmission{Base: raw}, noHeaders); err == nil {
+		t.Fatal("expected PoW failure")
+	}
+}
@@ -0,0 +1,248 @@
+package blocklist
+
+import (
+	"doge-mapping-contract/sdk"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// The header archive is a Merkle Mountain Range over pruned blocks, leaf i
+// being the block at the archive's start height plus i. A leaf commits to the
+// block hash and to the block's observed tx list, which is pruned with the
+// header. Headers are archived in height order as they are pruned, and a
+// header is only archived once it leaves the retention window, so the archive
+// never holds a block a reorg could still replace. Only its peaks are stored;
+// a proof is the sibling path from a leaf up to the peak of its mountain.
+// Mapping from a pruned block rewrites its leaf, so every archived observed
+// list is logged for proofs to be rebuilt from.
+
+// headerMMR is the state of the header archive: the height of its first
+// leaf, the number of leaves and one peak per set bit of it, the largest
+// mountain first.
+type headerMMR struct {
+	start  uint32
+	leaves uint64
+	peaks  []chainhash.Hash
+}
+
+// headerMMRFixedSize is the encoded size of start and leaves.
+const headerMMRFixedSize = 4 + 8
+
+func loadHeaderMMR() (*headerMMR, error) {
+	raw := sdk.StateGetObject(constants.HeaderMMRKey)
+	if raw == nil || *raw == "" {
+		return &headerMMR{}, nil
+	}
+	data := []byte(*raw)
+	if len(data) < headerMMRFixedSize {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "invalid header archive length")
+	}
+	m := &headerMMR{
+		start:  binary.BigEndian.Uint32(data[:4]),
+		leaves: binary.BigEndian.Uint64(data[4:headerMMRFixedSize]),
+	}
+	data = data[headerMMRFixedSize:]
+	if len(data) != bits.OnesCount64(m.leaves)*chainhash.HashSize {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "invalid header archive length")
+	}
+	m.peaks = make([]chainhash.Hash, len(data)/chainhash.HashSize)
+	for i := range m.peaks {
+		copy(m.peaks[i][:], data[i*chainhash.HashSize:])
+	}
+	return m, nil
+}
+
+func (m *headerMMR) save() {
+	data := make([]byte, headerMMRFixedSize, headerMMRFixedSize+len(m.peaks)*chainhash.HashSize)
+	binary.BigEndian.PutUint32(data, m.start)
+	binary.BigEndian.PutUint64(data[4:], m.leaves)
+	for i := range m.peaks {
+		data = append(data, m.peaks[i][:]...)
+	}
+	sdk.StateSetObject(constants.HeaderMMRKey, string(data))
+}
+
+// archive adds the leaf of the pruned block at height, if it is the next one
+// the archive expects. The first archived block sets its start.
+func (m *headerMMR) archive(height uint32, leaf chainhash.Hash) bool {
+	if m.leaves == 0 {
+		m.start = height
+	} else if uint64(height) != uint64(m.start)+m.leaves {
+		return false
+	}
+	m.append(leaf)
+	return true
+}
+
+// append adds a leaf, merging equal-sized mountains into their parent.
+func (m *headerMMR) append(leaf chainhash.Hash) {
+	m.peaks = append(m.peaks, leaf)
+	for n := m.leaves; n&1 == 1; n >>= 1 {
+		last := len(m.peaks) - 1
+		m.peaks[last-1] = mmrParent(m.peaks[last-1], m.peaks[last])
+		m.peaks = m.peaks[:last]
+	}
+	m.leaves++
+}
+
+// verify reports whether proof links leaf at index to the peak of the
+// mountain holding it.
+func (m *headerMMR) verify(index uint64, leaf chainhash.Hash, proof []chainhash.Hash) bool {
+	peak, ok := m.peakOf(index, len(proof))
+	if !ok {
+		return false
+	}
+	root := mmrRoot(leaf, index, proof)
+	return root.IsEqual(&m.peaks[peak])
+}
+
+// update replaces leaf at index with newLeaf, given the proof of leaf. The
+// proofs of the other leaves under the same peak change with it.
+func (m *headerMMR) update(index uint64, leaf, newLeaf chainhash.Hash, proof []chainhash.Hash) bool {
+	if !m.verify(index, leaf, proof) {
+		return false
+	}
+	peak, _ := m.peakOf(index, len(proof))
+	m.peaks[peak] = mmrRoot(newLeaf, index, proof)
+	return true
+}
+
+// peakOf returns the position of the peak of the mountain holding leaf
+// index, and whether a proof of proofLen siblings reaches it.
+func (m *headerMMR) peakOf(index uint64, proofLen int) (int, bool) {
+	if index >= m.leaves {
+		return 0, false
+	}
+	var offset uint64
+	peak := 0
+	for height := 63; height >= 0; height-- {
+		size := uint64(1) << uint(height)
+		if m.leaves&size == 0 {
+			continue
+		}
+		if index >= offset+size {
+			offset += size
+			peak++
+			continue
+		}
+		return peak, proofLen == height
+	}
+	return 0, false
+}
+
+// mmrRoot folds leaf at index up its sibling path. A mountain starts at a
+// multiple of its size, so the low bits of index give the side of each node.
+func mmrRoot(leaf chainhash.Hash, index uint64, proof []chainhash.Hash) chainhash.Hash {
+	node := leaf
+	for _, sibling := range proof {
+		if index%2 == 0 {
+			node = mmrParent(node, sibling)
+		} else {
+			node = mmrParent(sibling, node)
+		}
+		index /= 2
+	}
+	return node
+}
+
+func mmrParent(left, right chainhash.Hash) chainhash.Hash {
+	var combined [2 * chainhash.HashSize]byte
+	copy(combined[:chainhash.HashSize], left[:])
+	copy(combined[chainhash.HashSize:], right[:])
+	return chainhash.DoubleHashH(combined[:])
+}
+
+// IsPruned reports whether the header at height has already been pruned.
+func IsPruned(height uint32) bool {
+	return height < pruneFloorFromState()
+}
+
+// archiveLeaf returns the archive leaf of a block: its hash paired with the
+// hash of its packed observed tx list.
+func archiveLeaf(blockHash chainhash.Hash, observed []byte) chainhash.Hash {
+	return mmrParent(blockHash, chainhash.DoubleHashH(observed))
+}
+
+// archivedIndex loads the header archive and returns the leaf index of the
+// block at height.
+func archivedIndex(height uint32) (*headerMMR, uint64, error) {
+	archive, err := loadHeaderMMR()
+	if err != nil {
+		return nil, 0, err
+	}
+	if height < archive.start || uint64(height-archive.start) >= archive.leaves {
+		return nil, 0, ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" is not in the header archive",
+		)
+	}
+	return archive, uint64(height - archive.start), nil
+}
+
+// VerifyArchivedHeader checks that header is the pruned block at height and
+// observed the packed observed tx list archived with it, given the sibling
+// path from their leaf to the peak of its mountain in the header archive.
+func VerifyArchivedHeader(height uint32, header *wire.BlockHeader, observed []byte, proof []chainhash.Hash) error {
+	archive, index, err := archivedIndex(height)
+	if err != nil {
+		return err
+	}
+	if !archive.verify(index, archiveLeaf(header.BlockHash(), observed), proof) {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" does not match the header archive",
+		)
+	}
+	return nil
+}
+
+// UpdateArchivedObserved replaces the observed tx list archived with the
+// pruned block at height by newObserved, given the proof VerifyArchivedHeader
+// accepted for observed.
+func UpdateArchivedObserved(height uint32, header *wire.BlockHeader, observed, newObserved []byte, proof []chainhash.Hash) error {
+	archive, index, err := archivedIndex(height)
+	if err != nil {
+		return err
+	}
+	hash := header.BlockHash()
+	if !archive.update(index, archiveLeaf(hash, observed), archiveLeaf(hash, newObserved), proof) {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+strconv.FormatUint(uint64(height), 10)+" does not match the header archive",
+		)
+	}
+	archive.save()
+	sdk.Log(createArchiveLog(height, newObserved))
+	return nil
+}
+
+// createArchiveLog records the observed tx list archived with the pruned
+// block at height, hex encoded.
+func createArchiveLog(height uint32, observed []byte) string {
+	var b strings.Builder
+	b.Grow(32 + 2*len(observed))
+	b.WriteString("archive")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("h")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(height), 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("o")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(hex.EncodeToString(observed))
+	return b.String()
+}
+
+// rawHeaderHash returns the block hash of a stored 80-byte header.
+func rawHeaderHash(raw []byte) chainhash.Hash {
+	return chainhash.DoubleHashH(raw)
+}
@@ -0,0 +1,160 @@
+package blocklist
+
+import (
+	"doge-mapping-contract/sdk"
+	"encoding/binary"
+	"slices"
+	"strconv"
+
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// With an oracle set configured, addBlocks does not apply a batch as soon as
+// it arrives. Each member's latest batch is staged under its address, and the
+// batch is applied once the threshold of members have staged the same
+// headers. Identical headers chain from the same parent, so they cover the
+// same height range.
+
+// OracleSet is the owner-managed set of oracles allowed to submit headers,
+// and how many of them must agree before a batch is applied.
+type OracleSet struct {
+	Oracles   []string
+	Threshold uint8
+}
+
+// LoadOracleSet returns the configured oracle set, or nil when none is set
+// and addBlocks takes the single oracle address.
+func LoadOracleSet() (*OracleSet, error) {
+	raw := sdk.StateGetObject(constants.OracleSetKey)
+	if raw == nil || *raw == "" {
+		return nil, nil
+	}
+	data := []byte(*raw)
+	set := &OracleSet{Threshold: data[0]}
+	data = data[1:]
+	for len(data) > 0 {
+		n := int(data[0])
+		if len(data) < 1+n {
+			return nil, ce.NewContractError(ce.ErrStateAccess, "invalid oracle set length")
+		}
+		set.Oracles = append(set.Oracles, string(data[1:1+n]))
+		data = data[1+n:]
+	}
+	if set.Threshold == 0 || int(set.Threshold) > len(set.Oracles) {
+		return nil, ce.NewContractError(ce.ErrStateAccess, "invalid oracle set threshold")
+	}
+	return set, nil
+}
+
+// Has reports whether address is a member of the set.
+func (s *OracleSet) Has(address string) bool {
+	return slices.Contains(s.Oracles, address)
+}
+
+func (s *OracleSet) save() {
+	data := []byte{s.Threshold}
+	for _, oracle := range s.Oracles {
+		data = append(data, byte(len(oracle)))
+		data = append(data, oracle...)
+	}
+	sdk.StateSetObject(constants.OracleSetKey, string(data))
+}
+
+// HandleSetOracles replaces the oracle set, or removes it when params lists
+// no oracles. Batches staged by the previous set are dropped.
+func HandleSetOracles(params SetOraclesParams) error {
+	if len(params.Oracles) > constants.MaxOracles {
+		return ce.NewContractError(ce.ErrInput, "at most "+strconv.Itoa(constants.MaxOracles)+" oracles")
+	}
+	for i, oracle := range params.Oracles {
+		if oracle == "" || len(oracle) > 255 {
+			return ce.NewContractError(ce.ErrInput, "invalid oracle address")
+		}
+		if slices.Contains(params.Oracles[:i], oracle) {
+			return ce.NewContractError(ce.ErrInput, "duplicate oracle "+oracle)
+		}
+	}
+	if len(params.Oracles) > 0 && (params.Threshold < 1 || int(params.Threshold) > len(params.Oracles)) {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"threshold must be between 1 and "+strconv.Itoa(len(params.Oracles)),
+		)
+	}
+
+	old, err := LoadOracleSet()
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		for _, oracle := range old.Oracles {
+			sdk.StateDeleteObject(constants.OracleSubmissionPrefix + oracle)
+		}
+	}
+	if len(params.Oracles) == 0 {
+		sdk.StateDeleteObject(constants.OracleSetKey)
+		return nil
+	}
+	set := &OracleSet{Oracles: params.Oracles, Threshold: uint8(params.Threshold)}
+	set.save()
+	return nil
+}
+
+// StageHeaders records headers and fee as the latest batch from oracle. Once
+// the threshold of members have staged the same headers it clears their
+// submissions and returns their fees with true. Otherwise it returns false
+// and the number of members that have staged these headers.
+func (s *OracleSet) StageHeaders(oracle string, headers []HeaderSubmission, fee int64) ([]int64, int, bool) {
+	digest := batchDigest(headers)
+
+	var submission [chainhash.HashSize + 8]byte
+	copy(submission[:], digest[:])
+	binary.BigEndian.PutUint64(submission[chainhash.HashSize:], uint64(fee))
+	sdk.StateSetObject(constants.OracleSubmissionPrefix+oracle, string(submission[:]))
+
+	var agreeing []string
+	var fees []int64
+	for _, member := range s.Oracles {
+		raw := sdk.StateGetObject(constants.OracleSubmissionPrefix + member)
+		if raw == nil || len(*raw) != len(submission) {
+			continue
+		}
+		staged := []byte(*raw)
+		if !digest.IsEqual((*chainhash.Hash)(staged[:chainhash.HashSize])) {
+			continue
+		}
+		agreeing = append(agreeing, member)
+		fees = append(fees, int64(binary.BigEndian.Uint64(staged[chainhash.HashSize:])))
+	}
+	if len(agreeing) < int(s.Threshold) {
+		return nil, len(agreeing), false
+	}
+	for _, member := range agreeing {
+		sdk.StateDeleteObject(constants.OracleSubmissionPrefix + member)
+	}
+	return fees, len(agreeing), true
+}
+
+// batchDigest commits to the base headers of a batch in order. The AuxPoW
+// proofs are left out: they do not change the block hashes being agreed on.
+func batchDigest(headers []HeaderSubmission) chainhash.Hash {
+	data := make([]byte, 0, len(headers)*len(BlockHeaderBytes{}))
+	for i := range headers {
+		data = append(data, headers[i].Base[:]...)
+	}
+	return chainhash.DoubleHashH(data)
+}
+
+// MedianFee returns the median of the fees the agreeing oracles submitted,
+// the mean of the middle two when there is an even number of them.
+func MedianFee(fees []int64) int64 {
+	sorted := slices.Clone(fees)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return sorted[mid-1] + (sorted[mid]-sorted[mid-1])/2
+}
@@ -0,0 +1,42 @@
+package blocklist
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"doge-mapping-contract/contract/constants"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Dogecoin mainnet genesis, hash 1a91e3dace36e2be3bf030a65679fe821aa1d6ef92e7c9902eb318182c355691.
+const dogeGenesisHeader = "010000000000000000000000000000000000000000000000000000000000000000000000696ad20e2dd4365c7459b4a4a5af743d5e92c6da3229e6532cd605f6533f2a5b24a6a152f0ff0f1e67860100"
+
+func TestScryptHashGenesis(t *testing.T) {
+	header, _ := hex.DecodeString(dogeGenesisHeader)
+	got := chainhash.Hash(scryptHash(header))
+	if got.String() != "0000026f3f7874ca0c251314eaed2d2fcf83d7da3acfaacf59417d485310b448" {
+		t.Fatalf("got %s", got)
+	}
+	if err := checkProofOfWork(testPowParams(constants.Mainnet), header, 0x1e0ffff0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScryptHashMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	header := make([]byte, 80)
+	for i := 0; i < 20; i++ {
+		r.Read(header)
+		want, err := scrypt.Key(header, header, 1024, 1, 1, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := scryptHash(header)
+		if hex.EncodeToString(got[:]) != hex.EncodeToString(want) {
+			t.Fatalf("mismatch for %x", header)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package blocklist
+
+import (
+	"doge-mapping-contract/sdk"
+	"slices"
+	"strconv"
+	"time"
+
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// medianTimeSpan is the number of headers, ending with the parent, whose
+// median timestamp a new header must exceed (Dogecoin Core's nMedianTimeSpan).
+const medianTimeSpan = 11
+
+// hiveTimeLayout is the format of the Hive block timestamp in the contract
+// environment, always UTC.
+const hiveTimeLayout = "2006-01-02T15:04:05"
+
+// medianTimePast returns the median timestamp of the 11 headers ending with
+// prev at prevHeight. It returns false while fewer are stored, as just after a
+// seed: a median over a shorter window could reject a valid header.
+func medianTimePast(prevHeight uint32, prev *wire.BlockHeader, headers headerLookup) (int64, bool) {
+	if prevHeight < medianTimeSpan-1 {
+		return 0, false
+	}
+	times := make([]int64, 0, medianTimeSpan)
+	times = append(times, prev.Timestamp.Unix())
+	for i := uint32(1); i < medianTimeSpan; i++ {
+		header, ok := headers(prevHeight - i)
+		if !ok {
+			return 0, false
+		}
+		times = append(times, header.Timestamp.Unix())
+	}
+	slices.Sort(times)
+	return times[len(times)/2], true
+}
+
+// checkTimestamp rejects a header at height whose timestamp is not after the
+// median time past of its parent, or is later than maxTime when it is set.
+func checkTimestamp(
+	height uint32,
+	prev *wire.BlockHeader,
+	header *wire.BlockHeader,
+	headers headerLookup,
+	maxTime int64,
+) error {
+	heightStr := strconv.FormatUint(uint64(height), 10)
+	timestamp := header.Timestamp.Unix()
+	if mtp, ok := medianTimePast(height-1, prev, headers); ok && timestamp <= mtp {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+heightStr+" timestamp "+strconv.FormatInt(timestamp, 10)+
+				" is not after the median time past "+strconv.FormatInt(mtp, 10),
+		)
+	}
+	if maxTime > 0 && timestamp > maxTime {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+heightStr+" timestamp "+strconv.FormatInt(timestamp, 10)+
+				" is too far in the future, max "+strconv.FormatInt(maxTime, 10),
+		)
+	}
+	return nil
+}
+
+// maxHeaderTime returns the latest header timestamp allowed by the owner-set
+// future drift past the Hive block time, or 0 if no drift bound is set.
+func maxHeaderTime() (int64, error) {
+	s := sdk.StateGetObject(constants.MaxFutureDriftKey)
+	if s == nil || *s == "" {
+		return 0, nil
+	}
+	drift, err := strconv.ParseUint(*s, 10, 32)
+	if err != nil || drift == 0 {
+		return 0, nil
+	}
+	now, err := time.Parse(hiveTimeLayout, sdk.GetEnv().Timestamp)
+	if err != nil {
+		return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error parsing hive block timestamp")
+	}
+	return now.Unix() + int64(drift), nil
+}
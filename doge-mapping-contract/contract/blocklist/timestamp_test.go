@@ -0,0 +1,53 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// timestampHistory returns the parent at height 99 and a lookup for the
+// headers below it, with the given timestamps from height 99 down.
+func timestampHistory(times ...int64) (*wire.BlockHeader, headerLookup) {
+	headers := make(map[uint32]*wire.BlockHeader)
+	for i, ts := range times {
+		headers[99-uint32(i)] = &wire.BlockHeader{Timestamp: time.Unix(ts, 0)}
+	}
+	return headers[99], func(height uint32) (*wire.BlockHeader, bool) {
+		header, ok := headers[height]
+		return header, ok
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	// Out of order, as real timestamps may be; the median is 1005.
+	prev, headers := timestampHistory(1010, 1001, 1009, 1002, 1008, 1003, 1007, 1004, 1006, 1005, 1000)
+	header := func(ts int64) *wire.BlockHeader {
+		return &wire.BlockHeader{Timestamp: time.Unix(ts, 0)}
+	}
+
+	if err := checkTimestamp(100, prev, header(1006), headers, 0); err != nil {
+		t.Errorf("timestamp after the median: %v", err)
+	}
+	// A header may be older than its parent, just not the median.
+	if err := checkTimestamp(100, prev, header(1005), headers, 0); err == nil {
+		t.Error("expected timestamp equal to the median to fail")
+	}
+	if err := checkTimestamp(100, prev, header(999), headers, 0); err == nil {
+		t.Error("expected timestamp below the median to fail")
+	}
+
+	if err := checkTimestamp(100, prev, header(2000), headers, 2000); err != nil {
+		t.Errorf("timestamp at the drift bound: %v", err)
+	}
+	if err := checkTimestamp(100, prev, header(2001), headers, 2000); err == nil {
+		t.Error("expected timestamp past the drift bound to fail")
+	}
+
+	// With fewer than 11 headers stored there is no median to check.
+	prev, short := timestampHistory(1010, 1009, 1008)
+	if err := checkTimestamp(100, prev, header(1000), short, 0); err != nil {
+		t.Errorf("short history: %v", err)
+	}
+}
@@ -22,7 +22,8 @@ const BalancePrefix = "a" + DirPathDelimiter
 
 // ObservedBlockPrefix stores the list of observed txid:vout pairs for a given
 // block height. Key: "o-<height>", Value: packed 34-byte entries (32-byte txid
-// + 2-byte vout BE). Pruned alongside block headers during addBlocks.
+// + 2-byte vout BE). Kept when the block header is pruned, so a deposit proven
+// against the header archive cannot be minted twice.
 const ObservedBlockPrefix = "o" + DirPathDelimiter
 const UtxoPrefix = "u" + DirPathDelimiter
 const UtxoRegistryKey = "r"
@@ -31,10 +32,61 @@ const TxSpendsRegistryKey = "p"
 const TxSpendsPrefix = "d" + DirPathDelimiter
 const SupplyKey = "s"
 
+// MintJournalPrefix stores the deposits minted against a block height, so they
+// can be reversed if the block is orphaned. Key: "mj-<height>", Value: packed
+// mint records. Pruned alongside block headers.
+const MintJournalPrefix = "mj" + DirPathDelimiter
+
+// OrphanedMintsKey stores the mints from orphaned blocks awaiting a new proof.
+// Any not re-proven by their deadline height are clawed back.
+const OrphanedMintsKey = "om"
+
+// MintDeficitKey stores the total sats of orphaned mints that could not be
+// clawed back because the recipient no longer held the balance (decimal int64).
+const MintDeficitKey = "mdef"
+
+// MintReproveWindow is the number of blocks past a reorg within which a
+// deposit from an orphaned block may be re-proven before it is clawed back:
+// about an hour of blocks.
+const MintReproveWindow uint32 = 60
+
+// DepositFinalityKey stores the owner-set number of blocks a deposit waits
+// in the pending table before it is credited (decimal uint32). Unset or 0
+// credits deposits as soon as they are proven.
+const DepositFinalityKey = "dfin"
+
+// PendingDepositPrefix stores a proven deposit that is not yet spendable.
+// Key: "pd-<txid>:<vout>", Value: 4-byte BE ready height || 4-byte BE proof
+// height || packed mint record.
+const PendingDepositPrefix = "pd" + DirPathDelimiter
+
+// PendingDepositQueueKey lists the pending deposits in the order they were
+// proven. Value: packed 4-byte BE ready height || 34-byte observed entry.
+const PendingDepositQueueKey = "pdq"
+
+// MaxFinalizePerCall limits how many pending deposits are credited in a
+// single finalizeDeposits or addBlocks call.
+const MaxFinalizePerCall = 50
+
+// CoinbaseMaturity is the number of blocks the tip must be past a coinbase
+// before a deposit paid by it is credited, matching the chain's own rule for
+// spending coinbase outputs.
+const CoinbaseMaturity uint32 = 240
+
 const LastHeightKey = "h"
 const SeedHeightKey = "sh"
 const PruneFloorKey = "pf" // lowest unpruned block height, updated during pruning
 
+// MaxFutureDriftKey stores the owner-set number of seconds a header's
+// timestamp may be ahead of the Hive block time (decimal uint32). Unset or 0
+// skips the check.
+const MaxFutureDriftKey = "mfd"
+
+// HeaderMMRKey stores the header archive, a Merkle Mountain Range over the
+// hashes of pruned block headers. Value: 4-byte BE height of the first leaf ||
+// 8-byte BE leaf count || 32-byte peaks, largest mountain first.
+const HeaderMMRKey = "hmmr"
+
 // BTC-C3: per-Hive-block withdrawal rate limit. The accumulator tracks
 // total sats deducted by HandleUnmap within a single Hive L1 block;
 // when MaxUnmapPerBlock is positive, HandleUnmap rejects any unmap
@@ -48,10 +100,91 @@ const PruneFloorKey = "pf" // lowest unpruned block height, updated during pruni
 const DefaultMaxUnmapPerBlock int64 = 100_000_000 // 1 DOGE in dogetoshis
 const MaxUnmapPerBlockKey = "muxb"
 
+// WithdrawalQueueModeKey stores whether unmaps are queued ("1") for
+// settleWithdrawals instead of each being sent in its own transaction.
+const WithdrawalQueueModeKey = "wqm"
+
+// WithdrawalQueueKey lists the queued withdrawals in the order they were
+// made. Value: packed entries of 8-byte BE withdrawal id || 8-byte BE payout
+// || 8-byte BE VSC fee || 8-byte BE max fee (-1 for none) || 1-byte length +
+// from || 1-byte length + destination.
+const WithdrawalQueueKey = "wq"
+
+// WithdrawalPrefix stores the record of each withdrawal, through which its
+// status can be queried. Key: "wd-<id>", Value: 1-byte status || 8-byte BE
+// amount deducted || 8-byte BE amount sent || 8-byte BE Hive height requested
+// || 8-byte BE Hive height last updated || 4-byte BE confirmation height ||
+// 1-byte length + from || 1-byte length + destination || 1-byte length +
+// txid.
+const WithdrawalPrefix = "wd" + DirPathDelimiter
+
+// WithdrawalLastIdKey stores the id of the latest withdrawal (decimal
+// uint64). Ids start at 1.
+const WithdrawalLastIdKey = "wdi"
+
+// WithdrawalTimeoutKey stores the owner-set number of Hive blocks a
+// withdrawal must go without a change of status before it can be cancelled
+// (decimal uint64). Unset uses DefaultWithdrawalTimeout, about a day.
+const WithdrawalTimeoutKey = "wto"
+const DefaultWithdrawalTimeout uint64 = 28800
+
+// CancelledSpendPrefix marks each version of a cancelled spend that may still
+// confirm, so that a late confirmation of it is reconciled. Key: "x-<txid>",
+// Value: 8-byte BE input total || 8-byte BE change || 1-byte version count ||
+// 2-byte BE input count || per version, 1-byte length + txid, 2-byte BE fee
+// charge count and per charge, 1-byte length + account and 8-byte BE amount ||
+// per input, 32-byte hash + 4-byte BE index || per withdrawal paid, 8-byte BE
+// id || 8-byte BE VSC fee.
+const CancelledSpendPrefix = "x" + DirPathDelimiter
+
+// CancelledInputPrefix indexes the cancelled spends that may still confirm by
+// the outputs they spend, so that a confirmed spend of one clears them. Key:
+// "xc-<txid>:<vout>", Value: comma-separated txids of the latest versions.
+const CancelledInputPrefix = "xc" + DirPathDelimiter
+
+// SpentInputPrefix marks an output a pending spend spends that cannot be
+// restored if that spend is cancelled: an input of a cancelled spend that
+// confirmed after all, or an orphaned deposit clawed back. Key:
+// "xi-<txid>:<vout>", Value: "1".
+const SpentInputPrefix = "xi" + DirPathDelimiter
+
+// MaxQueuedWithdrawals bounds the withdrawal queue, and MaxSettlePerCall how
+// many queued withdrawals one settleWithdrawals pays.
+const (
+	MaxQueuedWithdrawals = 100
+	MaxSettlePerCall     = 50
+)
+
+// CpfpPayerKey stores who pays the fee of a cpfp child transaction, and the
+// fee a bumpFee replacement adds:
+// CpfpPayerFees, the default, takes it from the protocol's fee supply, and
+// CpfpPayerWithdrawers from the withdrawals the stuck spend pays, in
+// proportion to their amounts.
+const CpfpPayerKey = "cpfpp"
+
+const (
+	CpfpPayerFees        = "fees"
+	CpfpPayerWithdrawers = "withdrawers"
+)
+
 // BlockUnmapAccKey stores the per-block unmap accumulator: 16 bytes
 // = uint64 BE Hive block height || uint64 BE accumulated sats.
 const BlockUnmapAccKey = "buac"
 
+// MinConfirmationsPrefix stores the owner-set minimum confirmation depth of
+// a proof-verifying action. Key: "mc-<action>", Value: decimal uint32. A
+// block at the tip has 1 confirmation; proofs against shallower blocks are
+// rejected. Unset actions use DefaultMinConfirmations, which accepts any
+// stored block.
+const MinConfirmationsPrefix = "mc" + DirPathDelimiter
+const DefaultMinConfirmations uint32 = 1
+
+// Actions with a configurable minimum confirmation depth.
+const (
+	MinConfirmationsMap          = "map"
+	MinConfirmationsConfirmSpend = "confirmSpend"
+)
+
 // Instruction URL search param keys
 const (
 	DepositToKey        = "deposit_to"
@@ -64,6 +197,17 @@ const (
 const BackupCSVBlocks = 43200 // ~1 month at DOGE 1-min block time
 const TestnetBackupCSVBlocks = 2
 
+// Script hash modes for deposit and change outputs. P2WSH outputs are spent
+// with a witness; P2SH outputs, for chains without segwit, with a scriptSig.
+const (
+	ScriptHashP2WSH string = "p2wsh"
+	ScriptHashP2SH  string = "p2sh"
+)
+
+// ScriptHashMode is the output type of deposit and change addresses. DOGE
+// has no segwit, so they are legacy P2SH.
+const ScriptHashMode = ScriptHashP2SH
+
 // Logs
 const (
 	LogDelimiter      = "|"
@@ -78,7 +222,7 @@ const MigrateVersionKey = "mv" // current migration version (decimal string)
 
 // LatestMigrateVersion is the newest migration version. Set this in init/seed
 // so freshly deployed contracts skip all migrations.
-const LatestMigrateVersion = "1"
+const LatestMigrateVersion = "2"
 
 // Old format constants (pre-migration)
 const (
@@ -86,22 +230,61 @@ const (
 )
 
 const OracleAddress = "did:vsc:oracle:doge"
+
+// OracleSetKey stores the owner-managed oracle set. Once set, addBlocks
+// applies a batch only after the threshold of members have submitted the
+// same headers. Value: 1-byte threshold || per oracle, 1-byte length ||
+// address.
+const OracleSetKey = "oset"
+
+// OracleSubmissionPrefix stores each oracle's latest staged batch.
+// Key: "osub-<address>", Value: 32-byte batch digest || 8-byte BE fee.
+const OracleSubmissionPrefix = "osub" + DirPathDelimiter
+
+// MaxOracles limits the size of the oracle set.
+const MaxOracles = 16
+
 const PrimaryPublicKeyStateKey = "pubkey"
 const BackupPublicKeyStateKey = "backupkey"
 
 const BlockPrefix = "b" + DirPathDelimiter
 
-// MaxBaseFeeRate caps the base fee rate at 500 sats/vbyte.
-// Pentest finding BTC-C6: the previous 1000 sat/vbyte ceiling
-// only protected against int overflow — within that range a
-// misbehaving or compromised oracle can drive a typical
-// ~200-vbyte withdrawal fee to ~$200, which is griefing. BTC
-// mainnet historical peaks (2017 bull run, 2023 inscription
-// mania) topped out near 500–750 sat/vbyte for short windows,
-// so 500 covers genuine extreme markets while halving the
-// oracle's griefing range. Any rate above this is clamped
-// during fee calculation; rates below 1 are clamped up to 1.
-const MaxBaseFeeRate int64 = 500
+// ChainWorkPrefix stores the cumulative proof of work from the seed header
+// up to and including each stored header, keyed by height. Key: "w-<height>",
+// Value: big-endian unsigned integer. addBlocks compares it to choose between
+// competing branches, and prunes it alongside the headers.
+const ChainWorkPrefix = "w" + DirPathDelimiter
+
+// StagedBranchKey stores the competing branch addBlocks is collecting until it
+// carries more work than the stored chain. Value: uint32 BE fork height ||
+// uint32 BE branch tip height || big-endian cumulative chain work at the tip.
+const StagedBranchKey = "br"
+
+// StagedHeaderPrefix stores the raw headers of the staged branch, keyed by
+// height. Key: "bs-<height>", Value: 80-byte header. They never overwrite the
+// stored chain until the branch is adopted.
+const StagedHeaderPrefix = "bs" + DirPathDelimiter
+
+// MaxBaseFeeRate caps the base fee rate at 1 DOGE per kB, in koinu.
+// Pentest finding BTC-C6: the ceiling bounds how far a misbehaving
+// or compromised oracle can drive withdrawal fees. Dogecoin Core's
+// recommended fee is 0.01 DOGE/kB, so 100 times that covers genuine
+// congestion while keeping a typical 1 kB withdrawal under 1 DOGE.
+// Any rate above this is clamped during fee calculation; rates below
+// the 0.001 DOGE/kB relay minimum are clamped up to it.
+const MaxBaseFeeRate int64 = 100_000_000
+
+// FeeWindowKey stores the most recent oracle fee observations, oldest first.
+// Value: packed 8-byte BE int64 fee rates, at most FeeWindowSize of them.
+const FeeWindowKey = "fwin"
+
+// FeeWindowSize is the number of fee observations whose median the base fee
+// rate follows.
+const FeeWindowSize = 12
+
+// MaxFeeRateStepPercent limits how far the base fee rate moves on one update,
+// as a percentage of its current value (at least 1 sat/vbyte).
+const MaxFeeRateStepPercent = 25
 
 // MaxBlockRetention is the number of recent block headers to keep.
 // Older headers are pruned during addBlocks to prevent unbounded state growth.
@@ -119,6 +302,32 @@ const (
 	Regtest string = "regtest"
 )
 
-func IsTestnet(networkName string) bool {
-	return networkName == Testnet || networkName == Regtest
+// NetworkKey stores the name of the network the contract is bridged to,
+// fixed by the first seedBlocks.
+const NetworkKey = "net"
+
+// Checkpoint pins the hash of a known block on a network.
+type Checkpoint struct {
+	Height uint32
+	Hash   string // block hash, big-endian hex as shown by block explorers
+}
+
+// Checkpoints are the compiled-in checkpoints per network, in ascending
+// height order. A seeded header run that covers a checkpoint height must match
+// it, and on mainnet a seed may not start below the last one. Mainnet follows
+// Dogecoin Core's chainparams; testnet only pins its genesis block so far.
+var Checkpoints = map[string][]Checkpoint{
+	Mainnet: {
+		{0, "1a91e3dace36e2be3bf030a65679fe821aa1d6ef92e7c9902eb318182c355691"},
+		{1000000, "6aae55bea74235f0c80bd066349d4440c31f2d0f27d54265ecd484d8c1d11b47"},
+		{1250000, "00c7a442055c1a990e11eea5371ca5c1c02a0677b33cc88ec728c45edc4ec060"},
+		{1500000, "f1d32d6920de7b617d51e74bdf4e58adccaa582ffdc8657464454f16a952fca6"},
+		{1750000, "5c8e7327984f0d6f59447d89d143e5f6eafc524c82ad95d176c5cec082ae2001"},
+		{2000000, "9914f0e82e39bbf21950792e8816620d71b9965bdbbc14e72a95e3ab9618fea8"},
+		{2031142, "893297d89afb7599a3c571ca31a3b80e8353f4cf39872400ad0f57d26c4c5d42"},
+		{2510150, "77e3f4a4bcb4a2c15e8015525e3d15b466f6c022f6ca82698f329edef7d9777e"},
+	},
+	Testnet: {
+		{0, "bb0a78264637406b6360aad926284d544d7049f45189db5664f3c4d07350559e"},
+	},
 }
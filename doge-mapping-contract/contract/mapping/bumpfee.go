@@ -0,0 +1,440 @@
+package mapping
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+	"doge-mapping-contract/sdk"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ---------------------------------------------------------------------------
+// Fee bumping
+//
+// bumpFee replaces a pending spend that is stuck at a stale fee rate. The
+// replacement spends the same inputs and pays the same outputs, but takes the
+// higher fee out of the change. The fee it adds is charged like a cpfp
+// child's, to the payer set by constants.CpfpPayerKey, and recorded in its
+// signing data. Every version stays in TxSpendsList, linked through its
+// signing data, until one of them confirms; the others are then retired. If
+// an older version confirmed, its change takes the place of the latest's in
+// the unconfirmed pool, and the fees added by the later versions go back to
+// whoever paid them.
+// ---------------------------------------------------------------------------
+
+// maxSpendVersions bounds how many times a spend can be replaced, which also
+// bounds the walks along its versions.
+const maxSpendVersions = 16
+
+// loadSigningData returns the signing data of the pending spend txId, or nil
+// if there is none.
+func loadSigningData(txId string) (*SigningData, error) {
+	raw := sdk.StateGetObject(constants.TxSpendsPrefix + txId)
+	if raw == nil || len(*raw) < 1 {
+		return nil, nil
+	}
+	sd, err := UnmarshalSigningData([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrJson, err, "error unmarshalling signing data")
+	}
+	return sd, nil
+}
+
+func saveSigningData(txId string, sd *SigningData) error {
+	data, err := MarshalSigningData(sd)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrJson, err, "error marshalling signing data")
+	}
+	sdk.StateSetObject(constants.TxSpendsPrefix+txId, string(data))
+	return nil
+}
+
+// HandleBumpFee replaces the pending spend txId with one paying the current
+// fee rate and returns the replacement's txid.
+func (cs *ContractState) HandleBumpFee(txId string) (string, error) {
+	sd, err := loadSigningData(txId)
+	if err != nil {
+		return "", err
+	}
+	if sd == nil {
+		return "", ce.NewContractError(ce.ErrInput, "no pending spend "+txId)
+	}
+	if sd.ReplacedBy != "" {
+		return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" was already replaced by "+sd.ReplacedBy)
+	}
+	if spendVersions(sd) >= maxSpendVersions {
+		return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" has been replaced too many times")
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(sd.Tx)); err != nil {
+		return "", ce.WrapContractError(ce.ErrTransaction, err, "error deserializing pending spend")
+	}
+	if len(sd.UnsignedSigHashes) != len(tx.TxIn) {
+		return "", ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+	}
+
+	// Rebuild each input's UTXO from the signing data, as the registry no
+	// longer holds it.
+	inputs := make([]*Utxo, len(tx.TxIn))
+	witnessScripts := make(map[int][]byte, len(tx.TxIn))
+	var totalIn int64
+	for _, h := range sd.UnsignedSigHashes {
+		if int(h.Index) >= len(tx.TxIn) || inputs[h.Index] != nil {
+			return "", ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+		}
+		if h.Amount <= 0 {
+			return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" was recorded without its input amounts")
+		}
+		prevOut := tx.TxIn[h.Index].PreviousOutPoint
+		pkScript, err := cs.spendPkScript(h.WitnessScript)
+		if err != nil {
+			return "", err
+		}
+		inputs[h.Index] = &Utxo{
+			TxId:     prevOut.Hash.String(),
+			Vout:     prevOut.Index,
+			Amount:   h.Amount,
+			PkScript: pkScript,
+		}
+		witnessScripts[int(h.Index)] = h.WitnessScript
+		if totalIn, err = safeAdd64(totalIn, h.Amount); err != nil {
+			return "", ce.WrapContractError(ce.ErrArithmetic, err, "error summing inputs")
+		}
+	}
+
+	changeAddress, _, err := createScriptAddressWithBackup(
+		cs.PublicKeys.Primary,
+		cs.PublicKeys.Backup,
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		return "", ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
+	}
+	changeScript, err := cs.destinationScript(changeAddress)
+	if err != nil {
+		return "", err
+	}
+
+	// The replacement keeps every payout, in order, and the change is
+	// rebuilt after them.
+	bumped := wire.NewMsgTx(tx.Version)
+	bumped.LockTime = tx.LockTime
+	for _, in := range tx.TxIn {
+		txIn := wire.NewTxIn(&in.PreviousOutPoint, nil, nil)
+		txIn.Sequence = rbfSequence
+		bumped.AddTxIn(txIn)
+	}
+	var totalOut, totalPaid int64
+	changeOutputs := int64(0)
+	for _, out := range tx.TxOut {
+		totalOut += out.Value
+		if bytes.Equal(out.PkScript, changeScript) {
+			changeOutputs++
+			continue
+		}
+		bumped.AddTxOut(wire.NewTxOut(out.Value, out.PkScript))
+		totalPaid += out.Value
+	}
+	if changeOutputs == 0 {
+		return "", ce.NewContractError(ce.ErrInput, "spend "+txId+" has no change to pay a higher fee from")
+	}
+	oldFee := totalIn - totalOut
+
+	// Replacing the spend evicts any spend of its change, so it may only be
+	// replaced while all of its change is still in the pool.
+	pooled, _, err := cs.unconfirmedChangeOf(txId)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(pooled)) != changeOutputs {
+		return "", ce.NewContractError(ce.ErrInput, "change of spend "+txId+" is already being spent")
+	}
+
+	payouts := len(bumped.TxOut)
+	for count := changeOutputs; ; count-- {
+		bumped.TxOut = bumped.TxOut[:payouts]
+		for range count {
+			bumped.AddTxOut(wire.NewTxOut(0, changeScript))
+		}
+		size := int64(bumped.SerializeSize())
+		fee, err := cs.calculateSegwitFee(size, witnessScripts)
+		if err != nil {
+			return "", err
+		}
+		if count == changeOutputs && fee <= oldFee {
+			return "", ce.NewContractError(
+				ce.ErrInput,
+				"fee at the current rate ("+strconv.FormatInt(fee, 10)+
+					") does not exceed the spend's ("+strconv.FormatInt(oldFee, 10)+")",
+			)
+		}
+		// BIP125 also requires the replacement to pay for its own relay on
+		// top of the fee it replaces.
+		relayFee, err := segwitFee(size, witnessScripts, chainPolicy.MinFeeRate)
+		if err != nil {
+			return "", err
+		}
+		fee = max(fee, oldFee+relayFee)
+		change := totalIn - totalPaid - fee
+		if count == 0 {
+			if change < 0 {
+				return "", ce.NewContractError(ce.ErrBalance, "change of spend "+txId+" cannot cover the higher fee")
+			}
+			// Change too small for an output goes to the miner.
+			bumped.TxOut = bumped.TxOut[:payouts]
+			break
+		}
+		if change > 0 && !chainPolicy.isDust(change/count) {
+			bumped.TxOut = bumped.TxOut[:payouts]
+			addChangeOutputs(bumped, change, count, changeScript)
+			break
+		}
+	}
+
+	signingData, err := signSpendTransaction(bumped, inputs, witnessScripts)
+	if err != nil {
+		return "", ce.WrapContractError(ce.ErrTransaction, err, "error signing replacement transaction")
+	}
+	newTxId := bumped.TxID()
+	signingData.Replaces = txId
+	signingData.Payouts = sd.Payouts
+	sd.ReplacedBy = newTxId
+	if err := saveSigningData(txId, sd); err != nil {
+		return "", err
+	}
+	cs.TxSpendsList = append(cs.TxSpendsList, newTxId)
+	err = updateWithdrawals(sd.Payouts, func(r *withdrawalRecord, _ *SpendPayout) bool {
+		r.Status = WithdrawalReplaced
+		r.TxId = newTxId
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The replacement's change takes the place of the spend's.
+	cs.dropUnconfirmed(pooled)
+	newChange, err := cs.addUnconfirmedChange(bumped, changeAddress)
+	if err != nil {
+		return "", err
+	}
+	added := totalIn - totalPaid - newChange - oldFee
+	payer := cpfpPayer()
+	if signingData.Charges, err = cs.chargeCpfpFee(payer, sd.Payouts, added, newTxId); err != nil {
+		return "", err
+	}
+	if err := saveSigningData(newTxId, signingData); err != nil {
+		return "", err
+	}
+	if cs.Supply.ActiveSupply, err = safeSubtract64(cs.Supply.ActiveSupply, added); err != nil {
+		return "", ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing active supply")
+	}
+	sdk.Log(createBumpLog(newTxId, txId, added, payer))
+	return newTxId, nil
+}
+
+// spendPkScript returns the output script of witnessScript, P2SH or P2WSH
+// as ScriptHashMode selects.
+func (cs *ContractState) spendPkScript(witnessScript []byte) ([]byte, error) {
+	var addr btcutil.Address
+	var err error
+	if constants.ScriptHashMode == constants.ScriptHashP2SH {
+		addr, err = btcutil.NewAddressScriptHash(witnessScript, cs.Network.Params)
+	} else {
+		hash := sha256.Sum256(witnessScript)
+		addr, err = btcutil.NewAddressWitnessScriptHash(hash[:], cs.Network.Params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// spendVersions returns how many versions of the spend precede sd, plus one.
+func spendVersions(sd *SigningData) int {
+	versions := 1
+	for prev := sd.Replaces; prev != "" && versions <= maxSpendVersions; versions++ {
+		prevSd, err := loadSigningData(prev)
+		if err != nil || prevSd == nil {
+			break
+		}
+		prev = prevSd.Replaces
+	}
+	return versions
+}
+
+// unconfirmedChangeOf returns the registry indices of the unconfirmed UTXOs
+// created by txId, and their total.
+func (cs *ContractState) unconfirmedChangeOf(txId string) ([]int, int64, error) {
+	var indices []int
+	var total int64
+	for i, entry := range cs.UtxoList {
+		if entry.Id >= constants.UtxoConfirmedPoolStart {
+			continue
+		}
+		utxo, err := loadUtxo(entry.Id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if utxo.TxId == txId {
+			indices = append(indices, i)
+			total += entry.Amount
+		}
+	}
+	return indices, total, nil
+}
+
+// dropUnconfirmed removes the registry entries at indices, as returned by
+// unconfirmedChangeOf, and their UTXOs.
+func (cs *ContractState) dropUnconfirmed(indices []int) {
+	ids := make([]uint16, len(indices))
+	for n, i := range indices {
+		ids[n] = cs.UtxoList[i].Id
+		sdk.StateDeleteObject(getUtxoKey(ids[n]))
+	}
+	cs.UtxoList = slices.DeleteFunc(cs.UtxoList, func(entry UtxoRegistryEntry) bool {
+		return slices.Contains(ids, entry.Id)
+	})
+}
+
+// adoptSpendVersion prepares the confirmation of txId when a later version
+// of the spend is pending in its place: the latest version's change leaves
+// the unconfirmed pool and txId's takes its place, the active supply gets
+// back the fee txId did not pay, and the later versions' charges for it are
+// credited back.
+func (cs *ContractState) adoptSpendVersion(txId string) error {
+	sd, err := loadSigningData(txId)
+	if err != nil || sd == nil || sd.ReplacedBy == "" {
+		return err
+	}
+	latest := sd.ReplacedBy
+	var charges []FeeCharge
+	for range maxSpendVersions {
+		next, err := loadSigningData(latest)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			break
+		}
+		charges = append(charges, next.Charges...)
+		if next.ReplacedBy == "" {
+			break
+		}
+		latest = next.ReplacedBy
+	}
+
+	pooled, latestChange, err := cs.unconfirmedChangeOf(latest)
+	if err != nil {
+		return err
+	}
+	cs.dropUnconfirmed(pooled)
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(sd.Tx)); err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error deserializing pending spend")
+	}
+	changeAddress, _, err := createScriptAddressWithBackup(
+		cs.PublicKeys.Primary,
+		cs.PublicKeys.Backup,
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
+	}
+	change, err := cs.addUnconfirmedChange(&tx, changeAddress)
+	if err != nil {
+		return err
+	}
+	if cs.Supply.ActiveSupply, err = safeAdd64(cs.Supply.ActiveSupply, change-latestChange); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error restoring active supply")
+	}
+	return cs.refundCharges(charges, txId)
+}
+
+// spendVersionIds returns the txids of the spend txId and of every other
+// version of it, txId first.
+func spendVersionIds(txId string) ([]string, error) {
+	versions := []string{txId}
+	sd, err := loadSigningData(txId)
+	if err != nil {
+		return nil, err
+	}
+	if sd != nil {
+		for _, link := range []func(*SigningData) string{
+			func(v *SigningData) string { return v.Replaces },
+			func(v *SigningData) string { return v.ReplacedBy },
+		} {
+			next := link(sd)
+			for next != "" && len(versions) <= 2*maxSpendVersions {
+				versions = append(versions, next)
+				v, err := loadSigningData(next)
+				if err != nil {
+					return nil, err
+				}
+				if v == nil {
+					break
+				}
+				next = link(v)
+			}
+		}
+	}
+	return versions, nil
+}
+
+// retireSpend removes the signing data of the spend txId and of every other
+// version of it, and drops them all from TxSpendsList.
+func (cs *ContractState) retireSpend(txId string) error {
+	versions, err := spendVersionIds(txId)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		sdk.StateDeleteObject(constants.TxSpendsPrefix + version)
+		for i, val := range cs.TxSpendsList {
+			if val == version {
+				// swap with the last element and shorten
+				cs.TxSpendsList[i] = cs.TxSpendsList[len(cs.TxSpendsList)-1]
+				cs.TxSpendsList = cs.TxSpendsList[:len(cs.TxSpendsList)-1]
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// createBumpLog records a fee bump: the replacement's txid, the txid it
+// replaces, the fee it adds and who paid it.
+func createBumpLog(txId, replaced string, added int64, payer string) string {
+	var b strings.Builder
+	b.Grow(200)
+	b.WriteString("bump")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("id")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(txId)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("r")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(replaced)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("b")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendInt(buf[:0], added, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("c")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(payer)
+	return b.String()
+}
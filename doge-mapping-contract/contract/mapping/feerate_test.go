@@ -8,11 +8,10 @@ import (
 
 // Pentest finding BTC-C6: clampedFeeRate previously allowed up to
 // 1000 sat/vbyte, which only acts as an overflow guard — within that
-// range the oracle can drive withdrawal fees to ~$200 per typical
-// 200-vbyte tx. BTC mainnet historical peaks (2017 bull run, 2023
-// inscription mania) topped out near 500–750 sat/vbyte for short
-// windows; anything above 500 represents either an oracle bug or
-// active griefing.
+// range the oracle can drive withdrawal fees arbitrarily high. DOGE
+// rates are per kB: Dogecoin Core recommends 0.01 DOGE/kB, and
+// anything above 1 DOGE/kB represents either an oracle bug or active
+// griefing.
 //
 // Pin the new ceiling so a future change loosening it trips this
 // test.
@@ -20,25 +19,27 @@ import (
 func TestBTCC6_ClampedFeeRateCeilingTightened(t *testing.T) {
 	// Constant pin — flag any change that re-loosens the ceiling
 	// above the audit-recommended value.
-	if constants.MaxBaseFeeRate > 500 {
-		t.Errorf("BTC-C6 leak: MaxBaseFeeRate %d > 500 sat/vbyte ceiling; oracle griefing range too wide",
+	if constants.MaxBaseFeeRate > 100_000_000 {
+		t.Errorf("BTC-C6 leak: MaxBaseFeeRate %d > 1 DOGE/kB ceiling; oracle griefing range too wide",
 			constants.MaxBaseFeeRate)
 	}
 }
 
 func TestBTCC6_ClampHonoursCeiling(t *testing.T) {
 	// Behavioural pin — anything well above the ceiling clamps down
-	// to MaxBaseFeeRate, not the legacy 1000.
+	// to MaxBaseFeeRate, and anything below the relay minimum up to it.
+	minRate := chainPolicy.MinFeeRate
 	cases := []struct {
 		input    int64
 		expected int64
 	}{
-		{0, 1},                                  // legacy floor still in place
-		{1, 1},                                  // boundary: ≥1 is fine
-		{50, 50},                                // ordinary fee passes through
+		{0, minRate},           // legacy floor still in place
+		{1, minRate},           // below the relay minimum
+		{minRate, minRate},     // boundary: the minimum is fine
+		{1_000_000, 1_000_000}, // ordinary fee passes through
 		{constants.MaxBaseFeeRate, constants.MaxBaseFeeRate}, // exactly at ceiling
 		{constants.MaxBaseFeeRate + 1, constants.MaxBaseFeeRate},
-		{1_000_000, constants.MaxBaseFeeRate}, // adversarial overflow
+		{1_000_000_000_000, constants.MaxBaseFeeRate}, // adversarial overflow
 	}
 	for _, tc := range cases {
 		got := clampedFeeRate(tc.input)
@@ -47,3 +48,43 @@ func TestBTCC6_ClampHonoursCeiling(t *testing.T) {
 		}
 	}
 }
+
+func TestPushFeeObservation(t *testing.T) {
+	var window []int64
+	for fee := int64(1); fee <= constants.FeeWindowSize+3; fee++ {
+		window = pushFeeObservation(window, fee)
+	}
+	if len(window) != constants.FeeWindowSize {
+		t.Fatalf("window holds %d observations, want %d", len(window), constants.FeeWindowSize)
+	}
+	if window[0] != 4 || window[len(window)-1] != constants.FeeWindowSize+3 {
+		t.Errorf("window should keep the newest observations, got %v", window)
+	}
+}
+
+func TestNextBaseFeeRate(t *testing.T) {
+	// Rates are koinu per kB, in multiples of the relay minimum.
+	k := chainPolicy.MinFeeRate
+	cases := []struct {
+		name    string
+		current int64
+		window  []int64
+		want    int64
+	}{
+		{"unset rate takes the first observation", 0, []int64{40 * k}, 40 * k},
+		{"first observation stepped from a set rate", 100 * k, []int64{400 * k}, 125 * k},
+		{"unset rate takes the median", 0, []int64{10 * k, 30 * k, 20 * k}, 20 * k},
+		{"median within the step", 20 * k, []int64{20 * k, 22 * k, 24 * k}, 22 * k},
+		{"one spike does not move the median", 20 * k, []int64{20 * k, 20 * k, 500 * k}, 20 * k},
+		{"one crash does not move the median", 20 * k, []int64{20 * k, 20 * k, k}, 20 * k},
+		{"rise capped at the step", 100 * k, []int64{400 * k, 400 * k, 400 * k}, 125 * k},
+		{"fall capped at the step", 100 * k, []int64{k, k, k}, 75 * k},
+		{"floored at the minimum", k, []int64{1, 1, 1}, k},
+		{"capped at the maximum", constants.MaxBaseFeeRate, []int64{constants.MaxBaseFeeRate, 2 * constants.MaxBaseFeeRate}, constants.MaxBaseFeeRate},
+	}
+	for _, tc := range cases {
+		if got := nextBaseFeeRate(tc.current, tc.window); got != tc.want {
+			t.Errorf("%s: nextBaseFeeRate(%d, %v) = %d, want %d", tc.name, tc.current, tc.window, got, tc.want)
+		}
+	}
+}
@@ -3,50 +3,15 @@ package mapping
 import (
 	"doge-mapping-contract/contract/constants"
 	ce "doge-mapping-contract/contract/contracterrors"
+	"doge-mapping-contract/contract/network"
 	"doge-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
 	"net/url"
 	"strings"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
-// DOGE-specific network params for address validation. DOGE uses
-// PubKeyHashAddrID 0x1e (mainnet) / 0x71 (testnet) — distinct from
-// Bitcoin's 0x00 / 0x6f. Native bech32 is unused.
-func dogeTestNetParams() *chaincfg.Params {
-	p := chaincfg.TestNet3Params
-	p.PubKeyHashAddrID = 0x71
-	p.ScriptHashAddrID = 0xc4
-	return &p
-}
-
-func dogeMainNetParams() *chaincfg.Params {
-	p := chaincfg.MainNetParams
-	p.PubKeyHashAddrID = 0x1e
-	p.ScriptHashAddrID = 0x16
-	return &p
-}
-
-func dogeRegTestParams() *chaincfg.Params {
-	p := chaincfg.RegressionNetParams
-	p.PubKeyHashAddrID = 0x71
-	p.ScriptHashAddrID = 0xc4
-	return &p
-}
-
-func IntializeContractState(publicKeys PublicKeys, networkMode string) (*ContractState, error) {
-	var networkParams *chaincfg.Params
-	switch networkMode {
-	case constants.Testnet:
-		networkParams = dogeTestNetParams()
-	case constants.Regtest:
-		networkParams = dogeRegTestParams()
-	default:
-		networkParams = dogeMainNetParams()
-	}
-
+func IntializeContractState(publicKeys PublicKeys, net *network.Network) (*ContractState, error) {
 	// Load UTXO registry (binary: 9 bytes/entry)
 	var utxos UtxoRegistry
 	utxoState := sdk.StateGetObject(constants.UtxoRegistryKey)
@@ -97,16 +62,16 @@ func IntializeContractState(publicKeys PublicKeys, networkMode string) (*Contrac
 		TxSpendsList:      txSpends,
 		Supply:            supply,
 		PublicKeys:        publicKeys,
-		NetworkParams:     networkParams,
+		Network:           net,
 	}, nil
 }
 
 func InitializeMappingState(
 	publicKeys PublicKeys,
-	networkMode string,
+	net *network.Network,
 	instructions ...string,
 ) (*MappingState, error) {
-	contractState, err := IntializeContractState(publicKeys, networkMode)
+	contractState, err := IntializeContractState(publicKeys, net)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +79,7 @@ func InitializeMappingState(
 	var registry map[string]*AddressMetadata
 	if len(instructions) > 0 {
 		var err error
-		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.NetworkParams)
+		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.Network)
 		if err != nil {
 			return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error unmarshalling address registry")
 		}
@@ -129,7 +94,7 @@ func InitializeMappingState(
 func (cs *ContractState) parseInstructions(
 	publicKeys PublicKeys,
 	instrs []string,
-	networkParams *chaincfg.Params,
+	net *network.Network,
 ) (map[string]*AddressMetadata, error) {
 	parsedInstructions := make([]url.Values, len(instrs))
 	registry := make(map[string]*AddressMetadata, len(instrs))
@@ -173,11 +138,11 @@ func (cs *ContractState) parseInstructions(
 			hasher := sha256.New()
 			hasher.Write([]byte(instr))
 			hashBytes := hasher.Sum(nil)
-			address, _, err := createP2WSHAddressWithBackup(
+			address, _, err := createScriptAddressWithBackup(
 				publicKeys.Primary,
 				publicKeys.Backup,
 				hashBytes,
-				networkParams,
+				net,
 			)
 			if err != nil {
 				return nil, err
@@ -0,0 +1,336 @@
+package mapping
+
+import (
+	"bytes"
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+	"doge-mapping-contract/sdk"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ---------------------------------------------------------------------------
+// Withdrawal records
+//
+// Every unmap gets a withdrawal record, kept after its spend is retired, so a
+// wallet can follow it by id. A queued withdrawal starts out requested, and
+// any other once its spend is built and signing is requested. reportSigned
+// moves it on to signed when the fully signed spend is submitted, bumpFee to
+// replaced while the replacement awaits its signatures, and confirmSpend or
+// map to confirmed. A queued withdrawal refunded at settlement has failed.
+// One whose spend is cancelled is refunded and cancelled, and confirmed after
+// all should a version of that spend confirm.
+// The spends paying the withdrawals list their ids in their payouts.
+// ---------------------------------------------------------------------------
+
+const withdrawalRecordFixedSize = 1 + 8 + 8 + 8 + 8 + 4
+
+type WithdrawalStatus uint8
+
+const (
+	WithdrawalRequested        WithdrawalStatus = iota + 1 // queued for settleWithdrawals
+	WithdrawalSigningRequested                             // spend built, TSS signing requested
+	WithdrawalSigned                                       // spend fully signed, ready to broadcast
+	WithdrawalConfirmed                                    // spend proven in a block
+	WithdrawalReplaced                                     // spend replaced, signing of the replacement requested
+	WithdrawalFailed                                       // refunded to the sender
+	WithdrawalCancelled                                    // spend cancelled and refunded
+)
+
+var withdrawalStatusNames = [...]string{
+	WithdrawalRequested:        "requested",
+	WithdrawalSigningRequested: "signing-requested",
+	WithdrawalSigned:           "signed",
+	WithdrawalConfirmed:        "confirmed",
+	WithdrawalReplaced:         "replaced",
+	WithdrawalFailed:           "failed",
+	WithdrawalCancelled:        "cancelled",
+}
+
+func (s WithdrawalStatus) String() string {
+	if s == 0 || int(s) >= len(withdrawalStatusNames) {
+		return "unknown"
+	}
+	return withdrawalStatusNames[s]
+}
+
+type withdrawalRecord struct {
+	Status      WithdrawalStatus
+	Debit       int64  // taken from the sender's balance, VSC fee included
+	Sent        int64  // paid to the destination; zero until the spend is built
+	RequestedAt uint64 // Hive block height of the unmap
+	UpdatedAt   uint64 // Hive block height of the last change of status
+	ConfirmedAt uint32 // height of the block the spend was proven in
+	From        string
+	To          string
+	TxId        string // the spend paying it, once built
+}
+
+func marshalWithdrawalRecord(r *withdrawalRecord) ([]byte, error) {
+	if len(r.From) > 255 || len(r.To) > 255 || len(r.TxId) > 255 {
+		return nil, errors.New("withdrawal record field too long")
+	}
+	buf := make([]byte, 0, withdrawalRecordFixedSize+3+len(r.From)+len(r.To)+len(r.TxId))
+	buf = append(buf, byte(r.Status))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(r.Debit))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(r.Sent))
+	buf = binary.BigEndian.AppendUint64(buf, r.RequestedAt)
+	buf = binary.BigEndian.AppendUint64(buf, r.UpdatedAt)
+	buf = binary.BigEndian.AppendUint32(buf, r.ConfirmedAt)
+	for _, s := range []string{r.From, r.To, r.TxId} {
+		buf = append(buf, byte(len(s)))
+		buf = append(buf, s...)
+	}
+	return buf, nil
+}
+
+func unmarshalWithdrawalRecord(data []byte) (*withdrawalRecord, error) {
+	if len(data) < withdrawalRecordFixedSize {
+		return nil, errors.New("truncated withdrawal record")
+	}
+	r := &withdrawalRecord{
+		Status:      WithdrawalStatus(data[0]),
+		Debit:       int64(binary.BigEndian.Uint64(data[1:])),
+		Sent:        int64(binary.BigEndian.Uint64(data[9:])),
+		RequestedAt: binary.BigEndian.Uint64(data[17:]),
+		UpdatedAt:   binary.BigEndian.Uint64(data[25:]),
+		ConfirmedAt: binary.BigEndian.Uint32(data[33:]),
+	}
+	data = data[withdrawalRecordFixedSize:]
+	for _, field := range []*string{&r.From, &r.To, &r.TxId} {
+		var ok bool
+		if *field, data, ok = readShortString(data); !ok {
+			return nil, errors.New("truncated withdrawal record")
+		}
+	}
+	if len(data) != 0 {
+		return nil, errors.New("trailing bytes after withdrawal record")
+	}
+	return r, nil
+}
+
+func withdrawalKey(id uint64) string {
+	return constants.WithdrawalPrefix + strconv.FormatUint(id, 10)
+}
+
+// loadWithdrawal returns the record of withdrawal id, or nil if there is none.
+func loadWithdrawal(id uint64) (*withdrawalRecord, error) {
+	raw := sdk.StateGetObject(withdrawalKey(id))
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	r, err := unmarshalWithdrawalRecord([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding withdrawal record")
+	}
+	return r, nil
+}
+
+func saveWithdrawal(id uint64, r *withdrawalRecord) error {
+	data, err := marshalWithdrawalRecord(r)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error encoding withdrawal record")
+	}
+	sdk.StateSetObject(withdrawalKey(id), string(data))
+	return nil
+}
+
+// nextWithdrawalId allocates the id of a new withdrawal.
+func nextWithdrawalId() (uint64, error) {
+	var last uint64
+	if raw := sdk.StateGetObject(constants.WithdrawalLastIdKey); raw != nil && *raw != "" {
+		var err error
+		if last, err = strconv.ParseUint(*raw, 10, 64); err != nil {
+			return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last withdrawal id")
+		}
+	}
+	last++
+	sdk.StateSetObject(constants.WithdrawalLastIdKey, strconv.FormatUint(last, 10))
+	return last, nil
+}
+
+// createWithdrawal stores the record of a new withdrawal with id.
+func createWithdrawal(id uint64, r *withdrawalRecord) error {
+	height := sdk.GetEnv().BlockHeight
+	r.RequestedAt = height
+	r.UpdatedAt = height
+	if err := saveWithdrawal(id, r); err != nil {
+		return err
+	}
+	sdk.Log(createWithdrawalLog(id, r))
+	return nil
+}
+
+// updateWithdrawals applies update to the record of each payout and stores
+// those it reports as changed. Payouts recorded without an id are skipped.
+func updateWithdrawals(payouts []SpendPayout, update func(*withdrawalRecord, *SpendPayout) bool) error {
+	height := sdk.GetEnv().BlockHeight
+	for i := range payouts {
+		p := &payouts[i]
+		if p.Id == 0 {
+			continue
+		}
+		r, err := loadWithdrawal(p.Id)
+		if err != nil {
+			return err
+		}
+		if r == nil || !update(r, p) {
+			continue
+		}
+		r.UpdatedAt = height
+		if err := saveWithdrawal(p.Id, r); err != nil {
+			return err
+		}
+		sdk.Log(createWithdrawalLog(p.Id, r))
+	}
+	return nil
+}
+
+// confirmWithdrawals marks the withdrawals paid by the pending spend txId
+// confirmed at blockHeight. Call it before the spend is retired.
+func confirmWithdrawals(txId string, blockHeight uint32) error {
+	sd, err := loadSigningData(txId)
+	if err != nil || sd == nil {
+		return err
+	}
+	return updateWithdrawals(sd.Payouts, func(r *withdrawalRecord, _ *SpendPayout) bool {
+		if r.Status == WithdrawalConfirmed {
+			return false
+		}
+		r.Status = WithdrawalConfirmed
+		r.TxId = txId
+		r.ConfirmedAt = blockHeight
+		return true
+	})
+}
+
+// HandleReportSigned checks that every input of the pending spend rawTx
+// carries a valid signature and marks the withdrawals waiting on it signed.
+// It returns the spend's txid.
+func (cs *ContractState) HandleReportSigned(rawTx []byte) (string, error) {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return "", ce.WrapContractError(ce.ErrInput, err, "could not deserialize transaction")
+	}
+	txId := tx.TxID()
+	sd, err := loadSigningData(txId)
+	if err != nil {
+		return "", err
+	}
+	if sd == nil {
+		return "", ce.NewContractError(ce.ErrInput, "no pending spend "+txId)
+	}
+	if err := cs.verifySpendSignatures(&tx, sd); err != nil {
+		return "", err
+	}
+
+	// Withdrawals since moved to a replacement wait on its signatures
+	// instead.
+	err = updateWithdrawals(sd.Payouts, func(r *withdrawalRecord, _ *SpendPayout) bool {
+		if r.TxId != txId || (r.Status != WithdrawalSigningRequested && r.Status != WithdrawalReplaced) {
+			return false
+		}
+		r.Status = WithdrawalSigned
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	return txId, nil
+}
+
+// verifySpendSignatures checks that every input of tx, a version of a spend
+// recorded with sd, carries a valid signature.
+func (cs *ContractState) verifySpendSignatures(tx *wire.MsgTx, sd *SigningData) error {
+	txId := tx.TxID()
+	if len(sd.UnsignedSigHashes) != len(tx.TxIn) {
+		return ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+	}
+
+	prevOuts := make(map[wire.OutPoint]*wire.TxOut, len(tx.TxIn))
+	for _, h := range sd.UnsignedSigHashes {
+		if int(h.Index) >= len(tx.TxIn) {
+			return ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+		}
+		if h.Amount <= 0 {
+			return ce.NewContractError(ce.ErrInput, "spend "+txId+" was recorded without its input amounts")
+		}
+		pkScript, err := cs.spendPkScript(h.WitnessScript)
+		if err != nil {
+			return err
+		}
+		prevOuts[tx.TxIn[h.Index].PreviousOutPoint] = wire.NewTxOut(h.Amount, pkScript)
+	}
+	fetcher := txscript.NewMultiPrevOutFetcher(prevOuts)
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	for i, in := range tx.TxIn {
+		prevOut := fetcher.FetchPrevOutput(in.PreviousOutPoint)
+		if prevOut == nil {
+			return ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+		}
+		vm, err := txscript.NewEngine(
+			prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, fetcher,
+		)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			return ce.WrapContractError(
+				ce.ErrTransaction,
+				err,
+				"input "+strconv.Itoa(i)+" of spend "+txId+" is not validly signed",
+			)
+		}
+	}
+	return nil
+}
+
+// HandleGetWithdrawal returns the record of withdrawal id.
+func HandleGetWithdrawal(id uint64) (*WithdrawalInfo, error) {
+	r, err := loadWithdrawal(id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ce.NewContractError(ce.ErrInput, "no withdrawal "+strconv.FormatUint(id, 10))
+	}
+	return &WithdrawalInfo{
+		Id:          id,
+		Status:      r.Status.String(),
+		From:        r.From,
+		To:          r.To,
+		Deducted:    strconv.FormatInt(r.Debit, 10),
+		Sent:        strconv.FormatInt(r.Sent, 10),
+		TxId:        r.TxId,
+		RequestedAt: r.RequestedAt,
+		UpdatedAt:   r.UpdatedAt,
+		ConfirmedAt: r.ConfirmedAt,
+	}, nil
+}
+
+// createWithdrawalLog records a withdrawal's change of status: its id, the
+// new status and the spend paying it, if built.
+func createWithdrawalLog(id uint64, r *withdrawalRecord) string {
+	var b strings.Builder
+	b.Grow(160)
+	b.WriteString("withdrawal")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("w")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendUint(buf[:0], id, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("s")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(r.Status.String())
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("id")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(r.TxId)
+	return b.String()
+}
@@ -0,0 +1,359 @@
+package mapping
+
+import (
+	"doge-mapping-contract/contract/constants"
+	ce "doge-mapping-contract/contract/contracterrors"
+	"doge-mapping-contract/contract/network"
+	"doge-mapping-contract/sdk"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Pending deposits
+//
+// When a deposit finality is set, a proven deposit is not credited straight
+// away. It is stored under its txid:vout with the tip height from which it can
+// be credited, and queued. finalizeDeposits, or the next addBlocks, credits
+// every queued deposit the tip has reached, so a reorg within the finality
+// window only ever has to cancel a pending entry. The deposit's output only
+// joins the UTXO pool when it is credited, so no withdrawal can spend it
+// before then.
+// ---------------------------------------------------------------------------
+
+const pendingQueueEntrySize = 4 + observedEntrySize
+
+type pendingDeposit struct {
+	mintRecord
+	ReadyAt uint32 // tip height from which the deposit can be credited
+	Height  uint32 // height the deposit was proven at
+	// Utxo is the output added to the pool when the deposit is credited. It
+	// is nil for deposits proven before outputs were held, which joined the
+	// pool straight away.
+	Utxo *Utxo
+}
+
+type pendingQueueEntry struct {
+	ReadyAt uint32
+	Entry   observedEntry
+}
+
+func marshalPendingDeposit(p *pendingDeposit) ([]byte, error) {
+	buf := binary.BigEndian.AppendUint32(nil, p.ReadyAt)
+	buf = binary.BigEndian.AppendUint32(buf, p.Height)
+	buf, err := appendMintRecord(buf, &p.mintRecord)
+	if err != nil || p.Utxo == nil {
+		return buf, err
+	}
+	utxo := MarshalUtxo(p.Utxo)
+	if utxo == nil {
+		return nil, errors.New("invalid pending deposit output")
+	}
+	return append(buf, utxo...), nil
+}
+
+func unmarshalPendingDeposit(data []byte) (*pendingDeposit, error) {
+	if len(data) < 8 {
+		return nil, errors.New("truncated pending deposit")
+	}
+	r, n, err := decodeMintRecord(data[8:])
+	if err != nil {
+		return nil, err
+	}
+	p := &pendingDeposit{
+		mintRecord: r,
+		ReadyAt:    binary.BigEndian.Uint32(data[0:]),
+		Height:     binary.BigEndian.Uint32(data[4:]),
+	}
+	if rest := data[8+n:]; len(rest) > 0 {
+		if p.Utxo, err = UnmarshalUtxo(rest); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func marshalPendingQueue(queue []pendingQueueEntry) []byte {
+	buf := make([]byte, 0, len(queue)*pendingQueueEntrySize)
+	for _, q := range queue {
+		buf = binary.BigEndian.AppendUint32(buf, q.ReadyAt)
+		buf = append(buf, q.Entry[:]...)
+	}
+	return buf
+}
+
+func unmarshalPendingQueue(data []byte) ([]pendingQueueEntry, error) {
+	if len(data)%pendingQueueEntrySize != 0 {
+		return nil, errors.New("invalid pending deposit queue length")
+	}
+	out := make([]pendingQueueEntry, len(data)/pendingQueueEntrySize)
+	for i := range out {
+		off := i * pendingQueueEntrySize
+		out[i].ReadyAt = binary.BigEndian.Uint32(data[off:])
+		copy(out[i].Entry[:], data[off+4:off+pendingQueueEntrySize])
+	}
+	return out, nil
+}
+
+func pendingDepositKey(entry observedEntry) string {
+	return constants.PendingDepositPrefix + observedEntryString(entry)
+}
+
+func loadPendingDeposit(entry observedEntry) (*pendingDeposit, error) {
+	raw := sdk.StateGetObject(pendingDepositKey(entry))
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	p, err := unmarshalPendingDeposit([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding pending deposit")
+	}
+	return p, nil
+}
+
+func savePendingDeposit(p *pendingDeposit) error {
+	data, err := marshalPendingDeposit(p)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error encoding pending deposit")
+	}
+	sdk.StateSetObject(pendingDepositKey(p.Entry), string(data))
+	return nil
+}
+
+func loadPendingQueue() ([]pendingQueueEntry, error) {
+	raw := sdk.StateGetObject(constants.PendingDepositQueueKey)
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	queue, err := unmarshalPendingQueue([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding pending deposit queue")
+	}
+	return queue, nil
+}
+
+func savePendingQueue(queue []pendingQueueEntry) {
+	if len(queue) == 0 {
+		sdk.StateDeleteObject(constants.PendingDepositQueueKey)
+		return
+	}
+	sdk.StateSetObject(constants.PendingDepositQueueKey, string(marshalPendingQueue(queue)))
+}
+
+// getDepositFinality returns the number of blocks a deposit stays pending, or
+// 0 if deposits are credited immediately.
+func getDepositFinality() uint32 {
+	s := sdk.StateGetObject(constants.DepositFinalityKey)
+	if s == nil || *s == "" {
+		return 0
+	}
+	v, err := strconv.ParseUint(*s, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(v)
+}
+
+// coinbaseMaturesAt returns the tip height from which deposits paid by a
+// coinbase at blockHeight can be credited, or 0 if the tip at lastHeight has
+// already reached it.
+func coinbaseMaturesAt(blockHeight, lastHeight uint32) uint32 {
+	maturesAt := blockHeight + constants.CoinbaseMaturity
+	if lastHeight >= maturesAt {
+		return 0
+	}
+	return maturesAt
+}
+
+// addPendingDeposits stores newly proven deposits and appends them to the
+// queue.
+func addPendingDeposits(deposits []pendingDeposit) error {
+	if len(deposits) == 0 {
+		return nil
+	}
+	queue, err := loadPendingQueue()
+	if err != nil {
+		return err
+	}
+	for i := range deposits {
+		if err := savePendingDeposit(&deposits[i]); err != nil {
+			return err
+		}
+		queue = append(queue, pendingQueueEntry{ReadyAt: deposits[i].ReadyAt, Entry: deposits[i].Entry})
+		sdk.Log(createPendingLog("pend", &deposits[i].mintRecord, deposits[i].ReadyAt))
+	}
+	savePendingQueue(queue)
+	return nil
+}
+
+// reprovePendingDeposit restarts the finality window of a pending deposit
+// that was orphaned and has been proven again at blockHeight, making it ready
+// at readyAt.
+func reprovePendingDeposit(entry observedEntry, blockHeight, readyAt uint32) error {
+	p, err := loadPendingDeposit(entry)
+	if err != nil || p == nil {
+		return err
+	}
+	queue, err := loadPendingQueue()
+	if err != nil {
+		return err
+	}
+	p.Height = blockHeight
+	p.ReadyAt = readyAt
+	if err := savePendingDeposit(p); err != nil {
+		return err
+	}
+	for i := range queue {
+		if queue[i].Entry == entry {
+			queue[i].ReadyAt = p.ReadyAt
+		}
+	}
+	savePendingQueue(queue)
+	sdk.Log(createPendingLog("pend", &p.mintRecord, p.ReadyAt))
+	return nil
+}
+
+// cancelPendingDeposit removes a pending deposit that will never be credited.
+// It returns the deposit, or nil if it was not pending.
+func cancelPendingDeposit(entry observedEntry) (*pendingDeposit, error) {
+	p, err := loadPendingDeposit(entry)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	queue, err := loadPendingQueue()
+	if err != nil {
+		return nil, err
+	}
+	queue = slices.DeleteFunc(queue, func(q pendingQueueEntry) bool { return q.Entry == entry })
+	savePendingQueue(queue)
+	sdk.StateDeleteObject(pendingDepositKey(entry))
+	sdk.Log(createPendingLog("cancel", &p.mintRecord, p.ReadyAt))
+	return p, nil
+}
+
+// readyDeposits splits the queue into the deposits that can be credited at
+// lastHeight, up to limit, and the rest. Deposits whose block has been
+// orphaned wait until they are re-proven.
+func readyDeposits(
+	queue []pendingQueueEntry,
+	orphaned []orphanedMint,
+	lastHeight uint32,
+	limit int,
+) (ready, rest []pendingQueueEntry) {
+	for _, q := range queue {
+		isOrphaned := slices.ContainsFunc(orphaned, func(m orphanedMint) bool { return m.Entry == q.Entry })
+		if len(ready) < limit && q.ReadyAt <= lastHeight && !isOrphaned {
+			ready = append(ready, q)
+		} else {
+			rest = append(rest, q)
+		}
+	}
+	return ready, rest
+}
+
+// FinalizeDeposits credits the pending deposits that the tip at lastHeight has
+// reached, at most MaxFinalizePerCall at a time, adds their outputs to the
+// UTXO pool and returns how many were credited.
+func FinalizeDeposits(lastHeight uint32, net *network.Network) (int, error) {
+	queue, err := loadPendingQueue()
+	if err != nil || len(queue) == 0 {
+		return 0, err
+	}
+	orphaned, err := loadOrphanedMints()
+	if err != nil {
+		return 0, err
+	}
+	ready, rest := readyDeposits(queue, orphaned, lastHeight, constants.MaxFinalizePerCall)
+	if len(ready) == 0 {
+		return 0, nil
+	}
+
+	cs, err := IntializeContractState(PublicKeys{}, net)
+	if err != nil {
+		return 0, err
+	}
+	credited := 0
+	for _, q := range ready {
+		p, err := loadPendingDeposit(q.Entry)
+		if err != nil {
+			return 0, err
+		}
+		if p == nil {
+			continue
+		}
+		if p.Utxo != nil {
+			id, err := cs.allocateConfirmedId()
+			if err != nil {
+				return 0, err
+			}
+			cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: id, Amount: p.Utxo.Amount})
+			saveUtxo(id, p.Utxo)
+			if err := setJournaledUtxoId(p.Height, p.Entry, id); err != nil {
+				return 0, err
+			}
+		}
+		if err := incAccBalance(p.Recipient, p.Amount); err != nil {
+			return 0, ce.Prepend(err, "error crediting pending deposit")
+		}
+		if cs.Supply.ActiveSupply, err = safeAdd64(cs.Supply.ActiveSupply, p.Amount); err != nil {
+			return 0, ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing active supply")
+		}
+		if cs.Supply.UserSupply, err = safeAdd64(cs.Supply.UserSupply, p.Amount); err != nil {
+			return 0, ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing user supply")
+		}
+		sdk.StateDeleteObject(pendingDepositKey(q.Entry))
+		sdk.Log(createPendingLog("final", &p.mintRecord, p.ReadyAt))
+		credited++
+	}
+	savePendingQueue(rest)
+	if err := cs.SaveToState(); err != nil {
+		return 0, err
+	}
+	return credited, nil
+}
+
+// setJournaledUtxoId records the pool id a credited deposit's output was given
+// in the mint journal of the height it was proven at, so that a clawback drops
+// that output. Heights already pruned keep no journal.
+func setJournaledUtxoId(blockHeight uint32, entry observedEntry, id uint16) error {
+	journal, err := loadMintJournal(blockHeight)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(journal, func(r mintRecord) bool { return r.Entry == entry })
+	if i < 0 {
+		return nil
+	}
+	journal[i].UtxoId = id
+	return saveMintJournal(blockHeight, journal)
+}
+
+// createPendingLog records a change to a pending deposit: its output,
+// recipient, amount and the tip height from which it can be credited. The
+// type is "pend" when it enters the table, "final" when it is credited and
+// "cancel" when its block is orphaned for good.
+func createPendingLog(kind string, r *mintRecord, readyAt uint32) string {
+	var b strings.Builder
+	b.Grow(160)
+	b.WriteString(kind)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("o")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(observedEntryString(r.Entry))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("t")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(r.Recipient)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("a")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatInt(r.Amount, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("r")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(readyAt), 10))
+	return b.String()
+}
@@ -3,6 +3,7 @@ package mapping
 import (
 	"doge-mapping-contract/contract/constants"
 	ce "doge-mapping-contract/contract/contracterrors"
+	"doge-mapping-contract/contract/network"
 	"doge-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
@@ -20,14 +21,13 @@ import (
 	"github.com/btcsuite/btcd/wire"
 )
 
-func createP2WSHAddressWithBackup(
-	primaryPubKey CompressedPubKey, backupPubKey CompressedPubKey, tag []byte, network *chaincfg.Params,
+// createScriptAddressWithBackup returns the address paying to the IF/CSV/ELSE
+// script for the given keys and tag, and the script itself. The address is
+// P2SH or P2WSH according to constants.ScriptHashMode.
+func createScriptAddressWithBackup(
+	primaryPubKey CompressedPubKey, backupPubKey CompressedPubKey, tag []byte, net *network.Network,
 ) (string, []byte, error) {
-	csvBlocks := constants.BackupCSVBlocks
-
-	if network.Net != chaincfg.MainNetParams.Net {
-		csvBlocks = constants.TestnetBackupCSVBlocks
-	}
+	csvBlocks := net.BackupCSVBlocks
 
 	scriptBuilder := txscript.NewScriptBuilder()
 
@@ -64,25 +64,23 @@ func createP2WSHAddressWithBackup(
 		return "", nil, err
 	}
 
-	witnessProgram := sha256.Sum256(script)
-	addressWitnessScriptHash, err := btcutil.NewAddressWitnessScriptHash(witnessProgram[:], network)
+	address, err := scriptHashAddress(script, net.Params)
 	if err != nil {
 		return "", nil, err
 	}
-
-	return addressWitnessScriptHash.EncodeAddress(), script, nil
+	return address, script, nil
 }
 
-func createP2WSHAddress(pubKeyHex string, tag []byte, network *chaincfg.Params) (string, []byte, error) {
+func createScriptAddress(pubKeyHex string, tag []byte, network *chaincfg.Params) (string, []byte, error) {
 	pubKeyBytes, err := hex.DecodeString(pubKeyHex)
 	if err != nil {
 		return "", nil, err
 	}
 
-	return createSimpleP2WSHAddress(pubKeyBytes, tag, network)
+	return createSimpleScriptAddress(pubKeyBytes, tag, network)
 }
 
-func createSimpleP2WSHAddress(pubKeyBytes []byte, tag []byte, network *chaincfg.Params) (string, []byte, error) {
+func createSimpleScriptAddress(pubKeyBytes []byte, tag []byte, network *chaincfg.Params) (string, []byte, error) {
 	scriptBuilder := txscript.NewScriptBuilder()
 	if len(tag) > 0 {
 		scriptBuilder.AddData(pubKeyBytes)
@@ -98,12 +96,30 @@ func createSimpleP2WSHAddress(pubKeyBytes []byte, tag []byte, network *chaincfg.
 		return "", nil, err
 	}
 
+	address, err := scriptHashAddress(script, network)
+	if err != nil {
+		return "", nil, err
+	}
+	return address, script, nil
+}
+
+// scriptHashAddress returns the address paying to script: the HASH160 of the
+// redeem script in P2SH mode, the SHA256 witness program in P2WSH mode.
+func scriptHashAddress(script []byte, network *chaincfg.Params) (string, error) {
+	if constants.ScriptHashMode == constants.ScriptHashP2SH {
+		addressScriptHash, err := btcutil.NewAddressScriptHash(script, network)
+		if err != nil {
+			return "", err
+		}
+		return addressScriptHash.EncodeAddress(), nil
+	}
+
 	witnessProgram := sha256.Sum256(script)
 	addressWitnessScriptHash, err := btcutil.NewAddressWitnessScriptHash(witnessProgram[:], network)
 	if err != nil {
-		return "", nil, err
+		return "", err
 	}
-	return addressWitnessScriptHash.EncodeAddress(), script, nil
+	return addressWitnessScriptHash.EncodeAddress(), nil
 }
 
 func checkAuth(env sdk.Env) error {
@@ -617,7 +633,11 @@ func loadObservedList(blockHeight uint32) []observedEntry {
 	if raw == nil || len(*raw) == 0 {
 		return nil
 	}
-	data := []byte(*raw)
+	return unpackObservedList([]byte(*raw))
+}
+
+// unpackObservedList splits packed observed entries.
+func unpackObservedList(data []byte) []observedEntry {
 	if len(data)%observedEntrySize != 0 {
 		return nil
 	}
@@ -640,11 +660,16 @@ func isObserved(list []observedEntry, entry observedEntry) bool {
 
 // saveObservedList writes the packed observed entries for a block height.
 func saveObservedList(blockHeight uint32, list []observedEntry) {
+	sdk.StateSetObject(observedBlockKey(blockHeight), string(packObservedList(list)))
+}
+
+// packObservedList concatenates observed entries.
+func packObservedList(list []observedEntry) []byte {
 	buf := make([]byte, len(list)*observedEntrySize)
 	for i, e := range list {
 		copy(buf[i*observedEntrySize:], e[:])
 	}
-	sdk.StateSetObject(observedBlockKey(blockHeight), string(buf))
+	return buf
 }
 
 // DeleteObservedList removes the observed tx list for a block height.
@@ -705,6 +730,20 @@ func getMaxUnmapPerBlock() int64 {
 	return v
 }
 
+// getMinConfirmations returns the minimum confirmation depth required of
+// proofs submitted to action, or DefaultMinConfirmations if none is set.
+func getMinConfirmations(action string) uint32 {
+	s := sdk.StateGetObject(constants.MinConfirmationsPrefix + action)
+	if s == nil || *s == "" {
+		return constants.DefaultMinConfirmations
+	}
+	v, err := strconv.ParseUint(*s, 10, 32)
+	if err != nil || v == 0 {
+		return constants.DefaultMinConfirmations
+	}
+	return uint32(v)
+}
+
 func loadUnmapAccumulator() (storedHeight uint64, accum int64) {
 	s := sdk.StateGetObject(constants.BlockUnmapAccKey)
 	if s == nil || len(*s) != 16 {
@@ -754,3 +793,19 @@ func checkAndUpdateUnmapRateLimit(blockHeight uint64, amount int64) error {
 	saveUnmapAccumulator(blockHeight, newAccum)
 	return nil
 }
+
+// releaseUnmapRateLimit gives amount back to the current Hive block's unmap
+// allowance when a queued withdrawal is refunded, so a refund does not keep
+// consuming the cap. It only frees allowance the block has used and never
+// lowers the accumulator below zero.
+func releaseUnmapRateLimit(blockHeight uint64, amount int64) {
+	storedHeight, accum := loadUnmapAccumulator()
+	if storedHeight != blockHeight || accum == 0 {
+		return
+	}
+	accum -= amount
+	if accum < 0 {
+		accum = 0
+	}
+	saveUnmapAccumulator(blockHeight, accum)
+}
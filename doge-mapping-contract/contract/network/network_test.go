@@ -0,0 +1,57 @@
+package network
+
+import (
+	"doge-mapping-contract/contract/constants"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		testnet bool
+		csv     int64
+	}{
+		{constants.Mainnet, false, constants.BackupCSVBlocks},
+		{constants.Testnet, true, constants.TestnetBackupCSVBlocks},
+		{constants.Regtest, true, constants.TestnetBackupCSVBlocks},
+	}
+	for _, tt := range tests {
+		n, ok := Lookup(tt.name)
+		if !ok {
+			t.Fatalf("%s: not found", tt.name)
+		}
+		if n.Testnet != tt.testnet || n.BackupCSVBlocks != tt.csv {
+			t.Errorf("%s: got testnet %v, csv %d", tt.name, n.Testnet, n.BackupCSVBlocks)
+		}
+	}
+	if _, ok := Lookup("testnet3"); ok {
+		t.Error("unknown network name should not be found")
+	}
+}
+
+func TestFixCompatible(t *testing.T) {
+	tests := []struct {
+		name, fallback string
+		want           string
+	}{
+		{"", "", constants.Mainnet},
+		{constants.Mainnet, "", constants.Mainnet},
+		{constants.Regtest, "", ""},
+		{constants.Testnet, constants.Mainnet, ""},
+		{"", constants.Testnet, constants.Testnet},
+		{constants.Regtest, constants.Testnet, constants.Regtest},
+		{constants.Mainnet, constants.Testnet, ""},
+	}
+	for _, tt := range tests {
+		n, err := Fix(tt.name, tt.fallback)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Fix(%q, %q) = %s, want an error", tt.name, tt.fallback, n.Name)
+			}
+			continue
+		}
+		if err != nil || n.Name != tt.want {
+			t.Errorf("Fix(%q, %q) = %v, %v, want %s", tt.name, tt.fallback, n, err, tt.want)
+		}
+	}
+}
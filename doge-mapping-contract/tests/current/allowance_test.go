@@ -365,7 +365,7 @@ func TestUnmapFromWithAllowance(t *testing.T) {
 	const instruction = "deposit_to=" + allowanceOwner
 	const fakeTxId0 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
 	const fakeTxId1 = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
-	const ownerBalance = int64(10000)
+	const ownerBalance = int64(10_000_000)
 
 	ct, contractId := setupAllowanceContract(t, ownerBalance)
 
@@ -374,11 +374,11 @@ func TestUnmapFromWithAllowance(t *testing.T) {
 		observedParam{fakeTxId0, 0}, observedParam{fakeTxId1, 0},
 	))
 	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
-		{Id: 1024, Amount: 5000},
-		{Id: 1025, Amount: 5000},
+		{Id: 1024, Amount: 5_000_000},
+		{Id: 1025, Amount: 5_000_000},
 	})))
-	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5000, instruction))
-	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5000))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5_000_000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5_000_000))
 	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1026, 0))
 	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
 		ActiveSupply: ownerBalance,
@@ -398,7 +398,7 @@ func TestUnmapFromWithAllowance(t *testing.T) {
 	}
 
 	// Spender calls unmap with From=owner
-	unmapAmount := int64(7500)
+	unmapAmount := int64(7_500_000)
 	payload, err := tinyjson.Marshal(mapping.TransferParams{
 		Amount: fmt.Sprintf("%d", unmapAmount),
 		To:     regtestDestAddress(t),
@@ -449,7 +449,7 @@ func TestUnmapFromWithoutAllowanceFails(t *testing.T) {
 	const instruction = "deposit_to=" + allowanceOwner
 	const fakeTxId0 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
 	const fakeTxId1 = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
-	const ownerBalance = int64(10000)
+	const ownerBalance = int64(10_000_000)
 
 	ct, contractId := setupAllowanceContract(t, ownerBalance)
 
@@ -457,11 +457,11 @@ func TestUnmapFromWithoutAllowanceFails(t *testing.T) {
 		observedParam{fakeTxId0, 0}, observedParam{fakeTxId1, 0},
 	))
 	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
-		{Id: 1024, Amount: 5000},
-		{Id: 1025, Amount: 5000},
+		{Id: 1024, Amount: 5_000_000},
+		{Id: 1025, Amount: 5_000_000},
 	})))
-	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5000, instruction))
-	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5000))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5_000_000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5_000_000))
 	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1026, 0))
 	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
 		ActiveSupply: ownerBalance,
@@ -477,7 +477,7 @@ func TestUnmapFromWithoutAllowanceFails(t *testing.T) {
 	// No approve — spender has no allowance
 
 	payload, err := tinyjson.Marshal(mapping.TransferParams{
-		Amount: "7500",
+		Amount: "7500000",
 		To:     regtestDestAddress(t),
 		From:   allowanceOwner,
 	})
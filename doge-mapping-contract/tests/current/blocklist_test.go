@@ -1,9 +1,10 @@
 package current_test
 
 import (
-	btcMapping "doge-mapping-contract"
 	"doge-mapping-contract/contract/constants"
+	"encoding/hex"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -18,12 +19,22 @@ import (
 const testContractId = "mapping_contract"
 const testOwner = "hive:milo-hpr"
 
-// Valid Bitcoin testnet4 block headers (116087 → 116088 → 116089).
+// The Dogecoin mainnet genesis followed by two scrypt-mined regtest headers:
+// a legacy block, then a merged-mined block carrying its AuxPoW. The regtest
+// build does not retarget, so they are stored at an arbitrary height.
 const lastBlockHeight = "116087"
 
-const lastBlockHeader = "00c0a520165303733ee5b0561d46da9dcce685fd12a807d64472931c46d5920c00000000c96f929654fc44fb69783b6cc4f2340ad85de5b10c5047836561901299ed23d162525469ffff001dda00dd53"
+const (
+	dogeGenesisHeader = "010000000000000000000000000000000000000000000000000000000000000000000000696ad20e2dd4365c7459b4a4a5af743d5e92c6da3229e6532cd605f6533f2a5b24a6a152f0ff0f1e67860100"
+	dogeLegacyBlock   = "010000009156352c1818b32e90c9e792efd6a11a82fe7956a630f03bbee236cedae3911af6fd9e59963ddf1c3dfcd05b48ed64e2813c72f6645f3481863b559af63e081e60a6a152ffff7f2000000000"
+	// Base header (80 bytes) followed by its AuxPoW.
+	dogeAuxPowBlock     = "0401620057419e8b50264977c8ae903f7f4d7c9928a37eb1eb5d1ab37c77d3baada607ab15e437611af65589ebdbec0b7b4805c7d7f282bb7ea66857ac5fc84e7cd4822e9ca6a152ffff7f200000000001000000010000000000000000000000000000000000000000000000000000000000000000ffffffff300340420ffabe6d6d0cc7583f9059227286974a0c474c8363f8b0dfd81002be0000b120cc9ca41d320200000007000000ffffffff0132000000000000000151000000000000000000000000000000000000000000000000000000000000000000000000017d10de8554ed5ca40f9d0f0e0f4375b5b338af3fb96d33c9b2f53b5289b8f4fe00000000016e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d01000000000000200000000000000000000000000000000000000000000000000000000000000000db909c16599ab78e80fb14a03ad705b7b25f4d166e3d765b3335820f16e90c629ca6a152ffff7f2001000000"
+	dogeAuxPowBlockBase = "0401620057419e8b50264977c8ae903f7f4d7c9928a37eb1eb5d1ab37c77d3baada607ab15e437611af65589ebdbec0b7b4805c7d7f282bb7ea66857ac5fc84e7cd4822e9ca6a152ffff7f2000000000"
+)
+
+const lastBlockHeader = dogeGenesisHeader
 
-const twoBlocksPayload = `{"blocks":"00c0fa213b04801d1b66efcf8f41290a675777893f5c6ac158a585654263ba0900000000fdf6162d92eee3af012f1ddab30a401bb371a0da32371d185fc25eb3655fd6d013575469ffff001db80220f80000002002883f9d7847a35a0d371cd11bf95c0f9d252ed41f46dde04172bf0c000000003d2af3ae86b3638665e6214df4dc12712fd7486348c3c319cedb3c69bc8a4ddac45b5469ffff001d1adfdc74","latest_fee":1}`
+const twoBlocksPayload = `{"blocks":"` + dogeLegacyBlock + dogeAuxPowBlock + `","latest_fee":1}`
 
 type ctWrapper struct {
 	ct *test_utils.ContractTest
@@ -273,55 +284,102 @@ func TestAllOperations(t *testing.T) {
 	// ========== AddBlocks round-trip (block sequence bug) ==========
 	// Reproduces the testnet bug: after addBlocks stores block headers as raw
 	// bytes, the next addBlocks must read them back and verify chain continuity.
-	// Uses real BTC testnet3 block headers at heights 4888515 → 4888516 → 4888517.
+	// Uses the Dogecoin genesis → legacy block → merged-mined block fixtures.
 
 	t.Run("AddBlocks_RoundTrip_ChainContinuity", func(t *testing.T) {
 		rtId := "roundtrip_blocklist"
-		// Use testnet3 WASM since we're testing with real testnet3 block headers
-		w.ct.RegisterContract(rtId, testOwner, btcMapping.TestnetWasm)
-
-		// Real BTC testnet3 block headers fetched from btcd RPC.
-		// hash(4888515) = 00000000f570586692e115a950b812f359b27ac1441c91b91223163843504515
-		// hash(4888516) = 000000000923f265408b69969242b68fbec135b24761ac2f51cd60b53855dc1c
-		// hash(4888517) = 000000002e42abbf29b8d01a647c0b42521966edb88e87e11c80720de1135a39
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
-
-		// Seed with block 4888515 stored as raw bytes (matching what the
-		// contract itself does in HandleAddBlocks line 124-126).
-		seedRaw := decodeHex(t, block4888515Hex)
-		w.ct.StateSet(rtId, constants.LastHeightKey, "4888515")
-		w.ct.StateSet(rtId, constants.BlockPrefix+"4888515", seedRaw)
+		w.ct.RegisterContract(rtId, testOwner, ContractWasm)
+
+		// hash(genesis) = 1a91e3dace36e2be3bf030a65679fe821aa1d6ef92e7c9902eb318182c355691
+		// hash(legacy)  = ab07a6adbad3777cb31a5debb17ea328997c4d7f3f90aec8774926508b9e4157
+		// hash(auxpow)  = a44ca272ea303ef387d8c4a2005da470b2fd3aecad223fce44e353bcf82cc43a
+
+		// Seed with the genesis stored as raw bytes (matching what the
+		// contract itself does in HandleAddBlocks).
+		seedRaw := decodeHex(t, dogeGenesisHeader)
+		w.ct.StateSet(rtId, constants.LastHeightKey, "0")
+		w.ct.StateSet(rtId, constants.BlockPrefix+"0", seedRaw)
 		// Supply: 4x int64 BE = 32 zero bytes for all-zero supply with base_fee=1
 		supply := make([]byte, 32)
 		supply[31] = 1 // base_fee_rate = 1
 		w.ct.StateSet(rtId, constants.SupplyKey, string(supply))
 
 		// Debug: verify the stored seed is readable
-		stored := w.ct.StateGet(rtId, constants.BlockPrefix+"4888515")
+		stored := w.ct.StateGet(rtId, constants.BlockPrefix+"0")
 		t.Logf("Stored seed length: %d, expected: 80", len(stored))
 		t.Logf("Stored seed hex: %x", []byte(stored)[:min(20, len(stored))])
 		t.Logf("Stored height: %s", w.ct.StateGet(rtId, constants.LastHeightKey))
 		t.Logf("Stored supply length: %d", len(w.ct.StateGet(rtId, constants.SupplyKey)))
 
-		// First addBlocks: submit block 4888516.
+		// First addBlocks: submit the legacy block.
 		// Use oracle DID as caller (always allowed) to bypass auth issues in test
 		oracleCaller := "did:vsc:oracle:doge"
-		payload1 := `{"blocks":"` + block4888516Hex + `","latest_fee":0}`
+		payload1 := `{"blocks":"` + dogeLegacyBlock + `","latest_fee":0}`
 		r1 := callActionOnContract(t, w, rtId, "addBlocks", payload1, oracleCaller)
 		t.Logf("r1: success=%v err=%q errMsg=%q ret=%q", r1.Success, r1.Err, r1.ErrMsg, r1.Ret)
-		require.True(t, r1.Success, "first addBlocks (4888516) should succeed: %s %s", r1.Err, r1.ErrMsg)
-		assert.Contains(t, r1.Ret, "4888516")
+		require.True(t, r1.Success, "first addBlocks (1) should succeed: %s %s", r1.Err, r1.ErrMsg)
+		assert.Contains(t, r1.Ret, "last height: 1")
 
-		// Second addBlocks: submit block 4888517.
+		// Second addBlocks: submit the merged-mined block with its AuxPoW.
 		// This reads back the raw bytes stored by the first call.
 		// If the raw byte round-trip corrupts the header, BlockHash()
 		// will differ and we get "block sequence incorrect".
-		payload2 := `{"blocks":"` + block4888517Hex + `","latest_fee":0}`
+		payload2 := `{"blocks":"` + dogeAuxPowBlock + `","latest_fee":0}`
 		r2 := callActionOnContract(t, w, rtId, "addBlocks", payload2, oracleCaller)
-		require.True(t, r2.Success, "second addBlocks (4888517) should succeed: %s %s", r2.Err, r2.ErrMsg)
-		assert.Contains(t, r2.Ret, "4888517")
+		require.True(t, r2.Success, "second addBlocks (2) should succeed: %s %s", r2.Err, r2.ErrMsg)
+		assert.Contains(t, r2.Ret, "last height: 2")
+
+		// Only the 80-byte base header is stored.
+		assert.Equal(t, decodeHex(t, dogeAuxPowBlockBase), w.ct.StateGet(rtId, constants.BlockPrefix+"2"))
+	})
+
+	t.Run("AddBlocks_BadAuxPowFails", func(t *testing.T) {
+		badId := "bad_auxpow_blocklist"
+		w.ct.RegisterContract(badId, testOwner, ContractWasm)
+		w.ct.StateSet(badId, constants.LastHeightKey, "1")
+		w.ct.StateSet(badId, constants.BlockPrefix+"1", decodeHex(t, dogeLegacyBlock))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(badId, constants.SupplyKey, string(supply))
+		oracleCaller := "did:vsc:oracle:doge"
+
+		// The base header alone: its version says merged-mined but no AuxPoW follows.
+		payload := `{"blocks":"` + dogeAuxPowBlockBase + `","latest_fee":1}`
+		r := callActionOnContract(t, w, badId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "addBlocks without the AuxPoW should fail")
+
+		// Changing the parent header breaks the coinbase merkle proof.
+		tampered := []byte(decodeHex(t, dogeAuxPowBlock))
+		tampered[len(tampered)-40]++
+		payload = `{"blocks":"` + hex.EncodeToString(tampered) + `","latest_fee":1}`
+		r = callActionOnContract(t, w, badId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "addBlocks with an invalid AuxPoW should fail")
+	})
+
+	t.Run("AddBlocks_CompetingBranchNeedsMoreWork", func(t *testing.T) {
+		fcId := "forkchoice_blocklist"
+		w.ct.RegisterContract(fcId, testOwner, ContractWasm)
+
+		w.ct.StateSet(fcId, constants.LastHeightKey, "0")
+		w.ct.StateSet(fcId, constants.BlockPrefix+"0", decodeHex(t, dogeGenesisHeader))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(fcId, constants.SupplyKey, string(supply))
+
+		oracleCaller := "did:vsc:oracle:doge"
+		payload := `{"blocks":"` + dogeLegacyBlock + dogeAuxPowBlock + `","latest_fee":1}`
+		r := callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
+		assert.NotEmpty(t, w.ct.StateGet(fcId, constants.ChainWorkPrefix+"2"))
+
+		// Resubmitting the tip adds nothing: it is already on the stored chain
+		// and must not be taken as a branch of its own.
+		payload = `{"blocks":"` + dogeAuxPowBlock + `","latest_fee":1}`
+		r = callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "resubmitted tip should be rejected")
+		assert.Contains(t, r.ErrMsg, "already stored")
+		assert.Equal(t, "2", w.ct.StateGet(fcId, constants.LastHeightKey))
+		assert.Empty(t, w.ct.StateGet(fcId, constants.StagedBranchKey))
 	})
 
 	// ========== ReplaceBlocks (multi-block reorg) ==========
@@ -342,61 +400,54 @@ func TestAllOperations(t *testing.T) {
 	})
 
 	t.Run("ReplaceBlocks_MultiBlock_Success", func(t *testing.T) {
-		// Set up a 3-block chain: 4888515 → 4888516 → 4888517
+		// Set up a 3-block chain: genesis → legacy → auxpow
 		rbId := "replaceblocks_test"
-		w.ct.RegisterContract(rbId, testOwner, btcMapping.TestnetWasm)
+		w.ct.RegisterContract(rbId, testOwner, ContractWasm)
 
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
-
-		// Seed with block 4888515
-		seedRaw := decodeHex(t, block4888515Hex)
-		w.ct.StateSet(rbId, constants.LastHeightKey, "4888515")
-		w.ct.StateSet(rbId, constants.BlockPrefix+"4888515", seedRaw)
+		// Seed with the genesis
+		seedRaw := decodeHex(t, dogeGenesisHeader)
+		w.ct.StateSet(rbId, constants.LastHeightKey, "0")
+		w.ct.StateSet(rbId, constants.BlockPrefix+"0", seedRaw)
 		supply := make([]byte, 32)
 		supply[31] = 1
 		w.ct.StateSet(rbId, constants.SupplyKey, string(supply))
 
-		// Add blocks 4888516 and 4888517
+		// Add blocks 1 and 2
 		oracleCaller := "did:vsc:oracle:doge"
-		payload := `{"blocks":"` + block4888516Hex + block4888517Hex + `","latest_fee":1}`
+		payload := `{"blocks":"` + dogeLegacyBlock + dogeAuxPowBlock + `","latest_fee":1}`
 		r := callActionOnContract(t, w, rbId, "addBlocks", payload, oracleCaller)
 		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
-		assert.Contains(t, r.Ret, "4888517")
+		assert.Contains(t, r.Ret, "last height: 2")
 
-		// Now replace both blocks 4888516 and 4888517 with themselves (same canonical headers).
+		// Now replace both blocks 1 and 2 with themselves (same canonical headers).
 		// This simulates a 2-block reorg where the canonical chain happens to match.
 		// The key test is that the chaining validation passes for multi-block replacement.
-		replacePayload := block4888516Hex + block4888517Hex
+		replacePayload := dogeLegacyBlock + dogeAuxPowBlock
 		r2 := callActionOnContract(t, w, rbId, "replaceBlocks", replacePayload, "")
 		require.True(t, r2.Success, "replaceBlocks (2 blocks) should succeed: %s %s", r2.Err, r2.ErrMsg)
 		assert.Contains(t, r2.Ret, "replaced 2 blocks")
-		assert.Contains(t, r2.Ret, "4888517")
+		assert.Contains(t, r2.Ret, "tip at height: 2")
 	})
 
 	t.Run("ReplaceBlocks_SingleBlock_DelegatesToReplaceBlock", func(t *testing.T) {
 		// Single-header replaceBlocks should delegate to HandleReplaceBlock
 		rbId2 := "replaceblocks_single"
-		w.ct.RegisterContract(rbId2, testOwner, btcMapping.TestnetWasm)
-
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
+		w.ct.RegisterContract(rbId2, testOwner, ContractWasm)
 
-		seedRaw := decodeHex(t, block4888515Hex)
-		raw516 := decodeHex(t, block4888516Hex)
-		w.ct.StateSet(rbId2, constants.LastHeightKey, "4888516")
-		w.ct.StateSet(rbId2, constants.BlockPrefix+"4888515", seedRaw)
-		w.ct.StateSet(rbId2, constants.BlockPrefix+"4888516", raw516)
+		seedRaw := decodeHex(t, dogeLegacyBlock)
+		raw2 := decodeHex(t, dogeAuxPowBlockBase)
+		w.ct.StateSet(rbId2, constants.LastHeightKey, "2")
+		w.ct.StateSet(rbId2, constants.BlockPrefix+"1", seedRaw)
+		w.ct.StateSet(rbId2, constants.BlockPrefix+"2", raw2)
 		supply := make([]byte, 32)
 		supply[31] = 1
 		w.ct.StateSet(rbId2, constants.SupplyKey, string(supply))
 
-		// Replace just the tip (single block)
-		replacePayload := block4888516Hex
+		// Replace just the tip (single block), AuxPoW included
+		replacePayload := dogeAuxPowBlock
 		r := callActionOnContract(t, w, rbId2, "replaceBlocks", replacePayload, "")
 		require.True(t, r.Success, "single-block replaceBlocks should succeed: %s %s", r.Err, r.ErrMsg)
-		assert.Contains(t, r.Ret, "4888516")
+		assert.Contains(t, r.Ret, "height: 2")
 	})
 
 	// ========== Unmap ==========
@@ -440,3 +491,142 @@ func TestAllOperations(t *testing.T) {
 		assert.False(t, r.Success, "transferFrom with insufficient balance should fail")
 	})
 }
+
+func TestSeedBlocksHeaderRun(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+
+	// Dropping the middle header breaks the run's linkage.
+	broken := dogeGenesisHeader + dogeAuxPowBlock
+	r := callAction(t, w, "seedBlocks", `{"block_headers":"`+broken+`","block_height":`+lastBlockHeight+`}`, "")
+	assert.False(t, r.Success, "seedBlocks with unlinked headers should fail")
+
+	// The merged-mined header carries its AuxPoW, as for addBlocks.
+	run := dogeGenesisHeader + dogeLegacyBlock + dogeAuxPowBlock
+	r = callAction(t, w, "seedBlocks", `{"block_headers":"`+run+`","block_height":`+lastBlockHeight+`}`, "")
+	require.True(t, r.Success, "seedBlocks with a header run should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.SeedHeightKey))
+	assert.Equal(t, decodeHex(t, dogeAuxPowBlockBase), ct.StateGet(testContractId, constants.BlockPrefix+"116089"))
+}
+
+func TestAddBlocksFutureDrift(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	// callAt calls as the owner in a Hive block with the given timestamp.
+	callAt := func(action, payload, timestamp string) test_utils.ContractTestCallResult {
+		self := basicSelf(t, testOwner)
+		self.Timestamp = timestamp
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self:       *self,
+			ContractId: testContractId,
+			Action:     action,
+			Payload:    json.RawMessage([]byte(payload)),
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     testOwner,
+		})
+	}
+
+	r := callAt("setMaxFutureDrift", "7200", "2013-12-06T00:00:00")
+	require.True(t, r.Success, "setMaxFutureDrift failed: %s %s", r.Err, r.ErrMsg)
+
+	// The headers were mined on 2013-12-06 between 10:26 and 10:28 UTC.
+	r = callAt("addBlocks", twoBlocksPayload, "2013-12-06T08:00:00")
+	assert.False(t, r.Success, "addBlocks with headers more than 2 hours ahead should fail")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = callAt("addBlocks", twoBlocksPayload, "2013-12-06T10:00:00")
+	require.True(t, r.Success, "addBlocks within the drift bound should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+}
+
+func TestAddBlocksOracleQuorum(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+	submit := func(blocks string, fee int, oracle string) test_utils.ContractTestCallResult {
+		payload := `{"blocks":"` + blocks + `","latest_fee":` + strconv.Itoa(fee) + `}`
+		return callAction(t, w, "addBlocks", payload, oracle)
+	}
+
+	r := callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "hive:oracle1")
+	assert.False(t, r.Success, "setOracles by a non-owner should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2"],"threshold":3}`, "")
+	assert.False(t, r.Success, "setOracles with a threshold above the set size should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "")
+	require.True(t, r.Success, "setOracles failed: %s %s", r.Err, r.ErrMsg)
+
+	r = submit(added.Blocks, 1, "did:vsc:oracle:doge")
+	assert.False(t, r.Success, "the single oracle address is not a member of the set")
+
+	r = submit(added.Blocks, 1_000_000, "hive:oracle1")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	// A different batch does not count towards the first.
+	r = submit(dogeLegacyBlock, 1_000_000, "hive:oracle2")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = submit(added.Blocks, 3_000_000, "hive:oracle3")
+	require.True(t, r.Success, "quorum commit failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Contains(t, r.Ret, "base fee: 2000000")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle1"))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle3"))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+
+	// The single oracle address cannot change the headers around the quorum.
+	for _, action := range []string{"seedBlocks", "replaceBlock", "replaceBlocks", "prune"} {
+		r = callAction(t, w, action, added.Blocks[160:], "did:vsc:oracle:doge")
+		assert.False(t, r.Success, "%s by the single oracle address should fail with an oracle set", action)
+	}
+	r = callAction(t, w, "prune", "", "")
+	require.True(t, r.Success, "prune by the owner failed: %s %s", r.Err, r.ErrMsg)
+
+	r = callAction(t, w, "setOracles", `{"oracles":[],"threshold":0}`, "")
+	require.True(t, r.Success, "clearing the oracle set failed: %s %s", r.Err, r.ErrMsg)
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+}
+
+func TestAddBlocksFeeRateWindow(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+
+	r := callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[:160]+`","latest_fee":1000000}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 1000000")
+
+	// The median of 0.01 and 0.5 DOGE/kB is 0.255 DOGE/kB, but one update
+	// moves the rate by at most a quarter.
+	r = callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[160:]+`","latest_fee":50000000}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 1250000")
+	assert.Len(t, ct.StateGet(testContractId, constants.FeeWindowKey), 16)
+}
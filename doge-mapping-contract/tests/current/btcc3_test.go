@@ -119,24 +119,24 @@ func TestBTCC3_PerBlockUnmapCapEnforced(t *testing.T) {
 	t.Cleanup(func() { ct.DataLayer.Stop() })
 
 	const contractId = "btcc3_cap"
-	// Balance + UTXOs sized so two ~4500-sat unmaps would together
-	// breach a 6000-sat cap. Each UTXO is 5000 sats so each unmap
-	// can stand alone.
-	btcc3SetupContract(t, &ct, contractId, 20000, 5000, 5000, 5000, 5000)
+	// Balance + UTXOs sized so two ~0.045 DOGE unmaps would together
+	// breach a 0.06 DOGE cap. Each UTXO is 0.05 DOGE so each unmap
+	// can stand alone, above the 0.01 DOGE dust limit.
+	btcc3SetupContract(t, &ct, contractId, 20_000_000, 5_000_000, 5_000_000, 5_000_000, 5_000_000)
 
 	// Set the cap deliberately low so the second unmap in the same
-	// Hive block trips the limit. 6000 sats < amount (4500) + amount (4500).
+	// Hive block trips the limit. 6000000 < amount (4500000) + amount (4500000).
 	w := &ctWrapper{ct: &ct}
-	r := callActionOnContract(t, w, contractId, "setMaxUnmapPerBlock", "6000", "hive:milo-hpr")
+	r := callActionOnContract(t, w, contractId, "setMaxUnmapPerBlock", "6000000", "hive:milo-hpr")
 	require.True(t, r.Success, "setMaxUnmapPerBlock should succeed: %s %s", r.Err, r.ErrMsg)
-	assert.Equal(t, "6000", ct.StateGet(contractId, constants.MaxUnmapPerBlockKey))
+	assert.Equal(t, "6000000", ct.StateGet(contractId, constants.MaxUnmapPerBlockKey))
 
-	// First unmap in block A — within the cap (finalAmt ≈ 4500 + small fees).
-	r1 := btcc3Unmap(t, &ct, contractId, 4500, "blockA")
+	// First unmap in block A — within the cap (finalAmt ≈ 4500000 + small fees).
+	r1 := btcc3Unmap(t, &ct, contractId, 4_500_000, "blockA")
 	require.True(t, r1.Success, "first unmap (within cap) should succeed: %s %s", r1.Err, r1.ErrMsg)
 
 	// Second unmap in the same Hive block — accumulator + finalAmt2 > cap.
-	r2 := btcc3Unmap(t, &ct, contractId, 4500, "blockA")
+	r2 := btcc3Unmap(t, &ct, contractId, 4_500_000, "blockA")
 	if r2.Success {
 		t.Fatalf("BTC-C3 leak: second unmap in same Hive block was not rejected by the rate limit. ret=%q", r2.Ret)
 	}
@@ -148,12 +148,12 @@ func TestBTCC3_PerBlockUnmapCapEnforced(t *testing.T) {
 	rawAcc := ct.StateGet(contractId, constants.BlockUnmapAccKey)
 	_, accum := decodeAccumulator(rawAcc)
 	assert.Greater(t, accum, int64(0), "accumulator must be positive after successful unmap")
-	assert.LessOrEqual(t, accum, int64(6000), "accumulator must not exceed the cap")
+	assert.LessOrEqual(t, accum, int64(6_000_000), "accumulator must not exceed the cap")
 
 	// Advance the Hive block height — accumulator should reset for the
 	// next unmap.
 	ct.IncrementBlocks(1)
-	r3 := btcc3Unmap(t, &ct, contractId, 4500, "blockB")
+	r3 := btcc3Unmap(t, &ct, contractId, 4_500_000, "blockB")
 	require.True(t, r3.Success, "unmap in NEXT Hive block should succeed (accumulator reset): %s %s", r3.Err, r3.ErrMsg)
 }
 
@@ -162,7 +162,7 @@ func TestBTCC3_DisabledByZeroCap(t *testing.T) {
 	t.Cleanup(func() { ct.DataLayer.Stop() })
 
 	const contractId = "btcc3_disabled"
-	btcc3SetupContract(t, &ct, contractId, 20000, 5000, 5000, 5000)
+	btcc3SetupContract(t, &ct, contractId, 20_000_000, 5_000_000, 5_000_000, 5_000_000)
 
 	// Explicitly disable the rate limit. Two big unmaps in the same
 	// Hive block should both succeed.
@@ -171,9 +171,9 @@ func TestBTCC3_DisabledByZeroCap(t *testing.T) {
 	require.True(t, r.Success, "setMaxUnmapPerBlock(0) should succeed: %s %s", r.Err, r.ErrMsg)
 	assert.Equal(t, "0", ct.StateGet(contractId, constants.MaxUnmapPerBlockKey))
 
-	r1 := btcc3Unmap(t, &ct, contractId, 4500, "blockA")
+	r1 := btcc3Unmap(t, &ct, contractId, 4_500_000, "blockA")
 	require.True(t, r1.Success, "first unmap with cap=0 should succeed: %s %s", r1.Err, r1.ErrMsg)
-	r2 := btcc3Unmap(t, &ct, contractId, 4500, "blockA2")
+	r2 := btcc3Unmap(t, &ct, contractId, 4_500_000, "blockA2")
 	require.True(t, r2.Success, "second unmap with cap=0 should succeed: %s %s", r2.Err, r2.ErrMsg)
 }
 
@@ -200,3 +200,47 @@ func TestBTCC3_SetMaxUnmapPerBlockRejectsNegative(t *testing.T) {
 	r := callActionOnContract(t, w, contractId, "setMaxUnmapPerBlock", "-1", "hive:milo-hpr")
 	assert.False(t, r.Success, "negative cap must be rejected")
 }
+
+// A queued withdrawal that is cancelled gives its debit back to the current
+// Hive block's allowance, so the refunded amount can be unmapped again in
+// the same block.
+func TestBTCC3_RefundReleasesUnmapCap(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+
+	const contractId = "btcc3_refund"
+	const sender = "hive:milo-hpr"
+	btcc3SetupContract(t, &ct, contractId, 20_000_000, 5_000_000, 5_000_000, 5_000_000, 5_000_000)
+
+	w := &ctWrapper{ct: &ct}
+	for _, c := range [][2]string{
+		{"setMaxUnmapPerBlock", "6000000"},
+		{"setWithdrawalQueue", "true"},
+		{"setWithdrawalTimeout", "1"},
+	} {
+		r := callActionOnContract(t, w, contractId, c[0], c[1], sender)
+		require.True(t, r.Success, "%s failed: %s %s", c[0], r.Err, r.ErrMsg)
+	}
+
+	r := btcc3Unmap(t, &ct, contractId, 4_500_000, "blockA")
+	require.True(t, r.Success, "queued unmap failed: %s %s", r.Err, r.ErrMsg)
+	ct.IncrementBlocks(2)
+
+	r = btcc3Unmap(t, &ct, contractId, 4_500_000, "blockB")
+	require.True(t, r.Success, "queued unmap failed: %s %s", r.Err, r.ErrMsg)
+	_, before := decodeAccumulator(ct.StateGet(contractId, constants.BlockUnmapAccKey))
+	require.GreaterOrEqual(t, before, int64(4_500_000))
+	assert.False(t, btcc3Unmap(t, &ct, contractId, 4_500_000, "blockB").Success, "the cap is used up")
+
+	// Only the first withdrawal has waited out the timeout; cancelling it
+	// frees the allowance the second one used.
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", "2", sender)
+	assert.False(t, r.Success, "a withdrawal queued this block cannot be cancelled yet")
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", "1", sender)
+	require.True(t, r.Success, "cancelWithdrawal failed: %s %s", r.Err, r.ErrMsg)
+	_, after := decodeAccumulator(ct.StateGet(contractId, constants.BlockUnmapAccKey))
+	assert.Less(t, after, before, "the refund must release its debit from the cap")
+
+	r = btcc3Unmap(t, &ct, contractId, 4_500_000, "blockB")
+	require.True(t, r.Success, "unmap after the refund should fit under the cap: %s %s", r.Err, r.ErrMsg)
+}
@@ -152,8 +152,8 @@ func TestUnmapInsufficientBalance(t *testing.T) {
 }
 
 // ---------------------------------------------------------------------------
-// TestUnmapAmountBelowDust — Unmap an amount below the dust threshold (546 sats).
-// Should fail because the BTC output would be unspendable.
+// TestUnmapAmountBelowDust — Unmap an amount below the dust limit (0.01 DOGE).
+// Should fail because the DOGE output would not relay.
 // ---------------------------------------------------------------------------
 func TestUnmapAmountBelowDust(t *testing.T) {
 	const instruction = "deposit_to=hive:milo-hpr"
@@ -180,7 +180,7 @@ func TestUnmapAmountBelowDust(t *testing.T) {
 	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
 	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
 
-	// 100 sats is below the dust threshold (546 sats) so the BTC output would be unspendable.
+	// 100 koinu is below the dust limit (0.01 DOGE) so the DOGE output would not relay.
 	payload, err := tinyjson.Marshal(mapping.TransferParams{
 		Amount: "100",
 		To:     regtestDestAddress(t),
@@ -300,11 +300,11 @@ func TestMapMultipleOutputsSameTx(t *testing.T) {
 	const amount2 = int64(3000)
 
 	// Derive both deposit addresses
-	addr1, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction1, regtestParams())
+	addr1, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction1, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive addr1:", err)
 	}
-	addr2, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction2, regtestParams())
+	addr2, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction2, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive addr2:", err)
 	}
@@ -400,19 +400,19 @@ func TestUnmapExactBalance(t *testing.T) {
 
 	// Set a large balance so we can unmap a moderate amount and verify
 	// the balance decreases correctly (amount + vscFee + btcFee).
-	const balance = int64(100000)
-	const unmapAmount = int64(50000)
+	const balance = int64(100_000_000)
+	const unmapAmount = int64(50_000_000)
 
 	ct.StateSet(contractId, constants.BalancePrefix+"hive:milo-hpr", encodeBalance(t, balance))
 	ct.StateSet(contractId, constants.ObservedBlockPrefix+"100", buildObservedList(t,
 		observedParam{fakeTxId0, 0}, observedParam{fakeTxId1, 0},
 	))
 	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
-		{Id: 1024, Amount: 50000},
-		{Id: 1025, Amount: 50000},
+		{Id: 1024, Amount: 50_000_000},
+		{Id: 1025, Amount: 50_000_000},
 	})))
-	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 50000, instruction))
-	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 50000))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 50_000_000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 50_000_000))
 	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1026, 0))
 	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
 		ActiveSupply: balance,
@@ -451,8 +451,8 @@ func TestUnmapExactBalance(t *testing.T) {
 
 	// After unmap, balance should be less than original
 	remainingBal := ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr")
-	// vscFee = 0 (VscFeeMinSats=0, VscFeeRateBps=0)
-	// So remaining should be 100000 - 50000 - btcFee = 50000 - btcFee
+	// vscFee = 0 (the chain policy has no VSC fee)
+	// So remaining should be 1 DOGE - 0.5 DOGE - btcFee = 0.5 DOGE - btcFee
 	t.Logf("remaining balance after unmap: %q", remainingBal)
 	// Verify it decreased significantly
 	if remainingBal != "" {
@@ -629,14 +629,14 @@ func TestUnmapSupplyUpdates(t *testing.T) {
 	contractId := "mapping_contract"
 	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
 
-	const balance = int64(100000)
-	const unmapAmount = int64(20000)
+	const balance = int64(100_000_000)
+	const unmapAmount = int64(20_000_000)
 
 	ct.StateSet(contractId, constants.BalancePrefix+"hive:milo-hpr", encodeBalance(t, balance))
 	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
-		{Id: 1024, Amount: 100000},
+		{Id: 1024, Amount: 100_000_000},
 	})))
-	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 100000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 100_000_000, instruction))
 	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1025, 0))
 	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
 		ActiveSupply: balance,
@@ -677,7 +677,7 @@ func TestUnmapSupplyUpdates(t *testing.T) {
 
 	t.Logf("supply after unmap: active=%d, user=%d, fee=%d", supply.ActiveSupply, supply.UserSupply, supply.FeeSupply)
 
-	// vscFee = 0 (VscFeeMinSats=0, VscFeeRateBps=0)
+	// vscFee = 0 (the chain policy has no VSC fee)
 	// ActiveSupply should decrease by (amount + btcFee)
 	assert.True(t, supply.ActiveSupply < balance, "active supply should decrease")
 	assert.True(t, supply.UserSupply < balance, "user supply should decrease")
@@ -691,7 +691,7 @@ func TestUnmapSupplyUpdates(t *testing.T) {
 func TestMapThenUnmapFullCycle(t *testing.T) {
 	const instruction = "deposit_to=hive:milo-hpr"
 	const blockHeight = uint32(100)
-	const depositAmount = int64(50000)
+	const depositAmount = int64(50_000_000)
 
 	fixture := buildMapFixture(t, instruction, depositAmount, blockHeight)
 
@@ -737,7 +737,7 @@ func TestMapThenUnmapFullCycle(t *testing.T) {
 	t.Log("balance after map:", depositAmount)
 
 	// Step 2: Unmap a portion (small enough to cover fees)
-	const unmapAmount = int64(10000)
+	const unmapAmount = int64(10_000_000)
 	unmapPayload, _ := tinyjson.Marshal(mapping.TransferParams{
 		Amount: fmt.Sprintf("%d", unmapAmount),
 		To:     regtestDestAddress(t),
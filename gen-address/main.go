@@ -1,11 +1,11 @@
 package main
 
 import (
+	"btc-mapping-contract/contract/constants"
 	"btc-mapping-contract/contract/mapping"
+	"btc-mapping-contract/contract/network"
 	"fmt"
 	"os"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
 func main() {
@@ -20,7 +20,8 @@ func main() {
 	instruction := "deposit_to=" + recipient
 	fmt.Println("Instruction:", instruction)
 
-	address, _, err := mapping.DepositAddress(primaryPubKey, backupPubKey, instruction, &chaincfg.TestNet3Params)
+	net, _ := network.Lookup(constants.Testnet3)
+	address, _, err := mapping.DepositAddress(primaryPubKey, backupPubKey, instruction, net)
 	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
@@ -6,11 +6,15 @@ import (
 	"encoding/hex"
 	"errors"
 	"math"
+	"math/big"
 	"strconv"
 
 	"ltc-mapping-contract/contract/constants"
 	ce "ltc-mapping-contract/contract/contracterrors"
+	"ltc-mapping-contract/contract/network"
 
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 )
 
@@ -26,6 +30,25 @@ type AddBlocksParams struct {
 type SeedBlocksParams struct {
 	BlockHeader string `json:"block_header"`
 	BlockHeight uint32 `json:"block_height"`
+	// EpochHeader is the header at the first height of the seed's difficulty
+	// epoch. Not required when the seed itself starts an epoch.
+	EpochHeader string `json:"epoch_header,omitempty"`
+	// RetargetHeader is the header just before the seed's epoch start, where
+	// Litecoin's next retarget window begins. Not required when the seed
+	// itself is that header.
+	RetargetHeader string `json:"retarget_header,omitempty"`
+	// BlockHeaders is a contiguous run of headers starting at BlockHeight,
+	// given in place of BlockHeader. The last header becomes the tip.
+	BlockHeaders string `json:"block_headers,omitempty"`
+	// Network names the chain the contract is bridged to. The first seed
+	// fixes it in state; later seeds may repeat it or leave it empty.
+	Network string `json:"network,omitempty"`
+}
+
+//tinyjson:json
+type SetOraclesParams struct {
+	Oracles   []string `json:"oracles"`
+	Threshold uint32   `json:"threshold"`
 }
 
 var ErrorLastHeightDNE = errors.New("last height does not exist")
@@ -90,67 +113,208 @@ func DivideHeaderList(blocksHex *string) ([]BlockHeaderBytes, error) {
 	return blockHeaders, nil
 }
 
-func HandleAddBlocks(rawHeaders []BlockHeaderBytes, networkMode string) (uint32, error) {
-	_ = networkMode // LTC skips PoW (Scrypt unavailable in btcsuite); kept for signature compat
+// HandleAddBlocks appends headers to the stored chain, or adds them to a
+// competing branch forking from a retained height, which replaces the stored
+// chain once it carries more work. It returns the new tip and the height the
+// batch builds on, which is below the previous tip only when a fork was taken;
+// a batch that is only staged leaves both at the current tip.
+func HandleAddBlocks(rawHeaders []BlockHeaderBytes, net *network.Network) (uint32, uint32, error) {
+	params := net.Pow
+	maxTime, err := maxHeaderTime()
+	if err != nil {
+		return 0, 0, err
+	}
 
 	lastHeight, err := LastHeightFromState()
 	if err != nil {
-		return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+		return 0, 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
 	}
 
 	// block headers stored as raw 80 bytes
 	lastBlockRaw := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatInt(int64(lastHeight), 10))
 	if lastBlockRaw == nil || *lastBlockRaw == "" {
-		return 0, ce.NewContractError(ce.ErrStateAccess, "no block header found at height "+strconv.FormatInt(int64(lastHeight), 10))
+		return 0, 0, ce.NewContractError(ce.ErrStateAccess, "no block header found at height "+strconv.FormatInt(int64(lastHeight), 10))
 	}
 	lastBlockBytes := []byte(*lastBlockRaw)
 	var lastBlockHeader wire.BlockHeader
 	err = lastBlockHeader.BtcDecode(bytes.NewReader(lastBlockBytes), wire.ProtocolVersion, wire.LatestEncoding)
 	if err != nil {
-		return 0, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+		return 0, 0, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+	}
+
+	tipHeight := lastHeight
+	floor := retentionFloor(tipHeight, net.BlockRetention)
+	branch, hasBranch := loadStagedBranch()
+	if hasBranch && !stagedBranchLive(branch, floor) {
+		clearStagedBranch(branch)
+		hasBranch = false
 	}
 
-	// LTC uses Scrypt for PoW which is not available in btcsuite, so we skip
-	// the PoW check here. Header integrity is enforced by the oracle before
-	// addBlocks is called; here we only validate decodability and chain
-	// linkage by PrevBlock hash.
-	for _, headerBytes := range rawHeaders {
+	// A batch that does not extend the tip either continues the staged
+	// branch or starts a new one from a retained height below the tip.
+	// Headers the stored chain already has are skipped first.
+	continuesBranch := false
+	if len(rawHeaders) > 0 {
+		var firstHeader wire.BlockHeader
+		err = firstHeader.BtcDecode(bytes.NewReader(rawHeaders[0][:]), wire.ProtocolVersion, wire.LatestEncoding)
+		if err != nil {
+			return 0, 0, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+		}
+		lastBlockHash := lastBlockHeader.BlockHash()
+		if !firstHeader.PrevBlock.IsEqual(&lastBlockHash) {
+			var stagedTip *wire.BlockHeader
+			if hasBranch {
+				stagedTip, _, _ = loadStagedHeader(branch.TipHeight)
+			}
+			var stagedHash chainhash.Hash
+			if stagedTip != nil {
+				stagedHash = stagedTip.BlockHash()
+			}
+			if stagedTip != nil && stagedHash.IsEqual(&firstHeader.PrevBlock) {
+				continuesBranch = true
+				lastHeight = branch.TipHeight
+				lastBlockHeader = *stagedTip
+			} else {
+				forkHeight, forkHeader, ok := findForkPoint(lastHeight, floor, &firstHeader.PrevBlock, loadHeader)
+				if !ok {
+					return 0, 0, ce.NewContractError(ce.ErrInput, "block sequence incorrect")
+				}
+				rawHeaders, forkHeight, forkHeader, err = skipStoredHeaders(rawHeaders, forkHeight, forkHeader, tipHeight)
+				if err != nil {
+					return 0, 0, err
+				}
+				lastHeight = forkHeight
+				lastBlockHeader = *forkHeader
+			}
+		}
+	}
+	forkHeight := lastHeight
+	if continuesBranch {
+		forkHeight = branch.ForkHeight
+	}
+	isFork := forkHeight < tipHeight
+
+	// Work is summed along the batch's own branch; the stored chain is not
+	// touched until the total is known.
+	work := parentChainWork(lastHeight)
+	var tipWork *big.Int
+	if isFork {
+		var forkOk, tipOk bool
+		if continuesBranch {
+			work, forkOk = new(big.Int).Set(branch.Work), true
+		} else {
+			work, forkOk = loadChainWork(lastHeight)
+		}
+		tipWork, tipOk = loadChainWork(tipHeight)
+		if !forkOk || !tipOk {
+			return 0, 0, ce.NewContractError(ce.ErrStateAccess, "no chain work stored to compare branches, call migrate")
+		}
+	}
+
+	view := newBranchView(forkHeight)
+	startHeight := lastHeight + 1
+	headers := make([]wire.BlockHeader, len(rawHeaders))
+	works := make([]*big.Int, len(rawHeaders))
+	for i, headerBytes := range rawHeaders {
 		// won't happen for 130 years but just in case
 		if lastHeight == math.MaxUint32 {
-			return 0, ce.NewContractError(ce.ErrArithmetic, "litecoin block height exceeds max possible")
+			return 0, 0, ce.NewContractError(ce.ErrArithmetic, "litecoin block height exceeds max possible")
 		}
 		blockHeight := lastHeight + 1
 
-		var blockHeader wire.BlockHeader
+		blockHeader := &headers[i]
 		err = blockHeader.BtcDecode(bytes.NewReader(headerBytes[:]), wire.ProtocolVersion, wire.LatestEncoding)
 		if err != nil {
-			return 0, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+			return 0, 0, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
 		}
-
 		lastBlockHash := lastBlockHeader.BlockHash()
 		if !blockHeader.PrevBlock.IsEqual(&lastBlockHash) {
-			return 0, ce.NewContractError(ce.ErrInput, "block sequence incorrect")
+			return 0, 0, ce.NewContractError(ce.ErrInput, "block sequence incorrect")
 		}
 
-		// store raw 80 bytes (not hex)
-		sdk.StateSetObject(
-			constants.BlockPrefix+strconv.FormatUint(uint64(blockHeight), 10),
-			string(headerBytes[:]),
-		)
+		if err := checkHeaderWith(params, blockHeight, &lastBlockHeader, blockHeader, headerBytes[:], view.anchor); err != nil {
+			return 0, 0, err
+		}
+		if err := checkTimestamp(blockHeight, &lastBlockHeader, blockHeader, view.header, maxTime); err != nil {
+			return 0, 0, err
+		}
+
+		view.batch[blockHeight] = blockHeader
+		work.Add(work, blockchain.CalcWork(blockHeader.Bits))
+		works[i] = new(big.Int).Set(work)
 		lastHeight = blockHeight
-		lastBlockHeader = blockHeader
+		lastBlockHeader = *blockHeader
 	}
 
-	PruneOldHeaders(lastHeight)
+	if isFork {
+		// A new branch replaces whatever was staged before it.
+		if hasBranch && !continuesBranch {
+			clearStagedBranch(branch)
+		}
+		if work.Cmp(tipWork) <= 0 {
+			for i, headerBytes := range rawHeaders {
+				sdk.StateSetObject(stagedHeaderKey(startHeight+uint32(i)), string(headerBytes[:]))
+			}
+			saveStagedBranch(&stagedBranch{ForkHeight: forkHeight, TipHeight: lastHeight, Work: work})
+			sdk.Log(createBranchLog(forkHeight, lastHeight))
+			return tipHeight, tipHeight, nil
+		}
+		if continuesBranch {
+			if err := adoptStagedBranch(branch); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
 
-	return lastHeight, nil
+	// store raw 80 bytes (not hex)
+	for i, headerBytes := range rawHeaders {
+		storeHeader(startHeight+uint32(i), &headers[i], headerBytes[:])
+		saveChainWork(startHeight+uint32(i), works[i])
+	}
+
+	if isFork {
+		dropStaleHeaders(lastHeight, tipHeight)
+		sdk.Log(createReorgLog(forkHeight, tipHeight, lastHeight))
+	}
+
+	PruneOldHeaders(lastHeight, net.BlockRetention)
+
+	return lastHeight, forkHeight, nil
+}
+
+// skipStoredHeaders drops the leading headers of a batch that the stored
+// chain already has above the fork point, so that an overlapping batch only
+// adds what is new. A batch made only of stored headers is rejected.
+func skipStoredHeaders(
+	rawHeaders []BlockHeaderBytes,
+	forkHeight uint32,
+	forkHeader *wire.BlockHeader,
+	tipHeight uint32,
+) ([]BlockHeaderBytes, uint32, *wire.BlockHeader, error) {
+	for len(rawHeaders) > 0 && forkHeight < tipHeight {
+		stored, ok := loadHeader(forkHeight + 1)
+		if !ok {
+			break
+		}
+		var buf bytes.Buffer
+		if err := stored.Serialize(&buf); err != nil || !bytes.Equal(buf.Bytes(), rawHeaders[0][:]) {
+			break
+		}
+		rawHeaders = rawHeaders[1:]
+		forkHeight++
+		forkHeader = stored
+	}
+	if len(rawHeaders) == 0 {
+		return nil, 0, nil, ce.NewContractError(ce.ErrInput, "all block headers are already stored")
+	}
+	return rawHeaders, forkHeight, forkHeader, nil
 }
 
-// PruneOldHeaders removes block headers beyond the retention window.
-// Uses a floor cursor to avoid re-scanning already-pruned regions.
-// Returns the number of headers pruned in this call.
-func PruneOldHeaders(lastHeight uint32) int {
-	retainFrom := int64(lastHeight) - int64(constants.MaxBlockRetention) + 1
+// PruneOldHeaders removes block headers beyond the latest retention headers,
+// with their observed tx lists. Uses a floor cursor to avoid re-scanning
+// already-pruned regions. Returns the number of headers pruned in this call.
+func PruneOldHeaders(lastHeight uint32, retention uint32) int {
+	retainFrom := int64(lastHeight) - int64(retention) + 1
 	if retainFrom <= 0 {
 		return 0
 	}
@@ -161,29 +325,60 @@ func PruneOldHeaders(lastHeight uint32) int {
 	if pruneFloor == 0 || int64(pruneFloor) >= retainFrom {
 		return 0
 	}
+	// Pruned headers are archived so deposits in them can still be proven.
+	// Headers below a re-seed belong to the old chain and are not.
+	seedHeight := seedHeightFromState()
+	archive, err := loadHeaderMMR()
+	archived := false
 	pruned := 0
 	h := int64(pruneFloor)
 	for ; h < retainFrom && pruned < constants.MaxPrunePerCall; h++ {
 		key := constants.BlockPrefix + strconv.FormatInt(h, 10)
+		observedKey := constants.ObservedBlockPrefix + strconv.FormatInt(h, 10)
 		existing := sdk.StateGetObject(key)
 		if existing != nil && *existing != "" {
+			// The observed tx list moves into the archive leaf, so a deposit
+			// already minted cannot be minted again from an archival proof.
+			var observed []byte
+			if raw := sdk.StateGetObject(observedKey); raw != nil {
+				observed = []byte(*raw)
+			}
+			leaf := archiveLeaf(rawHeaderHash([]byte(*existing)), observed)
+			if err == nil && uint32(h) >= seedHeight && archive.archive(uint32(h), leaf) {
+				archived = true
+				if len(observed) > 0 {
+					sdk.Log(createArchiveLog(uint32(h), observed))
+				}
+			}
 			sdk.StateDeleteObject(key)
 			pruned++
 		}
-		// Also prune the observed tx list for this block height
-		observedKey := constants.ObservedBlockPrefix + strconv.FormatInt(h, 10)
+		deleteChainWork(uint32(h))
 		sdk.StateDeleteObject(observedKey)
+		sdk.StateDeleteObject(constants.MintJournalPrefix + strconv.FormatInt(h, 10))
+		// The anchors for the previous epoch were last needed to retarget at h.
+		if h%constants.RetargetInterval == 0 && h > constants.RetargetInterval {
+			deleteRetargetAnchor(uint32(h) - constants.RetargetInterval)
+			deleteRetargetAnchor(uint32(h) - constants.RetargetInterval - 1)
+		}
+	}
+	if archived {
+		archive.save()
 	}
 	sdk.StateSetObject(constants.PruneFloorKey, strconv.FormatInt(h, 10))
 	return pruned
 }
 
 // HandleReplaceBlock replaces the block at the current tip height with a
-// corrected header. This is used to fix a stale/orphaned tip that prevents
-// new blocks from being appended. The replacement must chain correctly to
-// the block at height-1. PoW is not checked (LTC uses Scrypt).
-func HandleReplaceBlock(rawHeader BlockHeaderBytes, networkMode string) (uint32, error) {
-	_ = networkMode // LTC skips PoW (Scrypt unavailable in btcsuite); kept for signature compat
+// corrected header. addBlocks switches to a heavier branch on its own, so this
+// is an emergency override for when the oracle cannot supply one. The
+// replacement must pass scrypt PoW and chain correctly to the block at height-1.
+func HandleReplaceBlock(rawHeader BlockHeaderBytes, net *network.Network) (uint32, error) {
+	params := net.Pow
+	maxTime, err := maxHeaderTime()
+	if err != nil {
+		return 0, err
+	}
 
 	lastHeight, err := LastHeightFromState()
 	if err != nil {
@@ -216,42 +411,54 @@ func HandleReplaceBlock(rawHeader BlockHeaderBytes, networkMode string) (uint32,
 		return 0, ce.NewContractError(ce.ErrInput, "replacement block does not chain to block at height "+strconv.FormatUint(uint64(prevHeight), 10))
 	}
 
+	if err := checkHeader(params, lastHeight, &prevHeader, &newHeader, rawHeader[:]); err != nil {
+		return 0, err
+	}
+	if err := checkTimestamp(lastHeight, &prevHeader, &newHeader, loadHeader, maxTime); err != nil {
+		return 0, err
+	}
+
 	// overwrite the tip
-	sdk.StateSetObject(
-		constants.BlockPrefix+strconv.FormatUint(uint64(lastHeight), 10),
-		string(rawHeader[:]),
-	)
+	storeHeader(lastHeight, &newHeader, rawHeader[:])
+	work := parentChainWork(prevHeight)
+	saveChainWork(lastHeight, work.Add(work, blockchain.CalcWork(newHeader.Bits)))
 
-	// The observed TX list remains populated during a replacement, to protect against double mint where
-	// transactions are re-included in the replacement block. An incorrect mint from a replaced block can
-	// technically persist after replacement, but the oracle waits for 2 confirmations and a 3+ block
-	// reorg is unprecendented on BTC mainnet, so very low likelihood of encountering this guard at all
+	// The observed TX list remains populated during a replacement, so a deposit re-included in the
+	// replacement block is not minted twice. Mints journaled at the replaced height are queued for
+	// re-proof by the caller and clawed back if not re-proven in time.
 
 	return lastHeight, nil
 }
 
 // HandleReplaceBlocks replaces the top N blocks (from the tip downward) with
-// corrected headers. This handles multi-block reorgs where replaceBlock (single)
-// would fail because the block below the tip is also orphaned.
+// corrected headers. Like replaceBlock it is an emergency override of the
+// chain-work fork choice in addBlocks, for when the block below the tip is
+// also orphaned.
 //
 // The headers slice must be ordered lowest-to-highest (oldest first), matching
 // addBlocks ordering. The first header replaces lastHeight-(N-1), the last
-// header replaces lastHeight. PoW is not checked (LTC uses Scrypt).
-func HandleReplaceBlocks(rawHeaders []BlockHeaderBytes, networkMode string) (uint32, error) {
+// header replaces lastHeight. All must pass scrypt PoW and chain correctly.
+func HandleReplaceBlocks(rawHeaders []BlockHeaderBytes, net *network.Network) (uint32, error) {
 	if len(rawHeaders) == 0 {
 		return 0, ce.NewContractError(ce.ErrInput, "no replacement headers provided")
 	}
 
 	// Single header: delegate to the original single-block handler.
 	if len(rawHeaders) == 1 {
-		return HandleReplaceBlock(rawHeaders[0], networkMode)
+		return HandleReplaceBlock(rawHeaders[0], net)
 	}
 
 	// On mainnet, cap replacement depth to 2 blocks.
-	if !constants.IsTestnet(networkMode) && len(rawHeaders) > 2 {
+	if !net.Testnet && len(rawHeaders) > 2 {
 		return 0, ce.NewContractError(ce.ErrInput, "mainnet replacement limited to 2 blocks")
 	}
 
+	params := net.Pow
+	maxTime, err := maxHeaderTime()
+	if err != nil {
+		return 0, err
+	}
+
 	lastHeight, err := LastHeightFromState()
 	if err != nil {
 		return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
@@ -274,6 +481,8 @@ func HandleReplaceBlocks(rawHeaders []BlockHeaderBytes, networkMode string) (uin
 		return 0, ce.NewContractError(ce.ErrStateAccess, "error decoding block at anchor height "+strconv.FormatUint(uint64(anchorHeight), 10))
 	}
 	prevHash := anchorHeader.BlockHash()
+	prevHeader := anchorHeader
+	work := parentChainWork(anchorHeight)
 
 	// Validate and overwrite each header in order.
 	for i, headerBytes := range rawHeaders {
@@ -290,47 +499,216 @@ func HandleReplaceBlocks(rawHeaders []BlockHeaderBytes, networkMode string) (uin
 				"replacement block at height "+strconv.FormatUint(uint64(height), 10)+" does not chain to block at height "+strconv.FormatUint(uint64(height-1), 10))
 		}
 
-		sdk.StateSetObject(
-			constants.BlockPrefix+strconv.FormatUint(uint64(height), 10),
-			string(headerBytes[:]),
-		)
-		// The observed TX list remains populated during a replacement, to protect against double mint where
-		// transactions are re-included in the replacement block. An incorrect mint from a replaced block can
-		// technically persist after replacement, but the oracle waits for 2 confirmations and a 3+ block
-		// reorg is unprecendented on BTC mainnet, so very low likelihood of encountering this guard at all
+		if err := checkHeader(params, height, &prevHeader, &hdr, headerBytes[:]); err != nil {
+			return 0, err
+		}
+		if err := checkTimestamp(height, &prevHeader, &hdr, loadHeader, maxTime); err != nil {
+			return 0, err
+		}
+
+		storeHeader(height, &hdr, headerBytes[:])
+		saveChainWork(height, work.Add(work, blockchain.CalcWork(hdr.Bits)))
+		// The observed TX list remains populated during a replacement, so a deposit re-included in the
+		// replacement block is not minted twice. Mints journaled at the replaced height are queued for
+		// re-proof by the caller and clawed back if not re-proven in time.
 		prevHash = hdr.BlockHash()
+		prevHeader = hdr
 	}
 
 	return lastHeight, nil
 }
 
-func HandleSeedBlocks(seedParams SeedBlocksParams, allowReseed bool) (uint32, error) {
+// HandleSeedBlocks stores the first headers of the chain: a single header or a
+// contiguous run, checked for linkage, proof of work, difficulty and the
+// compiled-in checkpoints. Mainnet seeds once and never below the last
+// checkpoint; test networks may reseed above the current tip.
+func HandleSeedBlocks(seedParams SeedBlocksParams, net *network.Network) (uint32, error) {
 	lastHeight, err := LastHeightFromState()
 	if err != nil {
 		if err != ErrorLastHeightDNE {
 			return 0, err
 		}
-	} else if !allowReseed {
+	} else if !net.Testnet {
 		return 0, ce.NewContractError(ce.ErrInitialization, "blocks already seeded last height "+strconv.FormatUint(uint64(lastHeight), 10))
 	}
+	if lastHeight != 0 && lastHeight >= seedParams.BlockHeight {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"last height >= input block height. last height: "+strconv.FormatUint(uint64(lastHeight), 10),
+		)
+	}
 
-	if lastHeight == 0 || lastHeight < seedParams.BlockHeight {
-		// decode hex input → store raw bytes
+	rawHeaders, err := seedHeaders(seedParams, net.BlockRetention)
+	if err != nil {
+		return 0, err
+	}
+	if seedParams.BlockHeight > math.MaxUint32-uint32(len(rawHeaders)-1) {
+		return 0, ce.NewContractError(ce.ErrArithmetic, "litecoin block height exceeds max possible")
+	}
+	headers := make([]wire.BlockHeader, len(rawHeaders))
+	for i := range rawHeaders {
+		err := headers[i].BtcDecode(bytes.NewReader(rawHeaders[i][:]), wire.ProtocolVersion, wire.LatestEncoding)
+		if err != nil {
+			return 0, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+		}
+	}
+	tip := seedParams.BlockHeight + uint32(len(headers)-1)
+
+	// The next retarget needs the headers on either side of the seed's epoch
+	// start. Those the run covers are stored with it, otherwise they must be
+	// supplied.
+	supplied := make(map[uint32]*wire.BlockHeader)
+	start := epochStart(seedParams.BlockHeight)
+	anchorHexes := map[uint32]string{start: seedParams.EpochHeader}
+	if start > 0 {
+		anchorHexes[start-1] = seedParams.RetargetHeader
+	}
+	for height, headerHex := range anchorHexes {
+		if height >= seedParams.BlockHeight || headerHex == "" {
+			continue
+		}
+		supplied[height], err = decodeHeaderHex(headerHex)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = checkSeedRun(
+		net.Pow,
+		constants.Checkpoints[net.Name],
+		!net.Testnet,
+		seedParams.BlockHeight,
+		headers,
+		rawHeaders,
+		supplied,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// Chain work counts from the first seeded header.
+	work := new(big.Int)
+	for i := range headers {
+		height := seedParams.BlockHeight + uint32(i)
+		storeHeader(height, &headers[i], rawHeaders[i][:])
+		saveChainWork(height, work.Add(work, blockchain.CalcWork(headers[i].Bits)))
+	}
+	for height, header := range supplied {
+		saveRetargetAnchor(height, header)
+	}
+	// A re-seed starts a new header archive.
+	sdk.StateDeleteObject(constants.HeaderMMRKey)
+	sdk.StateSetObject(constants.LastHeightKey, strconv.FormatInt(int64(tip), 10))
+	sdk.StateSetObject(constants.SeedHeightKey, strconv.FormatInt(int64(seedParams.BlockHeight), 10))
+	return tip, nil
+}
+
+// seedHeaders returns the headers given to seedBlocks, either the run in
+// BlockHeaders or the single BlockHeader.
+func seedHeaders(seedParams SeedBlocksParams, retention uint32) ([]BlockHeaderBytes, error) {
+	if seedParams.BlockHeaders == "" {
 		headerBytes, err := hex.DecodeString(seedParams.BlockHeader)
 		if err != nil {
-			return 0, ce.WrapContractError(ce.ErrInvalidHex, err, "invalid block header hex")
+			return nil, ce.WrapContractError(ce.ErrInvalidHex, err, "invalid block header hex")
+		}
+		if len(headerBytes) != 80 {
+			return nil, ce.NewContractError(ce.ErrInput, "expected exactly 80 bytes (one block header)")
 		}
-		sdk.StateSetObject(
-			constants.BlockPrefix+strconv.FormatInt(int64(seedParams.BlockHeight), 10),
-			string(headerBytes),
+		return []BlockHeaderBytes{BlockHeaderBytes(headerBytes)}, nil
+	}
+	if seedParams.BlockHeader != "" {
+		return nil, ce.NewContractError(ce.ErrInput, "expected either block_header or block_headers, not both")
+	}
+	rawHeaders, err := DivideHeaderList(&seedParams.BlockHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawHeaders) == 0 || len(rawHeaders) > int(retention) {
+		return nil, ce.NewContractError(
+			ce.ErrInput,
+			"expected between 1 and "+strconv.FormatUint(uint64(retention), 10)+" seed headers",
 		)
-		sdk.StateSetObject(constants.LastHeightKey, strconv.FormatInt(int64(seedParams.BlockHeight), 10))
-		sdk.StateSetObject(constants.SeedHeightKey, strconv.FormatInt(int64(seedParams.BlockHeight), 10))
-		return seedParams.BlockHeight, nil
 	}
+	return rawHeaders, nil
+}
 
-	return 0, ce.NewContractError(
-		ce.ErrInput,
-		"last height >= input block height. last height: "+strconv.FormatUint(uint64(lastHeight), 10),
-	)
+// checkSeedRun validates a run of seed headers starting at firstHeight: each
+// meets its proof of work and the network's difficulty, links to the one
+// before it, and matches any checkpoint at its height. supplied holds the
+// retarget anchor headers given alongside the run, keyed by height. With
+// belowLast set, a run starting below the last checkpoint is refused.
+func checkSeedRun(
+	params *network.PowParams,
+	checkpoints []constants.Checkpoint,
+	belowLast bool,
+	firstHeight uint32,
+	headers []wire.BlockHeader,
+	rawHeaders []BlockHeaderBytes,
+	supplied map[uint32]*wire.BlockHeader,
+) error {
+	if belowLast && len(checkpoints) > 0 && firstHeight < checkpoints[len(checkpoints)-1].Height {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"seed height "+strconv.FormatUint(uint64(firstHeight), 10)+" is below the last checkpoint at "+
+				strconv.FormatUint(uint64(checkpoints[len(checkpoints)-1].Height), 10),
+		)
+	}
+
+	// Difficulty is checked against the anchors the run itself provides.
+	anchors := make(map[uint32]retargetAnchor)
+	lookup := func(height uint32) (retargetAnchor, bool) {
+		anchor, ok := anchors[height]
+		return anchor, ok
+	}
+	for height, header := range supplied {
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			return ce.NewContractError(ce.ErrInput, "error encoding block header: "+err.Error())
+		}
+		if err := checkProofOfWork(params, buf.Bytes(), header.Bits); err != nil {
+			return ce.Prepend(err, "block "+strconv.FormatUint(uint64(height), 10)+" failed PoW check")
+		}
+		if err := checkCheckpoint(checkpoints, height, header); err != nil {
+			return err
+		}
+		anchors[height] = newRetargetAnchor(header)
+	}
+
+	for i := range headers {
+		height := firstHeight + uint32(i)
+		if i == 0 {
+			if err := checkProofOfWork(params, rawHeaders[i][:], headers[i].Bits); err != nil {
+				return ce.Prepend(err, "block "+strconv.FormatUint(uint64(height), 10)+" failed PoW check")
+			}
+		} else {
+			prevHash := headers[i-1].BlockHash()
+			if !headers[i].PrevBlock.IsEqual(&prevHash) {
+				return ce.NewContractError(ce.ErrInput, "block sequence incorrect")
+			}
+			err := checkHeaderWith(params, height, &headers[i-1], &headers[i], rawHeaders[i][:], lookup)
+			if err != nil {
+				return err
+			}
+		}
+		if err := checkCheckpoint(checkpoints, height, &headers[i]); err != nil {
+			return err
+		}
+		if isAnchorHeight(height) {
+			anchors[height] = newRetargetAnchor(&headers[i])
+		}
+	}
+	return nil
+}
+
+// checkCheckpoint rejects a header at a checkpointed height whose hash differs.
+func checkCheckpoint(checkpoints []constants.Checkpoint, height uint32, header *wire.BlockHeader) error {
+	for _, cp := range checkpoints {
+		if cp.Height == height && header.BlockHash().String() != cp.Hash {
+			return ce.NewContractError(
+				ce.ErrInput,
+				"block "+strconv.FormatUint(uint64(height), 10)+" does not match the checkpoint "+cp.Hash,
+			)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,92 @@
+package blocklist
+
+import (
+	"bytes"
+	"ltc-mapping-contract/contract/constants"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func serializeHeaderBytes(t *testing.T, header *wire.BlockHeader) BlockHeaderBytes {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := header.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return BlockHeaderBytes(buf.Bytes())
+}
+
+// minedRegtestRun returns n linked regtest headers, each with a nonce that
+// meets the regtest scrypt proof of work.
+func minedRegtestRun(t *testing.T, n int) ([]wire.BlockHeader, []BlockHeaderBytes) {
+	t.Helper()
+	params := testPowParams(constants.Regtest)
+	headers := make([]wire.BlockHeader, n)
+	raw := make([]BlockHeaderBytes, n)
+	prev := chainhash.Hash{}
+	for i := range headers {
+		headers[i] = wire.BlockHeader{
+			Version:   4,
+			PrevBlock: prev,
+			Timestamp: time.Unix(1700000000+int64(i)*150, 0),
+			Bits:      params.PowLimitBits,
+		}
+		for {
+			raw[i] = serializeHeaderBytes(t, &headers[i])
+			if checkProofOfWork(params, raw[i][:], headers[i].Bits) == nil {
+				break
+			}
+			headers[i].Nonce++
+		}
+		prev = headers[i].BlockHash()
+	}
+	return headers, raw
+}
+
+func TestCheckSeedRun(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	headers, raw := minedRegtestRun(t, 5)
+	const first = 100
+
+	if err := checkSeedRun(params, nil, true, first, headers, raw, nil); err != nil {
+		t.Fatalf("valid run: %v", err)
+	}
+
+	broken := append([]wire.BlockHeader(nil), headers...)
+	brokenRaw := append([]BlockHeaderBytes(nil), raw...)
+	broken[3].PrevBlock = chainhash.Hash{1}
+	brokenRaw[3] = serializeHeaderBytes(t, &broken[3])
+	if err := checkSeedRun(params, nil, true, first, broken, brokenRaw, nil); err == nil {
+		t.Error("expected broken linkage to fail")
+	}
+
+	weak := append([]wire.BlockHeader(nil), headers...)
+	weakRaw := append([]BlockHeaderBytes(nil), raw...)
+	weak[2].Bits = 0x1e0fffff
+	weakRaw[2] = serializeHeaderBytes(t, &weak[2])
+	if err := checkSeedRun(params, nil, true, first, weak, weakRaw, nil); err == nil {
+		t.Error("expected header without proof of work to fail")
+	}
+
+	match := []constants.Checkpoint{{Height: first + 2, Hash: headers[2].BlockHash().String()}}
+	if err := checkSeedRun(params, match, false, first, headers, raw, nil); err != nil {
+		t.Errorf("matching checkpoint: %v", err)
+	}
+	mismatch := []constants.Checkpoint{{Height: first + 2, Hash: headers[1].BlockHash().String()}}
+	if err := checkSeedRun(params, mismatch, false, first, headers, raw, nil); err == nil {
+		t.Error("expected checkpoint mismatch to fail")
+	}
+
+	// Only networks that refuse old seeds reject a run below the last
+	// checkpoint.
+	later := []constants.Checkpoint{{Height: first + 10, Hash: headers[0].BlockHash().String()}}
+	if err := checkSeedRun(params, later, true, first, headers, raw, nil); err == nil {
+		t.Error("expected seed below the last checkpoint to fail")
+	}
+	if err := checkSeedRun(params, later, false, first, headers, raw, nil); err != nil {
+		t.Errorf("seed below checkpoint on a test network: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"ltc-mapping-contract/sdk"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"ltc-mapping-contract/contract/constants"
+	ce "ltc-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// A batch that forks below the tip starts a competing branch. The branch is
+// validated against its own headers, and nothing above the fork is written
+// to the stored chain until its total work beats the tip. Until then it is
+// staged under its own keys, and later batches may extend it, so a heavier
+// branch can arrive over as many batches as it needs.
+
+// stagedBranch is the competing branch collected so far: the height it forks
+// from, its last staged height and its cumulative work at that height.
+type stagedBranch struct {
+	ForkHeight uint32
+	TipHeight  uint32
+	Work       *big.Int
+}
+
+func loadStagedBranch() (*stagedBranch, bool) {
+	raw := sdk.StateGetObject(constants.StagedBranchKey)
+	if raw == nil || len(*raw) < 8 {
+		return nil, false
+	}
+	b := []byte(*raw)
+	return &stagedBranch{
+		ForkHeight: binary.BigEndian.Uint32(b[0:4]),
+		TipHeight:  binary.BigEndian.Uint32(b[4:8]),
+		Work:       new(big.Int).SetBytes(b[8:]),
+	}, true
+}
+
+func saveStagedBranch(branch *stagedBranch) {
+	work := branch.Work.Bytes()
+	b := make([]byte, 8, 8+len(work))
+	binary.BigEndian.PutUint32(b[0:4], branch.ForkHeight)
+	binary.BigEndian.PutUint32(b[4:8], branch.TipHeight)
+	sdk.StateSetObject(constants.StagedBranchKey, string(append(b, work...)))
+}
+
+func stagedHeaderKey(height uint32) string {
+	return constants.StagedHeaderPrefix + strconv.FormatUint(uint64(height), 10)
+}
+
+func loadStagedHeader(height uint32) (*wire.BlockHeader, []byte, bool) {
+	raw := sdk.StateGetObject(stagedHeaderKey(height))
+	if raw == nil || *raw == "" {
+		return nil, nil, false
+	}
+	b := []byte(*raw)
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader(b), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, nil, false
+	}
+	return &header, b, true
+}
+
+// clearStagedBranch deletes the staged branch and its headers.
+func clearStagedBranch(branch *stagedBranch) {
+	for h := branch.ForkHeight + 1; h <= branch.TipHeight; h++ {
+		sdk.StateDeleteObject(stagedHeaderKey(h))
+	}
+	sdk.StateDeleteObject(constants.StagedBranchKey)
+}
+
+// stagedBranchLive reports whether the staged branch still forks from the
+// stored chain: its fork height is retained and its first header builds on
+// the stored header there, which a replaced block may have changed.
+func stagedBranchLive(branch *stagedBranch, floor uint32) bool {
+	if branch.ForkHeight < floor || branch.TipHeight <= branch.ForkHeight {
+		return false
+	}
+	fork, ok := loadHeader(branch.ForkHeight)
+	if !ok {
+		return false
+	}
+	first, _, ok := loadStagedHeader(branch.ForkHeight + 1)
+	if !ok {
+		return false
+	}
+	forkHash := fork.BlockHash()
+	return first.PrevBlock.IsEqual(&forkHash)
+}
+
+// branchView reads headers along a branch forking from the stored chain at
+// forkHeight: stored headers up to the fork, then staged headers, then the
+// headers of the batch being validated.
+type branchView struct {
+	forkHeight uint32
+	batch      map[uint32]*wire.BlockHeader
+}
+
+func newBranchView(forkHeight uint32) *branchView {
+	return &branchView{forkHeight: forkHeight, batch: make(map[uint32]*wire.BlockHeader)}
+}
+
+func (v *branchView) header(height uint32) (*wire.BlockHeader, bool) {
+	if height <= v.forkHeight {
+		return loadHeader(height)
+	}
+	if header, ok := v.batch[height]; ok {
+		return header, true
+	}
+	header, _, ok := loadStagedHeader(height)
+	return header, ok
+}
+
+func (v *branchView) anchor(height uint32) (retargetAnchor, bool) {
+	if height <= v.forkHeight {
+		return loadRetargetAnchor(height)
+	}
+	header, ok := v.header(height)
+	if !ok {
+		return retargetAnchor{}, false
+	}
+	return newRetargetAnchor(header), true
+}
+
+// adoptStagedBranch writes the staged headers over the stored chain above
+// the fork, with their cumulative work, and clears the staged branch.
+func adoptStagedBranch(branch *stagedBranch) error {
+	work, ok := loadChainWork(branch.ForkHeight)
+	if !ok {
+		return ce.NewContractError(ce.ErrStateAccess, "no chain work stored to compare branches, call migrate")
+	}
+	for h := branch.ForkHeight + 1; h <= branch.TipHeight; h++ {
+		header, raw, ok := loadStagedHeader(h)
+		if !ok {
+			return ce.NewContractError(
+				ce.ErrStateAccess,
+				"no staged block header found at height "+strconv.FormatUint(uint64(h), 10),
+			)
+		}
+		storeHeader(h, header, raw)
+		work.Add(work, blockchain.CalcWork(header.Bits))
+		saveChainWork(h, work)
+	}
+	clearStagedBranch(branch)
+	return nil
+}
+
+// createBranchLog records a competing branch staged without enough work to
+// replace the stored chain: the fork height and the branch tip.
+func createBranchLog(forkHeight, branchTip uint32) string {
+	var b strings.Builder
+	b.Grow(48)
+	b.WriteString("branch")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("f")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(forkHeight), 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("t")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(branchTip), 10))
+	return b.String()
+}
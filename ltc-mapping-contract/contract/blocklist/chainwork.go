@@ -0,0 +1,167 @@
+package blocklist
+
+import (
+	"bytes"
+	"ltc-mapping-contract/sdk"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"ltc-mapping-contract/contract/constants"
+	ce "ltc-mapping-contract/contract/contracterrors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Chain work is stored cumulatively from the seed header, so it is only
+// comparable between branches that share a stored ancestor. That is all fork
+// choice needs: a branch can only fork from a retained height.
+
+// headerLookup returns the stored header at height, or false if there is none.
+type headerLookup func(height uint32) (*wire.BlockHeader, bool)
+
+func loadHeader(height uint32) (*wire.BlockHeader, bool) {
+	raw := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatUint(uint64(height), 10))
+	if raw == nil || *raw == "" {
+		return nil, false
+	}
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader([]byte(*raw)), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, false
+	}
+	return &header, true
+}
+
+func loadChainWork(height uint32) (*big.Int, bool) {
+	raw := sdk.StateGetObject(constants.ChainWorkPrefix + strconv.FormatUint(uint64(height), 10))
+	if raw == nil || *raw == "" {
+		return nil, false
+	}
+	return new(big.Int).SetBytes([]byte(*raw)), true
+}
+
+func saveChainWork(height uint32, work *big.Int) {
+	sdk.StateSetObject(constants.ChainWorkPrefix+strconv.FormatUint(uint64(height), 10), string(work.Bytes()))
+}
+
+func deleteChainWork(height uint32) {
+	sdk.StateDeleteObject(constants.ChainWorkPrefix + strconv.FormatUint(uint64(height), 10))
+}
+
+// parentChainWork returns the cumulative work up to height, or zero for
+// contracts whose chain work has not been backfilled yet.
+func parentChainWork(height uint32) *big.Int {
+	if work, ok := loadChainWork(height); ok {
+		return work
+	}
+	return new(big.Int)
+}
+
+// retentionFloor returns the lowest height a branch may fork from: the oldest
+// retained header, and never below the seed.
+func retentionFloor(lastHeight uint32, retention uint32) uint32 {
+	floor := int64(lastHeight) - int64(retention) + 1
+	if seed := int64(seedHeightFromState()); seed > floor {
+		floor = seed
+	}
+	if floor < 0 {
+		return 0
+	}
+	return uint32(floor)
+}
+
+// findForkPoint searches the stored headers below the tip, down to floor,
+// for the one with the given hash.
+func findForkPoint(
+	tipHeight uint32,
+	floor uint32,
+	hash *chainhash.Hash,
+	headers headerLookup,
+) (uint32, *wire.BlockHeader, bool) {
+	for h := int64(tipHeight) - 1; h >= int64(floor); h-- {
+		header, ok := headers(uint32(h))
+		if !ok {
+			break
+		}
+		headerHash := header.BlockHash()
+		if headerHash.IsEqual(hash) {
+			return uint32(h), header, true
+		}
+	}
+	return 0, nil, false
+}
+
+// dropStaleHeaders removes everything stored above a new, shorter tip that
+// belonged to the abandoned branch. Observed transaction lists are kept so
+// that deposits re-included in the new branch cannot be minted twice.
+func dropStaleHeaders(newTip uint32, oldTip uint32) {
+	for h := newTip + 1; h <= oldTip; h++ {
+		sdk.StateDeleteObject(constants.BlockPrefix + strconv.FormatUint(uint64(h), 10))
+		deleteChainWork(h)
+		if isAnchorHeight(h) {
+			deleteRetargetAnchor(h)
+		}
+	}
+}
+
+// createReorgLog records a switch to a branch with more work: the fork
+// height, the abandoned tip and the new tip.
+func createReorgLog(forkHeight, oldTip, newTip uint32) string {
+	var b strings.Builder
+	b.Grow(64)
+	b.WriteString("reorg")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("f")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(forkHeight), 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("o")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(oldTip), 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("t")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(strconv.FormatUint(uint64(newTip), 10))
+	return b.String()
+}
+
+// BackfillChainWork computes the cumulative chain work of the retained
+// headers, for contracts seeded before chain work was tracked. It returns
+// the number of heights written.
+func BackfillChainWork(retention uint32) (int, error) {
+	lastHeight, err := LastHeightFromState()
+	if err != nil {
+		if err == ErrorLastHeightDNE {
+			return 0, nil
+		}
+		return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+	}
+
+	// Walk down to the oldest contiguous header, then sum upwards.
+	floor := retentionFloor(lastHeight, retention)
+	start := lastHeight
+	for start > floor {
+		if _, ok := loadHeader(start - 1); !ok {
+			break
+		}
+		start--
+	}
+
+	work := new(big.Int)
+	written := 0
+	for h := start; h <= lastHeight; h++ {
+		header, ok := loadHeader(h)
+		if !ok {
+			return written, ce.NewContractError(
+				ce.ErrStateAccess,
+				"no block header found at height "+strconv.FormatUint(uint64(h), 10),
+			)
+		}
+		work.Add(work, blockchain.CalcWork(header.Bits))
+		saveChainWork(h, work)
+		written++
+	}
+	return written, nil
+}
@@ -0,0 +1,279 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"ltc-mapping-contract/sdk"
+	"math/big"
+	"strconv"
+
+	"ltc-mapping-contract/contract/constants"
+	ce "ltc-mapping-contract/contract/contracterrors"
+	"ltc-mapping-contract/contract/network"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// checkProofOfWork is blockchain.CheckProofOfWork with Litecoin's scrypt
+// hash in place of the double-SHA256 block hash.
+func checkProofOfWork(params *network.PowParams, headerBytes []byte, bits uint32) error {
+	target := blockchain.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return ce.NewContractError(ce.ErrInput, "block target difficulty is too low")
+	}
+	if target.Cmp(params.PowLimit) > 0 {
+		return ce.NewContractError(ce.ErrInput, "block target difficulty is higher than max")
+	}
+	powHash := chainhash.Hash(scryptHash(headerBytes))
+	if blockchain.HashToBig(&powHash).Cmp(target) > 0 {
+		return ce.NewContractError(ce.ErrInput, "block scrypt hash is higher than expected max")
+	}
+	return nil
+}
+
+// retargetAnchor is the part of a header that the difficulty calculation
+// needs once the header itself has been pruned.
+type retargetAnchor struct {
+	Timestamp uint32
+	Bits      uint32
+}
+
+// anchorLookup returns the retarget anchor stored for the given height, or
+// false if none is stored.
+type anchorLookup func(height uint32) (retargetAnchor, bool)
+
+func newRetargetAnchor(header *wire.BlockHeader) retargetAnchor {
+	return retargetAnchor{Timestamp: uint32(header.Timestamp.Unix()), Bits: header.Bits}
+}
+
+// epochStart returns the height of the first block of the epoch containing height.
+func epochStart(height uint32) uint32 {
+	return height - height%constants.RetargetInterval
+}
+
+// isAnchorHeight reports whether the header at height is kept as a retarget
+// anchor: the first block of an epoch or the last block of one.
+func isAnchorHeight(height uint32) bool {
+	m := height % constants.RetargetInterval
+	return m == 0 || m == constants.RetargetInterval-1
+}
+
+func loadRetargetAnchor(height uint32) (retargetAnchor, bool) {
+	raw := sdk.StateGetObject(constants.RetargetAnchorPrefix + strconv.FormatUint(uint64(height), 10))
+	if raw == nil || len(*raw) != 8 {
+		return retargetAnchor{}, false
+	}
+	b := []byte(*raw)
+	return retargetAnchor{
+		Timestamp: binary.BigEndian.Uint32(b[0:4]),
+		Bits:      binary.BigEndian.Uint32(b[4:8]),
+	}, true
+}
+
+func saveRetargetAnchor(height uint32, header *wire.BlockHeader) {
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(header.Timestamp.Unix()))
+	binary.BigEndian.PutUint32(b[4:8], header.Bits)
+	sdk.StateSetObject(constants.RetargetAnchorPrefix+strconv.FormatUint(uint64(height), 10), string(b[:]))
+}
+
+func deleteRetargetAnchor(height uint32) {
+	sdk.StateDeleteObject(constants.RetargetAnchorPrefix + strconv.FormatUint(uint64(height), 10))
+}
+
+// storeHeader writes the raw header at height and records it as a retarget
+// anchor when it sits on either side of an epoch boundary.
+func storeHeader(height uint32, header *wire.BlockHeader, raw []byte) {
+	sdk.StateSetObject(constants.BlockPrefix+strconv.FormatUint(uint64(height), 10), string(raw))
+	if isAnchorHeight(height) {
+		saveRetargetAnchor(height, header)
+	}
+}
+
+// calcRequiredBits returns the compact target that a header at height with
+// the given timestamp must carry, given its parent header. It follows
+// Litecoin Core's GetNextWorkRequired, reading pruned headers from the
+// retarget anchors.
+func calcRequiredBits(
+	params *network.PowParams,
+	height uint32,
+	prev *wire.BlockHeader,
+	timestamp int64,
+	anchors anchorLookup,
+) (uint32, error) {
+	if height == 0 {
+		return params.PowLimitBits, nil
+	}
+
+	if height%constants.RetargetInterval != 0 {
+		if !params.ReduceMinDifficulty {
+			return prev.Bits, nil
+		}
+
+		// Testnet: a block more than 5 minutes after its parent may be mined
+		// at the minimum difficulty.
+		if timestamp > prev.Timestamp.Unix()+params.MinDiffReductionTime {
+			return params.PowLimitBits, nil
+		}
+
+		// Otherwise it inherits the difficulty of the last block that was not
+		// mined under the min-difficulty exception, which is always the
+		// epoch's own difficulty.
+		if (height-1)%constants.RetargetInterval == 0 || prev.Bits != params.PowLimitBits {
+			return prev.Bits, nil
+		}
+		anchor, ok := anchors(epochStart(height))
+		if !ok {
+			return 0, missingAnchorError(epochStart(height))
+		}
+		return anchor.Bits, nil
+	}
+
+	// Litecoin measures the window from the last block of the previous epoch
+	// (2016 blocks back rather than Bitcoin's 2015), closing the off-by-one
+	// that let a 51% miner shift difficulty at will. The first retarget after
+	// genesis keeps Bitcoin's window.
+	if height < constants.RetargetInterval {
+		return prev.Bits, nil
+	}
+	first := uint32(0)
+	if height != constants.RetargetInterval {
+		first = height - constants.RetargetInterval - 1
+	}
+	anchor, ok := anchors(first)
+	if !ok {
+		return 0, missingAnchorError(first)
+	}
+
+	minTimespan := params.TargetTimespan / params.AdjustmentFactor
+	maxTimespan := params.TargetTimespan * params.AdjustmentFactor
+	actualTimespan := prev.Timestamp.Unix() - int64(anchor.Timestamp)
+	if actualTimespan < minTimespan {
+		actualTimespan = minTimespan
+	} else if actualTimespan > maxTimespan {
+		actualTimespan = maxTimespan
+	}
+
+	// Litecoin Core shifts near-limit targets right by one bit before
+	// scaling to avoid overflowing its 256-bit integer; the shift drops the
+	// lowest bit, so it is reproduced here to match its rounding.
+	newTarget := blockchain.CompactToBig(prev.Bits)
+	shift := newTarget.BitLen() > params.PowLimit.BitLen()-1
+	if shift {
+		newTarget.Rsh(newTarget, 1)
+	}
+	newTarget.Mul(newTarget, big.NewInt(actualTimespan))
+	newTarget.Div(newTarget, big.NewInt(params.TargetTimespan))
+	if shift {
+		newTarget.Lsh(newTarget, 1)
+	}
+	if newTarget.Cmp(params.PowLimit) > 0 {
+		newTarget.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(newTarget), nil
+}
+
+// checkHeader verifies a header's scrypt proof of work and that its bits are
+// the ones the network requires at height.
+func checkHeader(params *network.PowParams, height uint32, prev *wire.BlockHeader, header *wire.BlockHeader, raw []byte) error {
+	return checkHeaderWith(params, height, prev, header, raw, loadRetargetAnchor)
+}
+
+func checkHeaderWith(
+	params *network.PowParams,
+	height uint32,
+	prev *wire.BlockHeader,
+	header *wire.BlockHeader,
+	raw []byte,
+	anchors anchorLookup,
+) error {
+	heightStr := strconv.FormatUint(uint64(height), 10)
+	if err := checkProofOfWork(params, raw, header.Bits); err != nil {
+		return ce.Prepend(err, "block "+heightStr+" failed PoW check")
+	}
+
+	// Regtest never retargets; its headers are only bounded by PowLimit.
+	if params.NoRetargeting {
+		return nil
+	}
+
+	expected, err := calcRequiredBits(params, height, prev, header.Timestamp.Unix(), anchors)
+	if err != nil {
+		return err
+	}
+	if header.Bits != expected {
+		return ce.NewContractError(
+			ce.ErrInput,
+			"block "+heightStr+" has unexpected difficulty bits "+
+				strconv.FormatUint(uint64(header.Bits), 16)+", expected "+strconv.FormatUint(uint64(expected), 16),
+		)
+	}
+	return nil
+}
+
+func missingAnchorError(height uint32) error {
+	return ce.NewContractError(
+		ce.ErrStateAccess,
+		"no retarget anchor for height "+strconv.FormatUint(uint64(height), 10)+
+			", seed with epoch_header/retarget_header or call initRetarget",
+	)
+}
+
+// HandleInitRetarget stores a retarget anchor for contracts seeded before
+// retargeting was enforced. The header must be the first block of the tip's
+// epoch or the last block of the epoch before it; both are needed before the
+// next boundary (the latter only off testnet's min-difficulty path).
+func HandleInitRetarget(params SeedBlocksParams) (uint32, error) {
+	lastHeight, err := LastHeightFromState()
+	if err != nil {
+		return 0, ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height")
+	}
+	start := epochStart(lastHeight)
+	if params.BlockHeight != start && (start == 0 || params.BlockHeight != start-1) {
+		return 0, ce.NewContractError(
+			ce.ErrInput,
+			"block height must be "+strconv.FormatUint(uint64(start), 10)+" or the height just before it",
+		)
+	}
+	if _, ok := loadRetargetAnchor(params.BlockHeight); ok {
+		return 0, ce.NewContractError(ce.ErrInput, "retarget anchor already set")
+	}
+
+	header, err := decodeHeaderHex(params.BlockHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the header is still retained it must match the input.
+	stored := sdk.StateGetObject(constants.BlockPrefix + strconv.FormatUint(uint64(params.BlockHeight), 10))
+	if stored != nil && *stored != "" {
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			return 0, ce.NewContractError(ce.ErrInput, "error encoding block header: "+err.Error())
+		}
+		if !bytes.Equal(buf.Bytes(), []byte(*stored)) {
+			return 0, ce.NewContractError(ce.ErrInput, "header does not match stored block")
+		}
+	}
+
+	saveRetargetAnchor(params.BlockHeight, header)
+	return params.BlockHeight, nil
+}
+
+func decodeHeaderHex(headerHex string) (*wire.BlockHeader, error) {
+	headerBytes, err := hex.DecodeString(headerHex)
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrInvalidHex, err, "invalid block header hex")
+	}
+	if len(headerBytes) != 80 {
+		return nil, ce.NewContractError(ce.ErrInput, "expected exactly 80 bytes (one block header)")
+	}
+	var header wire.BlockHeader
+	if err := header.BtcDecode(bytes.NewReader(headerBytes), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, ce.NewContractError(ce.ErrInput, "error decoding block header: "+err.Error())
+	}
+	return &header, nil
+}
@@ -0,0 +1,188 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"ltc-mapping-contract/contract/constants"
+	"ltc-mapping-contract/contract/network"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// testPowParams returns a copy of the named network's PoW params, which a
+// test may change freely.
+func testPowParams(name string) *network.PowParams {
+	n, _ := network.Lookup(name)
+	params := *n.Pow
+	return &params
+}
+
+// Litecoin mainnet headers 0 → 4.
+var ltcMainnetHeaders = []string{
+	"010000000000000000000000000000000000000000000000000000000000000000000000d9ced4ed1130f7b7faad9be25323ffafa33232a17c3edf6cfd97bee6bafbdd97b9aa8e4ef0ff0f1ecd513f7c",
+	"01000000e2bf047e7e5a191aa4ef34d314979dc9986e0f19251edaba5940fd1fe365a712f6509b1757baa71bc746e17cb4d0ed22e8935f71e2d0724336789021a40639fabfed8f4ef0ff0f1e7f270400",
+	"010000008fc749ba1129b477844abee559079e4ed02cf9eab69e763de5020bd15e09ca80f27b45c33766594918ec67bd3a096dcc3a63fc015ce79821794e9fffa15743c5aafc944ef0ff0f1ed75e0000",
+	"01000000d7409ff04a253b6e424c6c509af96387310e5159fa0939992fd0d1cd077895137c8ef659c87e58ebbc2f0cf755bdd1433f5c7f0548db099ab08d3ae521bbc3531d54964ef0ff0f1ee7130000",
+	"010000009843df8efddd5c76111a6ffac130e2ed4c80dadf8bb67613f15f73a2dd73c1de65a3536bb0e7ba1b6650c6e0677d3f1d901dd0088b448becf8d23d8cda18aff52254964ef0ff0f1ed7170000",
+}
+
+func decodeTestHeader(t *testing.T, s string) (*wire.BlockHeader, []byte) {
+	t.Helper()
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h wire.BlockHeader
+	if err := h.BtcDecode(bytes.NewReader(raw), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		t.Fatal(err)
+	}
+	return &h, raw
+}
+
+func noAnchors(uint32) (retargetAnchor, bool) { return retargetAnchor{}, false }
+
+func TestCheckHeaderMainnetChain(t *testing.T) {
+	params := testPowParams(constants.Mainnet)
+	prev, _ := decodeTestHeader(t, ltcMainnetHeaders[0])
+	for height := 1; height < len(ltcMainnetHeaders); height++ {
+		header, raw := decodeTestHeader(t, ltcMainnetHeaders[height])
+		prevHash := prev.BlockHash()
+		if !header.PrevBlock.IsEqual(&prevHash) {
+			t.Fatalf("height %d does not link to its parent", height)
+		}
+		if err := checkHeaderWith(params, uint32(height), prev, header, raw, noAnchors); err != nil {
+			t.Fatalf("height %d: %v", height, err)
+		}
+		prev = header
+	}
+}
+
+func TestCheckHeaderRejectsBadScrypt(t *testing.T) {
+	params := testPowParams(constants.Mainnet)
+	prev, _ := decodeTestHeader(t, ltcMainnetHeaders[1])
+	header, raw := decodeTestHeader(t, ltcMainnetHeaders[2])
+
+	// Bumping the nonce keeps linkage and bits but breaks the scrypt PoW.
+	raw[76]++
+	header.Nonce++
+	if err := checkHeaderWith(params, 2, prev, header, raw, noAnchors); err == nil {
+		t.Fatal("expected PoW failure")
+	}
+
+	// The declared target itself may not exceed PowLimit.
+	_, raw = decodeTestHeader(t, ltcMainnetHeaders[2])
+	if err := checkProofOfWork(params, raw, 0x1f00ffff); err == nil {
+		t.Fatal("expected target above PowLimit to be rejected")
+	}
+}
+
+func TestCheckHeaderRejectsWrongBits(t *testing.T) {
+	params := testPowParams(constants.Mainnet)
+	prev, _ := decodeTestHeader(t, ltcMainnetHeaders[1])
+	prev.Bits = 0x1e0fffff
+	header, raw := decodeTestHeader(t, ltcMainnetHeaders[2])
+	if err := checkHeaderWith(params, 2, prev, header, raw, noAnchors); err == nil {
+		t.Fatal("expected unexpected-bits failure")
+	}
+}
+
+func TestRequiredBitsRetarget(t *testing.T) {
+	params := testPowParams(constants.Mainnet)
+	const boundary = 2016 * 200
+	const windowStart = 1700000000
+
+	cases := []struct {
+		name     string
+		timespan int64
+		bits     uint32
+		want     uint32
+	}{
+		{"half the target timespan doubles difficulty", 151200, 0x1c08c760, 0x1c0463b0},
+		{"fast epoch is clamped to 4x", 10, 0x1c08c760, 0x1c0231d8},
+		{"slow epoch is clamped to 1/4", 10_000_000, 0x1c08c760, 0x1c231d80},
+		{"capped at PowLimit", 302400 * 4, 0x1e0ffff0, 0x1e0fffff},
+		{"near-limit target", 302399, 0x1e0fffff, 0x1e0ffffb},
+	}
+	for _, c := range cases {
+		var lookedUp uint32
+		anchors := func(h uint32) (retargetAnchor, bool) {
+			lookedUp = h
+			return retargetAnchor{Timestamp: windowStart}, true
+		}
+		prev := &wire.BlockHeader{Bits: c.bits, Timestamp: time.Unix(windowStart+c.timespan, 0)}
+		got, err := calcRequiredBits(params, boundary, prev, prev.Timestamp.Unix()+150, anchors)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %08x, want %08x", c.name, got, c.want)
+		}
+		// Litecoin's window starts at the last block of the previous epoch.
+		if lookedUp != boundary-constants.RetargetInterval-1 {
+			t.Errorf("%s: window anchored at %d, want %d", c.name, lookedUp, boundary-constants.RetargetInterval-1)
+		}
+	}
+
+	// The first retarget after genesis keeps Bitcoin's 2015-block window.
+	var lookedUp uint32 = 1
+	anchors := func(h uint32) (retargetAnchor, bool) {
+		lookedUp = h
+		return retargetAnchor{Timestamp: windowStart}, true
+	}
+	prev := &wire.BlockHeader{Bits: 0x1e0ffff0, Timestamp: time.Unix(windowStart+302400, 0)}
+	if _, err := calcRequiredBits(params, constants.RetargetInterval, prev, 0, anchors); err != nil {
+		t.Fatal(err)
+	}
+	if lookedUp != 0 {
+		t.Errorf("first retarget anchored at %d, want 0", lookedUp)
+	}
+
+	// Without the anchor the boundary cannot be validated.
+	if _, err := calcRequiredBits(params, boundary, prev, 0, noAnchors); err == nil {
+		t.Fatal("expected missing anchor error")
+	}
+}
+
+func TestRequiredBitsTestnetMinDifficulty(t *testing.T) {
+	params := testPowParams(constants.Testnet)
+	const height = 2016*200 + 10
+	const epochBits = 0x1c08c760
+	anchors := func(h uint32) (retargetAnchor, bool) {
+		if h != 2016*200 {
+			t.Fatalf("unexpected anchor lookup %d", h)
+		}
+		return retargetAnchor{Bits: epochBits}, true
+	}
+
+	prev := &wire.BlockHeader{Bits: epochBits, Timestamp: time.Unix(1700000000, 0)}
+
+	// More than 5 minutes after the parent: min difficulty.
+	got, err := calcRequiredBits(params, height, prev, prev.Timestamp.Unix()+301, anchors)
+	if err != nil || got != params.PowLimitBits {
+		t.Fatalf("got %08x, %v; want PowLimitBits", got, err)
+	}
+	// Within 5 minutes of a normal block: the parent's bits.
+	got, err = calcRequiredBits(params, height, prev, prev.Timestamp.Unix()+300, anchors)
+	if err != nil || got != epochBits {
+		t.Fatalf("got %08x, %v; want %08x", got, err, epochBits)
+	}
+	// Within 5 minutes of a min-difficulty block: back to the epoch's bits.
+	minPrev := &wire.BlockHeader{Bits: params.PowLimitBits, Timestamp: prev.Timestamp}
+	got, err = calcRequiredBits(params, height, minPrev, prev.Timestamp.Unix()+60, anchors)
+	if err != nil || got != epochBits {
+		t.Fatalf("got %08x, %v; want %08x", got, err, epochBits)
+	}
+}
+
+func TestRegtestSkipsRetarget(t *testing.T) {
+	params := testPowParams(constants.Regtest)
+	prev, _ := decodeTestHeader(t, ltcMainnetHeaders[1])
+	header, raw := decodeTestHeader(t, ltcMainnetHeaders[2])
+	prev.Bits = params.PowLimitBits
+	if err := checkHeaderWith(params, 2016*200, prev, header, raw, noAnchors); err != nil {
+		t.Fatalf("regtest should only check PoW: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package blocklist
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Litecoin's proof-of-work hash is scrypt(N=1024, r=1, p=1) of the 80-byte
+// header, with the header used as both password and salt. This is a minimal
+// pure-Go implementation specialised to those parameters so that it builds
+// under TinyGo without golang.org/x/crypto.
+
+const (
+	scryptN     = 1024
+	scryptWords = 32 // 128*r bytes as little-endian uint32 words
+)
+
+// scryptV is the ROMix scratchpad (128 KiB). The contract is single-threaded,
+// so a package-level buffer avoids re-allocating it for every header.
+var scryptV [scryptN * scryptWords]uint32
+
+// scryptHash returns the Litecoin PoW hash of an 80-byte header, in the same
+// byte order as a block hash (interpret as little-endian to compare against
+// the target).
+func scryptHash(header []byte) [32]byte {
+	b := pbkdf2SHA256(header, header, scryptWords*4)
+
+	var x [scryptWords]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+
+	for i := 0; i < scryptN; i++ {
+		copy(scryptV[i*scryptWords:(i+1)*scryptWords], x[:])
+		blockMix(&x)
+	}
+	for i := 0; i < scryptN; i++ {
+		j := int(x[16] & (scryptN - 1))
+		v := scryptV[j*scryptWords : (j+1)*scryptWords]
+		for k := range x {
+			x[k] ^= v[k]
+		}
+		blockMix(&x)
+	}
+
+	for i := range x {
+		binary.LittleEndian.PutUint32(b[i*4:], x[i])
+	}
+
+	var out [32]byte
+	copy(out[:], pbkdf2SHA256(header, b, 32))
+	return out
+}
+
+// pbkdf2SHA256 is PBKDF2-HMAC-SHA256 with a single iteration, which is all
+// scrypt uses.
+func pbkdf2SHA256(password, salt []byte, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	out := make([]byte, 0, keyLen+sha256.Size)
+	var counter [4]byte
+	for block := uint32(1); len(out) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		out = prf.Sum(out)
+	}
+	return out[:keyLen]
+}
+
+// blockMix is scrypt's BlockMix for r=1: two Salsa20/8 rounds over the two
+// 64-byte halves of the block.
+func blockMix(b *[scryptWords]uint32) {
+	var x [16]uint32
+	copy(x[:], b[16:])
+
+	for k := 0; k < 16; k++ {
+		x[k] ^= b[k]
+	}
+	salsa208(&x)
+	copy(b[0:16], x[:])
+
+	for k := 0; k < 16; k++ {
+		x[k] ^= b[16+k]
+	}
+	salsa208(&x)
+	copy(b[16:], x[:])
+}
+
+func rotl(v uint32, n uint) uint32 { return v<<n | v>>(32-n) }
+
+// salsa208 applies the Salsa20/8 core to b in place.
+func salsa208(b *[16]uint32) {
+	x := *b
+	for i := 0; i < 8; i += 2 {
+		// columns
+		x[4] ^= rotl(x[0]+x[12], 7)
+		x[8] ^= rotl(x[4]+x[0], 9)
+		x[12] ^= rotl(x[8]+x[4], 13)
+		x[0] ^= rotl(x[12]+x[8], 18)
+		x[9] ^= rotl(x[5]+x[1], 7)
+		x[13] ^= rotl(x[9]+x[5], 9)
+		x[1] ^= rotl(x[13]+x[9], 13)
+		x[5] ^= rotl(x[1]+x[13], 18)
+		x[14] ^= rotl(x[10]+x[6], 7)
+		x[2] ^= rotl(x[14]+x[10], 9)
+		x[6] ^= rotl(x[2]+x[14], 13)
+		x[10] ^= rotl(x[6]+x[2], 18)
+		x[3] ^= rotl(x[15]+x[11], 7)
+		x[7] ^= rotl(x[3]+x[15], 9)
+		x[11] ^= rotl(x[7]+x[3], 13)
+		x[15] ^= rotl(x[11]+x[7], 18)
+		// rows
+		x[1] ^= rotl(x[0]+x[3], 7)
+		x[2] ^= rotl(x[1]+x[0], 9)
+		x[3] ^= rotl(x[2]+x[1], 13)
+		x[0] ^= rotl(x[3]+x[2], 18)
+		x[6] ^= rotl(x[5]+x[4], 7)
+		x[7] ^= rotl(x[6]+x[5], 9)
+		x[4] ^= rotl(x[7]+x[6], 13)
+		x[5] ^= rotl(x[4]+x[7], 18)
+		x[11] ^= rotl(x[10]+x[9], 7)
+		x[8] ^= rotl(x[11]+x[10], 9)
+		x[9] ^= rotl(x[8]+x[11], 13)
+		x[10] ^= rotl(x[9]+x[8], 18)
+		x[12] ^= rotl(x[15]+x[14], 7)
+		x[13] ^= rotl(x[12]+x[15], 9)
+		x[14] ^= rotl(x[13]+x[12], 13)
+		x[15] ^= rotl(x[14]+x[13], 18)
+	}
+	for i := range b {
+		b[i] += x[i]
+	}
+}
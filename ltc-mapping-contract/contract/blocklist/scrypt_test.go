@@ -0,0 +1,59 @@
+package blocklist
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Real Litecoin header vectors with their known scrypt PoW hashes.
+var scryptVectors = []struct {
+	name    string
+	header  string
+	powHash string
+}{
+	{
+		name:    "mainnet genesis",
+		header:  "010000000000000000000000000000000000000000000000000000000000000000000000d9ced4ed1130f7b7faad9be25323ffafa33232a17c3edf6cfd97bee6bafbdd97b9aa8e4ef0ff0f1ecd513f7c",
+		powHash: "0000050c34a64b415b6b15b37f2216634b5b1669cb9a2e38d76f7213b0671e00",
+	},
+	{
+		name:    "mainnet 277645",
+		header:  "01000000a9119b5d88d0688c436680d0d4e2ec2a98213f48cf4995c6a85625633e8474238607ad36572afabedd3a6f69c7cae9d1a3a90d14171f4b3e49ffbf445b66aecf5fa0f15060c7081c8029be40",
+		powHash: "0000000004d199288b894cf1bd8624cfa62a2ed10dab37720e09a600461fced1",
+	},
+	{
+		name:    "testnet4 genesis",
+		header:  "010000000000000000000000000000000000000000000000000000000000000000000000d9ced4ed1130f7b7faad9be25323ffafa33232a17c3edf6cfd97bee6bafbdd97f60ba158f0ff0f1ee1790400",
+		powHash: "000006cc0225c4b4c387604dd670b1ff4b95af0f46f86bef805c0d085b60de64",
+	},
+}
+
+func TestScryptHashVectors(t *testing.T) {
+	for _, v := range scryptVectors {
+		header, _ := hex.DecodeString(v.header)
+		got := chainhash.Hash(scryptHash(header))
+		if got.String() != v.powHash {
+			t.Errorf("%s: got %s, want %s", v.name, got, v.powHash)
+		}
+	}
+}
+
+func TestScryptHashMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	header := make([]byte, 80)
+	for i := 0; i < 20; i++ {
+		r.Read(header)
+		want, err := scrypt.Key(header, header, 1024, 1, 1, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := scryptHash(header)
+		if hex.EncodeToString(got[:]) != hex.EncodeToString(want) {
+			t.Fatalf("mismatch for %x", header)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"ltc-mapping-contract/contract/blocklist"
 	"ltc-mapping-contract/contract/constants"
 	ce "ltc-mapping-contract/contract/contracterrors"
+	"ltc-mapping-contract/contract/network"
 	"ltc-mapping-contract/contract/mapping"
 	_ "ltc-mapping-contract/sdk" // ensure sdk is imported
 	"encoding/hex"
@@ -31,15 +32,27 @@ import (
 	"github.com/CosmWasm/tinyjson"
 )
 
-// passed via ldflags, will compile for testnet when set to "testnet"
+// NetworkMode is passed via ldflags. It is only the default network for
+// contracts seeded before the network was stored; seedBlocks fixes the
+// network in state and every handler reads it from there.
 var NetworkMode string
 
+// currentNetwork returns the network the contract is bridged to.
+func currentNetwork() *network.Network {
+	net, err := network.Load(NetworkMode)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	return net
+}
+
 func checkOracle() {
+	net := currentNetwork()
 	caller := sdk.GetEnv().Caller.String()
-	if caller == constants.OracleAddress {
+	if caller == net.OracleAddress {
 		return
 	}
-	if constants.IsTestnet(NetworkMode) && caller == *sdk.GetEnvKey("contract.owner") {
+	if net.Testnet && caller == *sdk.GetEnvKey("contract.owner") {
 		return
 	}
 	ce.CustomAbort(
@@ -49,7 +62,7 @@ func checkOracle() {
 
 func checkAdmin() {
 	caller := sdk.GetEnv().Caller.String()
-	if caller == constants.OracleAddress || caller == *sdk.GetEnvKey("contract.owner") {
+	if caller == currentNetwork().OracleAddress || caller == *sdk.GetEnvKey("contract.owner") {
 		return
 	}
 	ce.CustomAbort(
@@ -57,6 +70,21 @@ func checkAdmin() {
 	)
 }
 
+// checkHeaderAdmin guards the admin actions that change the stored headers
+// outside addBlocks. Once an oracle set is configured the single oracle
+// address could use them to skip its quorum, so only the owner may call them.
+func checkHeaderAdmin() {
+	oracles, err := blocklist.LoadOracleSet()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	if oracles == nil {
+		checkAdmin()
+		return
+	}
+	checkOwner()
+}
+
 func checkOwner() {
 	if sdk.GetEnv().Caller.String() != *sdk.GetEnvKey("contract.owner") {
 		ce.CustomAbort(
@@ -65,9 +93,13 @@ func checkOwner() {
 	}
 }
 
-func checkNotPaused() {
+func isPaused() bool {
 	s := sdk.StateGetObject(constants.PausedKey)
-	if s != nil && *s == "1" {
+	return s != nil && *s == "1"
+}
+
+func checkNotPaused() {
+	if isPaused() {
 		ce.CustomAbort(
 			ce.NewContractError(ce.ErrTransaction, "contract is paused"),
 		)
@@ -76,7 +108,7 @@ func checkNotPaused() {
 
 //go:wasmexport seedBlocks
 func SeedBlocks(blockSeedInput *string) *string {
-	checkAdmin()
+	checkHeaderAdmin()
 
 	var seedParams blocklist.SeedBlocksParams
 	err := tinyjson.Unmarshal([]byte(*blockSeedInput), &seedParams)
@@ -84,7 +116,11 @@ func SeedBlocks(blockSeedInput *string) *string {
 		ce.CustomAbort(ce.WrapContractError(ce.ErrJson, err, "error unmarshalling seed blocks input"))
 	}
 
-	newLastHeight, err := blocklist.HandleSeedBlocks(seedParams, constants.IsTestnet(NetworkMode))
+	net, err := network.Fix(seedParams.Network, NetworkMode)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	newLastHeight, err := blocklist.HandleSeedBlocks(seedParams, net)
 	if err != nil {
 		ce.CustomAbort(err)
 	}
@@ -103,7 +139,7 @@ func SeedBlocks(blockSeedInput *string) *string {
 //
 //go:wasmexport initPruning
 func InitPruning(input *string) *string {
-	checkAdmin()
+	checkHeaderAdmin()
 
 	floor, err := strconv.ParseUint(*input, 10, 32)
 	if err != nil {
@@ -121,6 +157,29 @@ func InitPruning(input *string) *string {
 	return mapping.StrPtr("prune floor set to " + strconv.FormatUint(floor, 10))
 }
 
+// initRetarget stores a retarget anchor for contracts seeded before
+// difficulty retargeting was enforced. Input is the same shape as seedBlocks:
+// the header at the first height of the tip's difficulty epoch, or at the
+// height just before it, and that height. Call once for each.
+//
+//go:wasmexport initRetarget
+func InitRetarget(input *string) *string {
+	checkHeaderAdmin()
+
+	var params blocklist.SeedBlocksParams
+	err := tinyjson.Unmarshal([]byte(*input), &params)
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrJson, err, "error unmarshalling init retarget input"))
+	}
+
+	height, err := blocklist.HandleInitRetarget(params)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	return mapping.StrPtr("retarget anchor set at height " + strconv.FormatUint(uint64(height), 10))
+}
+
 // setMaxUnmapPerBlock tunes the BTC-C3 per-Hive-block withdrawal cap.
 // Argument is a non-negative integer string in sats. Setting 0
 // disables the rate limit; any positive value caps the aggregate
@@ -146,20 +205,140 @@ func SetMaxUnmapPerBlock(input *string) *string {
 	return mapping.StrPtr("max unmap per block set to " + strconv.FormatInt(v, 10) + " litoshis")
 }
 
+// setMinConfirmations sets how deep a block must be buried before proofs
+// against it are accepted by an action ("map" or "confirmSpend"). The tip
+// block counts as one confirmation, so 1 accepts any stored block. Faster
+// chains should require more depth than the oracle's submission delay alone
+// provides.
+//
+//go:wasmexport setMinConfirmations
+func SetMinConfirmations(input *string) *string {
+	checkOwner()
+
+	var params mapping.SetMinConfirmationsParams
+	err := tinyjson.Unmarshal([]byte(*input), &params)
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrJson, err, "error unmarshalling min confirmations input"))
+	}
+	switch params.Action {
+	case constants.MinConfirmationsMap, constants.MinConfirmationsConfirmSpend:
+	default:
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "unknown action "+params.Action))
+	}
+	if params.Confirmations < 1 {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "confirmations must be at least 1"))
+	}
+
+	confirmations := strconv.FormatUint(uint64(params.Confirmations), 10)
+	sdk.StateSetObject(constants.MinConfirmationsPrefix+params.Action, confirmations)
+	return mapping.StrPtr("min confirmations for " + params.Action + " set to " + confirmations)
+}
+
+// setDepositFinality sets how many blocks past its block a deposit waits in
+// the pending table before it is credited. Argument is a non-negative integer
+// string no greater than the header retention window; 0 credits deposits as
+// soon as they are proven. Deposits already pending keep their ready height.
+//
+//go:wasmexport setDepositFinality
+func SetDepositFinality(input *string) *string {
+	checkOwner()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected block count as integer string"))
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(*input), 10, 32)
+	retention := currentNetwork().BlockRetention
+	if err != nil || v > uint64(retention) {
+		ce.CustomAbort(ce.NewContractError(
+			ce.ErrInput,
+			"expected block count between 0 and "+strconv.FormatUint(uint64(retention), 10),
+		))
+	}
+	sdk.StateSetObject(constants.DepositFinalityKey, strconv.FormatUint(v, 10))
+	if v == 0 {
+		return mapping.StrPtr("deposit finality disabled (deposits credited when proven)")
+	}
+	return mapping.StrPtr("deposit finality set to " + strconv.FormatUint(v, 10) + " blocks")
+}
+
+// setMaxFutureDrift bounds how many seconds ahead of the Hive block time a
+// new header's timestamp may be. Argument is a non-negative integer string of
+// seconds; 0 disables the check. Litecoin Core allows two hours (7200).
+//
+//go:wasmexport setMaxFutureDrift
+func SetMaxFutureDrift(input *string) *string {
+	checkOwner()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected seconds as integer string"))
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(*input), 10, 32)
+	if err != nil {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected non-negative integer seconds"))
+	}
+	sdk.StateSetObject(constants.MaxFutureDriftKey, strconv.FormatUint(v, 10))
+	if v == 0 {
+		return mapping.StrPtr("max future drift disabled")
+	}
+	return mapping.StrPtr("max future drift set to " + strconv.FormatUint(v, 10) + " seconds")
+}
+
+// setOracles replaces the set of oracles allowed to call addBlocks and how
+// many of them must submit the same headers before they are applied.
+// Batches staged under the previous set are dropped. An empty oracle list
+// returns addBlocks to the single oracle address.
+//
+//go:wasmexport setOracles
+func SetOracles(input *string) *string {
+	checkOwner()
+
+	var params blocklist.SetOraclesParams
+	err := tinyjson.Unmarshal([]byte(*input), &params)
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrJson, err, "error unmarshalling oracle set input"))
+	}
+	if err := blocklist.HandleSetOracles(params); err != nil {
+		ce.CustomAbort(err)
+	}
+	if len(params.Oracles) == 0 {
+		return mapping.StrPtr("oracle set cleared")
+	}
+	return mapping.StrPtr(
+		"oracle set: " + strconv.FormatUint(uint64(params.Threshold), 10) + " of " + strconv.Itoa(len(params.Oracles)),
+	)
+}
+
+// finalizeDeposits credits the pending deposits whose block the tip is now
+// far enough past. Permissionless: it only applies what the stored headers
+// already allow, and addBlocks does the same on every call.
+//
+//go:wasmexport finalizeDeposits
+func FinalizeDeposits(_ *string) *string {
+	checkNotPaused()
+
+	lastHeight, err := blocklist.LastHeightFromState()
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height"))
+	}
+	finalized, err := mapping.FinalizeDeposits(lastHeight, currentNetwork())
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	return mapping.StrPtr("finalized " + strconv.Itoa(finalized) + " deposits")
+}
+
 // prune removes old block headers beyond the retention window.
 // Can be called independently of addBlocks to reduce state size.
 // Returns the number of headers pruned and the current prune floor.
 //
 //go:wasmexport prune
 func Prune(_ *string) *string {
-	checkAdmin()
+	checkHeaderAdmin()
 
 	lastHeight, err := blocklist.LastHeightFromState()
 	if err != nil {
 		ce.CustomAbort(ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height"))
 	}
 
-	pruned := blocklist.PruneOldHeaders(lastHeight)
+	pruned := blocklist.PruneOldHeaders(lastHeight, currentNetwork().BlockRetention)
 
 	return mapping.StrPtr(
 		"pruned " + strconv.Itoa(pruned) + " headers, last height: " + strconv.FormatUint(uint64(lastHeight), 10),
@@ -168,10 +347,21 @@ func Prune(_ *string) *string {
 
 //go:wasmexport addBlocks
 func AddBlocks(addBlocksInput *string) *string {
-	checkOracle()
+	oracles, err := blocklist.LoadOracleSet()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	caller := sdk.GetEnv().Caller.String()
+	if oracles == nil {
+		checkOracle()
+	} else if !oracles.Has(caller) {
+		ce.CustomAbort(
+			ce.NewContractError(ce.ErrNoPermission, "this action must be performed by a member of the oracle set"),
+		)
+	}
 
 	var addBlocksObj blocklist.AddBlocksParams
-	err := tinyjson.Unmarshal([]byte(*addBlocksInput), &addBlocksObj)
+	err = tinyjson.Unmarshal([]byte(*addBlocksInput), &addBlocksObj)
 	if err != nil {
 		ce.CustomAbort(
 			ce.NewContractError(ce.ErrInput, err.Error(), ce.MsgBadInput),
@@ -185,8 +375,26 @@ func AddBlocks(addBlocksInput *string) *string {
 		)
 	}
 
+	// With an oracle set, the batch waits until enough members agree on it,
+	// and the fee is their median.
+	latestFee := addBlocksObj.LatestFee
+	if oracles != nil {
+		fees, agreeing, ok := oracles.StageHeaders(caller, blockHeaders, latestFee)
+		if !ok {
+			return mapping.StrPtr(
+				"staged: " + strconv.Itoa(agreeing) + " of " + strconv.Itoa(int(oracles.Threshold)) + " oracles",
+			)
+		}
+		latestFee = blocklist.MedianFee(fees)
+	}
+
 	var resultBuilder strings.Builder
-	lastHeight, err := blocklist.HandleAddBlocks(blockHeaders, NetworkMode)
+	prevTip, err := blocklist.LastHeightFromState()
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrStateAccess, err, "error reading last block height"))
+	}
+	net := currentNetwork()
+	lastHeight, forkHeight, err := blocklist.HandleAddBlocks(blockHeaders, net)
 	if err != nil {
 		ce.CustomAbort(err)
 	}
@@ -194,16 +402,31 @@ func AddBlocks(addBlocksInput *string) *string {
 
 	blocklist.LastHeightToState(lastHeight)
 
+	// Deposits minted on the abandoned branch must be re-proven on the new one.
+	if forkHeight < prevTip {
+		if err := mapping.OrphanMints(forkHeight+1, prevTip, lastHeight); err != nil {
+			ce.CustomAbort(err)
+		}
+	}
+	if err := mapping.SettleOrphanedMints(lastHeight, net); err != nil {
+		ce.CustomAbort(err)
+	}
+	if !isPaused() {
+		if _, err := mapping.FinalizeDeposits(lastHeight, net); err != nil {
+			ce.CustomAbort(err)
+		}
+	}
+
 	// update base fee rate, do this after blocks because blocks more likely to fail
 	systemSupply, err := mapping.SupplyFromState()
 	if err != nil {
 		ce.CustomAbort(err)
 	}
-	latestFee := addBlocksObj.LatestFee
-	if latestFee == 0 {
-		latestFee = 1
+	// The observation is clamped to the chain policy's fee rate bounds before
+	// it enters the fee window, so a zero or negative fee rate is never persisted.
+	if err := mapping.UpdateBaseFeeRate(systemSupply, latestFee); err != nil {
+		ce.CustomAbort(err)
 	}
-	systemSupply.BaseFeeRate = latestFee
 	mapping.SaveSupplyToState(systemSupply)
 	resultBuilder.WriteString(", base fee: " + strconv.FormatInt(systemSupply.BaseFeeRate, 10))
 
@@ -212,7 +435,7 @@ func AddBlocks(addBlocksInput *string) *string {
 
 //go:wasmexport replaceBlock
 func ReplaceBlock(input *string) *string {
-	checkAdmin()
+	checkHeaderAdmin()
 
 	blockBytes, err := hex.DecodeString(*input)
 	if err != nil {
@@ -225,10 +448,17 @@ func ReplaceBlock(input *string) *string {
 	var header blocklist.BlockHeaderBytes
 	copy(header[:], blockBytes)
 
-	height, err := blocklist.HandleReplaceBlock(header, NetworkMode)
+	net := currentNetwork()
+	height, err := blocklist.HandleReplaceBlock(header, net)
 	if err != nil {
 		ce.CustomAbort(err)
 	}
+	if err := mapping.OrphanMints(height, height, height); err != nil {
+		ce.CustomAbort(err)
+	}
+	if err := mapping.SettleOrphanedMints(height, net); err != nil {
+		ce.CustomAbort(err)
+	}
 
 	outMsg := "replaced block at height: " + strconv.FormatUint(uint64(height), 10)
 	return &outMsg
@@ -240,17 +470,25 @@ func ReplaceBlock(input *string) *string {
 //
 //go:wasmexport replaceBlocks
 func ReplaceBlocks(input *string) *string {
-	checkAdmin()
+	checkHeaderAdmin()
 
 	blockHeaders, err := blocklist.DivideHeaderList(input)
 	if err != nil {
 		ce.CustomAbort(ce.WrapContractError(ce.ErrInput, err, "error parsing replacement block headers"))
 	}
 
-	height, err := blocklist.HandleReplaceBlocks(blockHeaders, NetworkMode)
+	net := currentNetwork()
+	height, err := blocklist.HandleReplaceBlocks(blockHeaders, net)
 	if err != nil {
 		ce.CustomAbort(err)
 	}
+	replaced := uint32(len(blockHeaders))
+	if err := mapping.OrphanMints(height-replaced+1, height, height); err != nil {
+		ce.CustomAbort(err)
+	}
+	if err := mapping.SettleOrphanedMints(height, net); err != nil {
+		ce.CustomAbort(err)
+	}
 
 	outMsg := "replaced " + strconv.Itoa(len(blockHeaders)) + " blocks, tip at height: " + strconv.FormatUint(uint64(height), 10)
 	return &outMsg
@@ -272,7 +510,7 @@ func Map(incomingTx *string) *string {
 		ce.CustomAbort(err)
 	}
 
-	contractState, err := mapping.InitializeMappingState(publicKeys, NetworkMode, mapInstructions.Instructions...)
+	contractState, err := mapping.InitializeMappingState(publicKeys, currentNetwork(), mapInstructions.Instructions...)
 	if err != nil {
 		ce.CustomAbort(err)
 	}
@@ -340,7 +578,7 @@ func doUnmap(instructions *mapping.TransferParams) {
 		ce.CustomAbort(err)
 	}
 
-	contractState, err := mapping.IntializeContractState(publicKeys, NetworkMode)
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
 	if err != nil {
 		ce.CustomAbort(ce.Prepend(err, "error initializing contract state"))
 	}
@@ -507,7 +745,7 @@ func ConfirmSpend(input *string) *string {
 		ce.CustomAbort(err)
 	}
 
-	contractState, err := mapping.IntializeContractState(publicKeys, NetworkMode)
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
 	if err != nil {
 		ce.CustomAbort(err)
 	}
@@ -525,6 +763,250 @@ func ConfirmSpend(input *string) *string {
 	return mapping.StrPtr("0")
 }
 
+// setWithdrawalQueue turns queued withdrawals on or off. Argument is a
+// boolean string. While on, unmap debits the sender and queues the payout for
+// settleWithdrawals instead of sending it in its own transaction. Turning it
+// off leaves already queued withdrawals to be settled.
+//
+//go:wasmexport setWithdrawalQueue
+func SetWithdrawalQueue(input *string) *string {
+	checkOwner()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected true or false"))
+	}
+	on, err := strconv.ParseBool(strings.TrimSpace(*input))
+	if err != nil {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected true or false"))
+	}
+	if on {
+		sdk.StateSetObject(constants.WithdrawalQueueModeKey, "1")
+		return mapping.StrPtr("withdrawal queue enabled")
+	}
+	sdk.StateSetObject(constants.WithdrawalQueueModeKey, "0")
+	return mapping.StrPtr("withdrawal queue disabled")
+}
+
+// settleWithdrawals pays the oldest queued withdrawals, up to
+// MaxSettlePerCall, from a single transaction and requests one signing round
+// for it. Withdrawals whose share of the miner fee would leave them as dust or
+// exceed their max_fee are refunded instead. Permissionless: it only pays
+// what was already queued and debited, so anyone may keep the queue moving.
+//
+//go:wasmexport settleWithdrawals
+func SettleWithdrawals(_ *string) *string {
+	checkNotPaused()
+
+	publicKeys, err := loadPublicKeys()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
+	if err != nil {
+		ce.CustomAbort(ce.Prepend(err, "error initializing contract state"))
+	}
+
+	txId, settled, err := contractState.HandleSettleWithdrawals()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	err = contractState.SaveToState()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	if txId == "" {
+		return mapping.StrPtr("no withdrawals settled")
+	}
+	return mapping.StrPtr("settled " + strconv.Itoa(settled) + " withdrawals in " + txId)
+}
+
+// bumpFee replaces a pending spend stuck at a stale fee rate with one paying
+// the current rate. Argument is the txid of the latest version of the spend.
+// The replacement spends the same inputs and pays the same outputs, and takes
+// the higher fee out of the change. The added fee is charged to the payer set
+// by setCpfpPayer.
+//
+//go:wasmexport bumpFee
+func BumpFee(input *string) *string {
+	checkAdmin()
+	checkNotPaused()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected txid of a pending spend"))
+	}
+	txId := strings.TrimSpace(*input)
+
+	publicKeys, err := loadPublicKeys()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
+	if err != nil {
+		ce.CustomAbort(ce.Prepend(err, "error initializing contract state"))
+	}
+
+	newTxId, err := contractState.HandleBumpFee(txId)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	err = contractState.SaveToState()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	return mapping.StrPtr("replaced " + txId + " with " + newTxId)
+}
+
+// setCpfpPayer sets who pays the fee of cpfp children and the fee bumpFee
+// adds. Argument is "fees" to
+// take it from the protocol fee supply, the default, or "withdrawers" to take
+// it from the balances of the withdrawals the stuck spend pays.
+//
+//go:wasmexport setCpfpPayer
+func SetCpfpPayer(input *string) *string {
+	checkOwner()
+	if input == nil {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected fees or withdrawers"))
+	}
+	payer := strings.TrimSpace(*input)
+	switch payer {
+	case constants.CpfpPayerFees, constants.CpfpPayerWithdrawers:
+	default:
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected fees or withdrawers"))
+	}
+	sdk.StateSetObject(constants.CpfpPayerKey, payer)
+	return mapping.StrPtr("cpfp fees paid by " + payer)
+}
+
+// cpfp accelerates a pending spend stuck at a stale fee rate with a child
+// transaction spending one of its change outputs, paying enough fee to lift
+// both to the current rate. Argument is the txid of the spend.
+//
+//go:wasmexport cpfp
+func Cpfp(input *string) *string {
+	checkAdmin()
+	checkNotPaused()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected txid of a pending spend"))
+	}
+	txId := strings.TrimSpace(*input)
+
+	publicKeys, err := loadPublicKeys()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
+	if err != nil {
+		ce.CustomAbort(ce.Prepend(err, "error initializing contract state"))
+	}
+
+	childTxId, err := contractState.HandleCpfp(txId)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	err = contractState.SaveToState()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	return mapping.StrPtr("accelerated " + txId + " with " + childTxId)
+}
+
+// reportSigned marks the withdrawals paid by a pending spend signed.
+// Argument is the hex of the fully signed spend; every input must carry a
+// valid signature. Permissionless, as the signatures are checked.
+//
+//go:wasmexport reportSigned
+func ReportSigned(input *string) *string {
+	checkNotPaused()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected signed transaction hex"))
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(*input))
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrInvalidHex, err, "error decoding signed transaction hex"))
+	}
+
+	publicKeys, err := loadPublicKeys()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
+	if err != nil {
+		ce.CustomAbort(ce.Prepend(err, "error initializing contract state"))
+	}
+
+	txId, err := contractState.HandleReportSigned(rawTx)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	return mapping.StrPtr("signed " + txId)
+}
+
+// setWithdrawalTimeout sets how many Hive blocks a withdrawal must go without
+// a change of status before it can be cancelled. Argument is a positive
+// integer string.
+//
+//go:wasmexport setWithdrawalTimeout
+func SetWithdrawalTimeout(input *string) *string {
+	checkOwner()
+	if input == nil || *input == "" {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected block count as integer string"))
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(*input), 10, 64)
+	if err != nil || v == 0 {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected positive integer block count"))
+	}
+	sdk.StateSetObject(constants.WithdrawalTimeoutKey, strconv.FormatUint(v, 10))
+	return mapping.StrPtr("withdrawal timeout set to " + strconv.FormatUint(v, 10) + " blocks")
+}
+
+// cancelWithdrawal cancels a withdrawal that has not changed status for the
+// withdrawal timeout, along with every other withdrawal its spend pays, and
+// refunds them. Argument is the withdrawal id. Only the contract owner and the
+// account the withdrawal was made from can cancel it.
+//
+//go:wasmexport cancelWithdrawal
+func CancelWithdrawal(input *string) *string {
+	checkNotPaused()
+	if input == nil {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected withdrawal id"))
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(*input), 10, 64)
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrInput, err, "invalid withdrawal id"))
+	}
+
+	publicKeys, err := loadPublicKeys()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	contractState, err := mapping.IntializeContractState(publicKeys, currentNetwork())
+	if err != nil {
+		ce.CustomAbort(ce.Prepend(err, "error initializing contract state"))
+	}
+
+	byOwner := sdk.GetEnv().Caller.String() == *sdk.GetEnvKey("contract.owner")
+	txId, err := contractState.HandleCancelWithdrawal(id, byOwner)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	err = contractState.SaveToState()
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+
+	if txId == "" {
+		return mapping.StrPtr("cancelled queued withdrawal " + strconv.FormatUint(id, 10))
+	}
+	return mapping.StrPtr("cancelled spend " + txId)
+}
+
 // Pauses all token operations (map, unmap, transfer, approve, confirmSpend).
 // Admin/owner operations remain available while paused.
 //
@@ -621,6 +1103,17 @@ func Migrate(_ *string) *string {
 		sdk.Log("migrate|v=1")
 	}
 
+	// --- v2: backfill cumulative chain work for the retained headers, so
+	// addBlocks can compare competing branches.
+	if version < "2" {
+		written, err := blocklist.BackfillChainWork(currentNetwork().BlockRetention)
+		if err != nil {
+			ce.CustomAbort(err)
+		}
+		sdk.StateSetObject(constants.MigrateVersionKey, "2")
+		sdk.Log("migrate|v=2|n=" + strconv.Itoa(written))
+	}
+
 	// --- future migrations go here ---
 
 	result := "migrated to v" + *sdk.StateGetObject(constants.MigrateVersionKey)
@@ -632,6 +1125,29 @@ func GetInfo(_ *string) *string {
 	return mapping.StrPtr(`{"name":"Litecoin","symbol":"LTC","decimals":"8"}`)
 }
 
+// getWithdrawal returns the status of a withdrawal as JSON. Argument is the
+// withdrawal id, as logged by unmap.
+//
+//go:wasmexport getWithdrawal
+func GetWithdrawal(input *string) *string {
+	if input == nil {
+		ce.CustomAbort(ce.NewContractError(ce.ErrInput, "expected withdrawal id"))
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(*input), 10, 64)
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrInput, err, "invalid withdrawal id"))
+	}
+	info, err := mapping.HandleGetWithdrawal(id)
+	if err != nil {
+		ce.CustomAbort(err)
+	}
+	data, err := tinyjson.Marshal(info)
+	if err != nil {
+		ce.CustomAbort(ce.WrapContractError(ce.ErrJson, err, "error marshalling withdrawal"))
+	}
+	return mapping.StrPtr(string(data))
+}
+
 func loadPublicKeys() (mapping.PublicKeys, error) {
 	primaryRaw := *sdk.StateGetObject(constants.PrimaryPublicKeyStateKey)
 	if primaryRaw == "" {
@@ -685,7 +1201,7 @@ func RegisterPublicKey(keyStr *string) *string {
 			ce.CustomAbort(ce.Prepend(err, "error registering primary public key"))
 		}
 		existingPrimary := sdk.StateGetObject(constants.PrimaryPublicKeyStateKey)
-		if *existingPrimary == "" || constants.IsTestnet(NetworkMode) {
+		if *existingPrimary == "" || currentNetwork().Testnet {
 			sdk.StateSetObject(constants.PrimaryPublicKeyStateKey, string(key[:]))
 			resultBuilder.WriteString("set primary key to: " + keys.PrimaryPubKey)
 		} else {
@@ -702,7 +1218,7 @@ func RegisterPublicKey(keyStr *string) *string {
 			resultBuilder.WriteString(", ")
 		}
 		existingBackup := sdk.StateGetObject(constants.BackupPublicKeyStateKey)
-		if *existingBackup == "" || constants.IsTestnet(NetworkMode) {
+		if *existingBackup == "" || currentNetwork().Testnet {
 			sdk.StateSetObject(constants.BackupPublicKeyStateKey, string(key[:]))
 			resultBuilder.WriteString("set backup key to: " + keys.BackupPubKey)
 		} else {
@@ -763,7 +1279,7 @@ func RegisterRouter(input *string) *string {
 
 	if router.ContractId != "" {
 		existingPrimary := sdk.StateGetObject(constants.RouterContractIdKey)
-		if *existingPrimary == "" || constants.IsTestnet(NetworkMode) {
+		if *existingPrimary == "" || currentNetwork().Testnet {
 			sdk.StateSetObject(constants.RouterContractIdKey, router.ContractId)
 			resultBuilder.WriteString("set router contract ID to: " + router.ContractId)
 		} else {
@@ -0,0 +1,58 @@
+package mapping
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestSigningDataVersionLinks(t *testing.T) {
+	sd := SigningData{
+		Tx: []byte{0x02, 0x00},
+		UnsignedSigHashes: []UnsignedSigHash{
+			{Index: 0, SigHash: []byte{0x01}, WitnessScript: []byte{0x51}, Amount: 150000},
+		},
+		Replaces:   "aa",
+		ReplacedBy: "bb",
+		Charges:    []FeeCharge{{Amount: 40}, {From: "hive:alice", Amount: 25}},
+	}
+	data, err := MarshalSigningData(&sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalSigningData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Replaces != "aa" || got.ReplacedBy != "bb" || got.UnsignedSigHashes[0].Amount != 150000 {
+		t.Fatalf("got %+v, want %+v", got, sd)
+	}
+	if len(got.Charges) != 2 || got.Charges[0] != sd.Charges[0] || got.Charges[1] != sd.Charges[1] {
+		t.Fatalf("charges = %+v, want %+v", got.Charges, sd.Charges)
+	}
+
+	// A spend that was never bumped is stored without the links.
+	sd.Replaces, sd.ReplacedBy, sd.Charges = "", "", nil
+	data, err = MarshalSigningData(&sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("rp")) || bytes.Contains(data, []byte("rb")) || bytes.Contains(data, []byte("ch")) {
+		t.Fatalf("empty links were encoded: %x", data)
+	}
+}
+
+func TestAddChangeOutputs(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	addChangeOutputs(tx, 1003, 4, []byte{0x51})
+	want := []int64{253, 250, 250, 250}
+	if len(tx.TxOut) != len(want) {
+		t.Fatalf("got %d outputs, want %d", len(tx.TxOut), len(want))
+	}
+	for i, out := range tx.TxOut {
+		if out.Value != want[i] {
+			t.Errorf("output %d = %d, want %d", i, out.Value, want[i])
+		}
+	}
+}
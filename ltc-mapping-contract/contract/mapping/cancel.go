@@ -0,0 +1,764 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"ltc-mapping-contract/contract/constants"
+	ce "ltc-mapping-contract/contract/contracterrors"
+	"ltc-mapping-contract/sdk"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ---------------------------------------------------------------------------
+// Withdrawal cancellation
+//
+// A withdrawal whose status has not changed for the withdrawal timeout can be
+// cancelled by the owner or by the account it was made from. A queued
+// withdrawal just leaves the queue. Otherwise its spend is undone as a whole:
+// the inputs return to the pool, the change leaves it, every version of the
+// spend is retired, every withdrawal it pays is refunded, VSC fee included,
+// and the fees bumpFee added are credited back to whoever paid them.
+//
+// TSS may still sign a cancelled spend and someone broadcast it, so every
+// version is marked cancelled until a transaction spending one of its inputs
+// confirms and it never can. If one of its versions confirms instead, map or
+// confirmSpend reconciles it: its inputs leave the pool again, its change
+// joins it, and the withdrawals are confirmed and their refunds, along with
+// the bump fees that version paid, clawed back, with whatever the accounts no
+// longer hold recorded as protocol deficit. An input that a pending spend has
+// spent again by then is marked, so that cancelling that spend, which can no
+// longer confirm, does not restore it.
+// ---------------------------------------------------------------------------
+
+const cancelledSpendFixedSize = 8 + 8 + 1 + 2
+const cancelledInputSize = chainhash.HashSize + 4
+const cancelledPayoutSize = 8 + 8
+
+type cancelledSpend struct {
+	InputTotal int64              // value of the spend's inputs
+	Change     int64              // value of the latest version's change outputs
+	Versions   []cancelledVersion // every version of the spend, latest first
+	Inputs     []wire.OutPoint    // outputs it spends
+	Payouts    []cancelledPayout
+}
+
+// cancelledVersion is a version of a cancelled spend, with the fee charges
+// bumpFee made for it.
+type cancelledVersion struct {
+	TxId    string
+	Charges []FeeCharge
+}
+
+// cancelledPayout is a withdrawal paid by a cancelled spend.
+type cancelledPayout struct {
+	Id     uint64
+	VscFee int64
+}
+
+func marshalCancelledSpend(c *cancelledSpend) ([]byte, error) {
+	if len(c.Versions) > 255 {
+		return nil, errors.New("too many versions of cancelled spend")
+	}
+	if len(c.Inputs) > 65535 {
+		return nil, errors.New("too many inputs of cancelled spend")
+	}
+	buf := make([]byte, 0, cancelledSpendFixedSize+67*len(c.Versions)+
+		cancelledInputSize*len(c.Inputs)+cancelledPayoutSize*len(c.Payouts))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(c.InputTotal))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(c.Change))
+	buf = append(buf, byte(len(c.Versions)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(c.Inputs)))
+	for _, v := range c.Versions {
+		if len(v.TxId) > 255 {
+			return nil, errors.New("cancelled spend txid too long")
+		}
+		if len(v.Charges) > 65535 {
+			return nil, errors.New("too many fee charges of cancelled spend")
+		}
+		buf = append(buf, byte(len(v.TxId)))
+		buf = append(buf, v.TxId...)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(v.Charges)))
+		for _, charge := range v.Charges {
+			if len(charge.From) > 255 {
+				return nil, errors.New("fee charge account too long")
+			}
+			buf = append(buf, byte(len(charge.From)))
+			buf = append(buf, charge.From...)
+			buf = binary.BigEndian.AppendUint64(buf, uint64(charge.Amount))
+		}
+	}
+	for _, in := range c.Inputs {
+		buf = append(buf, in.Hash[:]...)
+		buf = binary.BigEndian.AppendUint32(buf, in.Index)
+	}
+	for _, p := range c.Payouts {
+		buf = binary.BigEndian.AppendUint64(buf, p.Id)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(p.VscFee))
+	}
+	return buf, nil
+}
+
+func unmarshalCancelledSpend(data []byte) (*cancelledSpend, error) {
+	if len(data) < cancelledSpendFixedSize {
+		return nil, errors.New("truncated cancelled spend")
+	}
+	c := &cancelledSpend{
+		InputTotal: int64(binary.BigEndian.Uint64(data)),
+		Change:     int64(binary.BigEndian.Uint64(data[8:])),
+		Versions:   make([]cancelledVersion, data[16]),
+		Inputs:     make([]wire.OutPoint, binary.BigEndian.Uint16(data[17:])),
+	}
+	data = data[cancelledSpendFixedSize:]
+	for i := range c.Versions {
+		v := &c.Versions[i]
+		var ok bool
+		if v.TxId, data, ok = readShortString(data); !ok || len(data) < 2 {
+			return nil, errors.New("truncated cancelled spend")
+		}
+		if n := binary.BigEndian.Uint16(data); n > 0 {
+			v.Charges = make([]FeeCharge, n)
+		}
+		data = data[2:]
+		for j := range v.Charges {
+			if v.Charges[j].From, data, ok = readShortString(data); !ok || len(data) < 8 {
+				return nil, errors.New("truncated cancelled spend fee charge")
+			}
+			v.Charges[j].Amount = int64(binary.BigEndian.Uint64(data))
+			data = data[8:]
+		}
+	}
+	if len(data) < cancelledInputSize*len(c.Inputs) {
+		return nil, errors.New("truncated cancelled spend input")
+	}
+	for i := range c.Inputs {
+		copy(c.Inputs[i].Hash[:], data)
+		c.Inputs[i].Index = binary.BigEndian.Uint32(data[chainhash.HashSize:])
+		data = data[cancelledInputSize:]
+	}
+	if len(data)%cancelledPayoutSize != 0 {
+		return nil, errors.New("truncated cancelled spend payout")
+	}
+	for ; len(data) > 0; data = data[cancelledPayoutSize:] {
+		c.Payouts = append(c.Payouts, cancelledPayout{
+			Id:     binary.BigEndian.Uint64(data),
+			VscFee: int64(binary.BigEndian.Uint64(data[8:])),
+		})
+	}
+	return c, nil
+}
+
+// loadCancelledSpend returns the cancellation of the spend of which txId is a
+// version, or nil if it was not cancelled.
+func loadCancelledSpend(txId string) (*cancelledSpend, error) {
+	raw := sdk.StateGetObject(constants.CancelledSpendPrefix + txId)
+	if raw == nil || len(*raw) == 0 {
+		return nil, nil
+	}
+	c, err := unmarshalCancelledSpend([]byte(*raw))
+	if err != nil {
+		return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error decoding cancelled spend")
+	}
+	return c, nil
+}
+
+// cancelledSpendsOf returns the txids of the cancelled spends that spend
+// outPoint and may still confirm.
+func cancelledSpendsOf(outPoint wire.OutPoint) []string {
+	raw := sdk.StateGetObject(constants.CancelledInputPrefix + outPoint.String())
+	if raw == nil || *raw == "" {
+		return nil
+	}
+	return strings.Split(*raw, ",")
+}
+
+func setCancelledSpendsOf(outPoint wire.OutPoint, txIds []string) {
+	key := constants.CancelledInputPrefix + outPoint.String()
+	if len(txIds) == 0 {
+		sdk.StateDeleteObject(key)
+		return
+	}
+	sdk.StateSetObject(key, strings.Join(txIds, ","))
+}
+
+// saveCancelledSpend marks every version of the cancelled spend c and indexes
+// it under each of its inputs.
+func saveCancelledSpend(c *cancelledSpend) error {
+	data, err := marshalCancelledSpend(c)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrInput, err, "error encoding cancelled spend")
+	}
+	for _, version := range c.Versions {
+		sdk.StateSetObject(constants.CancelledSpendPrefix+version.TxId, string(data))
+	}
+	for _, in := range c.Inputs {
+		setCancelledSpendsOf(in, append(cancelledSpendsOf(in), c.Versions[0].TxId))
+	}
+	return nil
+}
+
+// deleteCancelledSpend removes the marks and index entries saveCancelledSpend
+// wrote for c.
+func deleteCancelledSpend(c *cancelledSpend) {
+	for _, version := range c.Versions {
+		sdk.StateDeleteObject(constants.CancelledSpendPrefix + version.TxId)
+	}
+	for _, in := range c.Inputs {
+		setCancelledSpendsOf(in, slices.DeleteFunc(cancelledSpendsOf(in), func(txId string) bool {
+			return txId == c.Versions[0].TxId
+		}))
+	}
+}
+
+// withdrawalTimeout returns the number of Hive blocks a withdrawal must go
+// without a change of status before it can be cancelled.
+func withdrawalTimeout() uint64 {
+	s := sdk.StateGetObject(constants.WithdrawalTimeoutKey)
+	if s == nil || *s == "" {
+		return constants.DefaultWithdrawalTimeout
+	}
+	v, err := strconv.ParseUint(*s, 10, 64)
+	if err != nil || v == 0 {
+		return constants.DefaultWithdrawalTimeout
+	}
+	return v
+}
+
+// HandleCancelWithdrawal cancels withdrawal id, along with every other
+// withdrawal paid by the same spend, and refunds them. byOwner is whether the
+// contract owner is cancelling it; anyone else must be the account it was
+// made from. It returns the txid of the cancelled spend, or "" if the
+// withdrawal was still queued.
+func (cs *ContractState) HandleCancelWithdrawal(id uint64, byOwner bool) (string, error) {
+	env := sdk.GetEnv()
+	idStr := strconv.FormatUint(id, 10)
+	r, err := loadWithdrawal(id)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", ce.NewContractError(ce.ErrInput, "no withdrawal "+idStr)
+	}
+	if !byOwner {
+		if env.Caller.String() != r.From {
+			return "", ce.NewContractError(
+				ce.ErrNoPermission,
+				"withdrawal "+idStr+" can only be cancelled by the contract owner or "+r.From,
+			)
+		}
+		if err := checkAuth(env); err != nil {
+			return "", err
+		}
+	}
+	switch r.Status {
+	case WithdrawalRequested, WithdrawalSigningRequested, WithdrawalSigned, WithdrawalReplaced:
+	default:
+		return "", ce.NewContractError(ce.ErrInput, "withdrawal "+idStr+" is "+r.Status.String())
+	}
+	timeout := withdrawalTimeout()
+	if elapsed := env.BlockHeight - r.UpdatedAt; elapsed < timeout {
+		return "", ce.NewContractError(
+			ce.ErrInput,
+			"withdrawal "+idStr+" can be cancelled "+strconv.FormatUint(timeout-elapsed, 10)+" blocks from now",
+		)
+	}
+
+	if r.Status == WithdrawalRequested {
+		return "", cs.cancelQueuedWithdrawal(id)
+	}
+	return r.TxId, cs.cancelSpend(r.TxId)
+}
+
+// cancelQueuedWithdrawal removes withdrawal id from the queue and refunds it.
+func (cs *ContractState) cancelQueuedWithdrawal(id uint64) error {
+	queue, err := loadWithdrawalQueue()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(queue, func(w queuedWithdrawal) bool { return w.Id == id })
+	if i < 0 {
+		return ce.NewContractError(ce.ErrStateAccess, "withdrawal "+strconv.FormatUint(id, 10)+" is not queued")
+	}
+	w := queue[i]
+	saveWithdrawalQueue(slices.Delete(queue, i, i+1))
+	return cs.refundWithdrawal(&w)
+}
+
+// cancelSpend undoes the pending spend txId, the latest version of its spend,
+// refunds the withdrawals it pays and the fee charges of its versions, and
+// marks every version cancelled unless it can no longer confirm.
+func (cs *ContractState) cancelSpend(txId string) error {
+	sd, err := loadSigningData(txId)
+	if err != nil {
+		return err
+	}
+	if sd == nil {
+		return ce.NewContractError(ce.ErrInput, "no pending spend "+txId)
+	}
+	if sd.ReplacedBy != "" {
+		return ce.NewContractError(ce.ErrInput, "spend "+txId+" was replaced by "+sd.ReplacedBy)
+	}
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(sd.Tx)); err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error deserializing pending spend")
+	}
+	if len(sd.UnsignedSigHashes) != len(tx.TxIn) {
+		return ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+	}
+
+	for _, p := range sd.Payouts {
+		if p.Id == 0 {
+			return ce.NewContractError(ce.ErrInput, "spend "+txId+" was recorded without its withdrawals")
+		}
+		r, err := loadWithdrawal(p.Id)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return ce.NewContractError(ce.ErrStateAccess, "no withdrawal "+strconv.FormatUint(p.Id, 10))
+		}
+	}
+
+	changeAddress, _, err := createP2WSHAddressWithBackup(
+		cs.PublicKeys.Primary,
+		cs.PublicKeys.Backup,
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		return ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
+	}
+	changeScript, err := cs.destinationScript(changeAddress)
+	if err != nil {
+		return err
+	}
+	var change int64
+	changeOutputs := 0
+	for _, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, changeScript) {
+			change += out.Value
+			changeOutputs++
+		}
+	}
+	// Undoing the spend would strand any spend of its change, so it may only
+	// be cancelled while all of its change is still in the pool.
+	pooled, _, err := cs.unconfirmedChangeOf(txId)
+	if err != nil {
+		return err
+	}
+	if len(pooled) != changeOutputs {
+		return ce.NewContractError(ce.ErrInput, "change of spend "+txId+" is already being spent")
+	}
+	cs.dropUnconfirmed(pooled)
+
+	cancelled := cancelledSpend{Change: change}
+	conflicted := false
+	for _, h := range sd.UnsignedSigHashes {
+		if int(h.Index) >= len(tx.TxIn) {
+			return ce.NewContractError(ce.ErrTransaction, "signing data does not match spend "+txId)
+		}
+		if h.Amount <= 0 {
+			return ce.NewContractError(ce.ErrInput, "spend "+txId+" was recorded without its input amounts")
+		}
+		if cancelled.InputTotal, err = safeAdd64(cancelled.InputTotal, h.Amount); err != nil {
+			return ce.WrapContractError(ce.ErrArithmetic, err, "error summing inputs")
+		}
+		outPoint := tx.TxIn[h.Index].PreviousOutPoint
+		restored, err := cs.restoreInput(outPoint, &h)
+		if err != nil {
+			return err
+		}
+		conflicted = conflicted || !restored
+		cancelled.Inputs = append(cancelled.Inputs, outPoint)
+	}
+
+	versions, err := spendVersionIds(txId)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		v, err := loadSigningData(version)
+		if err != nil {
+			return err
+		}
+		var charges []FeeCharge
+		if v != nil {
+			charges = v.Charges
+		}
+		cancelled.Versions = append(cancelled.Versions, cancelledVersion{TxId: version, Charges: charges})
+	}
+	for _, p := range sd.Payouts {
+		cancelled.Payouts = append(cancelled.Payouts, cancelledPayout{Id: p.Id, VscFee: p.VscFee})
+	}
+	if err := cs.retireSpend(txId); err != nil {
+		return err
+	}
+	if err := cs.refundCancelledSpend(&cancelled); err != nil {
+		return err
+	}
+	// An input already spent by a confirmed transaction means the spend can
+	// never confirm, so there is nothing left to reconcile.
+	if conflicted {
+		return nil
+	}
+	return saveCancelledSpend(&cancelled)
+}
+
+// refundCancelledSpend reverses everything the cancelled spend c took: each
+// withdrawal's debit goes back to its sender, its VSC fee leaves the fee
+// supply, every bump fee goes back to whoever paid it, and the spend's
+// outflow, the bump fees included, returns to the contract's holdings.
+func (cs *ContractState) refundCancelledSpend(c *cancelledSpend) error {
+	txId := c.Versions[0].TxId
+	payouts := make([]SpendPayout, len(c.Payouts))
+	var refunded, vscFees int64
+	for i, p := range c.Payouts {
+		payouts[i] = SpendPayout{Id: p.Id}
+		r, err := loadWithdrawal(p.Id)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return ce.NewContractError(ce.ErrStateAccess, "no withdrawal "+strconv.FormatUint(p.Id, 10))
+		}
+		if err := incAccBalance(r.From, r.Debit); err != nil {
+			return ce.Prepend(err, "error refunding cancelled withdrawal")
+		}
+		if refunded, err = safeAdd64(refunded, r.Debit); err != nil {
+			return ce.WrapContractError(ce.ErrArithmetic, err, "error summing refunds")
+		}
+		vscFees += p.VscFee
+		sdk.Log(createCancelLog(p.Id, txId, r.From, r.Debit))
+	}
+	err := updateWithdrawals(payouts, func(r *withdrawalRecord, _ *SpendPayout) bool {
+		r.Status = WithdrawalCancelled
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, v := range c.Versions {
+		if err := cs.refundCharges(v.Charges, v.TxId); err != nil {
+			return err
+		}
+	}
+
+	// The latest version's change is short of the first's by every fee
+	// bumpFee added, so this also returns the bump fees.
+	if cs.Supply.ActiveSupply, err = safeAdd64(cs.Supply.ActiveSupply, c.InputTotal-c.Change+vscFees); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing active supply")
+	}
+	if cs.Supply.UserSupply, err = safeAdd64(cs.Supply.UserSupply, refunded); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing user supply")
+	}
+	if cs.Supply.FeeSupply, err = safeSubtract64(cs.Supply.FeeSupply, vscFees); err != nil {
+		return ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing fee supply")
+	}
+	return nil
+}
+
+// forgetCancelledSpendsOf clears the marks of every cancelled spend of
+// outPoint, which a confirmed transaction has spent or which no longer
+// exists, so that none of them can confirm any more.
+func forgetCancelledSpendsOf(outPoint wire.OutPoint) error {
+	for _, txId := range cancelledSpendsOf(outPoint) {
+		c, err := loadCancelledSpend(txId)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			continue
+		}
+		deleteCancelledSpend(c)
+	}
+	setCancelledSpendsOf(outPoint, nil)
+	return nil
+}
+
+// forgetConflictingSpends clears the marks of every cancelled spend that
+// spends an output tx, now confirmed, also spends.
+func forgetConflictingSpends(tx *wire.MsgTx) error {
+	for _, in := range tx.TxIn {
+		if err := forgetCancelledSpendsOf(in.PreviousOutPoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreInput returns the output outPoint, spent by an input of a cancelled
+// spend as recorded in h, to the pool: the confirmed pool, unless it is change
+// of a spend still pending. An output marked spent is not restored, and false
+// is returned.
+func (cs *ContractState) restoreInput(outPoint wire.OutPoint, h *UnsignedSigHash) (bool, error) {
+	spentKey := constants.SpentInputPrefix + outPoint.String()
+	if spent := sdk.StateGetObject(spentKey); spent != nil && *spent != "" {
+		sdk.StateDeleteObject(spentKey)
+		return false, nil
+	}
+
+	tag, err := witnessScriptTag(h.WitnessScript)
+	if err != nil {
+		return false, ce.WrapContractError(ce.ErrTransaction, err, "error reading input witness script")
+	}
+	pkScript, err := cs.spendPkScript(h.WitnessScript)
+	if err != nil {
+		return false, err
+	}
+	utxo := &Utxo{
+		TxId:     outPoint.Hash.String(),
+		Vout:     outPoint.Index,
+		Amount:   h.Amount,
+		PkScript: pkScript,
+		Tag:      tag,
+	}
+	parent, err := loadSigningData(utxo.TxId)
+	if err != nil {
+		return false, err
+	}
+	allocate := cs.allocateConfirmedId
+	if parent != nil {
+		allocate = cs.allocateUnconfirmedId
+	}
+	id, err := allocate()
+	if err != nil {
+		return false, err
+	}
+	cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: id, Amount: utxo.Amount})
+	saveUtxo(id, utxo)
+	return true, nil
+}
+
+// witnessScriptTag returns the tag a witness script built by
+// createP2WSHAddressWithBackup commits to, or nil for change.
+func witnessScriptTag(script []byte) ([]byte, error) {
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	for i := 0; tokenizer.Next(); i++ {
+		switch i {
+		case 0:
+			if tokenizer.Opcode() != txscript.OP_IF {
+				return nil, errors.New("witness script does not start with OP_IF")
+			}
+		case 2:
+			switch tokenizer.Opcode() {
+			case txscript.OP_CHECKSIG:
+				return nil, nil
+			case txscript.OP_CHECKSIGVERIFY:
+			default:
+				return nil, errors.New("witness script has no primary signature check")
+			}
+		case 3:
+			return tokenizer.Data(), nil
+		}
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("truncated witness script")
+}
+
+// reconcileCancelledSpend settles the confirmation at blockHeight of tx if it
+// is a version of a cancelled spend, and reports whether it was.
+func (cs *ContractState) reconcileCancelledSpend(tx *wire.MsgTx, blockHeight uint32) (bool, error) {
+	txId := tx.TxID()
+	c, err := loadCancelledSpend(txId)
+	if err != nil || c == nil {
+		return false, err
+	}
+
+	if err := cs.takeRestoredInputs(tx); err != nil {
+		return false, err
+	}
+	changeAddress, _, err := createP2WSHAddressWithBackup(
+		cs.PublicKeys.Primary,
+		cs.PublicKeys.Backup,
+		nil,
+		cs.Network,
+	)
+	if err != nil {
+		return false, ce.WrapContractError(ce.ErrTransaction, err, "error creating change address")
+	}
+	changeUtxos, err := indexUnconfimedOutputs(tx, changeAddress, cs.Network.Params)
+	if err != nil {
+		return false, err
+	}
+	var change int64
+	for _, utxo := range changeUtxos {
+		id, err := cs.allocateConfirmedId()
+		if err != nil {
+			return false, err
+		}
+		cs.UtxoList = append(cs.UtxoList, UtxoRegistryEntry{Id: id, Amount: utxo.Amount})
+		saveUtxo(id, utxo)
+		change += utxo.Amount
+	}
+	deleteCancelledSpend(c)
+
+	// The refunds are clawed back as far as the accounts still hold them.
+	payouts := make([]SpendPayout, len(c.Payouts))
+	var taken, deficit, vscFees int64
+	for i, p := range c.Payouts {
+		payouts[i] = SpendPayout{Id: p.Id}
+		vscFees += p.VscFee
+		r, err := loadWithdrawal(p.Id)
+		if err != nil {
+			return false, err
+		}
+		if r == nil {
+			continue
+		}
+		t := reclaimBalance(r.From, r.Debit)
+		taken += t
+		deficit += r.Debit - t
+		sdk.Log(createReclaimLog(p.Id, txId, r.From, t, r.Debit-t))
+	}
+	// So are the bump fees of this version and those before it; the later
+	// versions' were never paid.
+	confirmed := slices.IndexFunc(c.Versions, func(v cancelledVersion) bool { return v.TxId == txId })
+	var feesTaken int64
+	for _, v := range c.Versions[max(confirmed, 0):] {
+		for _, charge := range v.Charges {
+			var t int64
+			if charge.From == "" {
+				t = min(max(cs.Supply.FeeSupply, 0), charge.Amount)
+				feesTaken += t
+			} else {
+				t = reclaimBalance(charge.From, charge.Amount)
+				taken += t
+				sdk.Log(createChargeLog(v.TxId, charge.From, t))
+			}
+			deficit += charge.Amount - t
+		}
+	}
+	err = updateWithdrawals(payouts, func(r *withdrawalRecord, _ *SpendPayout) bool {
+		r.Status = WithdrawalConfirmed
+		r.TxId = txId
+		r.ConfirmedAt = blockHeight
+		return true
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := addMintDeficit(deficit); err != nil {
+		return false, err
+	}
+
+	if cs.Supply.ActiveSupply, err = safeSubtract64(cs.Supply.ActiveSupply, c.InputTotal-change+vscFees-deficit); err != nil {
+		return false, ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing active supply")
+	}
+	if cs.Supply.UserSupply, err = safeSubtract64(cs.Supply.UserSupply, taken); err != nil {
+		return false, ce.WrapContractError(ce.ErrArithmetic, err, "error decrementing user supply")
+	}
+	if cs.Supply.FeeSupply, err = safeAdd64(cs.Supply.FeeSupply, vscFees-feesTaken); err != nil {
+		return false, ce.WrapContractError(ce.ErrArithmetic, err, "error incrementing fee supply")
+	}
+	return true, nil
+}
+
+// reclaimBalance takes up to amount back from the balance of account and
+// returns how much it took.
+func reclaimBalance(account string, amount int64) int64 {
+	bal := getAccBal(account)
+	taken := min(bal, amount)
+	setAccBal(account, bal-taken)
+	return taken
+}
+
+// takeRestoredInputs drops the outputs spent by tx, a confirmed version of a
+// cancelled spend, from the pool they were restored to. Any a pending spend
+// has spent again is marked spent instead.
+func (cs *ContractState) takeRestoredInputs(tx *wire.MsgTx) error {
+	spent := make(map[wire.OutPoint]bool, len(tx.TxIn))
+	for _, in := range tx.TxIn {
+		spent[in.PreviousOutPoint] = true
+	}
+	var ids []uint16
+	for _, entry := range cs.UtxoList {
+		utxo, err := loadUtxo(entry.Id)
+		if err != nil {
+			return err
+		}
+		hash, err := chainhash.NewHashFromStr(utxo.TxId)
+		if err != nil {
+			return ce.WrapContractError(ce.ErrStateAccess, err, "invalid utxo txid")
+		}
+		outPoint := wire.OutPoint{Hash: *hash, Index: utxo.Vout}
+		if spent[outPoint] {
+			delete(spent, outPoint)
+			ids = append(ids, entry.Id)
+			sdk.StateDeleteObject(getUtxoKey(entry.Id))
+		}
+	}
+	cs.UtxoList = slices.DeleteFunc(cs.UtxoList, func(entry UtxoRegistryEntry) bool {
+		return slices.Contains(ids, entry.Id)
+	})
+	for _, in := range tx.TxIn {
+		if spent[in.PreviousOutPoint] {
+			sdk.StateSetObject(constants.SpentInputPrefix+in.PreviousOutPoint.String(), "1")
+		}
+	}
+	return nil
+}
+
+// createCancelLog records the refund of a cancelled withdrawal: its id, the
+// spend cancelled, the account refunded and the amount.
+func createCancelLog(id uint64, txId, from string, amount int64) string {
+	var b strings.Builder
+	b.Grow(200)
+	b.WriteString("cancel")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("w")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendUint(buf[:0], id, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("id")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(txId)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("f")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(from)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("d")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.Write(strconv.AppendInt(buf[:0], amount, 10))
+	return b.String()
+}
+
+// createReclaimLog records the clawback of a cancelled withdrawal's refund
+// once its spend confirmed after all: its id, the spend, the account, the
+// amount taken back and the shortfall left as deficit.
+func createReclaimLog(id uint64, txId, from string, taken, deficit int64) string {
+	var b strings.Builder
+	b.Grow(200)
+	b.WriteString("reclaim")
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("w")
+	b.WriteString(constants.LogKeyDelimiter)
+	var buf [20]byte
+	b.Write(strconv.AppendUint(buf[:0], id, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("id")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(txId)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("f")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.WriteString(from)
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("a")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.Write(strconv.AppendInt(buf[:0], taken, 10))
+	b.WriteString(constants.LogDelimiter)
+	b.WriteString("d")
+	b.WriteString(constants.LogKeyDelimiter)
+	b.Write(strconv.AppendInt(buf[:0], deficit, 10))
+	return b.String()
+}
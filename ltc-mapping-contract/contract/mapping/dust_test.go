@@ -2,6 +2,8 @@ package mapping
 
 import (
 	"encoding/hex"
+	"ltc-mapping-contract/contract/constants"
+	"ltc-mapping-contract/contract/network"
 	"testing"
 
 	"github.com/btcsuite/btcd/btcutil"
@@ -35,18 +37,23 @@ func mustDecodePub(t *testing.T, h string) CompressedPubKey {
 	return k
 }
 
+// regtestNetwork returns the regtest network the unit tests run against.
+func regtestNetwork() *network.Network {
+	net, _ := network.Lookup(constants.Regtest)
+	return net
+}
+
 func newTestState(t *testing.T, baseFeeRate int64) *ContractState {
 	t.Helper()
 	primaryHex := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
 	backupHex := "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
-	params := &chaincfg.RegressionNetParams
 	return &ContractState{
 		PublicKeys: PublicKeys{
 			Primary: mustDecodePub(t, primaryHex),
 			Backup:  mustDecodePub(t, backupHex),
 		},
-		NetworkParams: params,
-		Supply:        SystemSupply{BaseFeeRate: baseFeeRate},
+		Network: regtestNetwork(),
+		Supply:  SystemSupply{BaseFeeRate: baseFeeRate},
 	}
 }
 
@@ -90,7 +97,7 @@ func TestBTCC5_DustAbsorbedFeeReportsActualOutflow(t *testing.T) {
 	cs := newTestState(t, 1) // 1 sat/vbyte for predictable fees
 
 	// Pick a sendAmount and inputs that leave a tiny residual after the
-	// size-based fee — small enough that availableChange ≤ dustThreshold.
+	// size-based fee — small enough that availableChange is dust.
 	// The exact crafting depends on the segwit fee curve; we iterate from
 	// a generous input down until the function reports a fee with NO
 	// change output, then assert the invariant.
@@ -102,7 +109,7 @@ func TestBTCC5_DustAbsorbedFeeReportsActualOutflow(t *testing.T) {
 			hex.EncodeToString(cs.PublicKeys.Primary[:]),
 			hex.EncodeToString(cs.PublicKeys.Backup[:]),
 			nil,
-			cs.NetworkParams,
+			cs.Network,
 		)
 		if err != nil {
 			t.Fatalf("derive change address: %v", err)
@@ -157,7 +164,7 @@ func TestBTCC5_NormalChangeStillBalances(t *testing.T) {
 		hex.EncodeToString(cs.PublicKeys.Primary[:]),
 		hex.EncodeToString(cs.PublicKeys.Backup[:]),
 		nil,
-		cs.NetworkParams,
+		cs.Network,
 	)
 	if err != nil {
 		t.Fatalf("derive change address: %v", err)
@@ -3,55 +3,15 @@ package mapping
 import (
 	"ltc-mapping-contract/contract/constants"
 	ce "ltc-mapping-contract/contract/contracterrors"
+	"ltc-mapping-contract/contract/network"
 	"ltc-mapping-contract/sdk"
 	"crypto/sha256"
 	"encoding/binary"
 	"net/url"
 	"strings"
-
-	"github.com/btcsuite/btcd/chaincfg"
 )
 
-// LTC-specific network params for address validation. LTC uses different
-// address version bytes and Bech32 HRPs than Bitcoin. Bech32HRPSegwit is
-// set for LTC's native bech32 addresses (ltc/tltc).
-func ltcTestNetParams() *chaincfg.Params {
-	p := chaincfg.TestNet3Params
-	p.PubKeyHashAddrID = 0x6f
-	p.ScriptHashAddrID = 0xc4
-	p.Bech32HRPSegwit = "tltc"
-	return &p
-}
-
-func ltcMainNetParams() *chaincfg.Params {
-	p := chaincfg.MainNetParams
-	p.PubKeyHashAddrID = 0x30
-	p.ScriptHashAddrID = 0x32
-	p.Bech32HRPSegwit = "ltc"
-	return &p
-}
-
-func ltcRegTestParams() *chaincfg.Params {
-	// LTC regtest reuses LTC testnet base58 prefixes. Bech32HRPSegwit is
-	// left at the inherited "bcrt" since the regtest test harness uses
-	// bcrt1... destination addresses derived from btcsuite defaults.
-	p := chaincfg.RegressionNetParams
-	p.PubKeyHashAddrID = 0x6f
-	p.ScriptHashAddrID = 0xc4
-	return &p
-}
-
-func IntializeContractState(publicKeys PublicKeys, networkMode string) (*ContractState, error) {
-	var networkParams *chaincfg.Params
-	switch networkMode {
-	case constants.Testnet:
-		networkParams = ltcTestNetParams()
-	case constants.Regtest:
-		networkParams = ltcRegTestParams()
-	default:
-		networkParams = ltcMainNetParams()
-	}
-
+func IntializeContractState(publicKeys PublicKeys, net *network.Network) (*ContractState, error) {
 	// Load UTXO registry (binary: 9 bytes/entry)
 	var utxos UtxoRegistry
 	utxoState := sdk.StateGetObject(constants.UtxoRegistryKey)
@@ -102,16 +62,16 @@ func IntializeContractState(publicKeys PublicKeys, networkMode string) (*Contrac
 		TxSpendsList:      txSpends,
 		Supply:            supply,
 		PublicKeys:        publicKeys,
-		NetworkParams:     networkParams,
+		Network:           net,
 	}, nil
 }
 
 func InitializeMappingState(
 	publicKeys PublicKeys,
-	networkMode string,
+	net *network.Network,
 	instructions ...string,
 ) (*MappingState, error) {
-	contractState, err := IntializeContractState(publicKeys, networkMode)
+	contractState, err := IntializeContractState(publicKeys, net)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +79,7 @@ func InitializeMappingState(
 	var registry map[string]*AddressMetadata
 	if len(instructions) > 0 {
 		var err error
-		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.NetworkParams)
+		registry, err = contractState.parseInstructions(publicKeys, instructions, contractState.Network)
 		if err != nil {
 			return nil, ce.WrapContractError(ce.ErrStateAccess, err, "error unmarshalling address registry")
 		}
@@ -134,7 +94,7 @@ func InitializeMappingState(
 func (cs *ContractState) parseInstructions(
 	publicKeys PublicKeys,
 	instrs []string,
-	networkParams *chaincfg.Params,
+	net *network.Network,
 ) (map[string]*AddressMetadata, error) {
 	parsedInstructions := make([]url.Values, len(instrs))
 	registry := make(map[string]*AddressMetadata, len(instrs))
@@ -182,7 +142,7 @@ func (cs *ContractState) parseInstructions(
 				publicKeys.Primary,
 				publicKeys.Backup,
 				hashBytes,
-				networkParams,
+				net,
 			)
 			if err != nil {
 				return nil, err
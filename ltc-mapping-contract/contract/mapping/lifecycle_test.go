@@ -0,0 +1,128 @@
+package mapping
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestWithdrawalRecordRoundTrip(t *testing.T) {
+	records := []withdrawalRecord{
+		{
+			Status:      WithdrawalRequested,
+			Debit:       25050,
+			RequestedAt: 90000000,
+			UpdatedAt:   90000000,
+			From:        "hive:milo-hpr",
+			To:          "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq",
+		},
+		{
+			Status:      WithdrawalConfirmed,
+			Debit:       1000050,
+			Sent:        999700,
+			RequestedAt: 90000000,
+			UpdatedAt:   90001200,
+			ConfirmedAt: 850000,
+			From:        "hive:vaultec",
+			To:          "1BoatSLRHtKNngkdXEeobR76b53LETtpyT",
+			TxId:        "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+	}
+	for _, r := range records {
+		data, err := marshalWithdrawalRecord(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := unmarshalWithdrawalRecord(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *got != r {
+			t.Fatalf("got %+v, want %+v", *got, r)
+		}
+		if _, err := unmarshalWithdrawalRecord(data[:len(data)-1]); err == nil {
+			t.Error("expected a truncated record to fail")
+		}
+		if _, err := unmarshalWithdrawalRecord(append(data, 0)); err == nil {
+			t.Error("expected trailing bytes to fail")
+		}
+	}
+}
+
+func TestWithdrawalStatusNames(t *testing.T) {
+	for s := WithdrawalRequested; s <= WithdrawalCancelled; s++ {
+		if s.String() == "unknown" {
+			t.Errorf("status %d has no name", s)
+		}
+	}
+	if WithdrawalStatus(0).String() != "unknown" || (WithdrawalCancelled+1).String() != "unknown" {
+		t.Error("expected statuses out of range to be unknown")
+	}
+}
+
+func TestVerifySpendSignatures(t *testing.T) {
+	primary, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x11}, 32))
+	backup, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x22}, 32))
+	cs := &ContractState{Network: regtestNetwork()}
+	copy(cs.PublicKeys.Primary[:], primary.PubKey().SerializeCompressed())
+	copy(cs.PublicKeys.Backup[:], backup.PubKey().SerializeCompressed())
+
+	// One input from a tagged deposit address and one from change.
+	tags := [][]byte{bytes.Repeat([]byte{0xab}, 32), nil}
+	amounts := []int64{150000, 80000}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	sd := &SigningData{}
+	prevOuts := make(map[wire.OutPoint]*wire.TxOut)
+	for i, tag := range tags {
+		_, witnessScript, err := createP2WSHAddressWithBackup(cs.PublicKeys.Primary, cs.PublicKeys.Backup, tag, cs.Network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkScript, err := cs.spendPkScript(witnessScript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outPoint := wire.OutPoint{Hash: chainhash.Hash{byte(i + 1)}, Index: uint32(i)}
+		tx.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+		prevOuts[outPoint] = wire.NewTxOut(amounts[i], pkScript)
+		sd.UnsignedSigHashes = append(sd.UnsignedSigHashes, UnsignedSigHash{
+			Index: uint32(i), WitnessScript: witnessScript, Amount: amounts[i],
+		})
+	}
+	destScript, err := cs.destinationScript(regtestDestAddr(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.AddTxOut(wire.NewTxOut(229000, destScript))
+
+	sigHashes := txscript.NewTxSigHashes(tx, txscript.NewMultiPrevOutFetcher(prevOuts))
+	for i, h := range sd.UnsignedSigHashes {
+		sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, i, h.Amount, h.WitnessScript, txscript.SigHashAll, primary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.TxIn[i].Witness = wire.TxWitness{sig, {0x01}, h.WitnessScript}
+	}
+	if err := cs.verifySpendSignatures(tx, sd); err != nil {
+		t.Fatal(err)
+	}
+
+	// A signature by any other key is rejected.
+	sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, 1, amounts[1], sd.UnsignedSigHashes[1].WitnessScript, txscript.SigHashAll, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.TxIn[1].Witness[0] = sig
+	if err := cs.verifySpendSignatures(tx, sd); err == nil {
+		t.Fatal("expected a signature by the wrong key to be rejected")
+	}
+
+	tx.TxIn[1].Witness = nil
+	if err := cs.verifySpendSignatures(tx, sd); err == nil {
+		t.Fatal("expected an unsigned input to be rejected")
+	}
+}
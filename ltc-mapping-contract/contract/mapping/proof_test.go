@@ -0,0 +1,114 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestCheckConfirmations(t *testing.T) {
+	tests := []struct {
+		name        string
+		blockHeight uint32
+		lastHeight  uint32
+		min         uint32
+		wantErr     bool
+	}{
+		{"tip with default depth", 100, 100, 1, false},
+		{"tip below required depth", 100, 100, 6, true},
+		{"one short of required depth", 95, 100, 7, true},
+		{"exactly required depth", 95, 100, 6, false},
+		{"deeper than required", 50, 100, 6, false},
+		{"above the tip", 101, 100, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConfirmations(tt.blockHeight, tt.lastHeight, tt.min)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCoinbaseProof(t *testing.T) {
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), []byte{0x01, 0x64}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+	var buf bytes.Buffer
+	if err := coinbase.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	coinbaseHex := hex.EncodeToString(buf.Bytes())
+
+	// Four leaves: the coinbase and three transactions, two levels deep.
+	leaves := []chainhash.Hash{coinbase.TxHash(), {1}, {2}, {3}}
+	pair := func(a, b chainhash.Hash) chainhash.Hash {
+		return chainhash.DoubleHashH(append(a[:], b[:]...))
+	}
+	left, right := pair(leaves[0], leaves[1]), pair(leaves[2], leaves[3])
+	root := pair(left, right)
+	coinbaseProof := hex.EncodeToString(append(leaves[1][:], right[:]...))
+
+	if !verifyMerkleProof(leaves[2], 2, []chainhash.Hash{leaves[3], left}, root) {
+		t.Fatal("fixture tree does not verify")
+	}
+	// The inner node "right" hashes up to the root one level short, which is
+	// exactly what a forged 64-byte transaction would exploit.
+	if !verifyMerkleProof(right, 1, []chainhash.Hash{left}, root) {
+		t.Fatal("inner node does not verify")
+	}
+
+	tests := []struct {
+		name     string
+		coinbase string
+		proofHex string
+		txIndex  uint32
+		depth    int
+		wantErr  bool
+	}{
+		{"matching depth", coinbaseHex, coinbaseProof, 2, 2, false},
+		{"inner node one level short", coinbaseHex, coinbaseProof, 1, 1, true},
+		{"index beyond depth", coinbaseHex, coinbaseProof, 4, 2, true},
+		{"bad coinbase proof", coinbaseHex, coinbaseProof[:64], 2, 2, true},
+		{"not a coinbase", strings.Replace(coinbaseHex, "ffffffff", "00000000", 1), coinbaseProof, 2, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCoinbaseProof(tt.coinbase, tt.proofHex, tt.txIndex, tt.depth, root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Beyond a single-transaction block, the coinbase proof is mandatory.
+	deposit := wire.NewMsgTx(wire.TxVersion)
+	deposit.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	depthTests := []struct {
+		name    string
+		req     VerificationRequest
+		tx      *wire.MsgTx
+		depth   int
+		wantErr bool
+	}{
+		{"single transaction block", VerificationRequest{}, deposit, 0, false},
+		{"coinbase proves itself", VerificationRequest{}, coinbase, 2, false},
+		{"missing coinbase", VerificationRequest{TxIndex: 2}, deposit, 2, true},
+		{"missing coinbase proof", VerificationRequest{TxIndex: 2, CoinbaseTxHex: coinbaseHex}, deposit, 2, true},
+		{"with coinbase", VerificationRequest{TxIndex: 2, CoinbaseTxHex: coinbaseHex, CoinbaseProofHex: coinbaseProof}, deposit, 2, false},
+		{"inner node with coinbase", VerificationRequest{TxIndex: 1, CoinbaseTxHex: coinbaseHex, CoinbaseProofHex: coinbaseProof}, deposit, 1, true},
+	}
+	for _, tt := range depthTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMerkleDepth(&tt.req, tt.tx, tt.depth, root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package current_test
 
 import (
-	btcMapping "ltc-mapping-contract"
 	"ltc-mapping-contract/contract/constants"
+	"encoding/hex"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -18,12 +19,19 @@ import (
 const testContractId = "mapping_contract"
 const testOwner = "hive:milo-hpr"
 
-// Valid Bitcoin testnet4 block headers (116087 → 116088 → 116089).
+// Real Litecoin mainnet block headers 1 → 2 → 3 (scrypt PoW valid). The
+// regtest build does not retarget, so they are stored at an arbitrary height.
 const lastBlockHeight = "116087"
 
-const lastBlockHeader = "00c0a520165303733ee5b0561d46da9dcce685fd12a807d64472931c46d5920c00000000c96f929654fc44fb69783b6cc4f2340ad85de5b10c5047836561901299ed23d162525469ffff001dda00dd53"
+const (
+	ltcMainnetBlock1 = "01000000e2bf047e7e5a191aa4ef34d314979dc9986e0f19251edaba5940fd1fe365a712f6509b1757baa71bc746e17cb4d0ed22e8935f71e2d0724336789021a40639fabfed8f4ef0ff0f1e7f270400"
+	ltcMainnetBlock2 = "010000008fc749ba1129b477844abee559079e4ed02cf9eab69e763de5020bd15e09ca80f27b45c33766594918ec67bd3a096dcc3a63fc015ce79821794e9fffa15743c5aafc944ef0ff0f1ed75e0000"
+	ltcMainnetBlock3 = "01000000d7409ff04a253b6e424c6c509af96387310e5159fa0939992fd0d1cd077895137c8ef659c87e58ebbc2f0cf755bdd1433f5c7f0548db099ab08d3ae521bbc3531d54964ef0ff0f1ee7130000"
+)
+
+const lastBlockHeader = ltcMainnetBlock1
 
-const twoBlocksPayload = `{"blocks":"00c0fa213b04801d1b66efcf8f41290a675777893f5c6ac158a585654263ba0900000000fdf6162d92eee3af012f1ddab30a401bb371a0da32371d185fc25eb3655fd6d013575469ffff001db80220f80000002002883f9d7847a35a0d371cd11bf95c0f9d252ed41f46dde04172bf0c000000003d2af3ae86b3638665e6214df4dc12712fd7486348c3c319cedb3c69bc8a4ddac45b5469ffff001d1adfdc74","latest_fee":1}`
+const twoBlocksPayload = `{"blocks":"` + ltcMainnetBlock2 + ltcMainnetBlock3 + `","latest_fee":1}`
 
 type ctWrapper struct {
 	ct *test_utils.ContractTest
@@ -273,55 +281,97 @@ func TestAllOperations(t *testing.T) {
 	// ========== AddBlocks round-trip (block sequence bug) ==========
 	// Reproduces the testnet bug: after addBlocks stores block headers as raw
 	// bytes, the next addBlocks must read them back and verify chain continuity.
-	// Uses real BTC testnet3 block headers at heights 4888515 → 4888516 → 4888517.
+	// Uses real Litecoin mainnet block headers at heights 1 → 2 → 3.
 
 	t.Run("AddBlocks_RoundTrip_ChainContinuity", func(t *testing.T) {
 		rtId := "roundtrip_blocklist"
-		// Use testnet3 WASM since we're testing with real testnet3 block headers
-		w.ct.RegisterContract(rtId, testOwner, btcMapping.TestnetWasm)
-
-		// Real BTC testnet3 block headers fetched from btcd RPC.
-		// hash(4888515) = 00000000f570586692e115a950b812f359b27ac1441c91b91223163843504515
-		// hash(4888516) = 000000000923f265408b69969242b68fbec135b24761ac2f51cd60b53855dc1c
-		// hash(4888517) = 000000002e42abbf29b8d01a647c0b42521966edb88e87e11c80720de1135a39
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
-
-		// Seed with block 4888515 stored as raw bytes (matching what the
-		// contract itself does in HandleAddBlocks line 124-126).
-		seedRaw := decodeHex(t, block4888515Hex)
-		w.ct.StateSet(rtId, constants.LastHeightKey, "4888515")
-		w.ct.StateSet(rtId, constants.BlockPrefix+"4888515", seedRaw)
+		w.ct.RegisterContract(rtId, testOwner, ContractWasm)
+
+		// hash(1) = 80ca095ed10b02e53d769eb6eaf92cd04e9e0759e5be4a8477b42911ba49c78f
+		// hash(2) = 13957807cdd1d02f993909fa59510e318763f99a506c4c426e3b254af09f40d7
+		// hash(3) = dec173dda2735ff11376b68bdfda804cede230c1fa6f1a11765cddfd8edf4398
+		block1Hex := ltcMainnetBlock1
+		block2Hex := ltcMainnetBlock2
+		block3Hex := ltcMainnetBlock3
+
+		// Seed with block 1 stored as raw bytes (matching what the
+		// contract itself does in HandleAddBlocks).
+		seedRaw := decodeHex(t, block1Hex)
+		w.ct.StateSet(rtId, constants.LastHeightKey, "1")
+		w.ct.StateSet(rtId, constants.BlockPrefix+"1", seedRaw)
 		// Supply: 4x int64 BE = 32 zero bytes for all-zero supply with base_fee=1
 		supply := make([]byte, 32)
 		supply[31] = 1 // base_fee_rate = 1
 		w.ct.StateSet(rtId, constants.SupplyKey, string(supply))
 
 		// Debug: verify the stored seed is readable
-		stored := w.ct.StateGet(rtId, constants.BlockPrefix+"4888515")
+		stored := w.ct.StateGet(rtId, constants.BlockPrefix+"1")
 		t.Logf("Stored seed length: %d, expected: 80", len(stored))
 		t.Logf("Stored seed hex: %x", []byte(stored)[:min(20, len(stored))])
 		t.Logf("Stored height: %s", w.ct.StateGet(rtId, constants.LastHeightKey))
 		t.Logf("Stored supply length: %d", len(w.ct.StateGet(rtId, constants.SupplyKey)))
 
-		// First addBlocks: submit block 4888516.
+		// First addBlocks: submit block 2.
 		// Use oracle DID as caller (always allowed) to bypass auth issues in test
 		oracleCaller := "did:vsc:oracle:ltc"
-		payload1 := `{"blocks":"` + block4888516Hex + `","latest_fee":0}`
+		payload1 := `{"blocks":"` + block2Hex + `","latest_fee":0}`
 		r1 := callActionOnContract(t, w, rtId, "addBlocks", payload1, oracleCaller)
 		t.Logf("r1: success=%v err=%q errMsg=%q ret=%q", r1.Success, r1.Err, r1.ErrMsg, r1.Ret)
-		require.True(t, r1.Success, "first addBlocks (4888516) should succeed: %s %s", r1.Err, r1.ErrMsg)
-		assert.Contains(t, r1.Ret, "4888516")
+		require.True(t, r1.Success, "first addBlocks (2) should succeed: %s %s", r1.Err, r1.ErrMsg)
+		assert.Contains(t, r1.Ret, "last height: 2")
 
-		// Second addBlocks: submit block 4888517.
+		// Second addBlocks: submit block 3.
 		// This reads back the raw bytes stored by the first call.
 		// If the raw byte round-trip corrupts the header, BlockHash()
 		// will differ and we get "block sequence incorrect".
-		payload2 := `{"blocks":"` + block4888517Hex + `","latest_fee":0}`
+		payload2 := `{"blocks":"` + block3Hex + `","latest_fee":0}`
 		r2 := callActionOnContract(t, w, rtId, "addBlocks", payload2, oracleCaller)
-		require.True(t, r2.Success, "second addBlocks (4888517) should succeed: %s %s", r2.Err, r2.ErrMsg)
-		assert.Contains(t, r2.Ret, "4888517")
+		require.True(t, r2.Success, "second addBlocks (3) should succeed: %s %s", r2.Err, r2.ErrMsg)
+		assert.Contains(t, r2.Ret, "last height: 3")
+	})
+
+	t.Run("AddBlocks_BadScryptPoWFails", func(t *testing.T) {
+		badId := "bad_pow_blocklist"
+		w.ct.RegisterContract(badId, testOwner, ContractWasm)
+		w.ct.StateSet(badId, constants.LastHeightKey, "1")
+		w.ct.StateSet(badId, constants.BlockPrefix+"1", decodeHex(t, ltcMainnetBlock1))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(badId, constants.SupplyKey, string(supply))
+
+		// Block 2 with its nonce bumped still links to block 1 but no longer
+		// meets its scrypt target.
+		tampered := []byte(decodeHex(t, ltcMainnetBlock2))
+		tampered[76]++
+		payload := `{"blocks":"` + hex.EncodeToString(tampered) + `","latest_fee":1}`
+		r := callActionOnContract(t, w, badId, "addBlocks", payload, "did:vsc:oracle:ltc")
+		assert.False(t, r.Success, "addBlocks with invalid scrypt PoW should fail")
+	})
+
+	t.Run("AddBlocks_CompetingBranchNeedsMoreWork", func(t *testing.T) {
+		fcId := "forkchoice_blocklist"
+		w.ct.RegisterContract(fcId, testOwner, ContractWasm)
+
+		w.ct.StateSet(fcId, constants.LastHeightKey, "1")
+		w.ct.StateSet(fcId, constants.BlockPrefix+"1", decodeHex(t, ltcMainnetBlock1))
+		supply := make([]byte, 32)
+		supply[31] = 1
+		w.ct.StateSet(fcId, constants.SupplyKey, string(supply))
+
+		oracleCaller := "did:vsc:oracle:ltc"
+		payload := `{"blocks":"` + ltcMainnetBlock2 + ltcMainnetBlock3 + `","latest_fee":1}`
+		r := callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
+		assert.NotEmpty(t, w.ct.StateGet(fcId, constants.ChainWorkPrefix+"3"))
+
+		// Resubmitting the tip adds nothing: it is already on the stored chain
+		// and must not be taken as a branch of its own.
+		payload = `{"blocks":"` + ltcMainnetBlock3 + `","latest_fee":1}`
+		r = callActionOnContract(t, w, fcId, "addBlocks", payload, oracleCaller)
+		assert.False(t, r.Success, "resubmitted tip should be rejected")
+		assert.Contains(t, r.ErrMsg, "already stored")
+		assert.Equal(t, "3", w.ct.StateGet(fcId, constants.LastHeightKey))
+		assert.Empty(t, w.ct.StateGet(fcId, constants.StagedBranchKey))
 	})
 
 	// ========== ReplaceBlocks (multi-block reorg) ==========
@@ -342,61 +392,54 @@ func TestAllOperations(t *testing.T) {
 	})
 
 	t.Run("ReplaceBlocks_MultiBlock_Success", func(t *testing.T) {
-		// Set up a 3-block chain: 4888515 → 4888516 → 4888517
+		// Set up a 3-block chain: 1 → 2 → 3
 		rbId := "replaceblocks_test"
-		w.ct.RegisterContract(rbId, testOwner, btcMapping.TestnetWasm)
+		w.ct.RegisterContract(rbId, testOwner, ContractWasm)
 
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
-		block4888517Hex := "000000201cdc5538b560cd512fac6147b235c1be8fb6429296698b4065f22309000000000061c5ef9468eb91177b04aabf349991b9000774ac4f87f747f07935d4ad9e3ffbbdbc69ffff001d89201fc7"
-
-		// Seed with block 4888515
-		seedRaw := decodeHex(t, block4888515Hex)
-		w.ct.StateSet(rbId, constants.LastHeightKey, "4888515")
-		w.ct.StateSet(rbId, constants.BlockPrefix+"4888515", seedRaw)
+		// Seed with block 1
+		seedRaw := decodeHex(t, ltcMainnetBlock1)
+		w.ct.StateSet(rbId, constants.LastHeightKey, "1")
+		w.ct.StateSet(rbId, constants.BlockPrefix+"1", seedRaw)
 		supply := make([]byte, 32)
 		supply[31] = 1
 		w.ct.StateSet(rbId, constants.SupplyKey, string(supply))
 
-		// Add blocks 4888516 and 4888517
+		// Add blocks 2 and 3
 		oracleCaller := "did:vsc:oracle:ltc"
-		payload := `{"blocks":"` + block4888516Hex + block4888517Hex + `","latest_fee":1}`
+		payload := `{"blocks":"` + ltcMainnetBlock2 + ltcMainnetBlock3 + `","latest_fee":1}`
 		r := callActionOnContract(t, w, rbId, "addBlocks", payload, oracleCaller)
 		require.True(t, r.Success, "addBlocks should succeed: %s %s", r.Err, r.ErrMsg)
-		assert.Contains(t, r.Ret, "4888517")
+		assert.Contains(t, r.Ret, "last height: 3")
 
-		// Now replace both blocks 4888516 and 4888517 with themselves (same canonical headers).
+		// Now replace both blocks 2 and 3 with themselves (same canonical headers).
 		// This simulates a 2-block reorg where the canonical chain happens to match.
 		// The key test is that the chaining validation passes for multi-block replacement.
-		replacePayload := block4888516Hex + block4888517Hex
+		replacePayload := ltcMainnetBlock2 + ltcMainnetBlock3
 		r2 := callActionOnContract(t, w, rbId, "replaceBlocks", replacePayload, "")
 		require.True(t, r2.Success, "replaceBlocks (2 blocks) should succeed: %s %s", r2.Err, r2.ErrMsg)
 		assert.Contains(t, r2.Ret, "replaced 2 blocks")
-		assert.Contains(t, r2.Ret, "4888517")
+		assert.Contains(t, r2.Ret, "tip at height: 3")
 	})
 
 	t.Run("ReplaceBlocks_SingleBlock_DelegatesToReplaceBlock", func(t *testing.T) {
 		// Single-header replaceBlocks should delegate to HandleReplaceBlock
 		rbId2 := "replaceblocks_single"
-		w.ct.RegisterContract(rbId2, testOwner, btcMapping.TestnetWasm)
-
-		block4888515Hex := "000000209bfa65ae0af2ba13fd4403312a44554123c4b972374fd1995adce62c0000000081af5bae21b11430df381ee109e51181f5ff4164f744f0747ce980cf43c6c73797b4bc69ffff001d28ffa61f"
-		block4888516Hex := "000000201545504338162312b9911c44c17ab259f312b850a915e192665870f500000000d2684baebc7ac8401ac29754247d1091bd3c6604bb4b26aeff107b6cfde0787648b9bc69ffff001d176c1809"
+		w.ct.RegisterContract(rbId2, testOwner, ContractWasm)
 
-		seedRaw := decodeHex(t, block4888515Hex)
-		raw516 := decodeHex(t, block4888516Hex)
-		w.ct.StateSet(rbId2, constants.LastHeightKey, "4888516")
-		w.ct.StateSet(rbId2, constants.BlockPrefix+"4888515", seedRaw)
-		w.ct.StateSet(rbId2, constants.BlockPrefix+"4888516", raw516)
+		seedRaw := decodeHex(t, ltcMainnetBlock1)
+		raw2 := decodeHex(t, ltcMainnetBlock2)
+		w.ct.StateSet(rbId2, constants.LastHeightKey, "2")
+		w.ct.StateSet(rbId2, constants.BlockPrefix+"1", seedRaw)
+		w.ct.StateSet(rbId2, constants.BlockPrefix+"2", raw2)
 		supply := make([]byte, 32)
 		supply[31] = 1
 		w.ct.StateSet(rbId2, constants.SupplyKey, string(supply))
 
 		// Replace just the tip (single block)
-		replacePayload := block4888516Hex
+		replacePayload := ltcMainnetBlock2
 		r := callActionOnContract(t, w, rbId2, "replaceBlocks", replacePayload, "")
 		require.True(t, r.Success, "single-block replaceBlocks should succeed: %s %s", r.Err, r.ErrMsg)
-		assert.Contains(t, r.Ret, "4888516")
+		assert.Contains(t, r.Ret, "height: 2")
 	})
 
 	// ========== Unmap ==========
@@ -440,3 +483,141 @@ func TestAllOperations(t *testing.T) {
 		assert.False(t, r.Success, "transferFrom with insufficient balance should fail")
 	})
 }
+
+func TestSeedBlocksHeaderRun(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+
+	// Dropping the middle header breaks the run's linkage.
+	broken := ltcMainnetBlock1 + ltcMainnetBlock3
+	r := callAction(t, w, "seedBlocks", `{"block_headers":"`+broken+`","block_height":`+lastBlockHeight+`}`, "")
+	assert.False(t, r.Success, "seedBlocks with unlinked headers should fail")
+
+	run := ltcMainnetBlock1 + ltcMainnetBlock2 + ltcMainnetBlock3
+	r = callAction(t, w, "seedBlocks", `{"block_headers":"`+run+`","block_height":`+lastBlockHeight+`}`, "")
+	require.True(t, r.Success, "seedBlocks with a header run should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.SeedHeightKey))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.BlockPrefix+"116088"))
+}
+
+func TestAddBlocksFutureDrift(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	// callAt calls as the owner in a Hive block with the given timestamp.
+	callAt := func(action, payload, timestamp string) test_utils.ContractTestCallResult {
+		self := basicSelf(t, testOwner)
+		self.Timestamp = timestamp
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self:       *self,
+			ContractId: testContractId,
+			Action:     action,
+			Payload:    json.RawMessage([]byte(payload)),
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     testOwner,
+		})
+	}
+
+	r := callAt("setMaxFutureDrift", "7200", "2011-10-12T00:00:00")
+	require.True(t, r.Success, "setMaxFutureDrift failed: %s %s", r.Err, r.ErrMsg)
+
+	// The headers were mined at 2011-10-12 02:34 and 2011-10-13 02:59 UTC.
+	r = callAt("addBlocks", twoBlocksPayload, "2011-10-12T02:00:00")
+	assert.False(t, r.Success, "addBlocks with headers more than 2 hours ahead should fail")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = callAt("addBlocks", twoBlocksPayload, "2011-10-13T02:00:00")
+	require.True(t, r.Success, "addBlocks within the drift bound should succeed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+}
+
+func TestAddBlocksOracleQuorum(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+	submit := func(blocks string, fee int, oracle string) test_utils.ContractTestCallResult {
+		payload := `{"blocks":"` + blocks + `","latest_fee":` + strconv.Itoa(fee) + `}`
+		return callAction(t, w, "addBlocks", payload, oracle)
+	}
+
+	r := callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "hive:oracle1")
+	assert.False(t, r.Success, "setOracles by a non-owner should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2"],"threshold":3}`, "")
+	assert.False(t, r.Success, "setOracles with a threshold above the set size should fail")
+	r = callAction(t, w, "setOracles", `{"oracles":["hive:oracle1","hive:oracle2","hive:oracle3"],"threshold":2}`, "")
+	require.True(t, r.Success, "setOracles failed: %s %s", r.Err, r.ErrMsg)
+
+	r = submit(added.Blocks, 1, "did:vsc:oracle:ltc")
+	assert.False(t, r.Success, "the single oracle address is not a member of the set")
+
+	r = submit(added.Blocks, 10, "hive:oracle1")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	// A different batch does not count towards the first.
+	r = submit(added.Blocks[:160], 10, "hive:oracle2")
+	require.True(t, r.Success, "staging failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "staged: 1 of 2")
+	assert.Equal(t, lastBlockHeight, ct.StateGet(testContractId, constants.LastHeightKey))
+
+	r = submit(added.Blocks, 30, "hive:oracle3")
+	require.True(t, r.Success, "quorum commit failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "last height: 116089")
+	assert.Contains(t, r.Ret, "base fee: 20")
+	assert.Equal(t, "116089", ct.StateGet(testContractId, constants.LastHeightKey))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle1"))
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle3"))
+	assert.NotEmpty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+
+	// The single oracle address cannot change the headers around the quorum.
+	for _, action := range []string{"seedBlocks", "replaceBlock", "replaceBlocks", "prune"} {
+		r = callAction(t, w, action, added.Blocks[160:], "did:vsc:oracle:ltc")
+		assert.False(t, r.Success, "%s by the single oracle address should fail with an oracle set", action)
+	}
+	r = callAction(t, w, "prune", "", "")
+	require.True(t, r.Success, "prune by the owner failed: %s %s", r.Err, r.ErrMsg)
+
+	r = callAction(t, w, "setOracles", `{"oracles":[],"threshold":0}`, "")
+	require.True(t, r.Success, "clearing the oracle set failed: %s %s", r.Err, r.ErrMsg)
+	assert.Empty(t, ct.StateGet(testContractId, constants.OracleSubmissionPrefix+"hive:oracle2"))
+}
+
+func TestAddBlocksFeeRateWindow(t *testing.T) {
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	w := &ctWrapper{ct: &ct}
+	ct.RegisterContract(testContractId, testOwner, ContractWasm)
+	seedViaAction(t, w, testContractId)
+
+	var added struct {
+		Blocks string `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(twoBlocksPayload), &added))
+
+	r := callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[:160]+`","latest_fee":10}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 10")
+
+	// The median of 10 and 500 is 255, but one update moves the rate by at
+	// most a quarter.
+	r = callAction(t, w, "addBlocks", `{"blocks":"`+added.Blocks[160:]+`","latest_fee":500}`, "")
+	require.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "base fee: 12")
+	assert.Len(t, ct.StateGet(testContractId, constants.FeeWindowKey), 16)
+}
@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"ltc-mapping-contract/contract/constants"
 	"ltc-mapping-contract/contract/mapping"
+	"ltc-mapping-contract/contract/network"
 
 	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcutil"
@@ -17,6 +19,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"golang.org/x/crypto/scrypt"
 )
 
 // Well-known secp256k1 test vectors: private keys 1 and 2.
@@ -29,6 +32,11 @@ func regtestParams() *chaincfg.Params {
 	return &chaincfg.RegressionNetParams
 }
 
+func regtestNetwork() *network.Network {
+	net, _ := network.Lookup(constants.Regtest)
+	return net
+}
+
 // encodeBalance encodes amount using the same compact big-endian binary
 // format as setAccBal, so the value can be seeded directly into contract state.
 func encodeBalance(t *testing.T, amount int64) string {
@@ -103,8 +111,9 @@ func regtestDestAddress(t *testing.T) string {
 }
 
 // buildRegtestHeader creates a valid regtest block header by mining for a nonce
-// that satisfies the compact target 0x207fffff (hash must be ≤ 7fffff000...0).
-// On average this needs ~2 iterations since ~50% of random hashes pass.
+// whose scrypt hash satisfies the compact target 0x207fffff (hash must be
+// ≤ 7fffff000...0). On average this needs ~2 iterations since ~50% of random
+// hashes pass.
 func buildRegtestHeader(prevBlock, merkleRoot chainhash.Hash, ts time.Time) *wire.BlockHeader {
 	h := &wire.BlockHeader{
 		Version:    1,
@@ -115,9 +124,18 @@ func buildRegtestHeader(prevBlock, merkleRoot chainhash.Hash, ts time.Time) *wir
 		Nonce:      0,
 	}
 	target := blockchain.CompactToBig(0x207fffff)
+	var buf bytes.Buffer
 	for {
-		hash := h.BlockHash()
-		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+		buf.Reset()
+		if err := h.Serialize(&buf); err != nil {
+			panic(err)
+		}
+		sum, err := scrypt.Key(buf.Bytes(), buf.Bytes(), 1024, 1, 1, 32)
+		if err != nil {
+			panic(err)
+		}
+		powHash := chainhash.Hash(sum)
+		if blockchain.HashToBig(&powHash).Cmp(target) <= 0 {
 			return h
 		}
 		h.Nonce++
@@ -151,7 +169,8 @@ func buildTestTx(t *testing.T, toAddress string, amount int64) *wire.MsgTx {
 	t.Helper()
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.AddTxIn(&wire.TxIn{
-		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{}, Index: 0xffffffff},
+		// any non-null outpoint; a null one would make this a coinbase
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0},
 		SignatureScript:  []byte{0x00},
 		Sequence:         wire.MaxTxInSequenceNum,
 	})
@@ -182,7 +201,7 @@ func serializeTx(t *testing.T, tx *wire.MsgTx) string {
 // matching the on-chain address derivation.
 func depositUtxoBinary(t *testing.T, txId string, vout uint32, amount int64, instruction string) string {
 	t.Helper()
-	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams())
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive deposit address:", err)
 	}
@@ -210,7 +229,7 @@ func depositUtxoBinary(t *testing.T, txId string, vout uint32, amount int64, ins
 func changeUtxoBinary(t *testing.T, txId string, vout uint32, amount int64) string {
 	t.Helper()
 	// nil tag → change address path (OP_CHECKSIGVERIFY + OP_DATA_0)
-	address, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestParams())
+	address, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive change address:", err)
 	}
@@ -246,7 +265,7 @@ type MapTestFixture struct {
 // empty proof, TxIndex = 0) so the on-chain Merkle verification trivially passes.
 func buildMapFixture(t *testing.T, instruction string, amount int64, blockHeight uint32) MapTestFixture {
 	t.Helper()
-	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestParams())
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive deposit address:", err)
 	}
@@ -278,7 +297,7 @@ type ConfirmSpendFixture struct {
 // empty proof, TxIndex = 0) so the on-chain Merkle verification trivially passes.
 func buildConfirmSpendFixture(t *testing.T, blockHeight uint32) ConfirmSpendFixture {
 	t.Helper()
-	changeAddr, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestParams())
+	changeAddr, _, err := mapping.AddressWithBackup(TestPrimaryPubKeyHex, TestBackupPubKeyHex, nil, regtestNetwork())
 	if err != nil {
 		t.Fatal("failed to derive change address:", err)
 	}
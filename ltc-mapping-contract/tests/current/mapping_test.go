@@ -1,10 +1,15 @@
 package current_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"ltc-mapping-contract/contract/blocklist"
 	"ltc-mapping-contract/contract/constants"
 	"ltc-mapping-contract/contract/mapping"
-	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,7 +18,10 @@ import (
 	stateEngine "vsc-node/modules/state-processing"
 
 	"github.com/CosmWasm/tinyjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	btcMapping "ltc-mapping-contract"
 )
@@ -82,6 +90,494 @@ func TestMap(t *testing.T) {
 	fmt.Println("Return value:", r.Ret)
 }
 
+func TestMapRejectsBlockBelowMinConfirmations(t *testing.T) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const blockHeight = uint32(100)
+
+	fixture := buildMapFixture(t, instruction, 10000, blockHeight)
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{BaseFeeRate: 1})))
+	ct.StateSet(contractId, constants.LastHeightKey, "105")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", decodeHex(t, fixture.BlockHeaderHex))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	setPayload, err := tinyjson.Marshal(mapping.SetMinConfirmationsParams{
+		Action:        constants.MinConfirmationsMap,
+		Confirmations: 7,
+	})
+	if err != nil {
+		t.Fatal("error marshalling params:", err)
+	}
+	r := ct.Call(stateEngine.TxVscCallContract{
+		Self: stateEngine.TxSelf{
+			TxId:                 "setmc",
+			BlockId:              "block:setmc",
+			Index:                0,
+			OpIndex:              0,
+			Timestamp:            "2025-10-14T00:00:00",
+			RequiredAuths:        []string{"hive:milo-hpr"},
+			RequiredPostingAuths: []string{},
+		},
+		ContractId: contractId,
+		Action:     "setMinConfirmations",
+		Payload:    setPayload,
+		RcLimit:    10000,
+		Intents:    []contracts.Intent{},
+		Caller:     "hive:milo-hpr",
+	})
+	assert.True(t, r.Success, "setMinConfirmations failed: %s %s", r.Err, r.ErrMsg)
+
+	payload, err := tinyjson.Marshal(mapping.MapParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight:    blockHeight,
+			RawTxHex:       fixture.RawTxHex,
+			MerkleProofHex: fixture.MerkleProofHex,
+			TxIndex:        fixture.TxIndex,
+		},
+		Instructions: []string{instruction},
+	})
+	if err != nil {
+		t.Fatal("error marshalling params:", err)
+	}
+	mapCall := func(txId string) stateEngine.TxVscCallContract {
+		return stateEngine.TxVscCallContract{
+			Self: stateEngine.TxSelf{
+				TxId:                 txId,
+				BlockId:              "block:" + txId,
+				Index:                69,
+				OpIndex:              0,
+				Timestamp:            "2025-10-14T00:00:00",
+				RequiredAuths:        []string{"hive:milo-hpr"},
+				RequiredPostingAuths: []string{},
+			},
+			ContractId: contractId,
+			Action:     "map",
+			Payload:    payload,
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     "hive:milo-hpr",
+		}
+	}
+
+	// 6 confirmations (100..105) is one short of the required 7.
+	r = ct.Call(mapCall("shallow"))
+	assert.False(t, r.Success, "map should fail below the minimum confirmation depth")
+	assert.Contains(t, r.ErrMsg, "confirmations")
+
+	ct.StateSet(contractId, constants.LastHeightKey, "106")
+	r = ct.Call(mapCall("deep"))
+	assert.True(t, r.Success, "map failed at the minimum confirmation depth: %s %s", r.Err, r.ErrMsg)
+}
+
+func TestMapOrphanedDepositIsClawedBack(t *testing.T) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const blockHeight = uint32(100)
+
+	fixture := buildMapFixture(t, instruction, 10000, blockHeight)
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	parent := buildRegtestHeader(chainhash.Hash{}, chainhash.Hash{}, ts)
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{BaseFeeRate: 1})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"99", serializeHeaderRaw(t, parent))
+	ct.StateSet(contractId, constants.BlockPrefix+"100", decodeHex(t, fixture.BlockHeaderHex))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	call := func(action string, payload []byte) test_utils.ContractTestCallResult {
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self: stateEngine.TxSelf{
+				TxId:                 action,
+				BlockId:              "block:" + action,
+				Index:                69,
+				OpIndex:              0,
+				Timestamp:            "2025-10-14T00:00:00",
+				RequiredAuths:        []string{"hive:milo-hpr"},
+				RequiredPostingAuths: []string{},
+			},
+			ContractId: contractId,
+			Action:     action,
+			Payload:    payload,
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     "hive:milo-hpr",
+		})
+	}
+
+	payload, err := tinyjson.Marshal(mapping.MapParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight:    blockHeight,
+			RawTxHex:       fixture.RawTxHex,
+			MerkleProofHex: fixture.MerkleProofHex,
+			TxIndex:        fixture.TxIndex,
+		},
+		Instructions: []string{instruction},
+	})
+	if err != nil {
+		t.Fatal("error marshalling params:", err)
+	}
+	r := call("map", payload)
+	assert.True(t, r.Success, "map failed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.NotEmpty(t, ct.StateGet(contractId, constants.MintJournalPrefix+"100"))
+
+	// Replace block 100 with one that does not contain the deposit.
+	replacement := buildRegtestHeader(parent.BlockHash(), chainhash.Hash{}, ts.Add(time.Minute))
+	r = call("replaceBlock", []byte(serializeHeader(t, replacement)))
+	assert.True(t, r.Success, "replaceBlock failed: %s %s", r.Err, r.ErrMsg)
+	assert.NotEmpty(t, ct.StateGet(contractId, constants.OrphanedMintsKey))
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+
+	// Move the tip past the re-proof window without the deposit reappearing.
+	var blocks strings.Builder
+	prev := replacement
+	for i := uint32(1); i <= constants.MintReproveWindow+1; i++ {
+		next := buildRegtestHeader(prev.BlockHash(), chainhash.Hash{}, ts.Add(time.Duration(i+1)*time.Minute))
+		blocks.WriteString(serializeHeader(t, next))
+		prev = next
+	}
+	r = call("addBlocks", []byte(`{"blocks":"`+blocks.String()+`","latest_fee":1}`))
+	assert.True(t, r.Success, "addBlocks failed: %s %s", r.Err, r.ErrMsg)
+	dumpLogs(t, r.Logs)
+
+	assert.Equal(t, "", ct.StateGet(contractId, constants.OrphanedMintsKey))
+	assert.Equal(t, "", ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.Equal(t, "", ct.StateGet(contractId, constants.MintDeficitKey))
+}
+
+func TestMapPendingDepositFinalizes(t *testing.T) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const blockHeight = uint32(100)
+
+	fixture := buildMapFixture(t, instruction, 10000, blockHeight)
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{BaseFeeRate: 1})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", decodeHex(t, fixture.BlockHeaderHex))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	call := func(action string, payload []byte) test_utils.ContractTestCallResult {
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self: stateEngine.TxSelf{
+				TxId:                 action,
+				BlockId:              "block:" + action,
+				Index:                69,
+				OpIndex:              0,
+				Timestamp:            "2025-10-14T00:00:00",
+				RequiredAuths:        []string{"hive:milo-hpr"},
+				RequiredPostingAuths: []string{},
+			},
+			ContractId: contractId,
+			Action:     action,
+			Payload:    payload,
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     "hive:milo-hpr",
+		})
+	}
+
+	r := call("setDepositFinality", []byte("3"))
+	assert.True(t, r.Success, "setDepositFinality failed: %s %s", r.Err, r.ErrMsg)
+
+	payload, err := tinyjson.Marshal(mapping.MapParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight:    blockHeight,
+			RawTxHex:       fixture.RawTxHex,
+			MerkleProofHex: fixture.MerkleProofHex,
+			TxIndex:        fixture.TxIndex,
+		},
+		Instructions: []string{instruction},
+	})
+	if err != nil {
+		t.Fatal("error marshalling params:", err)
+	}
+	r = call("map", payload)
+	assert.True(t, r.Success, "map failed: %s %s", r.Err, r.ErrMsg)
+	dumpLogs(t, r.Logs)
+
+	// Proven but not spendable yet.
+	assert.Equal(t, "", ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.NotEmpty(t, ct.StateGet(contractId, constants.PendingDepositQueueKey))
+
+	r = call("finalizeDeposits", []byte("{}"))
+	assert.True(t, r.Success, "finalizeDeposits failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "finalized 0")
+	assert.Equal(t, "", ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+
+	ct.StateSet(contractId, constants.LastHeightKey, "103")
+	r = call("finalizeDeposits", []byte("{}"))
+	assert.True(t, r.Success, "finalizeDeposits failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "finalized 1")
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.Equal(t, "", ct.StateGet(contractId, constants.PendingDepositQueueKey))
+}
+
+func TestMapCoinbaseDepositWaitsForMaturity(t *testing.T) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const blockHeight = uint32(100)
+
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
+	if err != nil {
+		t.Fatal("failed to derive deposit address:", err)
+	}
+	tx := buildTestTx(t, address, 10000)
+	tx.TxIn[0].PreviousOutPoint = wire.OutPoint{Index: wire.MaxPrevOutIndex}
+	header := buildRegtestHeader(chainhash.Hash{}, tx.TxHash(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{BaseFeeRate: 1})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", serializeHeaderRaw(t, header))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	call := func(action string, payload []byte) test_utils.ContractTestCallResult {
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self: stateEngine.TxSelf{
+				TxId:                 action,
+				BlockId:              "block:" + action,
+				Index:                69,
+				OpIndex:              0,
+				Timestamp:            "2025-10-14T00:00:00",
+				RequiredAuths:        []string{"hive:milo-hpr"},
+				RequiredPostingAuths: []string{},
+			},
+			ContractId: contractId,
+			Action:     action,
+			Payload:    payload,
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     "hive:milo-hpr",
+		})
+	}
+
+	payload, err := tinyjson.Marshal(mapping.MapParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight: blockHeight,
+			RawTxHex:    serializeTx(t, tx),
+		},
+		Instructions: []string{instruction},
+	})
+	if err != nil {
+		t.Fatal("error marshalling params:", err)
+	}
+	r := call("map", payload)
+	assert.True(t, r.Success, "map failed: %s %s", r.Err, r.ErrMsg)
+	dumpLogs(t, r.Logs)
+
+	// An immature coinbase is held even without a deposit finality.
+	assert.Equal(t, "", ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.NotEmpty(t, ct.StateGet(contractId, constants.PendingDepositQueueKey))
+
+	ct.StateSet(contractId, constants.LastHeightKey, "199")
+	r = call("finalizeDeposits", []byte("{}"))
+	assert.True(t, r.Success, "finalizeDeposits failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "finalized 0")
+
+	ct.StateSet(contractId, constants.LastHeightKey, "200")
+	r = call("finalizeDeposits", []byte("{}"))
+	assert.True(t, r.Success, "finalizeDeposits failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, "finalized 1")
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+}
+
+// TestUnmapCannotSpendImmatureCoinbase maps a coinbase deposit and then
+// unmaps against a balance credited elsewhere: the held output is not in the
+// UTXO pool, so the withdrawal has nothing to spend until the coinbase
+// matures.
+func TestUnmapCannotSpendImmatureCoinbase(t *testing.T) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const blockHeight = uint32(100)
+
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
+	if err != nil {
+		t.Fatal("failed to derive deposit address:", err)
+	}
+	tx := buildTestTx(t, address, 10000)
+	tx.TxIn[0].PreviousOutPoint = wire.OutPoint{Index: wire.MaxPrevOutIndex}
+	header := buildRegtestHeader(chainhash.Hash{}, tx.TxHash(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{BaseFeeRate: 1})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", serializeHeaderRaw(t, header))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	call := func(action string, payload []byte) test_utils.ContractTestCallResult {
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self: stateEngine.TxSelf{
+				TxId:                 action,
+				BlockId:              "block:" + action,
+				Index:                69,
+				OpIndex:              0,
+				Timestamp:            "2025-10-14T00:00:00",
+				RequiredAuths:        []string{"hive:milo-hpr"},
+				RequiredPostingAuths: []string{},
+			},
+			ContractId: contractId,
+			Action:     action,
+			Payload:    payload,
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     "hive:milo-hpr",
+		})
+	}
+
+	payload, err := tinyjson.Marshal(mapping.MapParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight: blockHeight,
+			RawTxHex:    serializeTx(t, tx),
+		},
+		Instructions: []string{instruction},
+	})
+	if err != nil {
+		t.Fatal("error marshalling params:", err)
+	}
+	r := call("map", payload)
+	require.True(t, r.Success, "map failed: %s %s", r.Err, r.ErrMsg)
+	assert.Empty(t, ct.StateGet(contractId, constants.UtxoRegistryKey))
+
+	// A balance credited by some other route, backed by nothing in the pool.
+	ct.StateSet(contractId, constants.BalancePrefix+"hive:milo-hpr", encodeBalance(t, 10000))
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
+		ActiveSupply: 10000,
+		UserSupply:   10000,
+		BaseFeeRate:  1,
+	})))
+
+	payload, err = tinyjson.Marshal(mapping.TransferParams{
+		Amount: "7500",
+		To:     regtestDestAddress(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = call("unmap", payload)
+	dumpLogs(t, r.Logs)
+	assert.False(t, r.Success, "unmap must not spend an immature coinbase")
+	assert.Empty(t, ct.StateGet(contractId, constants.UtxoRegistryKey))
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.NotEmpty(t, ct.StateGet(contractId, constants.PendingDepositQueueKey))
+}
+
+func TestMapArchivedDeposit(t *testing.T) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const blockHeight = uint32(100)
+
+	address, _, err := mapping.DepositAddress(TestPrimaryPubKeyHex, TestBackupPubKeyHex, instruction, regtestNetwork())
+	if err != nil {
+		t.Fatal("failed to derive deposit address:", err)
+	}
+	tx := buildTestTx(t, address, 10000)
+	header := buildRegtestHeader(chainhash.Hash{}, tx.TxHash(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// The header at 100 has been pruned into a two-leaf archive starting
+	// at 99, so its proof is the leaf of block 99. A leaf pairs the block
+	// hash with the hash of its observed list, empty until the map.
+	sibling := chainhash.Hash{0x99}
+	blockHash := header.BlockHash()
+	archiveOf := func(observed string) string {
+		observedHash := chainhash.DoubleHashH([]byte(observed))
+		leaf := chainhash.DoubleHashH(append(blockHash[:], observedHash[:]...))
+		peak := chainhash.DoubleHashH(append(sibling[:], leaf[:]...))
+		archive := binary.BigEndian.AppendUint32(nil, 99)
+		archive = binary.BigEndian.AppendUint64(archive, 2)
+		return string(append(archive, peak[:]...))
+	}
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, "hive:milo-hpr", ContractWasm)
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{BaseFeeRate: 1})))
+	ct.StateSet(contractId, constants.LastHeightKey, "2000")
+	ct.StateSet(contractId, constants.PruneFloorKey, "101")
+	ct.StateSet(contractId, constants.HeaderMMRKey, archiveOf(""))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	mapWith := func(archivalHeader, archiveProof, observed string) test_utils.ContractTestCallResult {
+		payload, err := tinyjson.Marshal(mapping.MapParams{
+			TxData: &mapping.VerificationRequest{
+				BlockHeight:         blockHeight,
+				RawTxHex:            serializeTx(t, tx),
+				ArchivalHeaderHex:   archivalHeader,
+				ArchiveProofHex:     archiveProof,
+				ArchivedObservedHex: hex.EncodeToString([]byte(observed)),
+			},
+			Instructions: []string{instruction},
+		})
+		if err != nil {
+			t.Fatal("error marshalling params:", err)
+		}
+		return ct.Call(stateEngine.TxVscCallContract{
+			Self: stateEngine.TxSelf{
+				TxId:                 "map",
+				BlockId:              "block:map",
+				Index:                69,
+				OpIndex:              0,
+				Timestamp:            "2025-10-14T00:00:00",
+				RequiredAuths:        []string{"hive:milo-hpr"},
+				RequiredPostingAuths: []string{},
+			},
+			ContractId: contractId,
+			Action:     "map",
+			Payload:    payload,
+			RcLimit:    10000,
+			Intents:    []contracts.Intent{},
+			Caller:     "hive:milo-hpr",
+		})
+	}
+	headerHex := hex.EncodeToString([]byte(serializeHeaderRaw(t, header)))
+
+	r := mapWith("", "", "")
+	assert.False(t, r.Success, "map against a pruned block without an archival header should fail")
+	wrong := chainhash.Hash{0x98}
+	r = mapWith(headerHex, hex.EncodeToString(wrong[:]), "")
+	assert.False(t, r.Success, "map with a wrong archive proof should fail")
+
+	r = mapWith(headerHex, hex.EncodeToString(sibling[:]), "")
+	assert.True(t, r.Success, "map failed: %s %s", r.Err, r.ErrMsg)
+	dumpLogs(t, r.Logs)
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.Equal(t, "", ct.StateGet(contractId, constants.MintJournalPrefix+"100"), "a pruned block needs no mint journal")
+
+	// The deposit is now in the block's archived observed list, not in state.
+	observed := buildObservedList(t, observedParam{tx.TxID(), 0})
+	assert.Equal(t, archiveOf(observed), ct.StateGet(contractId, constants.HeaderMMRKey))
+	assert.Equal(t, "", ct.StateGet(contractId, constants.ObservedBlockPrefix+"100"))
+
+	// The stale empty list no longer matches the archive, so the deposit
+	// cannot be credited twice.
+	r = mapWith(headerHex, hex.EncodeToString(sibling[:]), "")
+	assert.False(t, r.Success, "map with a stale observed list should fail")
+	r = mapWith(headerHex, hex.EncodeToString(sibling[:]), observed)
+	assert.True(t, r.Success, "map failed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, encodeBalance(t, 10000), ct.StateGet(contractId, constants.BalancePrefix+"hive:milo-hpr"))
+	assert.Equal(t, archiveOf(observed), ct.StateGet(contractId, constants.HeaderMMRKey))
+}
+
 func TestUnmap(t *testing.T) {
 	const instruction = "deposit_to=hive:milo-hpr"
 	const fakeTxId0 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
@@ -154,6 +650,388 @@ func TestUnmap(t *testing.T) {
 	fmt.Println("Return value:", r.Ret)
 }
 
+// TestCancelledSpendConfirmsLate cancels a spend that TSS may already have
+// signed and then proves it in a block. The sender can cancel it and is
+// refunded at once, leaving the supply as before the unmap. The late
+// confirmation claws the refund back, as far as the sender still holds it,
+// and leaves the supply as if the spend had never been cancelled.
+func TestCancelledSpendConfirmsLate(t *testing.T) {
+	t.Run("refund kept", func(t *testing.T) {
+		testCancelledSpendConfirmsLate(t, false)
+	})
+	t.Run("refund spent", func(t *testing.T) {
+		testCancelledSpendConfirmsLate(t, true)
+	})
+}
+
+func testCancelledSpendConfirmsLate(t *testing.T, spendRefund bool) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const fakeTxId0 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	const fakeTxId1 = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	const owner = "hive:owner"
+	const sender = "hive:milo-hpr"
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, owner, ContractWasm)
+	ct.StateSet(contractId, constants.BalancePrefix+sender, encodeBalance(t, 10000))
+	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
+		{Id: 1024, Amount: 5000},
+		{Id: 1025, Amount: 5000},
+	})))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5000))
+	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1026, 0))
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
+		ActiveSupply: 10000,
+		UserSupply:   10000,
+		BaseFeeRate:  1,
+	})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", buildSeedHeaderRaw(t, time.Unix(0, 0)))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	w := &ctWrapper{ct: &ct}
+	balanceBefore := ct.StateGet(contractId, constants.BalancePrefix+sender)
+	supplyBefore := ct.StateGet(contractId, constants.SupplyKey)
+	payload, err := tinyjson.Marshal(mapping.TransferParams{
+		Amount: "7500",
+		To:     regtestDestAddress(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := callActionOnContract(t, w, contractId, "unmap", string(payload), sender)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "unmap failed: %s %s", r.Err, r.ErrMsg)
+	balance := ct.StateGet(contractId, constants.BalancePrefix+sender)
+	supply := ct.StateGet(contractId, constants.SupplyKey)
+
+	spends, err := mapping.UnmarshalTxSpendsRegistry([]byte(ct.StateGet(contractId, constants.TxSpendsRegistryKey)))
+	require.NoError(t, err)
+	require.Len(t, spends, 1)
+	txId := spends[0]
+	sd, err := mapping.UnmarshalSigningData([]byte(ct.StateGet(contractId, constants.TxSpendsPrefix+txId)))
+	require.NoError(t, err)
+	require.Len(t, sd.Payouts, 1)
+	withdrawalId := strconv.FormatUint(sd.Payouts[0].Id, 10)
+
+	r = callActionOnContract(t, w, contractId, "setWithdrawalTimeout", "1", owner)
+	require.True(t, r.Success, "setWithdrawalTimeout failed: %s %s", r.Err, r.ErrMsg)
+	ct.IncrementBlocks(2)
+
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", withdrawalId, "hive:someone-else")
+	assert.False(t, r.Success, "only the owner or the sender can cancel a withdrawal")
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", withdrawalId, sender)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "cancelWithdrawal failed: %s %s", r.Err, r.ErrMsg)
+	assert.Equal(t, balanceBefore, ct.StateGet(contractId, constants.BalancePrefix+sender), "the sender is refunded in full")
+	assert.Equal(t, supplyBefore, ct.StateGet(contractId, constants.SupplyKey), "the supply is as before the unmap")
+	assert.NotEmpty(t, ct.StateGet(contractId, constants.CancelledSpendPrefix+txId), "the spend may still confirm")
+	r = callActionOnContract(t, w, contractId, "getWithdrawal", withdrawalId, sender)
+	require.True(t, r.Success, "getWithdrawal failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, `"status":"cancelled"`)
+
+	if spendRefund {
+		payload, err := tinyjson.Marshal(mapping.TransferParams{Amount: "10000", To: "hive:vaultec"})
+		require.NoError(t, err)
+		r = callActionOnContract(t, w, contractId, "transfer", string(payload), sender)
+		require.True(t, r.Success, "transfer failed: %s %s", r.Err, r.ErrMsg)
+	}
+
+	// The cancelled spend is broadcast anyway and confirms.
+	txHash, err := chainhash.NewHashFromStr(txId)
+	require.NoError(t, err)
+	header := buildRegtestHeader(chainhash.Hash{}, *txHash, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	ct.StateSet(contractId, constants.BlockPrefix+"101", serializeHeaderRaw(t, header))
+	ct.StateSet(contractId, constants.LastHeightKey, "101")
+
+	r = callConfirmSpend(t, &ct, contractId, sender, mapping.ConfirmSpendParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight:    101,
+			RawTxHex:       hex.EncodeToString(sd.Tx),
+			MerkleProofHex: "",
+			TxIndex:        0,
+		},
+		Indices: []uint32{0},
+	})
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "confirmSpend failed: %s %s", r.Err, r.ErrMsg)
+	assert.Empty(t, ct.StateGet(contractId, constants.CancelledSpendPrefix+txId))
+	if spendRefund {
+		// Nothing is left to claw back; the whole refund becomes deficit,
+		// and stays in the active supply until it is covered.
+		debit := decodeBalance(t, balanceBefore) - decodeBalance(t, balance)
+		assert.Equal(t, encodeBalance(t, 0), ct.StateGet(contractId, constants.BalancePrefix+sender))
+		assert.Equal(t, strconv.FormatInt(debit, 10), ct.StateGet(contractId, constants.MintDeficitKey))
+		want, err := mapping.UnmarshalSupply([]byte(supply))
+		require.NoError(t, err)
+		want.ActiveSupply += debit
+		want.UserSupply += debit // now held by the account it was sent to
+		assert.Equal(t, string(mapping.MarshalSupply(want)), ct.StateGet(contractId, constants.SupplyKey))
+	} else {
+		assert.Empty(t, ct.StateGet(contractId, constants.MintDeficitKey), "a refund still held leaves no deficit")
+		assert.Equal(t, balance, ct.StateGet(contractId, constants.BalancePrefix+sender))
+		assert.Equal(t, supply, ct.StateGet(contractId, constants.SupplyKey), "the spend is paid as if never cancelled")
+	}
+
+	r = callActionOnContract(t, w, contractId, "getWithdrawal", withdrawalId, sender)
+	require.True(t, r.Success, "getWithdrawal failed: %s %s", r.Err, r.ErrMsg)
+	assert.Contains(t, r.Ret, `"status":"confirmed"`)
+}
+
+// TestBumpedSpendOriginalConfirms bumps the fee of a spend and confirms the
+// original instead. Whoever paid the added fee gets it back, and the supply
+// ends up as if the spend had never been bumped.
+func TestBumpedSpendOriginalConfirms(t *testing.T) {
+	for _, payer := range []string{constants.CpfpPayerFees, constants.CpfpPayerWithdrawers} {
+		t.Run(payer, func(t *testing.T) {
+			testBumpedSpendOriginalConfirms(t, payer)
+		})
+	}
+}
+
+func testBumpedSpendOriginalConfirms(t *testing.T, payer string) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const fakeTxId0 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	const fakeTxId1 = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	const owner = "hive:owner"
+	const sender = "hive:milo-hpr"
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, owner, ContractWasm)
+	ct.StateSet(contractId, constants.BalancePrefix+sender, encodeBalance(t, 10000))
+	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
+		{Id: 1024, Amount: 5000},
+		{Id: 1025, Amount: 5000},
+	})))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5000))
+	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1026, 0))
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
+		ActiveSupply: 10000,
+		UserSupply:   10000,
+		BaseFeeRate:  1,
+	})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", buildSeedHeaderRaw(t, time.Unix(0, 0)))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	w := &ctWrapper{ct: &ct}
+	r := callActionOnContract(t, w, contractId, "setCpfpPayer", payer, owner)
+	require.True(t, r.Success, "setCpfpPayer failed: %s %s", r.Err, r.ErrMsg)
+	payload, err := tinyjson.Marshal(mapping.TransferParams{
+		Amount: "6000",
+		To:     regtestDestAddress(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = callActionOnContract(t, w, contractId, "unmap", string(payload), sender)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "unmap failed: %s %s", r.Err, r.ErrMsg)
+
+	spends, err := mapping.UnmarshalTxSpendsRegistry([]byte(ct.StateGet(contractId, constants.TxSpendsRegistryKey)))
+	require.NoError(t, err)
+	require.Len(t, spends, 1)
+	txId := spends[0]
+	sd, err := mapping.UnmarshalSigningData([]byte(ct.StateGet(contractId, constants.TxSpendsPrefix+txId)))
+	require.NoError(t, err)
+
+	// The fee rate rises, and the fee supply has enough to pay for a bump.
+	supply, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	supply.BaseFeeRate = 5
+	supply.FeeSupply += 2000
+	supply.ActiveSupply += 2000
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(supply)))
+	balance := ct.StateGet(contractId, constants.BalancePrefix+sender)
+
+	r = callActionOnContract(t, w, contractId, "bumpFee", txId, owner)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "bumpFee failed: %s %s", r.Err, r.ErrMsg)
+	bumped, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	require.Less(t, bumped.ActiveSupply, supply.ActiveSupply, "the bump pays a higher fee")
+	if payer == constants.CpfpPayerWithdrawers {
+		require.NotEqual(t, balance, ct.StateGet(contractId, constants.BalancePrefix+sender), "the sender pays the added fee")
+	} else {
+		require.Less(t, bumped.FeeSupply, supply.FeeSupply, "the fee supply pays the added fee")
+	}
+
+	// The original confirms instead of the replacement.
+	var tx wire.MsgTx
+	require.NoError(t, tx.Deserialize(bytes.NewReader(sd.Tx)))
+	indices := make([]uint32, len(tx.TxOut))
+	for i := range indices {
+		indices[i] = uint32(i)
+	}
+	txHash, err := chainhash.NewHashFromStr(txId)
+	require.NoError(t, err)
+	header := buildRegtestHeader(chainhash.Hash{}, *txHash, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	ct.StateSet(contractId, constants.BlockPrefix+"101", serializeHeaderRaw(t, header))
+	ct.StateSet(contractId, constants.LastHeightKey, "101")
+
+	r = callConfirmSpend(t, &ct, contractId, sender, mapping.ConfirmSpendParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight:    101,
+			RawTxHex:       hex.EncodeToString(sd.Tx),
+			MerkleProofHex: "",
+			TxIndex:        0,
+		},
+		Indices: indices,
+	})
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "confirmSpend failed: %s %s", r.Err, r.ErrMsg)
+
+	confirmed, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	assert.Equal(t, supply.ActiveSupply, confirmed.ActiveSupply, "active supply")
+	assert.Equal(t, supply.UserSupply, confirmed.UserSupply, "user supply")
+	assert.Equal(t, supply.FeeSupply, confirmed.FeeSupply, "fee supply")
+	assert.Equal(t, balance, ct.StateGet(contractId, constants.BalancePrefix+sender), "the sender's balance")
+	spends, err = mapping.UnmarshalTxSpendsRegistry([]byte(ct.StateGet(contractId, constants.TxSpendsRegistryKey)))
+	require.NoError(t, err)
+	assert.Empty(t, spends, "both versions of the spend are retired")
+}
+
+// TestBumpedSpendCancelled bumps the fee of a spend, cancels it, and then
+// confirms the original. Cancelling refunds the sender and whoever paid the
+// added fee, leaving the supply as before the unmap. The original confirming
+// claws back only the sender's refund, as the added fee was never paid.
+func TestBumpedSpendCancelled(t *testing.T) {
+	for _, payer := range []string{constants.CpfpPayerFees, constants.CpfpPayerWithdrawers} {
+		t.Run(payer, func(t *testing.T) {
+			testBumpedSpendCancelled(t, payer)
+		})
+	}
+}
+
+func testBumpedSpendCancelled(t *testing.T, payer string) {
+	const instruction = "deposit_to=hive:milo-hpr"
+	const fakeTxId0 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	const fakeTxId1 = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	const owner = "hive:owner"
+	const sender = "hive:milo-hpr"
+
+	ct := test_utils.NewContractTest()
+	t.Cleanup(func() { ct.DataLayer.Stop() })
+	contractId := "mapping_contract"
+	ct.RegisterContract(contractId, owner, ContractWasm)
+	ct.StateSet(contractId, constants.BalancePrefix+sender, encodeBalance(t, 10000))
+	ct.StateSet(contractId, constants.UtxoRegistryKey, string(mapping.MarshalUtxoRegistry(mapping.UtxoRegistry{
+		{Id: 1024, Amount: 5000},
+		{Id: 1025, Amount: 5000},
+	})))
+	ct.StateSet(contractId, constants.UtxoPrefix+"400", depositUtxoBinary(t, fakeTxId0, 0, 5000, instruction))
+	ct.StateSet(contractId, constants.UtxoPrefix+"401", changeUtxoBinary(t, fakeTxId1, 0, 5000))
+	ct.StateSet(contractId, constants.UtxoLastIdKey, encodeUtxoCounters(1026, 0))
+	// The fee supply has enough to pay for a bump.
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(&mapping.SystemSupply{
+		ActiveSupply: 12000,
+		UserSupply:   10000,
+		FeeSupply:    2000,
+		BaseFeeRate:  1,
+	})))
+	ct.StateSet(contractId, constants.LastHeightKey, "100")
+	ct.StateSet(contractId, constants.BlockPrefix+"100", buildSeedHeaderRaw(t, time.Unix(0, 0)))
+	ct.StateSet(contractId, constants.PrimaryPublicKeyStateKey, decodeHex(t, TestPrimaryPubKeyHex))
+	ct.StateSet(contractId, constants.BackupPublicKeyStateKey, decodeHex(t, TestBackupPubKeyHex))
+
+	w := &ctWrapper{ct: &ct}
+	r := callActionOnContract(t, w, contractId, "setCpfpPayer", payer, owner)
+	require.True(t, r.Success, "setCpfpPayer failed: %s %s", r.Err, r.ErrMsg)
+	balanceBefore := ct.StateGet(contractId, constants.BalancePrefix+sender)
+	supplyBefore, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	payload, err := tinyjson.Marshal(mapping.TransferParams{
+		Amount: "6000",
+		To:     regtestDestAddress(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = callActionOnContract(t, w, contractId, "unmap", string(payload), sender)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "unmap failed: %s %s", r.Err, r.ErrMsg)
+
+	spends, err := mapping.UnmarshalTxSpendsRegistry([]byte(ct.StateGet(contractId, constants.TxSpendsRegistryKey)))
+	require.NoError(t, err)
+	require.Len(t, spends, 1)
+	txId := spends[0]
+	sd, err := mapping.UnmarshalSigningData([]byte(ct.StateGet(contractId, constants.TxSpendsPrefix+txId)))
+	require.NoError(t, err)
+	require.Len(t, sd.Payouts, 1)
+	withdrawalId := strconv.FormatUint(sd.Payouts[0].Id, 10)
+
+	// The fee rate rises.
+	supply, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	supply.BaseFeeRate = 5
+	supplyBefore.BaseFeeRate = 5
+	ct.StateSet(contractId, constants.SupplyKey, string(mapping.MarshalSupply(supply)))
+	balance := ct.StateGet(contractId, constants.BalancePrefix+sender)
+
+	r = callActionOnContract(t, w, contractId, "bumpFee", txId, owner)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "bumpFee failed: %s %s", r.Err, r.ErrMsg)
+	bumped, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	require.Less(t, bumped.ActiveSupply, supply.ActiveSupply, "the bump pays a higher fee")
+
+	r = callActionOnContract(t, w, contractId, "setWithdrawalTimeout", "1", owner)
+	require.True(t, r.Success, "setWithdrawalTimeout failed: %s %s", r.Err, r.ErrMsg)
+	ct.IncrementBlocks(2)
+	r = callActionOnContract(t, w, contractId, "cancelWithdrawal", withdrawalId, sender)
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "cancelWithdrawal failed: %s %s", r.Err, r.ErrMsg)
+
+	cancelled, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	assert.Equal(t, supplyBefore.ActiveSupply, cancelled.ActiveSupply, "active supply")
+	assert.Equal(t, supplyBefore.UserSupply, cancelled.UserSupply, "user supply")
+	assert.Equal(t, supplyBefore.FeeSupply, cancelled.FeeSupply, "fee supply")
+	assert.Equal(t, balanceBefore, ct.StateGet(contractId, constants.BalancePrefix+sender), "the sender's balance")
+
+	// The original confirms after all.
+	var tx wire.MsgTx
+	require.NoError(t, tx.Deserialize(bytes.NewReader(sd.Tx)))
+	indices := make([]uint32, len(tx.TxOut))
+	for i := range indices {
+		indices[i] = uint32(i)
+	}
+	txHash, err := chainhash.NewHashFromStr(txId)
+	require.NoError(t, err)
+	header := buildRegtestHeader(chainhash.Hash{}, *txHash, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	ct.StateSet(contractId, constants.BlockPrefix+"101", serializeHeaderRaw(t, header))
+	ct.StateSet(contractId, constants.LastHeightKey, "101")
+
+	r = callConfirmSpend(t, &ct, contractId, sender, mapping.ConfirmSpendParams{
+		TxData: &mapping.VerificationRequest{
+			BlockHeight:    101,
+			RawTxHex:       hex.EncodeToString(sd.Tx),
+			MerkleProofHex: "",
+			TxIndex:        0,
+		},
+		Indices: indices,
+	})
+	dumpLogs(t, r.Logs)
+	require.True(t, r.Success, "confirmSpend failed: %s %s", r.Err, r.ErrMsg)
+
+	confirmed, err := mapping.UnmarshalSupply([]byte(ct.StateGet(contractId, constants.SupplyKey)))
+	require.NoError(t, err)
+	assert.Equal(t, supply.ActiveSupply, confirmed.ActiveSupply, "active supply")
+	assert.Equal(t, supply.UserSupply, confirmed.UserSupply, "user supply")
+	assert.Equal(t, supply.FeeSupply, confirmed.FeeSupply, "fee supply")
+	assert.Equal(t, balance, ct.StateGet(contractId, constants.BalancePrefix+sender), "the sender's balance")
+	assert.Empty(t, ct.StateGet(contractId, constants.MintDeficitKey))
+}
+
 func TestTransfer(t *testing.T) {
 	ct := test_utils.NewContractTest()
 	t.Cleanup(func() { ct.DataLayer.Stop() })